@@ -0,0 +1,665 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/background
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package background
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// Model is the surface fitted to the background samples.
+type Model int
+
+/*****************************************************************************************************************/
+
+const (
+	// A 2D polynomial of the given degree, fitted by least squares, for smooth gradients:
+	Polynomial Model = iota
+	// A bicubic spline through the (median filtered) grid of samples, for more complex gradients:
+	Spline
+)
+
+/*****************************************************************************************************************/
+
+// Returns the name of the background model, e.g., "polynomial".
+func (m Model) String() string {
+	switch m {
+	case Polynomial:
+		return "polynomial"
+	case Spline:
+		return "spline"
+	default:
+		return "unknown"
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Correction is how the background model is removed from the image.
+type Correction int
+
+/*****************************************************************************************************************/
+
+const (
+	// Subtract the background model, i.e., for additive gradients such as light pollution or moonlight:
+	Subtract Correction = iota
+	// Divide by the background model, i.e., for multiplicative gradients such as vignetting:
+	Divide
+)
+
+/*****************************************************************************************************************/
+
+// Returns the name of the background correction, e.g., "subtract".
+func (c Correction) String() string {
+	switch c {
+	case Subtract:
+		return "subtract"
+	case Divide:
+		return "divide"
+	default:
+		return "unknown"
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Params describes how the background is sampled, modelled and removed.
+type Params struct {
+	BoxSize           int        // The size of the (square) sample boxes, in pixels
+	Sigma             float64    // The sigma clipping threshold of the pixels of each box, and of the box residuals
+	Iterations        int        // The number of sigma clipping iterations
+	Model             Model      // The surface fitted to the samples
+	Degree            int        // The degree of the polynomial model
+	FilterSize        int        // The size of the median filter of the grid of samples of the spline model (1 disables)
+	Correction        Correction // How the background model is removed from the image
+	StarRadius        float32    // The radius of the star extraction
+	StarSigma         float32    // The detection threshold of the star extraction
+	StarInOut         float32    // The HFR in-out ratio of the star extraction
+	StarMaskScale     float64    // The radius of the mask of each star, as a multiple of its HFR, up to a quarter of a box (zero disables masking)
+	MaxMaskedFraction float64    // The maximum fraction of masked pixels of a box before it is rejected
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default background parameters, i.e., a subtracted 2nd degree polynomial fitted to 64 pixel boxes.
+func DefaultParams() Params {
+	return Params{
+		BoxSize:           64,
+		Sigma:             3,
+		Iterations:        5,
+		Model:             Polynomial,
+		Degree:            2,
+		FilterSize:        3,
+		Correction:        Subtract,
+		StarRadius:        16,
+		StarSigma:         5,
+		StarInOut:         2,
+		StarMaskScale:     3,
+		MaxMaskedFraction: 0.5,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Sample is the background sampled within a box of the image.
+type Sample struct {
+	X        float64 // The x position of the centre of the box, in pixels
+	Y        float64 // The y position of the centre of the box, in pixels
+	Value    float64 // The sigma-clipped median of the (unmasked) pixels of the box, in ADU
+	Sigma    float64 // The robust standard deviation of the clipped pixels of the box, in ADU
+	Pixels   int     // The number of pixels of the box retained by the mask and sigma clipping
+	Rejected bool    // Whether the sample is excluded from the model
+	Reason   string  // Why the sample is excluded from the model, if it is
+}
+
+/*****************************************************************************************************************/
+
+// Background is the background model of an image.
+type Background struct {
+	Width        int       // The width of the image, in pixels
+	Height       int       // The height of the image, in pixels
+	Params       Params    // The parameters of the model
+	Columns      int       // The number of columns of the grid of sample boxes
+	Rows         int       // The number of rows of the grid of sample boxes
+	Samples      []Sample  // The samples of the grid of boxes, in row-major order
+	Stars        int       // The number of stars masked
+	Coefficients []float64 // The coefficients of the polynomial model, for the terms u^i·v^j (i + j <= degree)
+	Level        float64   // The median of the background model, which is preserved by its removal
+	Data         []float32 // The background model, of the dimensions of the image
+}
+
+/*****************************************************************************************************************/
+
+/*
+Estimate()
+
+Estimates the background of the image from a grid of sample boxes.
+
+Stars are found by StarsExtractor.FindStars(), and masked within a multiple of their HFR. Each
+box is sampled by the sigma-clipped median of its unmasked pixels, and boxes which are mostly
+masked (e.g., by a bright star or nebula) are rejected.
+
+For the polynomial model, the surface is fitted to the samples by least squares, iteratively
+rejecting samples whose residuals are beyond the sigma clipping threshold. For the spline model,
+rejected samples are filled from their nearest neighbours, and the grid of samples is median
+filtered, before a bicubic spline is passed through it.
+*/
+func Estimate(f *fits.FITSImage, params Params) (*Background, error) {
+	xs, ys, ok := f.GetDimensions()
+
+	if !ok || len(f.Data) != xs*ys {
+		return nil, fmt.Errorf("the image data of %d pixels does not match the dimensions %dx%d", len(f.Data), xs, ys)
+	}
+
+	if params.BoxSize < 4 {
+		return nil, errors.New("the sample boxes must be at least 4 pixels")
+	}
+
+	b := &Background{
+		Width:   xs,
+		Height:  ys,
+		Params:  params,
+		Columns: max(xs/params.BoxSize, 1),
+		Rows:    max(ys/params.BoxSize, 1),
+	}
+
+	mask := b.getStarMask(f)
+
+	b.sample(f.Data, mask)
+
+	var err error
+
+	switch params.Model {
+	case Polynomial:
+		err = b.fitPolynomial()
+	case Spline:
+		err = b.fitSpline()
+	default:
+		err = fmt.Errorf("unknown background model: %d", params.Model)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	b.Level = float64(qsort.MedianFloat32(b.Data))
+
+	return b, nil
+}
+
+/*****************************************************************************************************************/
+
+/*
+Remove()
+
+Estimates the background of the image, and removes it by subtraction or division.
+
+The median level of the background is preserved, i.e., the corrected image is the image minus
+the model plus its median level, or the image divided by the model times its median level, such
+that the corrected background is flat at the original sky level.
+
+Returns the corrected image, the background model as an image of the same dimensions, and the
+background model itself, e.g., for its samples.
+*/
+func Remove(f *fits.FITSImage, params Params) (*fits.FITSImage, *fits.FITSImage, *Background, error) {
+	b, err := Estimate(f, params)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	corrected, err := b.Apply(f)
+
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return corrected, b.GetFITSImage(f), b, nil
+}
+
+/*****************************************************************************************************************/
+
+// Removes the background model from the image by its correction, preserving the median level of the background,
+// returning a copy of the image with the corrected data.
+func (b *Background) Apply(f *fits.FITSImage) (*fits.FITSImage, error) {
+	if len(f.Data) != len(b.Data) {
+		return nil, fmt.Errorf("the image data of %d pixels does not match the background model of %d pixels", len(f.Data), len(b.Data))
+	}
+
+	data := make([]float32, len(f.Data))
+
+	switch b.Params.Correction {
+	case Subtract:
+		for i, v := range f.Data {
+			data[i] = v - b.Data[i] + float32(b.Level)
+		}
+	case Divide:
+		for i, m := range b.Data {
+			if m <= 0 {
+				return nil, errors.New("the background model is not positive, so it cannot be divided out")
+			}
+
+			data[i] = f.Data[i] / m * float32(b.Level)
+		}
+	default:
+		return nil, fmt.Errorf("unknown background correction: %d", b.Params.Correction)
+	}
+
+	corrected := f.Copy()
+
+	corrected.Data = data
+
+	corrected.Header.Set("BKGMODEL", b.Params.Model.String(), "The background model removed from the image")
+
+	corrected.Header.Set("BKGCORR", b.Params.Correction.String(), "How the background model was removed")
+
+	corrected.Header.Set("BKGLEVEL", float32(b.Level), "The median level of the background model (ADU)")
+
+	corrected.Header.History = append(
+		corrected.Header.History,
+		fmt.Sprintf("Background removed (%s) by a %s model of %d of %d samples", b.Params.Correction, b.Params.Model, b.GetAcceptedSamples(), len(b.Samples)),
+	)
+
+	return corrected, nil
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the background model as an image, with the header of the given image.
+func (b *Background) GetFITSImage(f *fits.FITSImage) *fits.FITSImage {
+	model := f.Copy()
+
+	model.Data = make([]float32, len(b.Data))
+
+	copy(model.Data, b.Data)
+
+	model.Header.Set("IMAGETYP", "Background", "The type of the image")
+
+	model.Header.Set("BKGMODEL", b.Params.Model.String(), "The background model")
+
+	model.Header.Set("BKGLEVEL", float32(b.Level), "The median level of the background model (ADU)")
+
+	return model
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the number of samples of the model which were not rejected.
+func (b *Background) GetAcceptedSamples() int {
+	n := 0
+
+	for _, s := range b.Samples {
+		if !s.Rejected {
+			n++
+		}
+	}
+
+	return n
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the mask of the pixels within the mask radius of the stars of the image.
+func (b *Background) getStarMask(f *fits.FITSImage) []bool {
+	if b.Params.StarMaskScale <= 0 {
+		return make([]bool, b.Width*b.Height)
+	}
+
+	stars, _ := photometry.FindStars(f.Data, b.Width, b.Height, f.ADU, b.Params.StarRadius, b.Params.StarSigma, b.Params.StarInOut)
+
+	b.Stars = len(stars)
+
+	// The HFR is overestimated on strong gradients, so the mask is limited to a quarter of a box:
+	return photometry.GetStarMask(b.Width, b.Height, stars, b.Params.StarMaskScale, 3, float64(b.Params.BoxSize)/4)
+}
+
+/*****************************************************************************************************************/
+
+// Samples the background within each box of the grid, by the sigma-clipped median of its unmasked pixels.
+func (b *Background) sample(data []float32, mask []bool) {
+	b.Samples = make([]Sample, 0, b.Columns*b.Rows)
+
+	values := make([]float64, 0, (b.Width/b.Columns+1)*(b.Height/b.Rows+1))
+
+	for r := 0; r < b.Rows; r++ {
+		y0, y1 := r*b.Height/b.Rows, (r+1)*b.Height/b.Rows
+
+		for c := 0; c < b.Columns; c++ {
+			x0, x1 := c*b.Width/b.Columns, (c+1)*b.Width/b.Columns
+
+			values = values[:0]
+
+			for j := y0; j < y1; j++ {
+				for i := x0; i < x1; i++ {
+					if v := float64(data[j*b.Width+i]); !mask[j*b.Width+i] && !math.IsNaN(v) {
+						values = append(values, v)
+					}
+				}
+			}
+
+			s := Sample{
+				X: float64(x0+x1-1) / 2,
+				Y: float64(y0+y1-1) / 2,
+			}
+
+			total := (x1 - x0) * (y1 - y0)
+
+			if float64(total-len(values)) > b.Params.MaxMaskedFraction*float64(total) || len(values) < 10 {
+				s.Rejected, s.Reason = true, "masked"
+
+				b.Samples = append(b.Samples, s)
+
+				continue
+			}
+
+			s.Value, s.Sigma, s.Pixels = getSigmaClippedMedian(values, b.Params.Sigma, b.Params.Iterations)
+
+			b.Samples = append(b.Samples, s)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Fits the polynomial model to the samples, iteratively rejecting the samples with outlying residuals.
+func (b *Background) fitPolynomial() error {
+	degree := max(b.Params.Degree, 0)
+
+	// The terms u^i·v^j of the polynomial, with i + j <= degree:
+	terms := make([][2]int, 0)
+
+	for n := 0; n <= degree; n++ {
+		for j := 0; j <= n; j++ {
+			terms = append(terms, [2]int{n - j, j})
+		}
+	}
+
+	// The positions are normalised to [-1, 1], such that the least squares system is well conditioned:
+	u := func(x float64) float64 { return 2*x/float64(max(b.Width-1, 1)) - 1 }
+
+	v := func(y float64) float64 { return 2*y/float64(max(b.Height-1, 1)) - 1 }
+
+	evaluate := func(coefficients []float64, x float64, y float64) float64 {
+		value := 0.0
+
+		for k, t := range terms {
+			value += coefficients[k] * math.Pow(u(x), float64(t[0])) * math.Pow(v(y), float64(t[1]))
+		}
+
+		return value
+	}
+
+	var coefficients []float64
+
+	iterations := max(b.Params.Iterations, 1)
+
+	for iteration := 0; iteration <= iterations; iteration++ {
+		accepted := make([]int, 0, len(b.Samples))
+
+		for i, s := range b.Samples {
+			if !s.Rejected {
+				accepted = append(accepted, i)
+			}
+		}
+
+		if len(accepted) < len(terms) {
+			return fmt.Errorf("only %d background samples remain, %d are required for a degree %d polynomial", len(accepted), len(terms), degree)
+		}
+
+		a := mat.NewDense(len(accepted), len(terms), nil)
+
+		z := mat.NewVecDense(len(accepted), nil)
+
+		for row, i := range accepted {
+			s := b.Samples[i]
+
+			for k, t := range terms {
+				a.Set(row, k, math.Pow(u(s.X), float64(t[0]))*math.Pow(v(s.Y), float64(t[1])))
+			}
+
+			z.SetVec(row, s.Value)
+		}
+
+		var x mat.VecDense
+
+		if err := x.SolveVec(a, z); err != nil {
+			return fmt.Errorf("the background polynomial could not be fitted: %w", err)
+		}
+
+		coefficients = x.RawVector().Data
+
+		if iteration == iterations {
+			break
+		}
+
+		// Reject the samples whose residuals are beyond the threshold, by a robust (MAD) estimate of their scatter:
+		residuals := make([]float64, len(accepted))
+
+		for row, i := range accepted {
+			residuals[row] = math.Abs(b.Samples[i].Value - evaluate(coefficients, b.Samples[i].X, b.Samples[i].Y))
+		}
+
+		sigma := 1.4826 * qsort.MedianFloat64(residuals)
+
+		rejected := 0
+
+		for row, i := range accepted {
+			if sigma > 0 && residuals[row] > b.Params.Sigma*sigma && len(accepted)-rejected > len(terms) {
+				b.Samples[i].Rejected, b.Samples[i].Reason = true, "outlier"
+
+				rejected++
+			}
+		}
+
+		if rejected == 0 {
+			break
+		}
+	}
+
+	b.Coefficients = coefficients
+
+	b.Data = make([]float32, b.Width*b.Height)
+
+	// Evaluate the model with the powers of each column and row precomputed:
+	powers := func(n int, normalise func(float64) float64) [][]float64 {
+		p := make([][]float64, n)
+
+		for i := range p {
+			p[i] = make([]float64, degree+1)
+
+			for k := range p[i] {
+				p[i][k] = math.Pow(normalise(float64(i)), float64(k))
+			}
+		}
+
+		return p
+	}
+
+	pu, pv := powers(b.Width, u), powers(b.Height, v)
+
+	for j := 0; j < b.Height; j++ {
+		for i := 0; i < b.Width; i++ {
+			value := 0.0
+
+			for k, t := range terms {
+				value += coefficients[k] * pu[i][t[0]] * pv[j][t[1]]
+			}
+
+			b.Data[j*b.Width+i] = float32(value)
+		}
+	}
+
+	return nil
+}
+
+/*****************************************************************************************************************/
+
+// Passes a bicubic spline through the grid of samples, with the rejected samples filled from their nearest accepted
+// neighbours, and the grid median filtered.
+func (b *Background) fitSpline() error {
+	if b.GetAcceptedSamples() == 0 {
+		return errors.New("no background samples remain for the spline model")
+	}
+
+	grid := make([]float64, len(b.Samples))
+
+	for i, s := range b.Samples {
+		if !s.Rejected {
+			grid[i] = s.Value
+			continue
+		}
+
+		// Fill the rejected sample by the mean of its nearest accepted samples:
+		best, sum, n := math.Inf(1), 0.0, 0
+
+		for _, t := range b.Samples {
+			if t.Rejected {
+				continue
+			}
+
+			d := math.Hypot(t.X-s.X, t.Y-s.Y)
+
+			switch {
+			case d < best-1e-9:
+				best, sum, n = d, t.Value, 1
+			case math.Abs(d-best) <= 1e-9:
+				sum += t.Value
+				n++
+			}
+		}
+
+		grid[i] = sum / float64(n)
+	}
+
+	// Median filter the grid, such that residual stars and nebulosity do not distort the surface:
+	if half := b.Params.FilterSize / 2; half > 0 {
+		filtered := make([]float64, len(grid))
+
+		window := make([]float64, 0, (2*half+1)*(2*half+1))
+
+		for r := 0; r < b.Rows; r++ {
+			for c := 0; c < b.Columns; c++ {
+				window = window[:0]
+
+				for j := max(r-half, 0); j <= min(r+half, b.Rows-1); j++ {
+					for i := max(c-half, 0); i <= min(c+half, b.Columns-1); i++ {
+						window = append(window, grid[j*b.Columns+i])
+					}
+				}
+
+				filtered[r*b.Columns+c] = qsort.MedianFloat64(window)
+			}
+		}
+
+		grid = filtered
+	}
+
+	xs := make([]float64, b.Columns)
+
+	for c := range xs {
+		xs[c] = b.Samples[c].X
+	}
+
+	ys := make([]float64, b.Rows)
+
+	for r := range ys {
+		ys[r] = b.Samples[r*b.Columns].Y
+	}
+
+	// Interpolate each row of the grid along x, at every column of the image:
+	rows := make([][]float64, b.Rows)
+
+	for r := 0; r < b.Rows; r++ {
+		spline := newCubicSpline(xs, grid[r*b.Columns:(r+1)*b.Columns])
+
+		rows[r] = make([]float64, b.Width)
+
+		for i := range rows[r] {
+			rows[r][i] = spline.evaluate(float64(i))
+		}
+	}
+
+	// Interpolate each column of the image along y, through the interpolated rows:
+	b.Data = make([]float32, b.Width*b.Height)
+
+	column := make([]float64, b.Rows)
+
+	for i := 0; i < b.Width; i++ {
+		for r := range column {
+			column[r] = rows[r][i]
+		}
+
+		spline := newCubicSpline(ys, column)
+
+		for j := 0; j < b.Height; j++ {
+			b.Data[j*b.Width+i] = float32(spline.evaluate(float64(j)))
+		}
+	}
+
+	return nil
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the median and robust standard deviation (from the median absolute deviation) of the values, iteratively
+// clipped beyond sigma standard deviations of the median, with the number of values retained.
+func getSigmaClippedMedian(values []float64, sigma float64, iterations int) (float64, float64, int) {
+	clipped := make([]float64, len(values))
+
+	copy(clipped, values)
+
+	deviations := make([]float64, len(values))
+
+	median, deviation := 0.0, 0.0
+
+	for iteration := 0; iteration <= max(iterations, 1); iteration++ {
+		median = qsort.MedianFloat64(clipped)
+
+		deviations = deviations[:len(clipped)]
+
+		for i, v := range clipped {
+			deviations[i] = math.Abs(v - median)
+		}
+
+		deviation = 1.4826 * qsort.MedianFloat64(deviations)
+
+		if iteration == max(iterations, 1) || sigma <= 0 {
+			break
+		}
+
+		retained := clipped[:0]
+
+		for _, v := range clipped {
+			if math.Abs(v-median) <= sigma*deviation {
+				retained = append(retained, v)
+			}
+		}
+
+		if len(retained) == len(clipped) || len(retained) == 0 {
+			break
+		}
+
+		clipped = retained
+	}
+
+	return median, deviation, len(clipped)
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,263 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/background
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package background
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/internal/starfield"
+)
+
+/*****************************************************************************************************************/
+
+// Creates a test image of a field of stars on the given background, with Gaussian noise:
+func getTestImage(xs int, ys int, background func(x float64, y float64) float64) *fits.FITSImage {
+	random := rand.New(rand.NewSource(42))
+
+	data := make([]float32, xs*ys)
+
+	for j := 0; j < ys; j++ {
+		for i := 0; i < xs; i++ {
+			data[j*xs+i] = float32(background(float64(i), float64(j)) + random.NormFloat64()*5)
+		}
+	}
+
+	for n := 0; n < 40; n++ {
+		x, y, flux := 10+random.Float64()*float64(xs-20), 10+random.Float64()*float64(ys-20), 20000+random.Float64()*60000
+
+		starfield.AddStar(data, xs, ys, x, y, flux)
+	}
+
+	f := fits.NewFITSImage(2, int32(xs), int32(ys), 65535)
+
+	f.Data = data
+
+	f.Naxisn = []int32{int32(xs), int32(ys)}
+
+	return f
+}
+
+/*****************************************************************************************************************/
+
+// A light pollution gradient, brighter towards the horizon (i.e., the bottom right):
+func getTestGradient(x float64, y float64) float64 {
+	return 1000 + 1.5*x + 0.5*y + 0.002*(x-128)*(x-128)
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the maximum absolute error of the model against the true background, away from the edges:
+func getTestModelError(b *Background, background func(x float64, y float64) float64) float64 {
+	worst := 0.0
+
+	for j := 16; j < b.Height-16; j++ {
+		for i := 16; i < b.Width-16; i++ {
+			worst = math.Max(worst, math.Abs(float64(b.Data[j*b.Width+i])-background(float64(i), float64(j))))
+		}
+	}
+
+	return worst
+}
+
+/*****************************************************************************************************************/
+
+func TestEstimatePolynomial(t *testing.T) {
+	f := getTestImage(256, 192, getTestGradient)
+
+	params := DefaultParams()
+
+	params.BoxSize = 32
+
+	b, err := Estimate(f, params)
+
+	if err != nil {
+		t.Fatalf("Estimate() failed: %v", err)
+	}
+
+	if b.Columns != 8 || b.Rows != 6 || len(b.Samples) != 48 {
+		t.Errorf("expected a grid of 8x6 samples, but got %dx%d (%d samples)", b.Columns, b.Rows, len(b.Samples))
+	}
+
+	if b.Stars == 0 {
+		t.Errorf("expected the stars to be masked")
+	}
+
+	if e := getTestModelError(b, getTestGradient); e > 3 {
+		t.Errorf("expected the polynomial model within 3 ADU of the background, but got an error of %f", e)
+	}
+
+	if len(b.Coefficients) != 6 {
+		t.Errorf("expected 6 coefficients of a 2nd degree polynomial, but got %d", len(b.Coefficients))
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestEstimateSpline(t *testing.T) {
+	// A gradient which a low degree polynomial does not describe:
+	background := func(x float64, y float64) float64 {
+		return 1000 + 40*math.Sin(x/60) + 30*math.Cos(y/50)
+	}
+
+	f := getTestImage(256, 192, background)
+
+	params := DefaultParams()
+
+	params.BoxSize, params.Model, params.FilterSize = 16, Spline, 1
+
+	b, err := Estimate(f, params)
+
+	if err != nil {
+		t.Fatalf("Estimate() failed: %v", err)
+	}
+
+	if e := getTestModelError(b, background); e > 6 {
+		t.Errorf("expected the spline model within 6 ADU of the background, but got an error of %f", e)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestEstimateRejectsContaminatedBoxes(t *testing.T) {
+	f := getTestImage(256, 192, getTestGradient)
+
+	// A bright, extended nebula in a single box, which is not found as a star:
+	for j := 64; j < 96; j++ {
+		for i := 96; i < 128; i++ {
+			f.Data[j*256+i] += 500
+		}
+	}
+
+	params := DefaultParams()
+
+	params.BoxSize = 32
+
+	b, err := Estimate(f, params)
+
+	if err != nil {
+		t.Fatalf("Estimate() failed: %v", err)
+	}
+
+	// The box of the nebula is the 4th column of the 3rd row:
+	if s := b.Samples[2*8+3]; !s.Rejected {
+		t.Errorf("expected the sample of the nebula to be rejected, but got %+v", s)
+	}
+
+	if e := getTestModelError(b, getTestGradient); e > 3 {
+		t.Errorf("expected the polynomial model within 3 ADU of the background, but got an error of %f", e)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRemoveSubtract(t *testing.T) {
+	f := getTestImage(256, 192, getTestGradient)
+
+	params := DefaultParams()
+
+	params.BoxSize = 32
+
+	corrected, model, b, err := Remove(f, params)
+
+	if err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+
+	if len(model.Data) != len(f.Data) || model.Header.Strings["IMAGETYP"].Value != "Background" {
+		t.Errorf("expected a background model image of the dimensions of the image")
+	}
+
+	// The corrected background is flat, at the median level of the model:
+	for _, p := range [][2]int{{20, 20}, {230, 20}, {20, 170}, {230, 170}, {128, 96}} {
+		i := p[1]*256 + p[0]
+
+		expected := float64(f.Data[i]) - getTestGradient(float64(p[0]), float64(p[1])) + b.Level
+
+		if math.Abs(float64(corrected.Data[i])-expected) > 3 {
+			t.Errorf("expected the corrected value %f at %v, but got %f", expected, p, corrected.Data[i])
+		}
+	}
+
+	if corrected.Header.Strings["BKGCORR"].Value != "subtract" || len(corrected.Header.History) == 0 {
+		t.Errorf("expected the background correction to be recorded in the header")
+	}
+
+	// The original image is unchanged:
+	if f.Header.Strings["BKGCORR"].Value != "" {
+		t.Errorf("expected the original image to be unchanged")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRemoveDivide(t *testing.T) {
+	// A vignetted background, darker towards the corners:
+	vignetting := func(x float64, y float64) float64 {
+		r2 := ((x-128)*(x-128) + (y-96)*(y-96)) / (160 * 160)
+
+		return 2000 * (1 - 0.3*r2)
+	}
+
+	f := getTestImage(256, 192, vignetting)
+
+	params := DefaultParams()
+
+	params.BoxSize, params.Correction = 32, Divide
+
+	corrected, _, b, err := Remove(f, params)
+
+	if err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+
+	for _, p := range [][2]int{{20, 20}, {230, 170}, {128, 96}} {
+		i := p[1]*256 + p[0]
+
+		expected := float64(f.Data[i]) / vignetting(float64(p[0]), float64(p[1])) * b.Level
+
+		// Within 0.5%, i.e., the model is within a few ADU of the background:
+		if math.Abs(float64(corrected.Data[i])-expected) > 0.005*expected {
+			t.Errorf("expected the corrected value %f at %v, but got %f", expected, p, corrected.Data[i])
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestEstimateErrors(t *testing.T) {
+	f := getTestImage(256, 192, getTestGradient)
+
+	params := DefaultParams()
+
+	// A grid of 2x1 boxes cannot constrain a 2nd degree polynomial:
+	params.BoxSize = 128
+
+	if _, err := Estimate(f, params); err == nil {
+		t.Errorf("expected an error for too few samples")
+	}
+
+	params.BoxSize = 2
+
+	if _, err := Estimate(f, params); err == nil {
+		t.Errorf("expected an error for too small boxes")
+	}
+
+	f.Data = f.Data[:100]
+
+	if _, err := Estimate(f, DefaultParams()); err == nil {
+		t.Errorf("expected an error for mismatched dimensions")
+	}
+}
+
+/*****************************************************************************************************************/
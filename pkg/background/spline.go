@@ -0,0 +1,103 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/background
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package background
+
+/*****************************************************************************************************************/
+
+// A natural cubic spline through the knots { x, y }, extrapolated linearly beyond the first and last knots.
+type cubicSpline struct {
+	x []float64 // The (increasing) positions of the knots
+	y []float64 // The values of the knots
+	m []float64 // The second derivatives of the spline at the knots
+}
+
+/*****************************************************************************************************************/
+
+// Creates the natural cubic spline through the knots, i.e., with zero second derivatives at the first and last knots.
+func newCubicSpline(x []float64, y []float64) *cubicSpline {
+	n := len(x)
+
+	m := make([]float64, n)
+
+	if n > 2 {
+		// Solve the tridiagonal system for the interior second derivatives, by the Thomas algorithm:
+		c := make([]float64, n)
+
+		d := make([]float64, n)
+
+		for i := 1; i < n-1; i++ {
+			h0, h1 := x[i]-x[i-1], x[i+1]-x[i]
+
+			a, b := h0/6, (h0+h1)/3
+
+			r := (y[i+1]-y[i])/h1 - (y[i]-y[i-1])/h0
+
+			if i > 1 {
+				b -= a * c[i-1]
+
+				r -= a * d[i-1]
+			}
+
+			c[i], d[i] = h1/6/b, r/b
+		}
+
+		for i := n - 2; i > 0; i-- {
+			m[i] = d[i] - c[i]*m[i+1]
+		}
+	}
+
+	return &cubicSpline{x: x, y: y, m: m}
+}
+
+/*****************************************************************************************************************/
+
+// Evaluates the spline at t.
+func (s *cubicSpline) evaluate(t float64) float64 {
+	n := len(s.x)
+
+	switch {
+	case n == 0:
+		return 0
+	case n == 1:
+		return s.y[0]
+	case t <= s.x[0]:
+		h := s.x[1] - s.x[0]
+
+		slope := (s.y[1]-s.y[0])/h - h*(2*s.m[0]+s.m[1])/6
+
+		return s.y[0] + slope*(t-s.x[0])
+	case t >= s.x[n-1]:
+		h := s.x[n-1] - s.x[n-2]
+
+		slope := (s.y[n-1]-s.y[n-2])/h + h*(s.m[n-2]+2*s.m[n-1])/6
+
+		return s.y[n-1] + slope*(t-s.x[n-1])
+	}
+
+	// Find the interval of the knots containing t, by bisection:
+	lo, hi := 0, n-1
+
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+
+		if s.x[mid] > t {
+			hi = mid
+		} else {
+			lo = mid
+		}
+	}
+
+	h := s.x[hi] - s.x[lo]
+
+	a, b := (s.x[hi]-t)/h, (t-s.x[lo])/h
+
+	return a*s.y[lo] + b*s.y[hi] + ((a*a*a-a)*s.m[lo]+(b*b*b-b)*s.m[hi])*h*h/6
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,63 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/background
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package background
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+func TestCubicSplineInterpolatesKnots(t *testing.T) {
+	x := []float64{0, 1, 2.5, 4, 6}
+
+	y := []float64{1, 3, 2, 5, 4}
+
+	s := newCubicSpline(x, y)
+
+	for i := range x {
+		if v := s.evaluate(x[i]); math.Abs(v-y[i]) > 1e-9 {
+			t.Errorf("expected the spline to pass through { %f, %f }, but got %f", x[i], y[i], v)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCubicSplineReproducesLine(t *testing.T) {
+	x := []float64{0, 10, 20, 30}
+
+	y := []float64{5, 25, 45, 65}
+
+	s := newCubicSpline(x, y)
+
+	// A natural spline through collinear knots is the line, including its linear extrapolation:
+	for _, v := range []float64{-5, 3, 17.5, 29, 40} {
+		if e := 5 + 2*v; math.Abs(s.evaluate(v)-e) > 1e-9 {
+			t.Errorf("expected %f at %f, but got %f", e, v, s.evaluate(v))
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCubicSplineFewKnots(t *testing.T) {
+	if v := newCubicSpline([]float64{3}, []float64{7}).evaluate(100); v != 7 {
+		t.Errorf("expected a single knot spline to be constant 7, but got %f", v)
+	}
+
+	if v := newCubicSpline([]float64{0, 2}, []float64{1, 5}).evaluate(1); math.Abs(v-3) > 1e-9 {
+		t.Errorf("expected a two knot spline to be linear, i.e., 3 at 1, but got %f", v)
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,307 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/cosmic
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package cosmic
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// Params describes how cosmic rays are detected and removed.
+type Params struct {
+	Gain          float64 // The gain of the camera, in electrons per ADU
+	ReadNoise     float64 // The read noise of the camera, in electrons
+	SigmaClip     float64 // The detection threshold of the Laplacian significance of a cosmic ray
+	SigmaFrac     float64 // The fraction of the detection threshold for neighbouring pixels of a cosmic ray
+	ObjectLimit   float64 // The minimum contrast of the Laplacian to the fine structure of the image, to reject compact sources
+	Iterations    int     // The maximum number of detection iterations
+	StarRadius    float32 // The radius of the star extraction
+	StarSigma     float32 // The detection threshold of the star extraction
+	StarInOut     float32 // The HFR in-out ratio of the star extraction
+	StarMaskScale float64 // The radius of the protected region of each star, as a multiple of its HFR (zero disables protection)
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default cosmic ray parameters, i.e., those of L.A.Cosmic for a camera of unit gain.
+func DefaultParams() Params {
+	return Params{
+		Gain:          1,
+		ReadNoise:     5,
+		SigmaClip:     4.5,
+		SigmaFrac:     0.3,
+		ObjectLimit:   5,
+		Iterations:    4,
+		StarRadius:    16,
+		StarSigma:     5,
+		StarInOut:     2,
+		StarMaskScale: 1.5,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Result is the outcome of the detection and removal of cosmic rays from an image.
+type Result struct {
+	Cleaned    *fits.FITSImage // The image with the cosmic rays replaced
+	Mask       []bool          // The mask of the pixels detected as cosmic rays, of the dimensions of the image
+	Detected   int             // The number of pixels detected as cosmic rays
+	Iterations int             // The number of detection iterations performed
+	Stars      int             // The number of stars protected from detection
+}
+
+/*****************************************************************************************************************/
+
+/*
+Clean()
+
+Detects and removes the cosmic rays of a single image, by the Laplacian edge detection of
+L.A.Cosmic, returning a copy of the image with the cosmic rays replaced.
+
+Stars are found by StarsExtractor.FindStars(), and pixels within a multiple of their HFR are
+protected from detection, such that the cores of real (and undersampled) stars are never
+replaced.
+
+@see van Dokkum, P. G. (2001). Cosmic-Ray Rejection by Laplacian Edge Detection. PASP, 113, 1420
+*/
+func Clean(f *fits.FITSImage, params Params) (*Result, error) {
+	xs, ys, ok := f.GetDimensions()
+
+	if !ok || len(f.Data) != xs*ys {
+		return nil, fmt.Errorf("the image data of %d pixels does not match the dimensions %dx%d", len(f.Data), xs, ys)
+	}
+
+	protected, stars := getStarMask(f, xs, ys, params)
+
+	data, mask, iterations, err := CleanData(f.Data, xs, ys, protected, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	detected := 0
+
+	for _, m := range mask {
+		if m {
+			detected++
+		}
+	}
+
+	cleaned := f.Copy()
+
+	cleaned.Data = data
+
+	cleaned.Header.Set("NCOSMIC", detected, "The number of cosmic ray pixels replaced")
+
+	cleaned.Header.History = append(
+		cleaned.Header.History,
+		fmt.Sprintf("Cosmic rays removed (L.A.Cosmic) in %d iterations: %d pixels replaced", iterations, detected),
+	)
+
+	return &Result{
+		Cleaned:    cleaned,
+		Mask:       mask,
+		Detected:   detected,
+		Iterations: iterations,
+		Stars:      stars,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+/*
+CleanData()
+
+Detects and removes the cosmic rays of the image data (in ADU), never detecting those pixels
+which are protected (which may be nil), returning the cleaned data, the mask of the detected
+pixels and the number of iterations performed.
+
+Each iteration finds the pixels of significant positive Laplacian, relative to the noise of
+the image, which are sharper than the fine structure of the image (i.e., not the cores of
+compact sources), grows them to their significant neighbours, and replaces them by the median
+of their unmasked surroundings. Iteration stops when no new pixels are detected.
+*/
+func CleanData(data []float32, xs int, ys int, protected []bool, params Params) ([]float32, []bool, int, error) {
+	if xs <= 0 || ys <= 0 || len(data) != xs*ys {
+		return nil, nil, 0, fmt.Errorf("the image data of %d pixels does not match the dimensions %dx%d", len(data), xs, ys)
+	}
+
+	if protected != nil && len(protected) != len(data) {
+		return nil, nil, 0, errors.New("the protected mask does not match the dimensions of the image")
+	}
+
+	if params.Gain <= 0 {
+		return nil, nil, 0, errors.New("the gain must be positive")
+	}
+
+	if params.SigmaClip <= 0 || params.ObjectLimit <= 0 {
+		return nil, nil, 0, errors.New("the detection thresholds must be positive")
+	}
+
+	cleaned := make([]float32, len(data))
+
+	copy(cleaned, data)
+
+	mask := make([]bool, len(data))
+
+	iterations := 0
+
+	for iterations < max(params.Iterations, 1) {
+		iterations++
+
+		detected := detect(cleaned, xs, ys, params)
+
+		added := 0
+
+		for i, d := range detected {
+			if d && !mask[i] && (protected == nil || !protected[i]) {
+				mask[i] = true
+				added++
+			}
+		}
+
+		if added == 0 {
+			break
+		}
+
+		replace(cleaned, xs, ys, mask)
+	}
+
+	return cleaned, mask, iterations, nil
+}
+
+/*****************************************************************************************************************/
+
+// Detects the cosmic ray pixels of the image, by a single iteration of L.A.Cosmic.
+func detect(data []float32, xs int, ys int, params Params) []bool {
+	laplacian := positiveLaplacian(data, xs, ys)
+
+	// The noise model of the image, from its smoothed (positive) level, the gain and the read noise:
+	med5 := medianFilter(data, xs, ys, 5)
+
+	noise := make([]float32, len(data))
+
+	rn2 := params.ReadNoise * params.ReadNoise
+
+	for i, m := range med5 {
+		noise[i] = float32(math.Max(math.Sqrt(params.Gain*math.Max(float64(m), 0)+rn2)/params.Gain, 1e-5))
+	}
+
+	// The significance of the Laplacian, where the factor of two accounts for the subsampling:
+	significance := make([]float32, len(data))
+
+	for i, l := range laplacian {
+		significance[i] = l / (2 * noise[i])
+	}
+
+	// Remove the large structures of the significance image, e.g., the wings of bright stars:
+	smooth := medianFilter(significance, xs, ys, 5)
+
+	residual := make([]float32, len(data))
+
+	for i, s := range significance {
+		residual[i] = s - smooth[i]
+	}
+
+	// The fine structure of the image, i.e., the small-scale structure of compact sources, relative to the noise:
+	med3 := medianFilter(data, xs, ys, 3)
+
+	med7 := medianFilter(med3, xs, ys, 7)
+
+	candidates := make([]bool, len(data))
+
+	for i := range data {
+		// NaN pixels (e.g., uncovered pixels of a resampled frame) are never detected:
+		if math.IsNaN(float64(data[i])) {
+			continue
+		}
+
+		fine := max((med3[i]-med7[i])/noise[i], 0.01)
+
+		candidates[i] = float64(residual[i]) > params.SigmaClip && float64(significance[i]/fine) > params.ObjectLimit
+	}
+
+	// Grow the candidates to their significant neighbours, then to their marginally significant neighbours:
+	grown := dilate(candidates, xs, ys)
+
+	for i := range grown {
+		grown[i] = grown[i] && float64(residual[i]) > params.SigmaClip
+	}
+
+	detected := dilate(grown, xs, ys)
+
+	low := params.SigmaFrac * params.SigmaClip
+
+	for i := range detected {
+		detected[i] = detected[i] && float64(residual[i]) > low && !math.IsNaN(float64(data[i]))
+	}
+
+	return detected
+}
+
+/*****************************************************************************************************************/
+
+// Replaces the masked pixels of the image by the median of the unmasked (non-NaN) pixels of their 5x5 neighbourhood,
+// widening the neighbourhood for large clusters of masked pixels.
+func replace(data []float32, xs int, ys int, mask []bool) {
+	source := make([]float32, len(data))
+
+	copy(source, data)
+
+	window := make([]float32, 0, 25)
+
+	for y := 0; y < ys; y++ {
+		for x := 0; x < xs; x++ {
+			if !mask[y*xs+x] {
+				continue
+			}
+
+			for half := 2; half <= max(xs, ys); half *= 2 {
+				window = window[:0]
+
+				for j := max(y-half, 0); j <= min(y+half, ys-1); j++ {
+					for i := max(x-half, 0); i <= min(x+half, xs-1); i++ {
+						if !mask[j*xs+i] && !math.IsNaN(float64(source[j*xs+i])) {
+							window = append(window, source[j*xs+i])
+						}
+					}
+				}
+
+				if len(window) > 0 {
+					data[y*xs+x] = qsort.QSelectMedianFloat32(window)
+					break
+				}
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the mask of the pixels within the protected radius of the stars of the image, and the number of stars.
+func getStarMask(f *fits.FITSImage, xs int, ys int, params Params) ([]bool, int) {
+	if params.StarMaskScale <= 0 {
+		return nil, 0
+	}
+
+	stars, _ := photometry.FindStars(f.Data, xs, ys, f.ADU, params.StarRadius, params.StarSigma, params.StarInOut)
+
+	return photometry.GetStarMask(xs, ys, stars, params.StarMaskScale, 2, 0), len(stars)
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,230 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/cosmic
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package cosmic
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/internal/starfield"
+)
+
+/*****************************************************************************************************************/
+
+// The positions of the stars of the test image:
+var testStars = [][2]float64{{40.3, 42.7}, {120.6, 60.2}, {80.1, 150.8}, {170.4, 190.5}, {210.8, 90.3}}
+
+/*****************************************************************************************************************/
+
+// The positions of the cosmic ray hits of the test image, single pixels and short tracks:
+var testHits = [][2]int{{60, 100}, {150, 30}, {200, 220}, {30, 200}, {100, 120}, {101, 121}, {102, 122}}
+
+/*****************************************************************************************************************/
+
+// Creates a test image of a field of stars on a flat background, with Gaussian noise and cosmic ray hits:
+func getTestImage(xs int, ys int, hits bool) *fits.FITSImage {
+	random := rand.New(rand.NewSource(42))
+
+	data := make([]float32, xs*ys)
+
+	for i := range data {
+		data[i] = float32(1000 + random.NormFloat64()*math.Sqrt(1000+25))
+	}
+
+	for _, s := range testStars {
+		starfield.AddStar(data, xs, ys, s[0], s[1], 50000)
+	}
+
+	if hits {
+		for _, h := range testHits {
+			data[h[1]*xs+h[0]] += 5000
+		}
+	}
+
+	f := fits.NewFITSImage(2, int32(xs), int32(ys), 65535)
+
+	f.Data = data
+
+	f.Naxisn = []int32{int32(xs), int32(ys)}
+
+	return f
+}
+
+/*****************************************************************************************************************/
+
+func TestCleanDetectsCosmicRays(t *testing.T) {
+	f := getTestImage(256, 256, true)
+
+	result, err := Clean(f, DefaultParams())
+
+	if err != nil {
+		t.Fatalf("Clean() failed: %v", err)
+	}
+
+	for _, h := range testHits {
+		i := h[1]*256 + h[0]
+
+		if !result.Mask[i] {
+			t.Errorf("expected the hit at (%d, %d) to be detected", h[0], h[1])
+		}
+
+		if math.Abs(float64(result.Cleaned.Data[i])-1000) > 150 {
+			t.Errorf("expected the hit at (%d, %d) to be replaced by the background, got %v", h[0], h[1], result.Cleaned.Data[i])
+		}
+	}
+
+	if result.Detected > 3*len(testHits) {
+		t.Errorf("expected at most %d detections, got %d", 3*len(testHits), result.Detected)
+	}
+
+	if result.Cleaned.Header.Ints["NCOSMIC"].Value != int32(result.Detected) {
+		t.Errorf("expected NCOSMIC to be %d, got %d", result.Detected, result.Cleaned.Header.Ints["NCOSMIC"].Value)
+	}
+
+	// The original image must be unchanged:
+	if f.Data[100*256+60] < 5000 {
+		t.Errorf("expected the original image to be unchanged")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCleanPreservesStars(t *testing.T) {
+	f := getTestImage(256, 256, true)
+
+	result, err := Clean(f, DefaultParams())
+
+	if err != nil {
+		t.Fatalf("Clean() failed: %v", err)
+	}
+
+	if result.Stars == 0 {
+		t.Fatalf("expected stars to be protected")
+	}
+
+	for _, s := range testStars {
+		for j := int(s[1]) - 3; j <= int(s[1])+3; j++ {
+			for i := int(s[0]) - 3; i <= int(s[0])+3; i++ {
+				if result.Mask[j*256+i] {
+					t.Errorf("expected the star at (%v, %v) not to be detected at (%d, %d)", s[0], s[1], i, j)
+				}
+
+				if result.Cleaned.Data[j*256+i] != f.Data[j*256+i] {
+					t.Errorf("expected the star at (%v, %v) to be unchanged at (%d, %d)", s[0], s[1], i, j)
+				}
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCleanDataWithoutCosmicRays(t *testing.T) {
+	f := getTestImage(128, 128, false)
+
+	params := DefaultParams()
+
+	params.StarMaskScale = 0
+
+	_, mask, iterations, err := CleanData(f.Data, 128, 128, nil, params)
+
+	if err != nil {
+		t.Fatalf("CleanData() failed: %v", err)
+	}
+
+	detected := 0
+
+	for _, m := range mask {
+		if m {
+			detected++
+		}
+	}
+
+	// Only the (unprotected) cores of the stars may be falsely detected, which the object limit should reject:
+	if detected > 2 {
+		t.Errorf("expected no detections in an image without cosmic rays, got %d", detected)
+	}
+
+	if iterations != 1 && detected == 0 {
+		t.Errorf("expected a single iteration without detections, got %d", iterations)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCleanDataInvalidParams(t *testing.T) {
+	params := DefaultParams()
+
+	params.Gain = 0
+
+	if _, _, _, err := CleanData(make([]float32, 16), 4, 4, nil, params); err == nil {
+		t.Errorf("expected an error for a non-positive gain")
+	}
+
+	if _, _, _, err := CleanData(make([]float32, 15), 4, 4, nil, DefaultParams()); err == nil {
+		t.Errorf("expected an error for mismatched dimensions")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCleanDataWithNaN(t *testing.T) {
+	f := getTestImage(256, 256, true)
+
+	// A single NaN pixel, and an uncovered (NaN) border, e.g., of a resampled frame:
+	nans := []int{80*256 + 80}
+
+	for y := 0; y < 256; y++ {
+		for x := 250; x < 256; x++ {
+			nans = append(nans, y*256+x)
+		}
+	}
+
+	for _, i := range nans {
+		f.Data[i] = float32(math.NaN())
+	}
+
+	params := DefaultParams()
+
+	params.StarMaskScale = 0
+
+	cleaned, mask, _, err := CleanData(f.Data, 256, 256, nil, params)
+
+	if err != nil {
+		t.Fatalf("CleanData() failed: %v", err)
+	}
+
+	for _, i := range nans {
+		if mask[i] {
+			t.Errorf("expected the NaN pixel at (%d, %d) not to be detected", i%256, i/256)
+		}
+
+		if !math.IsNaN(float64(cleaned[i])) {
+			t.Errorf("expected the NaN pixel at (%d, %d) to be unchanged, got %v", i%256, i/256, cleaned[i])
+		}
+	}
+
+	for _, h := range testHits {
+		i := h[1]*256 + h[0]
+
+		if !mask[i] {
+			t.Errorf("expected the hit at (%d, %d) to be detected", h[0], h[1])
+		}
+
+		if math.IsNaN(float64(cleaned[i])) || math.Abs(float64(cleaned[i])-1000) > 150 {
+			t.Errorf("expected the hit at (%d, %d) to be replaced by the background, got %v", h[0], h[1], cleaned[i])
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,145 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/cosmic
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package cosmic
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// Applies the function to each band of rows of the image in parallel, each writing only to its own rows.
+func forEachBand(ys int, fn func(y0 int, y1 int)) {
+	workers := min(runtime.NumCPU(), max(ys, 1))
+
+	band := (ys + workers - 1) / workers
+
+	var wg sync.WaitGroup
+
+	for y0 := 0; y0 < ys; y0 += band {
+		wg.Add(1)
+
+		go func(y0 int, y1 int) {
+			defer wg.Done()
+
+			fn(y0, y1)
+		}(y0, min(y0+band, ys))
+	}
+
+	wg.Wait()
+}
+
+/*****************************************************************************************************************/
+
+// Median filters the image with a square window of the given size, truncated at the edges of the image, ignoring
+// NaN pixels (the median of a window of only NaN pixels is NaN).
+func medianFilter(data []float32, xs int, ys int, size int) []float32 {
+	half := size / 2
+
+	out := make([]float32, len(data))
+
+	forEachBand(ys, func(y0 int, y1 int) {
+		window := make([]float32, 0, size*size)
+
+		for y := y0; y < y1; y++ {
+			for x := 0; x < xs; x++ {
+				window = window[:0]
+
+				for j := max(y-half, 0); j <= min(y+half, ys-1); j++ {
+					for _, v := range data[j*xs+max(x-half, 0) : j*xs+min(x+half, xs-1)+1] {
+						if !math.IsNaN(float64(v)) {
+							window = append(window, v)
+						}
+					}
+				}
+
+				if len(window) == 0 {
+					out[y*xs+x] = float32(math.NaN())
+					continue
+				}
+
+				out[y*xs+x] = qsort.QSelectMedianFloat32(window)
+			}
+		}
+	})
+
+	return out
+}
+
+/*****************************************************************************************************************/
+
+/*
+Obtains the positive Laplacian of the image, as if the image were subsampled by a factor of two,
+convolved with the Laplacian kernel, clipped at zero, and block averaged back to its original
+size. Subsampling ensures that the sharp edges of a cosmic ray are not cancelled by the
+negative lobes of the kernel on adjacent pixels.
+
+Each of the four subpixels of a pixel has the pixel and one of its horizontal and one of its
+vertical neighbours as its neighbours, so its Laplacian is 2·D(x, y) - D(x ± 1, y) - D(x, y ± 1).
+*/
+func positiveLaplacian(data []float32, xs int, ys int) []float32 {
+	out := make([]float32, len(data))
+
+	at := func(x, y int) float32 {
+		return data[min(max(y, 0), ys-1)*xs+min(max(x, 0), xs-1)]
+	}
+
+	forEachBand(ys, func(y0 int, y1 int) {
+		for y := y0; y < y1; y++ {
+			for x := 0; x < xs; x++ {
+				d := 2 * data[y*xs+x]
+
+				sum := float32(0)
+
+				for _, dx := range [2]int{-1, 1} {
+					for _, dy := range [2]int{-1, 1} {
+						if l := d - at(x+dx, y) - at(x, y+dy); l > 0 {
+							sum += l
+						}
+					}
+				}
+
+				out[y*xs+x] = sum / 4
+			}
+		}
+	})
+
+	return out
+}
+
+/*****************************************************************************************************************/
+
+// Dilates the mask by one pixel, i.e., by a 3x3 square structuring element.
+func dilate(mask []bool, xs int, ys int) []bool {
+	out := make([]bool, len(mask))
+
+	for y := 0; y < ys; y++ {
+		for x := 0; x < xs; x++ {
+			if !mask[y*xs+x] {
+				continue
+			}
+
+			for j := max(y-1, 0); j <= min(y+1, ys-1); j++ {
+				for i := max(x-1, 0); i <= min(x+1, xs-1); i++ {
+					out[j*xs+i] = true
+				}
+			}
+		}
+	}
+
+	return out
+}
+
+/*****************************************************************************************************************/
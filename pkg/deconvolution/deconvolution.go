@@ -0,0 +1,490 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/deconvolution
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package deconvolution
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// The smallest value by which the estimate is divided, i.e., of the reblurred estimate and the TV denominator:
+const (
+	minBlurred     = 1e-12
+	minDenominator = 0.1
+)
+
+/*****************************************************************************************************************/
+
+// Params describes the Richardson–Lucy deconvolution of an image.
+type Params struct {
+	Iterations     int       // The maximum number of iterations
+	Tolerance      float64   // The relative (L1) change of the estimate below which it has converged, or zero to iterate fully
+	Regularisation float64   // The total variation regularisation λ (e.g., 0.002), or zero for unregularised Richardson–Lucy
+	Mask           []float32 // The deringing mask, from 0 (deconvolved) to 1 (unchanged), e.g., of the stars, or nil
+	TileSize       int       // The width and height of each tile deconvolved in parallel, or zero for a single tile
+	Margin         int       // The overlap of each tile with its neighbours, or zero for twice the kernel size
+	Workers        int       // The number of tiles deconvolved in parallel, or zero for the number of CPUs
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default deconvolution parameters, i.e., up to 50 unregularised iterations of 256x256 tiles, until the
+// estimate changes by less than 0.01% per iteration.
+func DefaultParams() Params {
+	return Params{
+		Iterations:     50,
+		Tolerance:      1e-4,
+		Regularisation: 0,
+		Mask:           nil,
+		TileSize:       256,
+		Margin:         0,
+		Workers:        0,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Result is the deconvolved image, and the number of iterations required.
+type Result struct {
+	Image      *fits.FITSImage // The deconvolved image
+	Iterations int             // The number of iterations
+	Converged  bool            // Whether the estimate converged within the maximum number of iterations
+}
+
+/*****************************************************************************************************************/
+
+// A tile of the image, i.e., its core { x0, y0 } to { x1, y1 } and its extent including its margin, with the
+// state of its deconvolution.
+type tile struct {
+	x0, y0, x1, y1 int
+	ex0, ey0       int
+	xs, ys         int
+	fft            *fft2
+	kernel         []complex128 // The Fourier coefficients of the kernel
+	buffer         []complex128 // The buffer of the FFT
+	observed       []float64    // The observed data of the tile
+	mask           []float64    // The deringing mask of the tile, or nil
+	estimate       []float64    // The current estimate of the tile
+	next           []float64    // The next estimate of the tile
+	blurred        []float64    // The reblurred estimate, ratio and correction of the tile
+	denominator    []float64    // The TV regularisation denominator of the tile, or nil
+}
+
+/*****************************************************************************************************************/
+
+/*
+Deconvolve()
+
+Deconvolves a copy of the image by the kernel (e.g., of NewKernelFromPSFs() or NewKernelFromStars())
+with the Richardson–Lucy algorithm, optionally regularised by total variation, and records the
+deconvolution in its header.
+*/
+func Deconvolve(f *fits.FITSImage, kernel *Kernel, params Params) (*Result, error) {
+	xs, ys, _ := f.GetDimensions()
+
+	data, iterations, converged, err := DeconvolveData(f.Data, xs, ys, kernel, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	c := f.Copy()
+
+	c.Data = data
+
+	c.Header.Set("DECONITR", int32(iterations), "Richardson-Lucy deconvolution iterations")
+
+	history := fmt.Sprintf("Deconvolved by Richardson-Lucy with a %dx%d kernel in %d iterations", kernel.Size, kernel.Size, iterations)
+
+	if params.Regularisation > 0 {
+		history += fmt.Sprintf(", TV regularised (λ %g)", params.Regularisation)
+	}
+
+	if params.Mask != nil {
+		history += ", deringed by mask"
+	}
+
+	c.Header.History = append(c.Header.History, history)
+
+	return &Result{Image: c, Iterations: iterations, Converged: converged}, nil
+}
+
+/*****************************************************************************************************************/
+
+/*
+DeconvolveData()
+
+Deconvolves the image data by the kernel with the Richardson–Lucy algorithm, i.e., the estimate u
+of the image d is iteratively updated by u ← u · (d / (u ⊗ K)) ⋆ K, where ⊗ is convolution and ⋆
+is correlation, both computed by FFT, until its relative change is below the tolerance.
+
+If the regularisation λ is positive, the update is divided by 1 − λ·div(∇u / |∇u|), i.e., the
+total variation regularisation of Dey et al. (2006), which suppresses the amplification of noise.
+
+If the mask is set, the estimate is blended with the original data by the mask after each
+iteration, i.e., u ← m·d + (1 − m)·u, such that the masked (e.g., bright stars) pixels are not
+deconvolved and cannot ring.
+
+The image is divided into tiles, each with a margin of its neighbours (mirrored at the edges of
+the image), whose iterations are computed in parallel. The tiles step together, and converge
+together by the relative change of the estimate of the whole image, so that every tile has
+the same number of iterations. Only the core of each tile is kept. Each iteration extends the
+dependence of each pixel by the width of the kernel (twice), so the margin does not bound the
+difference from deconvolving the whole image at once, but the influence of distant pixels
+decays rapidly, such that the seams are negligible for margins of a few kernel widths. The
+margin may be increased (or the tiles disabled) where that is not the case, e.g., for very
+many iterations of a broad kernel.
+
+Negative data are offset to be positive, and NaN pixels are filled by the median and restored
+afterwards. Returns the deconvolved data, the number of iterations, and whether the estimate
+converged.
+*/
+func DeconvolveData(data []float32, xs int, ys int, kernel *Kernel, params Params) ([]float32, int, bool, error) {
+	if xs <= 0 || ys <= 0 || len(data) != xs*ys {
+		return nil, 0, false, fmt.Errorf("the image data of %d pixels does not match the dimensions %dx%d", len(data), xs, ys)
+	}
+
+	if kernel == nil || kernel.Size%2 == 0 || len(kernel.Data) != kernel.Size*kernel.Size {
+		return nil, 0, false, errors.New("the kernel must be square, of odd size")
+	}
+
+	if params.Mask != nil && len(params.Mask) != len(data) {
+		return nil, 0, false, fmt.Errorf("the mask of %d pixels does not match the image of %d pixels", len(params.Mask), len(data))
+	}
+
+	if params.Iterations <= 0 {
+		return nil, 0, false, errors.New("the number of iterations must be positive")
+	}
+
+	// The finite values, to fill the NaN pixels and to offset the data to be positive:
+	finite := make([]float64, 0, len(data))
+
+	minimum := math.Inf(1)
+
+	for _, v := range data {
+		if !math.IsNaN(float64(v)) && !math.IsInf(float64(v), 0) {
+			finite = append(finite, float64(v))
+
+			minimum = math.Min(minimum, float64(v))
+		}
+	}
+
+	if len(finite) == 0 {
+		return nil, 0, false, errors.New("the image has no finite pixels")
+	}
+
+	median := qsort.MedianFloat64(finite)
+
+	offset := 0.0
+
+	if minimum <= 0 {
+		offset = 1 - minimum
+	}
+
+	observed := make([]float64, len(data))
+
+	for i, v := range data {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			observed[i] = median + offset
+		} else {
+			observed[i] = float64(v) + offset
+		}
+	}
+
+	margin := params.Margin
+
+	if margin <= 0 {
+		margin = 2 * kernel.Size
+	}
+
+	size := params.TileSize
+
+	if size <= 0 {
+		size = max(xs, ys)
+	}
+
+	tiles := []*tile{}
+
+	for y0 := 0; y0 < ys; y0 += size {
+		for x0 := 0; x0 < xs; x0 += size {
+			x1, y1 := min(x0+size, xs), min(y0+size, ys)
+
+			tiles = append(tiles, &tile{
+				x0: x0, y0: y0, x1: x1, y1: y1,
+				ex0: x0 - margin, ey0: y0 - margin,
+				xs: x1 - x0 + 2*margin, ys: y1 - y0 + 2*margin,
+			})
+		}
+	}
+
+	workers := params.Workers
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	workers = min(workers, len(tiles))
+
+	changes, sums := make([]float64, len(tiles)), make([]float64, len(tiles))
+
+	// Applies the function to each tile in parallel, each writing only to its own tile:
+	forEachTile := func(fn func(i int, t *tile)) {
+		jobs := make(chan int, len(tiles))
+
+		for i := range tiles {
+			jobs <- i
+		}
+
+		close(jobs)
+
+		var wg sync.WaitGroup
+
+		for w := 0; w < workers; w++ {
+			wg.Add(1)
+
+			go func() {
+				defer wg.Done()
+
+				for i := range jobs {
+					fn(i, tiles[i])
+				}
+			}()
+		}
+
+		wg.Wait()
+	}
+
+	forEachTile(func(i int, t *tile) {
+		t.initialise(observed, xs, ys, kernel, params)
+	})
+
+	iteration, converged := 0, false
+
+	for iteration < params.Iterations && !converged {
+		iteration++
+
+		forEachTile(func(i int, t *tile) {
+			changes[i], sums[i] = t.iterate(params)
+		})
+
+		change, sum := 0.0, 0.0
+
+		for i := range tiles {
+			change, sum = change+changes[i], sum+sums[i]
+		}
+
+		converged = params.Tolerance > 0 && sum > 0 && change/sum < params.Tolerance
+	}
+
+	out := make([]float32, len(data))
+
+	// Write the core of each tile to the output, less the offset:
+	for _, t := range tiles {
+		m := t.x0 - t.ex0
+
+		for y := t.y0; y < t.y1; y++ {
+			for x := t.x0; x < t.x1; x++ {
+				out[y*xs+x] = float32(t.estimate[(y-t.y0+m)*t.xs+x-t.x0+m] - offset)
+			}
+		}
+	}
+
+	// Restore the NaN (and infinite) pixels:
+	for i, v := range data {
+		if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+			out[i] = v
+		}
+	}
+
+	return out, iteration, converged, nil
+}
+
+/*****************************************************************************************************************/
+
+// Initialises the tile from the (offset) observed data of the image, i.e., its data, mask and kernel, with the
+// observed data as the initial estimate.
+func (t *tile) initialise(observed []float64, xs int, ys int, kernel *Kernel, params Params) {
+	n := t.xs * t.ys
+
+	t.observed = make([]float64, n)
+
+	if params.Mask != nil {
+		t.mask = make([]float64, n)
+	}
+
+	for j := 0; j < t.ys; j++ {
+		y := getMirrored(t.ey0+j, ys)
+
+		for i := 0; i < t.xs; i++ {
+			x := getMirrored(t.ex0+i, xs)
+
+			t.observed[j*t.xs+i] = observed[y*xs+x]
+
+			if t.mask != nil {
+				t.mask[j*t.xs+i] = math.Min(math.Max(float64(params.Mask[y*xs+x]), 0), 1)
+			}
+		}
+	}
+
+	t.fft = newFFT2(t.xs, t.ys)
+
+	// The Fourier coefficients of the kernel, centred on the origin (i.e., wrapped around the edges of the tile):
+	t.kernel = make([]complex128, n)
+
+	r := kernel.Size / 2
+
+	for dy := -r; dy <= r; dy++ {
+		for dx := -r; dx <= r; dx++ {
+			y, x := ((dy%t.ys)+t.ys)%t.ys, ((dx%t.xs)+t.xs)%t.xs
+
+			t.kernel[y*t.xs+x] += complex(kernel.At(dx, dy), 0)
+		}
+	}
+
+	t.fft.forward(t.kernel)
+
+	t.estimate, t.next, t.blurred, t.buffer = make([]float64, n), make([]float64, n), make([]float64, n), make([]complex128, n)
+
+	copy(t.estimate, t.observed)
+
+	if params.Regularisation > 0 {
+		t.denominator = make([]float64, n)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Computes one Richardson–Lucy iteration of the estimate of the tile. Returns the L1 change of the estimate, and
+// the L1 norm of the previous estimate, over the core of the tile (i.e., such that the tiles do not overlap).
+func (t *tile) iterate(params Params) (float64, float64) {
+	u, f := t.estimate, t.observed
+
+	// The ratio of the observed data to the reblurred estimate:
+	t.fft.convolve(t.blurred, u, t.kernel, t.buffer, false)
+
+	for i := range t.blurred {
+		t.blurred[i] = f[i] / math.Max(t.blurred[i], minBlurred)
+	}
+
+	// The correction, i.e., the ratio correlated with the kernel:
+	t.fft.convolve(t.blurred, t.blurred, t.kernel, t.buffer, true)
+
+	if t.denominator != nil {
+		getTotalVariationDenominator(u, t.xs, t.ys, params.Regularisation, t.denominator)
+	}
+
+	for i := range u {
+		v := u[i] * math.Max(t.blurred[i], 0)
+
+		if t.denominator != nil {
+			v /= t.denominator[i]
+		}
+
+		if t.mask != nil {
+			v = t.mask[i]*f[i] + (1-t.mask[i])*v
+		}
+
+		t.next[i] = v
+	}
+
+	change, sum := 0.0, 0.0
+
+	m := t.x0 - t.ex0
+
+	for j := m; j < m+t.y1-t.y0; j++ {
+		for i := m; i < m+t.x1-t.x0; i++ {
+			change += math.Abs(t.next[j*t.xs+i] - u[j*t.xs+i])
+
+			sum += math.Abs(u[j*t.xs+i])
+		}
+	}
+
+	t.estimate, t.next = t.next, t.estimate
+
+	return change, sum
+}
+
+/*****************************************************************************************************************/
+
+// Computes the denominator of the total variation regularisation, 1 − λ·div(∇u / |∇u|), of the estimate, from its
+// forward differences and the backward differences of the normalised gradient, floored such that it is positive.
+func getTotalVariationDenominator(u []float64, xs int, ys int, lambda float64, dst []float64) {
+	gx, gy := make([]float64, len(u)), make([]float64, len(u))
+
+	for y := 0; y < ys; y++ {
+		for x := 0; x < xs; x++ {
+			i := y*xs + x
+
+			dx, dy := 0.0, 0.0
+
+			if x < xs-1 {
+				dx = u[i+1] - u[i]
+			}
+
+			if y < ys-1 {
+				dy = u[i+xs] - u[i]
+			}
+
+			norm := math.Sqrt(dx*dx + dy*dy)
+
+			if norm > 0 {
+				gx[i], gy[i] = dx/norm, dy/norm
+			}
+		}
+	}
+
+	for y := 0; y < ys; y++ {
+		for x := 0; x < xs; x++ {
+			i := y*xs + x
+
+			div := gx[i] + gy[i]
+
+			if x > 0 {
+				div -= gx[i-1]
+			}
+
+			if y > 0 {
+				div -= gy[i-xs]
+			}
+
+			dst[i] = math.Max(1-lambda*div, minDenominator)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the index mirrored (about the edge pixels) into [0, n).
+func getMirrored(i int, n int) int {
+	if n == 1 {
+		return 0
+	}
+
+	period := 2 * (n - 1)
+
+	i = ((i % period) + period) % period
+
+	if i >= n {
+		i = period - i
+	}
+
+	return i
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,282 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/deconvolution
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package deconvolution
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/photometry"
+)
+
+/*****************************************************************************************************************/
+
+// The Gaussian kernel of the test stars:
+func getTestKernel(t *testing.T) *Kernel {
+	k, err := NewKernelFromPSF(photometry.PSF{Model: photometry.GaussianPSF, Major: 1.5, Minor: 1.5}, 0)
+
+	if err != nil {
+		t.Fatalf("NewKernelFromPSF() error: %v", err)
+	}
+
+	return k
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the sum of the data, and its maximum within 2 pixels of { x, y }.
+func getTestSumAndPeak(data []float32, xs int, x int, y int) (float64, float64) {
+	sum, peak := 0.0, 0.0
+
+	for i, v := range data {
+		sum += float64(v)
+
+		if math.Abs(float64(i%xs-x)) <= 2 && math.Abs(float64(i/xs-y)) <= 2 {
+			peak = math.Max(peak, float64(v))
+		}
+	}
+
+	return sum, peak
+}
+
+/*****************************************************************************************************************/
+
+func TestDeconvolve(t *testing.T) {
+	xs, ys := 128, 128
+
+	data, _ := getTestStars(xs, ys, 50000, 0)
+
+	f := fits.NewFITSImage(2, int32(xs), int32(ys), 65535)
+
+	f.Data = data
+
+	params := DefaultParams()
+
+	params.Iterations = 30
+
+	params.Tolerance = 0
+
+	r, err := Deconvolve(f, getTestKernel(t), params)
+
+	if err != nil {
+		t.Fatalf("Deconvolve() error: %v", err)
+	}
+
+	if r.Iterations != 30 || r.Converged {
+		t.Errorf("iterations = %d, converged = %t, expected 30 iterations without converging", r.Iterations, r.Converged)
+	}
+
+	sum, peak := getTestSumAndPeak(data, xs, 64, 64)
+
+	dsum, dpeak := getTestSumAndPeak(r.Image.Data, xs, 64, 64)
+
+	// The stars are sharpened, conserving flux:
+	if dpeak < 2*peak {
+		t.Errorf("peak = %f, expected at least twice the original peak of %f", dpeak, peak)
+	}
+
+	if math.Abs(dsum/sum-1) > 0.001 {
+		t.Errorf("sum = %f, expected %f", dsum, sum)
+	}
+
+	if r.Image.Header.Ints["DECONITR"].Value != 30 || len(r.Image.Header.History) != len(f.Header.History)+1 {
+		t.Errorf("expected the deconvolution to be recorded in the header")
+	}
+
+	if f.Data[64*xs+64] != data[64*xs+64] {
+		t.Errorf("expected the original image to be unchanged")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestDeconvolveDataTiles(t *testing.T) {
+	xs, ys := 128, 96
+
+	data, _ := getTestStars(xs, ys, 50000, 2)
+
+	kernel := getTestKernel(t)
+
+	for _, c := range []struct {
+		iterations int
+		tolerance  float64
+	}{
+		{10, 0},
+		// The default tolerance, and a tolerance at which the tiles converge (together) early:
+		{50, 1e-4},
+		{50, 1e-2},
+	} {
+		params := DefaultParams()
+
+		params.Iterations, params.Tolerance = c.iterations, c.tolerance
+
+		params.TileSize = 0
+
+		whole, iterations, _, err := DeconvolveData(data, xs, ys, kernel, params)
+
+		if err != nil {
+			t.Fatalf("DeconvolveData() error: %v", err)
+		}
+
+		params.TileSize = 40
+
+		params.Workers = 4
+
+		tiled, tiledIterations, _, err := DeconvolveData(data, xs, ys, kernel, params)
+
+		if err != nil {
+			t.Fatalf("DeconvolveData() error: %v", err)
+		}
+
+		if tiledIterations != iterations {
+			t.Errorf("iterations = %d when tiled, expected %d", tiledIterations, iterations)
+		}
+
+		if c.tolerance == 1e-2 && iterations >= c.iterations {
+			t.Errorf("iterations = %d, expected to converge within %d iterations", iterations, c.iterations)
+		}
+
+		// The tiles are seamless, i.e., the tiled deconvolution is that of the whole image:
+		for i := range whole {
+			if math.Abs(float64(whole[i]-tiled[i])) > 0.01*math.Abs(float64(whole[i])) {
+				t.Fatalf("pixel { %d, %d } = %f when tiled, expected %f (tolerance %g)", i%xs, i/xs, tiled[i], whole[i], c.tolerance)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestDeconvolveDataConvergence(t *testing.T) {
+	xs, ys := 64, 64
+
+	data, _ := getTestStars(xs, ys, 50000, 2)
+
+	params := DefaultParams()
+
+	params.Tolerance = 0.01
+
+	_, iterations, converged, err := DeconvolveData(data, xs, ys, getTestKernel(t), params)
+
+	if err != nil {
+		t.Fatalf("DeconvolveData() error: %v", err)
+	}
+
+	if !converged || iterations >= params.Iterations {
+		t.Errorf("iterations = %d, converged = %t, expected to converge within %d iterations", iterations, converged, params.Iterations)
+	}
+
+	params.Iterations = 0
+
+	if _, _, _, err := DeconvolveData(data, xs, ys, getTestKernel(t), params); err == nil {
+		t.Errorf("expected an error for zero iterations")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestDeconvolveDataRegularisation(t *testing.T) {
+	xs, ys := 64, 64
+
+	data, _ := getTestStars(xs, ys, 50000, 5)
+
+	params := DefaultParams()
+
+	params.Iterations = 30
+
+	params.Tolerance = 0
+
+	// The standard deviation of a starless region of the background:
+	getNoise := func(d []float32) float64 {
+		sum, sum2, n := 0.0, 0.0, 0.0
+
+		for y := 2; y < 8; y++ {
+			for x := 2; x < 62; x++ {
+				v := float64(d[y*xs+x])
+
+				sum, sum2, n = sum+v, sum2+v*v, n+1
+			}
+		}
+
+		return math.Sqrt(sum2/n - (sum/n)*(sum/n))
+	}
+
+	plain, _, _, err := DeconvolveData(data, xs, ys, getTestKernel(t), params)
+
+	if err != nil {
+		t.Fatalf("DeconvolveData() error: %v", err)
+	}
+
+	params.Regularisation = 0.01
+
+	regularised, _, _, err := DeconvolveData(data, xs, ys, getTestKernel(t), params)
+
+	if err != nil {
+		t.Fatalf("DeconvolveData() error: %v", err)
+	}
+
+	if getNoise(regularised) >= getNoise(plain) {
+		t.Errorf("noise = %f, expected less than the unregularised noise of %f", getNoise(regularised), getNoise(plain))
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestDeconvolveDataMask(t *testing.T) {
+	xs, ys := 64, 64
+
+	data, _ := getTestStars(xs, ys, 50000, 2)
+
+	// The first star, and a NaN pixel, are masked:
+	mask := make([]float32, len(data))
+
+	for y := 10; y < 23; y++ {
+		for x := 10; x < 23; x++ {
+			mask[y*xs+x] = 1
+		}
+	}
+
+	data[50*xs+5] = float32(math.NaN())
+
+	params := DefaultParams()
+
+	params.Mask = mask
+
+	out, _, _, err := DeconvolveData(data, xs, ys, getTestKernel(t), params)
+
+	if err != nil {
+		t.Fatalf("DeconvolveData() error: %v", err)
+	}
+
+	for i, m := range mask {
+		if m == 1 && math.Abs(float64(out[i]-data[i])) > 1e-3 {
+			t.Fatalf("masked pixel { %d, %d } = %f, expected %f", i%xs, i/xs, out[i], data[i])
+		}
+	}
+
+	if !math.IsNaN(float64(out[50*xs+5])) {
+		t.Errorf("expected the NaN pixel to be restored")
+	}
+
+	// The unmasked stars are deconvolved:
+	if out[40*xs+40] <= data[40*xs+40] {
+		t.Errorf("expected the unmasked star to be sharpened")
+	}
+
+	params.Mask = mask[1:]
+
+	if _, _, _, err := DeconvolveData(data, xs, ys, getTestKernel(t), params); err == nil {
+		t.Errorf("expected an error for a mask of the wrong size")
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,119 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/deconvolution
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package deconvolution
+
+/*****************************************************************************************************************/
+
+import "gonum.org/v1/gonum/dsp/fourier"
+
+/*****************************************************************************************************************/
+
+// The two-dimensional FFT of a (tile of an) image, by the one-dimensional FFT of each row and then each column. The
+// FFTs hold buffers, so each tile (i.e., each goroutine) requires its own.
+type fft2 struct {
+	xs     int
+	ys     int
+	rows   *fourier.CmplxFFT
+	cols   *fourier.CmplxFFT
+	column []complex128
+}
+
+/*****************************************************************************************************************/
+
+// Creates the two-dimensional FFT of the dimensions.
+func newFFT2(xs int, ys int) *fft2 {
+	return &fft2{
+		xs:     xs,
+		ys:     ys,
+		rows:   fourier.NewCmplxFFT(xs),
+		cols:   fourier.NewCmplxFFT(ys),
+		column: make([]complex128, ys),
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Transforms the data (in row-major order) to its Fourier coefficients, in place.
+func (f *fft2) forward(data []complex128) {
+	for j := 0; j < f.ys; j++ {
+		row := data[j*f.xs : (j+1)*f.xs]
+
+		f.rows.Coefficients(row, row)
+	}
+
+	for i := 0; i < f.xs; i++ {
+		for j := 0; j < f.ys; j++ {
+			f.column[j] = data[j*f.xs+i]
+		}
+
+		f.cols.Coefficients(f.column, f.column)
+
+		for j := 0; j < f.ys; j++ {
+			data[j*f.xs+i] = f.column[j]
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Transforms the Fourier coefficients (in row-major order) to the data, in place, normalised such that the inverse
+// of the forward transform is the identity.
+func (f *fft2) inverse(data []complex128) {
+	for i := 0; i < f.xs; i++ {
+		for j := 0; j < f.ys; j++ {
+			f.column[j] = data[j*f.xs+i]
+		}
+
+		f.cols.Sequence(f.column, f.column)
+
+		for j := 0; j < f.ys; j++ {
+			data[j*f.xs+i] = f.column[j]
+		}
+	}
+
+	n := complex(float64(f.xs*f.ys), 0)
+
+	for j := 0; j < f.ys; j++ {
+		row := data[j*f.xs : (j+1)*f.xs]
+
+		f.rows.Sequence(row, row)
+
+		for i := range row {
+			row[i] /= n
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Convolves (or, if conjugate, correlates) the real data with the kernel of the Fourier coefficients, using the
+// buffer, and writes the real part of the result to the destination.
+func (f *fft2) convolve(dst []float64, data []float64, kernel []complex128, buffer []complex128, conjugate bool) {
+	for i, v := range data {
+		buffer[i] = complex(v, 0)
+	}
+
+	f.forward(buffer)
+
+	for i, k := range kernel {
+		if conjugate {
+			k = complex(real(k), -imag(k))
+		}
+
+		buffer[i] *= k
+	}
+
+	f.inverse(buffer)
+
+	for i := range dst {
+		dst[i] = real(buffer[i])
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,292 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/deconvolution
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package deconvolution
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// Kernel is the Point Spread Function by which the image is deconvolved, i.e., a square image of odd size, centred
+// on its central pixel, normalised to a sum of one.
+type Kernel struct {
+	Size int       // The width (and height) of the kernel, in pixels
+	Data []float64 // The values of the kernel, in row-major order
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the value of the kernel at the offset { x, y } from its centre, or zero outside of the kernel.
+func (k *Kernel) At(x int, y int) float64 {
+	r := k.Size / 2
+
+	if x < -r || x > r || y < -r || y > r {
+		return 0
+	}
+
+	return k.Data[(y+r)*k.Size+x+r]
+}
+
+/*****************************************************************************************************************/
+
+/*
+NewKernelFromPSF()
+
+Synthesises the kernel from the fitted PSF of a star (see photometry.FitPSF()), i.e., the
+Gaussian or Moffat profile of its major and minor widths, rotation and β, without its amplitude
+or background. The size is odd, or if zero is sized to contain the wings of the profile, i.e.,
+twice the major FWHM for a Gaussian or four times for a Moffat.
+*/
+func NewKernelFromPSF(psf photometry.PSF, size int) (*Kernel, error) {
+	if psf.Major <= 0 || psf.Minor <= 0 {
+		return nil, errors.New("the widths of the PSF must be positive")
+	}
+
+	if psf.Model == photometry.MoffatPSF && psf.Beta <= 1 {
+		return nil, errors.New("the Moffat β of the PSF must be greater than one")
+	}
+
+	if size <= 0 {
+		// The FWHM along the major axis:
+		fwhm := 2 * math.Sqrt(2*math.Ln2) * psf.Major
+
+		radius := math.Ceil(2 * fwhm)
+
+		if psf.Model == photometry.MoffatPSF {
+			fwhm = 2 * psf.Major * math.Sqrt(math.Pow(2, 1/psf.Beta)-1)
+
+			radius = math.Ceil(4 * fwhm)
+		}
+
+		size = 2*int(math.Max(radius, 2)) + 1
+	}
+
+	if size%2 == 0 {
+		return nil, fmt.Errorf("the size of the kernel must be odd, but is %d", size)
+	}
+
+	k := &Kernel{Size: size, Data: make([]float64, size*size)}
+
+	r := size / 2
+
+	cos, sin := math.Cos(psf.Theta), math.Sin(psf.Theta)
+
+	for y := -r; y <= r; y++ {
+		for x := -r; x <= r; x++ {
+			// The position along the major (u) and minor (v) axes:
+			u, v := float64(x)*cos+float64(y)*sin, -float64(x)*sin+float64(y)*cos
+
+			value := math.Exp(-(u*u/(2*psf.Major*psf.Major) + v*v/(2*psf.Minor*psf.Minor)))
+
+			if psf.Model == photometry.MoffatPSF {
+				value = math.Pow(1+u*u/(psf.Major*psf.Major)+v*v/(psf.Minor*psf.Minor), -psf.Beta)
+			}
+
+			k.Data[(y+r)*size+x+r] = value
+		}
+	}
+
+	return k, k.normalise()
+}
+
+/*****************************************************************************************************************/
+
+/*
+NewKernelFromPSFs()
+
+Synthesises the kernel, as NewKernelFromPSF(), from the median PSF of the fitted PSFs of the
+detected stars of the frame (see photometry.FitPSFs()), i.e., the median widths and β, and the
+mean rotation (of the axes, i.e., modulo π), which are robust to poorly fitted stars.
+*/
+func NewKernelFromPSFs(psfs []photometry.PSF, size int) (*Kernel, error) {
+	if len(psfs) == 0 {
+		return nil, errors.New("at least one PSF is required to synthesise the kernel")
+	}
+
+	major, minor, beta := make([]float64, len(psfs)), make([]float64, len(psfs)), make([]float64, len(psfs))
+
+	cos, sin := 0.0, 0.0
+
+	for i, p := range psfs {
+		if p.Model != psfs[0].Model {
+			return nil, errors.New("the PSFs must be of the same model")
+		}
+
+		major[i], minor[i], beta[i] = p.Major, p.Minor, p.Beta
+
+		// The rotation of the axes is modulo π, so its doubled angle is averaged, weighted by the ellipticity:
+		weight := 1 - p.Minor/p.Major
+
+		cos, sin = cos+weight*math.Cos(2*p.Theta), sin+weight*math.Sin(2*p.Theta)
+	}
+
+	return NewKernelFromPSF(photometry.PSF{
+		Model: psfs[0].Model,
+		Major: qsort.MedianFloat64(major),
+		Minor: qsort.MedianFloat64(minor),
+		Theta: math.Atan2(sin, cos) / 2,
+		Beta:  qsort.MedianFloat64(beta),
+	}, size)
+}
+
+/*****************************************************************************************************************/
+
+/*
+NewKernelFromStars()
+
+Extracts the kernel as the stacked image of the stars of the frame (e.g., as found by
+StarsExtractor.FindStars()), i.e., the median of the background-subtracted, normalised images
+of the stars, each resampled (bilinearly) to be centred on its centroid.
+
+Stars whose image extends beyond the frame, contains NaN pixels, or whose peak is at or above
+the saturation level (if positive) are excluded. The size is odd, or if zero is eight times the
+median HFR of the stars.
+*/
+func NewKernelFromStars(data []float32, xs int, ys int, stars []photometry.Star, size int, saturation float32) (*Kernel, error) {
+	if len(data) != xs*ys {
+		return nil, fmt.Errorf("the image data of %d pixels does not match the dimensions %dx%d", len(data), xs, ys)
+	}
+
+	if size <= 0 {
+		hfr := make([]float64, len(stars))
+
+		for i, s := range stars {
+			hfr[i] = float64(s.HFR)
+		}
+
+		size = 2*int(math.Max(math.Ceil(4*qsort.MedianFloat64(hfr)), 2)) + 1
+	}
+
+	if size%2 == 0 {
+		return nil, fmt.Errorf("the size of the kernel must be odd, but is %d", size)
+	}
+
+	r := size / 2
+
+	stamps := [][]float64{}
+
+	for _, s := range stars {
+		x, y := float64(s.X), float64(s.Y)
+
+		if x-float64(r) < 0 || y-float64(r) < 0 || x+float64(r)+1 > float64(xs-1) || y+float64(r)+1 > float64(ys-1) {
+			continue
+		}
+
+		stamp := make([]float64, size*size)
+
+		valid := true
+
+		for j := -r; j <= r && valid; j++ {
+			for i := -r; i <= r; i++ {
+				v := getBilinear(data, xs, x+float64(i), y+float64(j))
+
+				if math.IsNaN(v) || (saturation > 0 && v >= float64(saturation)) {
+					valid = false
+					break
+				}
+
+				stamp[(j+r)*size+i+r] = v
+			}
+		}
+
+		if !valid {
+			continue
+		}
+
+		// The background is the median of the border of the stamp:
+		border := make([]float64, 0, 4*size)
+
+		for i := 0; i < size; i++ {
+			border = append(border, stamp[i], stamp[(size-1)*size+i], stamp[i*size], stamp[i*size+size-1])
+		}
+
+		background, sum := qsort.MedianFloat64(border), 0.0
+
+		for i := range stamp {
+			stamp[i] -= background
+
+			sum += stamp[i]
+		}
+
+		if sum <= 0 {
+			continue
+		}
+
+		for i := range stamp {
+			stamp[i] /= sum
+		}
+
+		stamps = append(stamps, stamp)
+	}
+
+	if len(stamps) == 0 {
+		return nil, errors.New("no stars could be extracted to stack into the kernel")
+	}
+
+	k := &Kernel{Size: size, Data: make([]float64, size*size)}
+
+	values := make([]float64, len(stamps))
+
+	for i := range k.Data {
+		for n, stamp := range stamps {
+			values[n] = stamp[i]
+		}
+
+		// The kernel is non-negative, so the noise of its wings is clipped:
+		k.Data[i] = math.Max(qsort.MedianFloat64(values), 0)
+	}
+
+	return k, k.normalise()
+}
+
+/*****************************************************************************************************************/
+
+// Normalises the kernel to a sum of one.
+func (k *Kernel) normalise() error {
+	sum := 0.0
+
+	for _, v := range k.Data {
+		sum += v
+	}
+
+	if sum <= 0 || math.IsNaN(sum) {
+		return errors.New("the kernel has no positive values")
+	}
+
+	for i := range k.Data {
+		k.Data[i] /= sum
+	}
+
+	return nil
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the bilinear interpolation of the data at { x, y }, which must be within the data.
+func getBilinear(data []float32, xs int, x float64, y float64) float64 {
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+
+	fx, fy := x-float64(x0), y-float64(y0)
+
+	v00, v10 := float64(data[y0*xs+x0]), float64(data[y0*xs+x0+1])
+
+	v01, v11 := float64(data[(y0+1)*xs+x0]), float64(data[(y0+1)*xs+x0+1])
+
+	return (v00*(1-fx)+v10*fx)*(1-fy) + (v01*(1-fx)+v11*fx)*fy
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,167 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/deconvolution
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package deconvolution
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/observerly/iris/pkg/internal/starfield"
+	"github.com/observerly/iris/pkg/photometry"
+)
+
+/*****************************************************************************************************************/
+
+// Renders Gaussian stars (σ of 1.5 pixels) of the given flux on a grid of positions 24 pixels apart (offset by a
+// fraction of a pixel) on a flat background with Gaussian noise, returning the data and the stars.
+func getTestStars(xs int, ys int, flux float64, noise float64) ([]float32, []photometry.Star) {
+	random := rand.New(rand.NewSource(42))
+
+	data := make([]float32, xs*ys)
+
+	for i := range data {
+		data[i] = float32(100 + random.NormFloat64()*noise)
+	}
+
+	stars := []photometry.Star{}
+
+	for cy := 16; cy < ys-16; cy += 24 {
+		for cx := 16; cx < xs-16; cx += 24 {
+			x, y := float64(cx)+0.3*random.Float64(), float64(cy)+0.3*random.Float64()
+
+			starfield.AddStar(data, xs, ys, x, y, flux)
+
+			stars = append(stars, photometry.Star{X: float32(x), Y: float32(y), HFR: 1.8})
+		}
+	}
+
+	return data, stars
+}
+
+/*****************************************************************************************************************/
+
+func TestNewKernelFromPSF(t *testing.T) {
+	k, err := NewKernelFromPSF(photometry.PSF{Model: photometry.GaussianPSF, Major: 2, Minor: 1, Theta: math.Pi / 2}, 0)
+
+	if err != nil {
+		t.Fatalf("NewKernelFromPSF() error: %v", err)
+	}
+
+	// The radius is twice the FWHM of the major axis, i.e., ceil(2 × 4.71):
+	if k.Size != 21 {
+		t.Errorf("size = %d, expected 21", k.Size)
+	}
+
+	sum := 0.0
+
+	for _, v := range k.Data {
+		sum += v
+	}
+
+	if math.Abs(sum-1) > 1e-9 {
+		t.Errorf("sum = %f, expected 1", sum)
+	}
+
+	// The major axis is rotated to the y axis:
+	if k.At(0, 3) <= k.At(3, 0) || math.Abs(k.At(0, 3)-k.At(0, -3)) > 1e-12 {
+		t.Errorf("expected the kernel to be elongated along the y axis, but got %g along y and %g along x", k.At(0, 3), k.At(3, 0))
+	}
+
+	if k.At(0, 0) != k.Data[len(k.Data)/2] || k.At(11, 0) != 0 {
+		t.Errorf("expected the kernel to be centred on its central pixel")
+	}
+
+	m, err := NewKernelFromPSF(photometry.PSF{Model: photometry.MoffatPSF, Major: 2, Minor: 2, Beta: 3}, 15)
+
+	if err != nil {
+		t.Fatalf("NewKernelFromPSF() error: %v", err)
+	}
+
+	if m.Size != 15 || m.At(0, 0) <= m.At(1, 0) {
+		t.Errorf("expected a 15x15 Moffat kernel peaked at its centre")
+	}
+
+	if _, err := NewKernelFromPSF(photometry.PSF{Model: photometry.GaussianPSF, Major: 2, Minor: 2}, 10); err == nil {
+		t.Errorf("expected an error for an even size")
+	}
+
+	if _, err := NewKernelFromPSF(photometry.PSF{Model: photometry.MoffatPSF, Major: 2, Minor: 2, Beta: 1}, 0); err == nil {
+		t.Errorf("expected an error for a Moffat β of one")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewKernelFromPSFs(t *testing.T) {
+	psfs := []photometry.PSF{
+		{Model: photometry.GaussianPSF, Major: 2.0, Minor: 1.5, Theta: 0.1},
+		{Model: photometry.GaussianPSF, Major: 2.2, Minor: 1.4, Theta: -0.1},
+		{Model: photometry.GaussianPSF, Major: 9.0, Minor: 1.6, Theta: 0},
+	}
+
+	k, err := NewKernelFromPSFs(psfs, 0)
+
+	if err != nil {
+		t.Fatalf("NewKernelFromPSFs() error: %v", err)
+	}
+
+	// The median PSF is { 2.2, 1.5, 0 }, not skewed by the poorly fitted star:
+	expected, _ := NewKernelFromPSF(photometry.PSF{Model: photometry.GaussianPSF, Major: 2.2, Minor: 1.5}, k.Size)
+
+	for i := range k.Data {
+		if math.Abs(k.Data[i]-expected.Data[i]) > 1e-3 {
+			t.Fatalf("kernel[%d] = %g, expected %g", i, k.Data[i], expected.Data[i])
+		}
+	}
+
+	if _, err := NewKernelFromPSFs(nil, 0); err == nil {
+		t.Errorf("expected an error without PSFs")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewKernelFromStars(t *testing.T) {
+	xs, ys := 128, 128
+
+	data, stars := getTestStars(xs, ys, 50000, 2)
+
+	// A saturated star is excluded:
+	stars[0].X, stars[0].Y = 64, 64
+
+	data[64*xs+64] = 65535
+
+	k, err := NewKernelFromStars(data, xs, ys, stars, 15, 65535)
+
+	if err != nil {
+		t.Fatalf("NewKernelFromStars() error: %v", err)
+	}
+
+	expected, _ := NewKernelFromPSF(photometry.PSF{Model: photometry.GaussianPSF, Major: 1.5, Minor: 1.5}, 15)
+
+	// The stacked stars are the Gaussian, broadened slightly by the bilinear resampling:
+	for i := range k.Data {
+		if math.Abs(k.Data[i]-expected.Data[i]) > 0.01 {
+			t.Fatalf("kernel[%d] = %g, expected %g", i, k.Data[i], expected.Data[i])
+		}
+	}
+
+	if k.At(0, 0) < k.At(1, 0) || k.At(0, 0) < k.At(0, 1) {
+		t.Errorf("expected the kernel to be peaked at its centre")
+	}
+
+	if _, err := NewKernelFromStars(data, xs, ys, nil, 15, 0); err == nil {
+		t.Errorf("expected an error without stars")
+	}
+}
+
+/*****************************************************************************************************************/
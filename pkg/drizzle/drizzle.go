@@ -0,0 +1,465 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/drizzle
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package drizzle
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/iris"
+)
+
+/*****************************************************************************************************************/
+
+// Kernel is the shape of the "drop" into which each input pixel is shrunk before being drizzled onto the output.
+type Kernel int
+
+/*****************************************************************************************************************/
+
+const (
+	// A square drop of side pixfrac input pixels, whose flux is shared by the area of its overlap with each output
+	// pixel, as in the original variable-pixel linear reconstruction:
+	Square Kernel = iota
+	// A circular Gaussian drop with a FWHM of pixfrac input pixels, which is smoother at the cost of resolution:
+	Gaussian
+)
+
+/*****************************************************************************************************************/
+
+// Returns the name of the kernel, e.g., "square".
+func (k Kernel) String() string {
+	switch k {
+	case Square:
+		return "square"
+	case Gaussian:
+		return "gaussian"
+	default:
+		return "unknown"
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Params describes the output grid of the drizzle, and the drops into which each input pixel is shrunk.
+type Params struct {
+	Scale   float64 // The number of output pixels per input pixel along each axis, e.g., 2 halves the pixel size
+	Pixfrac float64 // The size of each drop as a fraction of the input pixel, from 0 (exclusive) to 1
+	Kernel  Kernel  // The shape of each drop
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default drizzle parameters, i.e., square drops of 0.7 input pixels onto an output grid of twice the
+// resolution of the reference frame.
+func DefaultParams() Params {
+	return Params{
+		Scale:   2,
+		Pixfrac: 0.7,
+		Kernel:  Square,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Drizzle accumulates the drops of each input frame onto the output grid, with the weight map of each channel.
+type Drizzle struct {
+	Width    int         // The width of the output grid, in pixels
+	Height   int         // The height of the output grid, in pixels
+	Channels int         // The number of channels, i.e., 1 for monochrome or 3 (R, G and B) for CFA drizzle
+	Count    int         // The number of frames drizzled
+	Params   Params      // The drizzle parameters
+	Sum      [][]float64 // The weighted sum of the drops onto each output pixel, for each channel
+	Weight   [][]float64 // The sum of the weights of the drops onto each output pixel, for each channel
+}
+
+/*****************************************************************************************************************/
+
+// Creates a monochrome drizzle onto the grid of a reference frame of the given width and height, scaled by
+// params.Scale.
+func NewDrizzle(width int, height int, params Params) (*Drizzle, error) {
+	return newDrizzle(width, height, 1, params)
+}
+
+/*****************************************************************************************************************/
+
+// Creates a CFA drizzle, i.e., of the R, G and B channels of raw one-shot colour frames, onto the grid of a
+// reference frame of the given width and height, scaled by params.Scale.
+func NewCFADrizzle(width int, height int, params Params) (*Drizzle, error) {
+	return newDrizzle(width, height, 3, params)
+}
+
+/*****************************************************************************************************************/
+
+func newDrizzle(width int, height int, channels int, params Params) (*Drizzle, error) {
+	if width <= 0 || height <= 0 {
+		return nil, fmt.Errorf("invalid reference frame dimensions %dx%d", width, height)
+	}
+
+	if params.Scale <= 0 {
+		return nil, fmt.Errorf("the drizzle scale must be positive, got %g", params.Scale)
+	}
+
+	if params.Pixfrac <= 0 || params.Pixfrac > 1 {
+		return nil, fmt.Errorf("the drizzle pixfrac must be in (0, 1], got %g", params.Pixfrac)
+	}
+
+	w := int(math.Round(float64(width) * params.Scale))
+
+	h := int(math.Round(float64(height) * params.Scale))
+
+	d := &Drizzle{
+		Width:    w,
+		Height:   h,
+		Channels: channels,
+		Params:   params,
+		Sum:      make([][]float64, channels),
+		Weight:   make([][]float64, channels),
+	}
+
+	for c := 0; c < channels; c++ {
+		d.Sum[c] = make([]float64, w*h)
+
+		d.Weight[c] = make([]float64, w*h)
+	}
+
+	return d, nil
+}
+
+/*****************************************************************************************************************/
+
+/*
+Add()
+
+Drizzles the xs by ys frame onto the output grid of a monochrome drizzle, through the affine
+or projective transform mapping the frame's pixel coordinates onto the reference frame's pixel
+coordinates (e.g., the transform of a registration.Result).
+
+Each input pixel may be given a weight (e.g., zero for known defects, or the inverse variance
+of the frame); a nil weights slice weights every pixel equally. NaN pixels are not drizzled.
+*/
+func (d *Drizzle) Add(data []float32, xs int, ys int, transform [3][3]float64, weights []float32) error {
+	if d.Channels != 1 {
+		return errors.New("the drizzle is of CFA data, use AddCFA to drizzle raw one-shot colour frames")
+	}
+
+	return d.add(data, xs, ys, transform, weights, func(x int, y int) int { return 0 })
+}
+
+/*****************************************************************************************************************/
+
+/*
+AddCFA()
+
+Drizzles the xs by ys raw (un-debayered) one-shot colour frame onto the R, G and B output grids
+of a CFA drizzle, dropping each photosite only onto the channel of its colour filter, such that
+no colour is interpolated, through the transform mapping the frame's pixel coordinates onto the
+reference frame's pixel coordinates.
+
+The cfa is the pattern of the Colour Filter Array, e.g., "RGGB".
+*/
+func (d *Drizzle) AddCFA(data []float32, xs int, ys int, cfa string, transform [3][3]float64, weights []float32) error {
+	if d.Channels != 3 {
+		return errors.New("the drizzle is of monochrome data, use Add to drizzle monochrome frames")
+	}
+
+	exposure := iris.RGGBExposure{ColourFilterArray: strings.TrimSpace(cfa)}
+
+	xOffset, yOffset, err := exposure.GetBayerMatrixOffset()
+
+	if err != nil {
+		return err
+	}
+
+	return d.add(data, xs, ys, transform, weights, func(x int, y int) int {
+		dx, dy := (x+xOffset)&1, (y+yOffset)&1
+
+		switch {
+		case dx == 0 && dy == 0:
+			return 0
+		case dx == 1 && dy == 1:
+			return 2
+		default:
+			return 1
+		}
+	})
+}
+
+/*****************************************************************************************************************/
+
+// Drizzles the raw Colour Filter Array of the exposure onto the R, G and B output grids of a CFA drizzle, skipping
+// the debayering step entirely.
+func (d *Drizzle) AddRGGBExposure(exposure *iris.RGGBExposure, transform [3][3]float64, weights []float32) error {
+	data := make([]float32, exposure.Width*exposure.Height)
+
+	for y := 0; y < exposure.Height && y < len(exposure.Raw); y++ {
+		for x := 0; x < exposure.Width && x < len(exposure.Raw[y]); x++ {
+			data[y*exposure.Width+x] = float32(exposure.Raw[y][x])
+		}
+	}
+
+	return d.AddCFA(data, exposure.Width, exposure.Height, exposure.ColourFilterArray, transform, weights)
+}
+
+/*****************************************************************************************************************/
+
+func (d *Drizzle) add(data []float32, xs int, ys int, transform [3][3]float64, weights []float32, channel func(x int, y int) int) error {
+	if xs <= 0 || ys <= 0 || len(data) != xs*ys {
+		return fmt.Errorf("the frame data of %d pixels does not match the dimensions %dx%d", len(data), xs, ys)
+	}
+
+	if weights != nil && len(weights) != len(data) {
+		return fmt.Errorf("the weights of %d pixels do not match the frame data of %d pixels", len(weights), len(data))
+	}
+
+	s := d.Params.Scale
+
+	// Maps the input pixel coordinates onto the output grid, where the output pixel centres are at integers:
+	toOutput := func(x float64, y float64) (vertex, bool) {
+		w := transform[2][0]*x + transform[2][1]*y + transform[2][2]
+
+		if w == 0 {
+			return vertex{}, false
+		}
+
+		u := (transform[0][0]*x + transform[0][1]*y + transform[0][2]) / w
+
+		v := (transform[1][0]*x + transform[1][1]*y + transform[1][2]) / w
+
+		return vertex{(u+0.5)*s - 0.5, (v+0.5)*s - 0.5}, true
+	}
+
+	half := d.Params.Pixfrac / 2
+
+	drop := make([]vertex, 4)
+
+	a, b := make([]vertex, 0, 8), make([]vertex, 0, 8)
+
+	for y := 0; y < ys; y++ {
+		for x := 0; x < xs; x++ {
+			i := y*xs + x
+
+			value := float64(data[i])
+
+			weight := 1.0
+
+			if weights != nil {
+				weight = float64(weights[i])
+			}
+
+			if math.IsNaN(value) || !(weight > 0) {
+				continue
+			}
+
+			sum, wmap := d.Sum[channel(x, y)], d.Weight[channel(x, y)]
+
+			// The corners of the drop, in anticlockwise order, on the output grid:
+			ok := true
+
+			for k, corner := range [4][2]float64{{-half, -half}, {half, -half}, {half, half}, {-half, half}} {
+				var valid bool
+
+				drop[k], valid = toOutput(float64(x)+corner[0], float64(y)+corner[1])
+
+				ok = ok && valid
+			}
+
+			if !ok {
+				continue
+			}
+
+			switch d.Params.Kernel {
+			case Gaussian:
+				d.dropGaussian(drop, value, weight, sum, wmap)
+			default:
+				d.dropSquare(drop, value, weight, sum, wmap, a, b)
+			}
+		}
+	}
+
+	d.Count++
+
+	return nil
+}
+
+/*****************************************************************************************************************/
+
+// Drops the value onto each output pixel by the area of its overlap with the (transformed) square drop.
+func (d *Drizzle) dropSquare(drop []vertex, value float64, weight float64, sum []float64, wmap []float64, a []vertex, b []vertex) {
+	minX, maxX, minY, maxY := drop[0].x, drop[0].x, drop[0].y, drop[0].y
+
+	for _, v := range drop[1:] {
+		minX, maxX = math.Min(minX, v.x), math.Max(maxX, v.x)
+
+		minY, maxY = math.Min(minY, v.y), math.Max(maxY, v.y)
+	}
+
+	x0, x1 := max(int(math.Floor(minX+0.5)), 0), min(int(math.Ceil(maxX-0.5)), d.Width-1)
+
+	y0, y1 := max(int(math.Floor(minY+0.5)), 0), min(int(math.Ceil(maxY-0.5)), d.Height-1)
+
+	for v := y0; v <= y1; v++ {
+		for u := x0; u <= x1; u++ {
+			overlap := getOverlapArea(drop, float64(u)-0.5, float64(u)+0.5, float64(v)-0.5, float64(v)+0.5, a, b)
+
+			if overlap <= 0 {
+				continue
+			}
+
+			sum[v*d.Width+u] += value * weight * overlap
+
+			wmap[v*d.Width+u] += weight * overlap
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Drops the value onto each output pixel by a circular Gaussian centred on the (transformed) drop, with a FWHM of
+// the side of the drop, normalised to the area of the drop.
+func (d *Drizzle) dropGaussian(drop []vertex, value float64, weight float64, sum []float64, wmap []float64) {
+	cx := (drop[0].x + drop[1].x + drop[2].x + drop[3].x) / 4
+
+	cy := (drop[0].y + drop[1].y + drop[2].y + drop[3].y) / 4
+
+	area := getPolygonArea(drop)
+
+	// The FWHM is the side of the (square) drop on the output grid:
+	sigma := math.Sqrt(area) / (2 * math.Sqrt(2*math.Ln2))
+
+	norm := area / (2 * math.Pi * sigma * sigma)
+
+	radius := 3 * sigma
+
+	x0, x1 := max(int(math.Floor(cx-radius)), 0), min(int(math.Ceil(cx+radius)), d.Width-1)
+
+	y0, y1 := max(int(math.Floor(cy-radius)), 0), min(int(math.Ceil(cy+radius)), d.Height-1)
+
+	for v := y0; v <= y1; v++ {
+		for u := x0; u <= x1; u++ {
+			r2 := (float64(u)-cx)*(float64(u)-cx) + (float64(v)-cy)*(float64(v)-cy)
+
+			if r2 > radius*radius {
+				continue
+			}
+
+			w := weight * norm * math.Exp(-r2/(2*sigma*sigma))
+
+			sum[v*d.Width+u] += value * w
+
+			wmap[v*d.Width+u] += w
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Returns the drizzled data of the channel, i.e., the weighted mean of the drops onto each output pixel, which
+// is NaN where no drop has fallen.
+func (d *Drizzle) GetData(channel int) ([]float32, error) {
+	if channel < 0 || channel >= d.Channels {
+		return nil, fmt.Errorf("invalid channel %d, the drizzle has %d channels", channel, d.Channels)
+	}
+
+	data := make([]float32, d.Width*d.Height)
+
+	for i := range data {
+		if d.Weight[channel][i] > 0 {
+			data[i] = float32(d.Sum[channel][i] / d.Weight[channel][i])
+		} else {
+			data[i] = float32(math.NaN())
+		}
+	}
+
+	return data, nil
+}
+
+/*****************************************************************************************************************/
+
+// Returns the weight map of the channel, i.e., the sum of the weights of the drops onto each output pixel.
+func (d *Drizzle) GetWeightMap(channel int) ([]float32, error) {
+	if channel < 0 || channel >= d.Channels {
+		return nil, fmt.Errorf("invalid channel %d, the drizzle has %d channels", channel, d.Channels)
+	}
+
+	weights := make([]float32, d.Width*d.Height)
+
+	for i, w := range d.Weight[channel] {
+		weights[i] = float32(w)
+	}
+
+	return weights, nil
+}
+
+/*****************************************************************************************************************/
+
+// Returns the drizzled channel as a FITS image, recording the drizzle parameters in its header.
+func (d *Drizzle) GetFITSImage(channel int, adu int32) (*fits.FITSImage, error) {
+	data, err := d.GetData(channel)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return d.newFITSImage(data, channel, adu), nil
+}
+
+/*****************************************************************************************************************/
+
+// Returns the weight map of the channel as a FITS image.
+func (d *Drizzle) GetWeightMapFITSImage(channel int) (*fits.FITSImage, error) {
+	weights, err := d.GetWeightMap(channel)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f := d.newFITSImage(weights, channel, 0)
+
+	f.Header.Set("IMAGETYP", "Weight Map", "The drizzle weight of each pixel")
+
+	return f, nil
+}
+
+/*****************************************************************************************************************/
+
+func (d *Drizzle) newFITSImage(data []float32, channel int, adu int32) *fits.FITSImage {
+	f := fits.NewFITSImage(2, int32(d.Width), int32(d.Height), adu)
+
+	f.Data = data
+
+	f.Naxisn = []int32{int32(d.Width), int32(d.Height)}
+
+	f.Pixels = int32(d.Width * d.Height)
+
+	f.Header.Set("NCOMBINE", d.Count, "The number of frames combined")
+
+	f.Header.Set("DRIZSCAL", float32(d.Params.Scale), "The drizzle output scale")
+
+	f.Header.Set("DRIZPFRC", float32(d.Params.Pixfrac), "The drizzle pixfrac (drop size)")
+
+	f.Header.Set("DRIZKERN", d.Params.Kernel.String(), "The drizzle kernel")
+
+	if d.Channels == 3 {
+		f.Header.Set("CHANNEL", []string{"Red", "Green", "Blue"}[channel], "RGB Channel")
+	}
+
+	f.Header.History = append(
+		f.Header.History,
+		fmt.Sprintf("Drizzled %d frames with a %s kernel, scale %g and pixfrac %g", d.Count, d.Params.Kernel, d.Params.Scale, d.Params.Pixfrac),
+	)
+
+	return f
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,247 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/drizzle
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package drizzle
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+
+	"github.com/observerly/iris/pkg/iris"
+)
+
+/*****************************************************************************************************************/
+
+var identity = [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+
+/*****************************************************************************************************************/
+
+func getTestData(xs int, ys int) []float32 {
+	data := make([]float32, xs*ys)
+
+	for i := range data {
+		data[i] = float32(i%7 + 10*(i/xs))
+	}
+
+	return data
+}
+
+/*****************************************************************************************************************/
+
+func TestGetOverlapArea(t *testing.T) {
+	square := []vertex{{0, 0}, {1, 0}, {1, 1}, {0, 1}}
+
+	if a := getOverlapArea(square, 0.5, 1.5, 0.5, 1.5, nil, nil); math.Abs(a-0.25) > 1e-12 {
+		t.Errorf("overlap = %f, expected 0.25", a)
+	}
+
+	diamond := []vertex{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+
+	if a := getOverlapArea(diamond, 0, 2, -2, 2, nil, nil); math.Abs(a-1) > 1e-12 {
+		t.Errorf("overlap = %f, expected 1", a)
+	}
+
+	if a := getOverlapArea(square, 2, 3, 2, 3, nil, nil); a != 0 {
+		t.Errorf("overlap = %f, expected 0", a)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestDrizzleIdentityReproducesFrame(t *testing.T) {
+	data := getTestData(6, 5)
+
+	for _, pixfrac := range []float64{1, 0.5} {
+		d, err := NewDrizzle(6, 5, Params{Scale: 1, Pixfrac: pixfrac, Kernel: Square})
+
+		if err != nil {
+			t.Fatalf("NewDrizzle() error: %v", err)
+		}
+
+		if err := d.Add(data, 6, 5, identity, nil); err != nil {
+			t.Fatalf("Add() error: %v", err)
+		}
+
+		out, _ := d.GetData(0)
+
+		weights, _ := d.GetWeightMap(0)
+
+		for i := range data {
+			if math.Abs(float64(out[i]-data[i])) > 1e-4 {
+				t.Errorf("pixfrac %g pixel %d = %f, expected %f", pixfrac, i, out[i], data[i])
+			}
+
+			if math.Abs(float64(weights[i])-pixfrac*pixfrac) > 1e-9 {
+				t.Errorf("pixfrac %g weight %d = %f, expected %f", pixfrac, i, weights[i], pixfrac*pixfrac)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestDrizzleConservesWeight(t *testing.T) {
+	d, err := NewDrizzle(8, 8, Params{Scale: 2, Pixfrac: 0.5, Kernel: Square})
+
+	if err != nil {
+		t.Fatalf("NewDrizzle() error: %v", err)
+	}
+
+	if d.Width != 16 || d.Height != 16 {
+		t.Fatalf("dimensions = %dx%d, expected 16x16", d.Width, d.Height)
+	}
+
+	data := make([]float32, 64)
+
+	for i := range data {
+		data[i] = float32(math.NaN())
+	}
+
+	data[3*8+4] = 100
+
+	// A sub-pixel shift and a rotation, such that the drop straddles several output pixels:
+	transform := [3][3]float64{{math.Cos(0.3), -math.Sin(0.3), 0.37}, {math.Sin(0.3), math.Cos(0.3), 0.81}, {0, 0, 1}}
+
+	if err := d.Add(data, 8, 8, transform, nil); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+
+	sum, weight, covered := 0.0, 0.0, 0
+
+	for i := range d.Weight[0] {
+		sum += d.Sum[0][i]
+
+		weight += d.Weight[0][i]
+
+		if d.Weight[0][i] > 0 {
+			covered++
+		}
+	}
+
+	// The drop of 0.5 input pixels covers an area of one output pixel:
+	if math.Abs(weight-1) > 1e-9 || math.Abs(sum-100) > 1e-7 {
+		t.Errorf("total weight = %f and sum = %f, expected 1 and 100", weight, sum)
+	}
+
+	if covered < 2 {
+		t.Errorf("the drop covered %d output pixels, expected it to straddle several", covered)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestDrizzleWeightsAndGaussianKernel(t *testing.T) {
+	data := make([]float32, 100)
+
+	for i := range data {
+		data[i] = 42
+	}
+
+	weights := make([]float32, 100)
+
+	for i := range weights {
+		weights[i] = 1
+	}
+
+	// A hot pixel, excluded by a zero weight:
+	data[55], weights[55] = 60000, 0
+
+	d, err := NewDrizzle(10, 10, Params{Scale: 1.5, Pixfrac: 0.8, Kernel: Gaussian})
+
+	if err != nil {
+		t.Fatalf("NewDrizzle() error: %v", err)
+	}
+
+	if err := d.Add(data, 10, 10, [3][3]float64{{1, 0, 0.25}, {0, 1, -0.4}, {0, 0, 1}}, weights); err != nil {
+		t.Fatalf("Add() error: %v", err)
+	}
+
+	out, _ := d.GetData(0)
+
+	for i, v := range out {
+		if !math.IsNaN(float64(v)) && math.Abs(float64(v)-42) > 1e-3 {
+			t.Errorf("pixel %d = %f, expected 42", i, v)
+		}
+	}
+
+	f, err := d.GetFITSImage(0, 65535)
+
+	if err != nil {
+		t.Fatalf("GetFITSImage() error: %v", err)
+	}
+
+	if f.Header.Naxis1 != 15 || f.Header.Strings["DRIZKERN"].Value != "gaussian" {
+		t.Errorf("NAXIS1 = %d and DRIZKERN = %q, expected 15 and gaussian", f.Header.Naxis1, f.Header.Strings["DRIZKERN"].Value)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestDrizzleRGGBExposure(t *testing.T) {
+	xs, ys := 8, 6
+
+	raw := make([][]uint32, ys)
+
+	for y := range raw {
+		raw[y] = make([]uint32, xs)
+
+		for x := range raw[y] {
+			switch {
+			case x%2 == 0 && y%2 == 0:
+				raw[y][x] = 100
+			case x%2 == 1 && y%2 == 1:
+				raw[y][x] = 300
+			default:
+				raw[y][x] = 200
+			}
+		}
+	}
+
+	exposure := iris.NewRGGBExposure(raw, 65535, xs, ys, "RGGB")
+
+	// Each output pixel covers a 2x2 Bayer matrix, so receives a drop of every colour:
+	d, err := NewCFADrizzle(xs, ys, Params{Scale: 0.5, Pixfrac: 1, Kernel: Square})
+
+	if err != nil {
+		t.Fatalf("NewCFADrizzle() error: %v", err)
+	}
+
+	if err := d.Add(make([]float32, xs*ys), xs, ys, identity, nil); err == nil {
+		t.Errorf("expected an error adding monochrome data to a CFA drizzle")
+	}
+
+	if err := d.AddRGGBExposure(exposure, identity, nil); err != nil {
+		t.Fatalf("AddRGGBExposure() error: %v", err)
+	}
+
+	for c, expected := range []float32{100, 200, 300} {
+		out, _ := d.GetData(c)
+
+		for i, v := range out {
+			if v != expected {
+				t.Errorf("channel %d pixel %d = %f, expected %f", c, i, v, expected)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewDrizzleInvalidParams(t *testing.T) {
+	if _, err := NewDrizzle(10, 10, Params{Scale: 2, Pixfrac: 1.5}); err == nil {
+		t.Errorf("expected an error for a pixfrac greater than 1")
+	}
+
+	if _, err := NewDrizzle(10, 10, Params{Scale: 0, Pixfrac: 0.5}); err == nil {
+		t.Errorf("expected an error for a zero scale")
+	}
+}
+
+/*****************************************************************************************************************/
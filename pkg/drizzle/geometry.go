@@ -0,0 +1,85 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/drizzle
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package drizzle
+
+/*****************************************************************************************************************/
+
+// A vertex of a polygon on the output pixel grid:
+type vertex struct {
+	x float64
+	y float64
+}
+
+/*****************************************************************************************************************/
+
+// Computes the area of the overlap of the convex polygon with the axis-aligned square [x0, x1] by [y0, y1], by
+// clipping the polygon against each edge of the square (Sutherland–Hodgman) and taking the area of what remains.
+func getOverlapArea(polygon []vertex, x0 float64, x1 float64, y0 float64, y1 float64, a []vertex, b []vertex) float64 {
+	a = append(a[:0], polygon...)
+
+	// Clip against each of the edges x >= x0, x <= x1, y >= y0 and y <= y1 in turn:
+	for edge := 0; edge < 4 && len(a) > 0; edge++ {
+		inside := func(v vertex) float64 {
+			switch edge {
+			case 0:
+				return v.x - x0
+			case 1:
+				return x1 - v.x
+			case 2:
+				return v.y - y0
+			default:
+				return y1 - v.y
+			}
+		}
+
+		b = b[:0]
+
+		for i := range a {
+			p, q := a[i], a[(i+1)%len(a)]
+
+			dp, dq := inside(p), inside(q)
+
+			if dp >= 0 {
+				b = append(b, p)
+			}
+
+			// The edge of the polygon crosses the clipping edge:
+			if (dp >= 0) != (dq >= 0) {
+				t := dp / (dp - dq)
+
+				b = append(b, vertex{p.x + t*(q.x-p.x), p.y + t*(q.y-p.y)})
+			}
+		}
+
+		a, b = b, a
+	}
+
+	return getPolygonArea(a)
+}
+
+/*****************************************************************************************************************/
+
+// Computes the (unsigned) area of the polygon by the shoelace formula.
+func getPolygonArea(polygon []vertex) float64 {
+	area := 0.0
+
+	for i := range polygon {
+		p, q := polygon[i], polygon[(i+1)%len(polygon)]
+
+		area += p.x*q.y - q.x*p.y
+	}
+
+	if area < 0 {
+		area = -area
+	}
+
+	return area / 2
+}
+
+/*****************************************************************************************************************/
@@ -71,6 +71,7 @@ type FITSObservation struct {
 	Telescope  string    `json:"telescope"`  // The telescope used to acquire the data
 	Instrument string    `json:"instrument"` // The instrument used to acquire the data
 	Observer   string    `json:"observer"`   // Who acquired the data
+	PixelScale float32   `json:"pixelScale"` // Pixel scale of observation (in arcseconds per pixel), or zero if unknown
 }
 
 /*****************************************************************************************************************/
@@ -168,6 +169,58 @@ func NewFITSImageFrom2DData(ex [][]uint32, naxis int32, naxis1 int32, naxis2 int
 
 /*****************************************************************************************************************/
 
+// Creates a deep copy of the FITS image, including the header and data, such that the copy can be
+// modified without any side effects on the original image.
+func (f *FITSImage) Copy() *FITSImage {
+	h := f.Header
+
+	h.Bools = make(map[string]FITSHeaderBool, len(f.Header.Bools))
+
+	for k, v := range f.Header.Bools {
+		h.Bools[k] = v
+	}
+
+	h.Ints = make(map[string]FITSHeaderInt, len(f.Header.Ints))
+
+	for k, v := range f.Header.Ints {
+		h.Ints[k] = v
+	}
+
+	h.Floats = make(map[string]FITSHeaderFloat, len(f.Header.Floats))
+
+	for k, v := range f.Header.Floats {
+		h.Floats[k] = v
+	}
+
+	h.Strings = make(map[string]FITSHeaderString, len(f.Header.Strings))
+
+	for k, v := range f.Header.Strings {
+		h.Strings[k] = v
+	}
+
+	h.Dates = make(map[string]FITSHeaderString, len(f.Header.Dates))
+
+	for k, v := range f.Header.Dates {
+		h.Dates[k] = v
+	}
+
+	h.Comments = append(make([]string, 0, len(f.Header.Comments)), f.Header.Comments...)
+
+	h.History = append(make([]string, 0, len(f.Header.History)), f.Header.History...)
+
+	c := *f
+
+	c.Header = h
+
+	c.Naxisn = append(make([]int32, 0, len(f.Naxisn)), f.Naxisn...)
+
+	c.Data = append(make([]float32, 0, len(f.Data)), f.Data...)
+
+	return &c
+}
+
+/*****************************************************************************************************************/
+
 func (f *FITSImage) AddObservationEntry(observation *FITSObservation) *FITSImage {
 	// Set the Object Name:
 	f.Header.Set("OBJECT", observation.Object, "The name for the object observed")
@@ -220,6 +273,11 @@ func (f *FITSImage) AddObservationEntry(observation *FITSObservation) *FITSImage
 	// Set the Observer Name:
 	f.Header.Set("OBSERVER", observation.Observer, "Who owns the observation data")
 
+	// Set the Pixel Scale of the Observation, if known:
+	if observation.PixelScale > 0 {
+		f.Header.Set("PIXSCALE", observation.PixelScale, "Pixel scale (in arcseconds per pixel)")
+	}
+
 	return f
 }
 
@@ -240,6 +298,54 @@ func (f *FITSImage) AddObserverEntry(observer *FITSObserver) *FITSImage {
 
 /*****************************************************************************************************************/
 
+// Obtains the width and height of the frame from its axis dimensions, falling back to the NAXIS1 and NAXIS2
+// header values (e.g., for frames created with NewFITSImage), returning false if they are not known.
+func (f *FITSImage) GetDimensions() (int, int, bool) {
+	if len(f.Naxisn) >= 2 && f.Naxisn[0] > 0 && f.Naxisn[1] > 0 {
+		return int(f.Naxisn[0]), int(f.Naxisn[1]), true
+	}
+
+	if f.Header.Naxis1 > 0 && f.Header.Naxis2 > 0 {
+		return int(f.Header.Naxis1), int(f.Header.Naxis2), true
+	}
+
+	return 0, 0, false
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the exposure time of the frame in seconds, from the frame or its EXPOSURE (or EXPTIME) keyword.
+func (f *FITSImage) GetExposureTime() float64 {
+	exposure := float64(f.Exposure)
+
+	for _, key := range []string{"EXPOSURE", "EXPTIME"} {
+		if exposure > 0 {
+			break
+		}
+
+		if v, ok := f.Header.GetFloat(key); ok {
+			exposure = v
+		}
+	}
+
+	return exposure
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the mid-exposure time of the frame, from its DATE-OBS and exposure time.
+func (f *FITSImage) GetMidExposureTime() (time.Time, error) {
+	t, err := f.Header.GetDateObs()
+
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return t.Add(time.Duration(f.GetExposureTime() / 2 * float64(time.Second))), nil
+}
+
+/*****************************************************************************************************************/
+
 func (f *FITSImage) ExtractHFR(radius float32, sigma float32, starInOut float32) float32 {
 	se := photometry.NewStarsExtractor(f.Data, int(f.Naxisn[0]), int(f.Naxisn[1]), radius, f.ADU)
 
@@ -636,3 +636,71 @@ func TestNewAddObserverEntry(t *testing.T) {
 }
 
 /*****************************************************************************************************************/
+
+func TestFITSImageCopy(t *testing.T) {
+	var ex = [][]uint32{
+		{1, 2, 3},
+		{4, 5, 6},
+	}
+
+	f := NewFITSImageFrom2DData(ex, 2, 3, 2, 255)
+
+	f.Header.Set("OBJECT", "M42", "The name for the object observed")
+
+	c := f.Copy()
+
+	c.Data[0] = 42
+
+	c.Naxisn[0] = 1
+
+	c.Header.Set("OBJECT", "M31", "The name for the object observed")
+
+	c.Header.History = append(c.Header.History, "copied")
+
+	if f.Data[0] != 1 {
+		t.Errorf("Copy() failed: expected original data to be unchanged, got %f", f.Data[0])
+	}
+
+	if f.Naxisn[0] != 3 {
+		t.Errorf("Copy() failed: expected original naxis1 to be unchanged, got %d", f.Naxisn[0])
+	}
+
+	if f.Header.Strings["OBJECT"].Value != "M42" {
+		t.Errorf("Copy() failed: expected original header to be unchanged, got %s", f.Header.Strings["OBJECT"].Value)
+	}
+
+	if len(f.Header.History) != 0 {
+		t.Errorf("Copy() failed: expected original history to be unchanged, got %v", f.Header.History)
+	}
+
+	if c.Pixels != f.Pixels || c.ADU != f.ADU {
+		t.Errorf("Copy() failed: expected pixels and ADU to be copied")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFITSImageGetDimensions(t *testing.T) {
+	f := NewFITSImage(2, 800, 600, 65535)
+
+	// Without axis dimensions, the NAXIS1 and NAXIS2 header values are used:
+	f.Naxisn = nil
+
+	if xs, ys, ok := f.GetDimensions(); !ok || xs != 800 || ys != 600 {
+		t.Errorf("GetDimensions() failed: expected 800x600, got %dx%d (%v)", xs, ys, ok)
+	}
+
+	f.Naxisn = []int32{400, 300}
+
+	if xs, ys, ok := f.GetDimensions(); !ok || xs != 400 || ys != 300 {
+		t.Errorf("GetDimensions() failed: expected 400x300, got %dx%d (%v)", xs, ys, ok)
+	}
+
+	f.Naxisn, f.Header.Naxis1, f.Header.Naxis2 = nil, 0, 0
+
+	if _, _, ok := f.GetDimensions(); ok {
+		t.Errorf("GetDimensions() failed: expected the dimensions to be unknown")
+	}
+}
+
+/*****************************************************************************************************************/
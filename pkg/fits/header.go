@@ -72,6 +72,8 @@ type FITSHeader struct {
 	History  []string
 	End      bool
 	Length   int32
+	// The key of the string value continued over the following CONTINUE records, if any:
+	continued string
 }
 
 /*****************************************************************************************************************/
@@ -146,6 +148,63 @@ func IsDate(s string) (time.Time, error) {
 
 /*****************************************************************************************************************/
 
+// Obtains the date and time of the observation from the DATE-OBS header value, combined with the TIME-OBS
+// header value when DATE-OBS only records the date of the observation (as written by AddObservationEntry).
+func (h *FITSHeader) GetDateObs() (time.Time, error) {
+	value, ok := h.Dates["DATE-OBS"]
+
+	if !ok {
+		value, ok = h.Strings["DATE-OBS"]
+	}
+
+	if !ok {
+		return time.Time{}, errors.New("DATE-OBS missing in header")
+	}
+
+	date := strings.TrimSpace(value.Value)
+
+	// FITS DATE-OBS values are ISO-8601 in UTC, with optional fractional seconds and no timezone designator:
+	for _, format := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(format, date); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	d, err := IsDate(date)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("could not parse DATE-OBS value: %s", date)
+	}
+
+	// If the observation time is recorded separately, combine it with the observation date:
+	if v, ok := h.Strings["TIME-OBS"]; ok {
+		for _, format := range []string{"15:04:05Z", "15:04:05.999999999", "15:04:05"} {
+			if t, err := time.Parse(format, strings.TrimSpace(v.Value)); err == nil {
+				return time.Date(d.Year(), d.Month(), d.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC), nil
+			}
+		}
+	}
+
+	return d.UTC(), nil
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the value of the (float or integer) header keyword, and whether it is present.
+func (h *FITSHeader) GetFloat(key string) (float64, bool) {
+	if v, ok := h.Floats[key]; ok {
+		return float64(v.Value), true
+	}
+
+	if v, ok := h.Ints[key]; ok {
+		return float64(v.Value), true
+	}
+
+	return 0, false
+}
+
+/*****************************************************************************************************************/
+
 // Set a new key-value pair to the FITS header, with an optional comment:
 func (h *FITSHeader) Set(key string, value interface{}, comment string) error {
 	switch v := value.(type) {
@@ -286,6 +345,16 @@ func (h *FITSHeader) WriteToBuffer(buf *bytes.Buffer) (*bytes.Buffer, error) {
 	writeInt(buf, "BZERO", 0, "")
 
 	// Write the rest of the header values:
+	h.writeKeywords(buf)
+
+	return buf, nil
+}
+
+/*****************************************************************************************************************/
+
+// Writes the keywords, comments and history of the header, followed by the END record, padding the header block
+// with spaces to a multiple of 2880 bytes.
+func (h *FITSHeader) writeKeywords(buf *bytes.Buffer) {
 	for k, v := range h.Bools {
 		writeBool(buf, k, v.Value, v.Comment)
 	}
@@ -306,6 +375,14 @@ func (h *FITSHeader) WriteToBuffer(buf *bytes.Buffer) (*bytes.Buffer, error) {
 		writeString(buf, k, v.Value, v.Comment)
 	}
 
+	for _, v := range h.Comments {
+		writeCommentary(buf, "COMMENT", v)
+	}
+
+	for _, v := range h.History {
+		writeCommentary(buf, "HISTORY", v)
+	}
+
 	h.End = writeEnd(buf)
 
 	// Pad current header block with spaces if necessary:
@@ -316,8 +393,6 @@ func (h *FITSHeader) WriteToBuffer(buf *bytes.Buffer) (*bytes.Buffer, error) {
 			buf.WriteRune(' ')
 		}
 	}
-
-	return buf, nil
 }
 
 /*****************************************************************************************************************/
@@ -343,11 +418,11 @@ func (h *FITSHeader) ParseLine(subNames []string, subValues [][]byte) error {
 
 			// Comment line:
 			case byte('C'):
-				h.Comments = append(h.Comments, string(subValues[i]))
+				h.Comments = append(h.Comments, strings.TrimRight(string(subValues[i]), " "))
 
 			// History line:
 			case byte('H'):
-				h.History = append(h.History, string(subValues[i]))
+				h.History = append(h.History, strings.TrimRight(string(subValues[i]), " "))
 
 			// Keyword line:
 			case byte('k'): // Keyword line
@@ -380,6 +455,11 @@ func (h *FITSHeader) ParseLine(subNames []string, subValues [][]byte) error {
 			case byte('s'):
 				value = strings.TrimSpace(string(subValues[i]))
 
+			// String value continuation line:
+			case byte('n'):
+				h.parseContinue(string(subValues[i]))
+				return nil
+
 			// Date-like string value line:
 			case byte('d'): // date
 				d, err := time.Parse(time.RFC3339, strings.TrimSpace(string(subValues[i])))
@@ -432,8 +512,16 @@ func (h *FITSHeader) ParseLine(subNames []string, subValues [][]byte) error {
 		}
 	}
 
+	h.continued = ""
+
 	// Check if value is a string:
 	if v, ok := value.(string); ok {
+		// A string value ending with an ampersand is continued over the following CONTINUE records:
+		if strings.HasSuffix(v, "&") {
+			v = strings.TrimSuffix(v, "&")
+			h.continued = key
+		}
+
 		h.Strings[key] = struct {
 			Value   string
 			Comment string
@@ -459,6 +547,32 @@ func (h *FITSHeader) ParseLine(subNames []string, subValues [][]byte) error {
 
 /*****************************************************************************************************************/
 
+// Appends the value of a CONTINUE record to the string value it continues, which may itself be continued.
+func (h *FITSHeader) parseContinue(value string) {
+	s, ok := h.Strings[h.continued]
+
+	if !ok {
+		return
+	}
+
+	key := h.continued
+
+	h.continued = ""
+
+	value = strings.TrimRight(value, " ")
+
+	if strings.HasSuffix(value, "&") {
+		value = strings.TrimSuffix(value, "&")
+		h.continued = key
+	}
+
+	s.Value += value
+
+	h.Strings[key] = s
+}
+
+/*****************************************************************************************************************/
+
 // Writes a FITS header boolean T/F value
 func writeBool(w io.Writer, key string, value bool, comment string) {
 	if len(key) > 8 {
@@ -496,6 +610,19 @@ func writeString(w io.Writer, key, value, comment string) {
 
 	if len(value) <= 18 {
 		fmt.Fprintf(w, "%-8s= '%s'%s / %-47s", key, value, strings.Repeat(" ", 18-len(value)), comment)
+	} else if len(value) <= 68 {
+		// The value fits in a single record, at the expense of (some of) the comment:
+		line := fmt.Sprintf("%-8s= '%s'", key, value)
+
+		if len(line)+3 < 80 && comment != "" {
+			line = fmt.Sprintf("%s / %s", line, comment)
+		}
+
+		if len(line) > 80 {
+			line = line[:80]
+		}
+
+		fmt.Fprintf(w, "%-80s", line)
 	} else {
 		fmt.Fprintf(w, "%-8s= '%s&' / %-47s", key, value[0:17], comment)
 
@@ -549,8 +676,9 @@ func writeFloat(w io.Writer, key string, value float32, comment string) {
 	// Format the float with upper-case 'E' and 6 decimal places
 	// The total width for the value field is 20 characters
 	// Example: " 2.460641E+06"
-	// Determine if the value requires scientific notation
-	if absValue >= 1e+04 || (absValue > 0 && absValue < 1e-04) {
+	// Determine if the value requires scientific notation, e.g., to retain the significant digits of small values
+	// such as the CDi_j matrix of a world coordinate system (of order 1e-04 degrees per pixel)
+	if absValue >= 1e+04 || (absValue > 0 && absValue < 1e-02) {
 		// Use scientific notation with upper-case 'E' and 6 decimal places
 		formattedValue = fmt.Sprintf("%20.6E", value)
 	} else {
@@ -564,6 +692,28 @@ func writeFloat(w io.Writer, key string, value float32, comment string) {
 
 /*****************************************************************************************************************/
 
+// Writes a FITS header commentary record, e.g., COMMENT or HISTORY, wrapping text longer than the 72 characters
+// available in each record over multiple records.
+func writeCommentary(w io.Writer, key string, value string) {
+	for {
+		line := value
+
+		if len(line) > 72 {
+			line = line[:72]
+		}
+
+		fmt.Fprintf(w, "%-8s%-72s", key, line)
+
+		value = value[len(line):]
+
+		if len(value) == 0 {
+			return
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
 // Writes a FITS header end record
 func writeEnd(w io.Writer) bool {
 	n, _ := fmt.Fprintf(w, "END%s", strings.Repeat(" ", 80-3))
@@ -600,14 +750,16 @@ func compileFITSHeaderRegEx() *regexp.Regexp {
 
 	val := "(?:" + b + "|" + i + "|" + f + "|" + s + "|" + d + ")"
 
-	// [TBI]: CONTINUE for strings
+	cont := "CONTINUE"
+	contLine := cont + white + "'(?P<n>[^']*)'" + whiteOpt + "(?:/.*)?"
+
 	// [TBI]: Complex int: (nr, nr)
 	// [TBI]: Complex float: (nr, nr)
 
 	commOpt := "(?:/(?P<c>.*))?"
 	keyLine := key + whiteOpt + equals + whiteOpt + val + whiteOpt + commOpt
 
-	lineRe := "^(?:" + whiteLine + "|" + histLine + "|" + commLine + "|" + keyLine + "|" + endLine + ")$"
+	lineRe := "^(?:" + whiteLine + "|" + histLine + "|" + commLine + "|" + contLine + "|" + keyLine + "|" + endLine + ")$"
 
 	return regexp.MustCompile(lineRe)
 }
@@ -16,6 +16,7 @@ import (
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 )
 
 /*****************************************************************************************************************/
@@ -85,10 +86,13 @@ func TestNewDefaultFITSHeaderWriteStringContinue(t *testing.T) {
 
 	buf := new(bytes.Buffer)
 
+	// Values of more than 68 characters are continued over CONTINUE records:
+	program := "observerly Online FITS Exposure Generator, for generating FITS exposures from an online observatory"
+
 	header.Strings["PROGRAM"] = struct {
 		Value   string
 		Comment string
-	}{Value: "observerly Online FITS Exposure Generator", Comment: FITS_STANDARD}
+	}{Value: program, Comment: FITS_STANDARD}
 
 	header.WriteToBuffer(buf)
 
@@ -99,6 +103,78 @@ func TestNewDefaultFITSHeaderWriteStringContinue(t *testing.T) {
 	if len(got) != want {
 		t.Errorf("NewFITSHeader() Header.Write() expected length of 2880 characters: got %v, want %v", len(got), want)
 	}
+
+	if !strings.Contains(got, "CONTINUE  '") {
+		t.Errorf("NewFITSHeader() Header.Write() expected PROGRAM to be continued over a CONTINUE record")
+	}
+
+	var read = NewFITSHeader(2, 600, 800)
+
+	if err := read.Read(buf); err != nil {
+		t.Fatalf("NewFITSHeader() Header.Read() failed: %v", err)
+	}
+
+	if got := read.Strings["PROGRAM"].Value; got != program {
+		t.Errorf("NewFITSHeader() Header.Read() expected PROGRAM to be read back in full: got %v, want %v", got, program)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewDefaultFITSHeaderWriteLongStringRoundTrip(t *testing.T) {
+	var header = NewFITSHeader(2, 600, 800)
+
+	buf := new(bytes.Buffer)
+
+	header.Set("PROGRAM", "observerly Online FITS Exposure Generator", "The program")
+
+	header.WriteToBuffer(buf)
+
+	var read = NewFITSHeader(2, 600, 800)
+
+	if err := read.Read(buf); err != nil {
+		t.Fatalf("NewFITSHeader() Header.Read() failed: %v", err)
+	}
+
+	// Values of up to 68 characters are written in a single record, so are read back in full:
+	if got := read.Strings["PROGRAM"].Value; got != "observerly Online FITS Exposure Generator" {
+		t.Errorf("NewFITSHeader() Header.Read() expected PROGRAM to be read back in full: got %v", got)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewDefaultFITSHeaderWriteHistory(t *testing.T) {
+	var header = NewFITSHeader(2, 600, 800)
+
+	buf := new(bytes.Buffer)
+
+	header.Comments = append(header.Comments, "Created by observerly")
+
+	header.History = append(header.History, "Calibrated with a master bias", strings.Repeat("x", 100))
+
+	header.WriteToBuffer(buf)
+
+	got := buf.String()
+
+	if len(got) != 2880 {
+		t.Errorf("NewFITSHeader() Header.Write() expected length of 2880 characters: got %v, want %v", len(got), 2880)
+	}
+
+	var read = NewFITSHeader(2, 600, 800)
+
+	if err := read.Read(buf); err != nil {
+		t.Fatalf("NewFITSHeader() Header.Read() failed: %v", err)
+	}
+
+	if len(read.Comments) != 1 || read.Comments[0] != "Created by observerly" {
+		t.Errorf("NewFITSHeader() Header.Read() expected the COMMENT to be read back: got %v", read.Comments)
+	}
+
+	// The long HISTORY entry is wrapped over two records of 72 and 28 characters:
+	if len(read.History) != 3 || read.History[0] != "Calibrated with a master bias" || len(read.History[1]) != 72 || len(read.History[2]) != 28 {
+		t.Errorf("NewFITSHeader() Header.Read() expected the HISTORY to be read back: got %v", read.History)
+	}
 }
 
 /*****************************************************************************************************************/
@@ -731,3 +807,90 @@ func TestReadHeaderFromFile(t *testing.T) {
 }
 
 /*****************************************************************************************************************/
+
+func TestGetDateObs(t *testing.T) {
+	var header = NewFITSHeader(2, 600, 800)
+
+	if _, err := header.GetDateObs(); err == nil {
+		t.Errorf("GetDateObs() failed: expected an error when DATE-OBS is missing")
+	}
+
+	header.Set("DATE-OBS", "2024-03-15T21:04:05.5", "Date of observation")
+
+	got, err := header.GetDateObs()
+
+	if err != nil {
+		t.Errorf("GetDateObs() failed: %s", err)
+	}
+
+	want := time.Date(2024, 3, 15, 21, 4, 5, 500000000, time.UTC)
+
+	if !got.Equal(want) {
+		t.Errorf("GetDateObs() failed: expected %v, got %v", want, got)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetDateObsWithTimeObs(t *testing.T) {
+	var header = NewFITSHeader(2, 600, 800)
+
+	header.Set("DATE-OBS", "2024-03-15", "Date of observation")
+
+	header.Set("TIME-OBS", "21:04:05Z", "Time of observation")
+
+	got, err := header.GetDateObs()
+
+	if err != nil {
+		t.Errorf("GetDateObs() failed: %s", err)
+	}
+
+	want := time.Date(2024, 3, 15, 21, 4, 5, 0, time.UTC)
+
+	if !got.Equal(want) {
+		t.Errorf("GetDateObs() failed: expected %v, got %v", want, got)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetFloat(t *testing.T) {
+	var header = NewFITSHeader(2, 600, 800)
+
+	header.Set("EXPOSURE", float32(30.5), "Exposure time (s)")
+
+	header.Set("XBINNING", int32(2), "Horizontal binning")
+
+	if got, ok := header.GetFloat("EXPOSURE"); !ok || got != 30.5 {
+		t.Errorf("GetFloat() failed: expected EXPOSURE of 30.5, got %f (%t)", got, ok)
+	}
+
+	// Integer values are also numeric:
+	if got, ok := header.GetFloat("XBINNING"); !ok || got != 2 {
+		t.Errorf("GetFloat() failed: expected XBINNING of 2, got %f (%t)", got, ok)
+	}
+
+	if _, ok := header.GetFloat("CCD-TEMP"); ok {
+		t.Errorf("GetFloat() failed: expected CCD-TEMP to be missing")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestWriteFloatSmallScientific(t *testing.T) {
+	var buf bytes.Buffer
+	key := "CD1_1"
+	value := float32(2.901234e-04) // Requires scientific notation to retain its significant digits
+	comment := "Linear transform of the world coordinates"
+
+	writeFloat(&buf, key, value, comment)
+
+	expected := "CD1_1   =         2.901234E-04 / Linear transform of the world coordinates      "
+	got := buf.String()
+
+	if got != expected {
+		t.Errorf("writeFloat() = %q, want %q", got, expected)
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,588 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/fits
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package fits
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+/*****************************************************************************************************************/
+
+// FITSColumn is a column of a FITS binary table.
+//
+// The data of the column is one of []bool (L), []int16 (I), []int32 (J), []int64 (K), []float32 (E), []float64 (D)
+// or []string (A, of the width of the longest string).
+type FITSColumn struct {
+	Name string      // The name of the column (TTYPEn)
+	Unit string      // The physical unit of the column (TUNITn), if any
+	Data interface{} // The values of the column, one per row
+}
+
+/*****************************************************************************************************************/
+
+// Represents a FITS binary table extension (XTENSION = 'BINTABLE')
+//
+// @see https://fits.gsfc.nasa.gov/standard40/fits_standard40aa-le.pdf §7.3
+type FITSBinaryTable struct {
+	Name    string       // The name of the extension (EXTNAME)
+	Header  FITSHeader   // Any additional keywords, comments and history of the extension
+	Columns []FITSColumn // The columns of the table
+	Rows    int          // The number of rows of the table
+}
+
+/*****************************************************************************************************************/
+
+// Creates a new FITS binary table from the columns, which must all have the same number of rows.
+func NewFITSBinaryTable(name string, columns []FITSColumn) (*FITSBinaryTable, error) {
+	if len(columns) == 0 {
+		return nil, errors.New("a binary table requires at least one column")
+	}
+
+	rows := -1
+
+	for _, c := range columns {
+		_, n, err := getColumnFormat(c)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if rows >= 0 && n != rows {
+			return nil, fmt.Errorf("column %s has %d rows, but the table has %d rows", c.Name, n, rows)
+		}
+
+		rows = n
+	}
+
+	return &FITSBinaryTable{
+		Name:    name,
+		Header:  newEmptyFITSHeader(),
+		Columns: columns,
+		Rows:    rows,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+/*
+NewFITSBinaryTableFromReader()
+
+Reads the binary table extension of the given name (EXTNAME) from the FITS file, or the first
+binary table extension if the name is empty, skipping the primary HDU and any other extensions.
+
+Columns of a single L, B, I, J, K, E or D value, or of an A string, are supported, and are read
+into []bool, []int16 (for both B and I), []int32, []int64, []float32, []float64 and []string
+respectively. The structural keywords of the extension are not retained in its header.
+*/
+func NewFITSBinaryTableFromReader(r io.Reader, name string) (*FITSBinaryTable, error) {
+	for {
+		h := newEmptyFITSHeader()
+
+		if err := h.Read(r); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, fmt.Errorf("no binary table extension %q was found", name)
+			}
+
+			return nil, err
+		}
+
+		if !h.End {
+			return nil, errors.New("not a valid FITS file; the header has no END record")
+		}
+
+		xtension := strings.TrimSpace(h.Strings["XTENSION"].Value)
+
+		extname := strings.TrimSpace(h.Strings["EXTNAME"].Value)
+
+		if xtension == "BINTABLE" && (name == "" || extname == name) {
+			return readFITSBinaryTable(r, h)
+		}
+
+		// Skip the data of the HDU, which is padded to a multiple of the 2880 byte FITS block:
+		size := getDataSize(h)
+
+		if partial := size % 2880; partial != 0 {
+			size += 2880 - partial
+		}
+
+		if _, err := io.CopyN(io.Discard, r, size); err != nil {
+			return nil, fmt.Errorf("failed to skip the data of the %q extension: %w", extname, err)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the column of the given name, or nil if there is no such column.
+func (t *FITSBinaryTable) GetColumn(name string) *FITSColumn {
+	for i := range t.Columns {
+		if strings.EqualFold(strings.TrimSpace(t.Columns[i].Name), name) {
+			return &t.Columns[i]
+		}
+	}
+
+	return nil
+}
+
+/*****************************************************************************************************************/
+
+// Creates a FITS header without any keywords.
+func newEmptyFITSHeader() FITSHeader {
+	return FITSHeader{
+		Bools:    make(map[string]FITSHeaderBool),
+		Ints:     make(map[string]FITSHeaderInt),
+		Floats:   make(map[string]FITSHeaderFloat),
+		Strings:  make(map[string]FITSHeaderString),
+		Dates:    make(map[string]FITSHeaderString),
+		Comments: make([]string, 0),
+		History:  make([]string, 0),
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the size of the data of the HDU described by the header (excluding its padding), in bytes.
+func getDataSize(h FITSHeader) int64 {
+	naxis := int(h.Ints["NAXIS"].Value)
+
+	if naxis == 0 {
+		return 0
+	}
+
+	size := int64(1)
+
+	for i := 1; i <= naxis; i++ {
+		size *= int64(h.Ints[fmt.Sprintf("NAXIS%d", i)].Value)
+	}
+
+	gcount := int64(1)
+
+	if v, ok := h.Ints["GCOUNT"]; ok {
+		gcount = int64(v.Value)
+	}
+
+	bitpix := int64(h.Ints["BITPIX"].Value)
+
+	if bitpix < 0 {
+		bitpix = -bitpix
+	}
+
+	return bitpix / 8 * gcount * (int64(h.Ints["PCOUNT"].Value) + size)
+}
+
+/*****************************************************************************************************************/
+
+// Reads the rows of the binary table extension described by the header, and the heap which follows them.
+func readFITSBinaryTable(r io.Reader, h FITSHeader) (*FITSBinaryTable, error) {
+	width, rows := int(h.Ints["NAXIS1"].Value), int(h.Ints["NAXIS2"].Value)
+
+	fields := int(h.Ints["TFIELDS"].Value)
+
+	if width < 0 || rows < 0 || fields <= 0 {
+		return nil, errors.New("not a valid binary table; NAXIS1, NAXIS2 or TFIELDS is missing from the header")
+	}
+
+	t := &FITSBinaryTable{
+		Name:    strings.TrimSpace(h.Strings["EXTNAME"].Value),
+		Columns: make([]FITSColumn, fields),
+		Rows:    rows,
+	}
+
+	types := make([]byte, fields)
+
+	repeats := make([]int, fields)
+
+	offsets := make([]int, fields)
+
+	offset := 0
+
+	for i := 0; i < fields; i++ {
+		format := strings.TrimSpace(h.Strings[fmt.Sprintf("TFORM%d", i+1)].Value)
+
+		if format == "" {
+			return nil, fmt.Errorf("not a valid binary table; TFORM%d is missing from the header", i+1)
+		}
+
+		// The format is an optional repeat count followed by the type code, e.g., "1D" or "12A":
+		n := strings.IndexFunc(format, func(c rune) bool { return c < '0' || c > '9' })
+
+		repeat := 1
+
+		if n > 0 {
+			v, err := strconv.Atoi(format[:n])
+
+			if err != nil {
+				return nil, fmt.Errorf("invalid TFORM%d = %q", i+1, format)
+			}
+
+			repeat = v
+		}
+
+		types[i], repeats[i], offsets[i] = format[n], repeat, offset
+
+		size := map[byte]int{'L': 1, 'B': 1, 'A': 1, 'I': 2, 'J': 4, 'E': 4, 'K': 8, 'D': 8}[types[i]]
+
+		if size == 0 {
+			return nil, fmt.Errorf("unsupported TFORM%d = %q", i+1, format)
+		}
+
+		if repeat != 1 && types[i] != 'A' {
+			return nil, fmt.Errorf("unsupported TFORM%d = %q; only single values or strings are supported", i+1, format)
+		}
+
+		offset += size * repeat
+
+		t.Columns[i] = FITSColumn{
+			Name: strings.TrimSpace(h.Strings[fmt.Sprintf("TTYPE%d", i+1)].Value),
+			Unit: strings.TrimSpace(h.Strings[fmt.Sprintf("TUNIT%d", i+1)].Value),
+		}
+	}
+
+	if offset > width {
+		return nil, fmt.Errorf("the columns of %d bytes exceed the row width of %d bytes", offset, width)
+	}
+
+	data := make([]byte, width*rows)
+
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, fmt.Errorf("failed to read the rows of the binary table: %w", err)
+	}
+
+	for i := range t.Columns {
+		t.Columns[i].Data = readColumnData(data, width, rows, offsets[i], types[i], repeats[i])
+	}
+
+	// Retain only the non-structural keywords of the extension in its header:
+	t.Header = newEmptyFITSHeader()
+
+	t.Header.Comments, t.Header.History = h.Comments, h.History
+
+	structural := func(key string) bool {
+		for _, prefix := range []string{"TTYPE", "TFORM", "TUNIT"} {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		}
+
+		switch key {
+		case "XTENSION", "BITPIX", "NAXIS", "NAXIS1", "NAXIS2", "PCOUNT", "GCOUNT", "TFIELDS", "EXTNAME":
+			return true
+		}
+
+		return false
+	}
+
+	for k, v := range h.Bools {
+		if !structural(k) {
+			t.Header.Bools[k] = v
+		}
+	}
+
+	for k, v := range h.Ints {
+		if !structural(k) {
+			t.Header.Ints[k] = v
+		}
+	}
+
+	for k, v := range h.Floats {
+		if !structural(k) {
+			t.Header.Floats[k] = v
+		}
+	}
+
+	for k, v := range h.Strings {
+		if !structural(k) {
+			t.Header.Strings[k] = v
+		}
+	}
+
+	for k, v := range h.Dates {
+		if !structural(k) {
+			t.Header.Dates[k] = v
+		}
+	}
+
+	// Skip the heap and the padding of the data, such that the reader is positioned at the next HDU:
+	size := getDataSize(h)
+
+	if partial := size % 2880; partial != 0 {
+		size += 2880 - partial
+	}
+
+	if _, err := io.CopyN(io.Discard, r, size-int64(len(data))); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to skip the heap of the binary table: %w", err)
+	}
+
+	return t, nil
+}
+
+/*****************************************************************************************************************/
+
+// Reads the values of a column of the given type code from the rows of the binary table, in network byte order.
+func readColumnData(data []byte, width int, rows int, offset int, code byte, repeat int) interface{} {
+	switch code {
+	case 'L':
+		v := make([]bool, rows)
+
+		for row := range v {
+			v[row] = data[row*width+offset] == 'T'
+		}
+
+		return v
+	case 'B':
+		v := make([]int16, rows)
+
+		for row := range v {
+			v[row] = int16(data[row*width+offset])
+		}
+
+		return v
+	case 'I':
+		v := make([]int16, rows)
+
+		for row := range v {
+			v[row] = int16(binary.BigEndian.Uint16(data[row*width+offset:]))
+		}
+
+		return v
+	case 'J':
+		v := make([]int32, rows)
+
+		for row := range v {
+			v[row] = int32(binary.BigEndian.Uint32(data[row*width+offset:]))
+		}
+
+		return v
+	case 'K':
+		v := make([]int64, rows)
+
+		for row := range v {
+			v[row] = int64(binary.BigEndian.Uint64(data[row*width+offset:]))
+		}
+
+		return v
+	case 'E':
+		v := make([]float32, rows)
+
+		for row := range v {
+			v[row] = math.Float32frombits(binary.BigEndian.Uint32(data[row*width+offset:]))
+		}
+
+		return v
+	case 'D':
+		v := make([]float64, rows)
+
+		for row := range v {
+			v[row] = math.Float64frombits(binary.BigEndian.Uint64(data[row*width+offset:]))
+		}
+
+		return v
+	default:
+		v := make([]string, rows)
+
+		for row := range v {
+			v[row] = strings.TrimRight(string(data[row*width+offset:row*width+offset+repeat]), " \x00")
+		}
+
+		return v
+	}
+}
+
+/*****************************************************************************************************************/
+
+/*
+WriteToBuffer()
+
+Writes the binary table extension to the buffer, e.g., after the primary HDU of a FITS image
+written by FITSImage.WriteToBuffer().
+
+If the buffer is empty, a primary HDU without data (NAXIS = 0, EXTEND = T) is written first,
+such that the buffer is a complete FITS file containing only the table.
+*/
+func (t *FITSBinaryTable) WriteToBuffer(buf *bytes.Buffer) (*bytes.Buffer, error) {
+	if buf == nil {
+		buf = new(bytes.Buffer)
+	}
+
+	formats := make([]string, len(t.Columns))
+
+	width := 0
+
+	for i, c := range t.Columns {
+		format, n, err := getColumnFormat(c)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if n != t.Rows {
+			return nil, fmt.Errorf("column %s has %d rows, but the table has %d rows", c.Name, n, t.Rows)
+		}
+
+		formats[i] = format
+
+		width += getColumnWidth(c)
+	}
+
+	if buf.Len() == 0 {
+		writeBool(buf, "SIMPLE", true, FITS_STANDARD)
+		writeInt(buf, "BITPIX", 8, "Number of bits per data pixel")
+		writeInt(buf, "NAXIS", 0, "No data in the primary HDU")
+		writeBool(buf, "EXTEND", true, "The file may contain extensions")
+		writeEnd(buf)
+		padBuffer(buf, ' ')
+	}
+
+	// The mandatory keywords of the extension must be written first, and in order:
+	writeString(buf, "XTENSION", "BINTABLE", "Binary table extension")
+	writeInt(buf, "BITPIX", 8, "8-bit bytes")
+	writeInt(buf, "NAXIS", 2, "2-dimensional binary table")
+	writeInt(buf, "NAXIS1", int32(width), "Width of a row in bytes")
+	writeInt(buf, "NAXIS2", int32(t.Rows), "Number of rows in the table")
+	writeInt(buf, "PCOUNT", 0, "Size of the heap in bytes")
+	writeInt(buf, "GCOUNT", 1, "One data group")
+	writeInt(buf, "TFIELDS", int32(len(t.Columns)), "Number of columns in the table")
+
+	for i, c := range t.Columns {
+		writeString(buf, fmt.Sprintf("TTYPE%d", i+1), c.Name, "Name of the column")
+		writeString(buf, fmt.Sprintf("TFORM%d", i+1), formats[i], "Format of the column")
+
+		if c.Unit != "" {
+			writeString(buf, fmt.Sprintf("TUNIT%d", i+1), c.Unit, "Physical unit of the column")
+		}
+	}
+
+	if t.Name != "" {
+		writeString(buf, "EXTNAME", t.Name, "Name of the extension")
+	}
+
+	t.Header.writeKeywords(buf)
+
+	// Write the rows of the table, in network byte order:
+	for row := 0; row < t.Rows; row++ {
+		for _, c := range t.Columns {
+			if err := writeColumnValue(buf, c, row); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	padBuffer(buf, 0x00)
+
+	return buf, nil
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the TFORMn format and the number of rows of the column.
+func getColumnFormat(c FITSColumn) (string, int, error) {
+	switch v := c.Data.(type) {
+	case []bool:
+		return "1L", len(v), nil
+	case []int16:
+		return "1I", len(v), nil
+	case []int32:
+		return "1J", len(v), nil
+	case []int64:
+		return "1K", len(v), nil
+	case []float32:
+		return "1E", len(v), nil
+	case []float64:
+		return "1D", len(v), nil
+	case []string:
+		return fmt.Sprintf("%dA", getStringColumnWidth(v)), len(v), nil
+	default:
+		return "", 0, fmt.Errorf("unsupported column %s data type %T", c.Name, c.Data)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the width of a value of the column, in bytes.
+func getColumnWidth(c FITSColumn) int {
+	switch v := c.Data.(type) {
+	case []bool:
+		return 1
+	case []int16:
+		return 2
+	case []int32, []float32:
+		return 4
+	case []int64, []float64:
+		return 8
+	case []string:
+		return getStringColumnWidth(v)
+	default:
+		return 0
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the width of a string column, i.e., the length of its longest string (at least one character).
+func getStringColumnWidth(values []string) int {
+	width := 1
+
+	for _, s := range values {
+		width = max(width, len(s))
+	}
+
+	return width
+}
+
+/*****************************************************************************************************************/
+
+// Writes the value of the column in the given row in network byte order, with strings padded by spaces.
+func writeColumnValue(buf *bytes.Buffer, c FITSColumn, row int) error {
+	switch v := c.Data.(type) {
+	case []bool:
+		if v[row] {
+			return buf.WriteByte('T')
+		}
+
+		return buf.WriteByte('F')
+	case []string:
+		width := getStringColumnWidth(v)
+
+		_, err := fmt.Fprintf(buf, "%-*s", width, v[row])
+
+		return err
+	case []int16:
+		return binary.Write(buf, binary.BigEndian, v[row])
+	case []int32:
+		return binary.Write(buf, binary.BigEndian, v[row])
+	case []int64:
+		return binary.Write(buf, binary.BigEndian, v[row])
+	case []float32:
+		return binary.Write(buf, binary.BigEndian, v[row])
+	case []float64:
+		return binary.Write(buf, binary.BigEndian, v[row])
+	default:
+		return fmt.Errorf("unsupported column %s data type %T", c.Name, c.Data)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Pads the buffer with the given byte to a multiple of the 2880 byte FITS block.
+func padBuffer(buf *bytes.Buffer, b byte) {
+	if partial := buf.Len() % 2880; partial != 0 {
+		buf.Write(bytes.Repeat([]byte{b}, 2880-partial))
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,276 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/fits
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package fits
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"strings"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+// Obtains the 80 character header records of the buffer, up to and including the first END record:
+func getTestRecords(data []byte) []string {
+	records := make([]string, 0)
+
+	for i := 0; i+80 <= len(data); i += 80 {
+		records = append(records, string(data[i:i+80]))
+
+		if strings.HasPrefix(records[len(records)-1], "END ") {
+			break
+		}
+	}
+
+	return records
+}
+
+/*****************************************************************************************************************/
+
+func TestNewFITSBinaryTable(t *testing.T) {
+	table, err := NewFITSBinaryTable("STARS", []FITSColumn{
+		{Name: "X", Data: []float64{1, 2}},
+		{Name: "NAME", Data: []string{"a", "bc"}},
+	})
+
+	if err != nil {
+		t.Fatalf("NewFITSBinaryTable() failed: %v", err)
+	}
+
+	if table.Rows != 2 {
+		t.Errorf("expected 2 rows, but got %d", table.Rows)
+	}
+
+	_, err = NewFITSBinaryTable("STARS", []FITSColumn{
+		{Name: "X", Data: []float64{1, 2}},
+		{Name: "Y", Data: []float64{1}},
+	})
+
+	if err == nil {
+		t.Errorf("expected an error for columns of different lengths")
+	}
+
+	_, err = NewFITSBinaryTable("STARS", []FITSColumn{{Name: "X", Data: []complex64{1}}})
+
+	if err == nil {
+		t.Errorf("expected an error for an unsupported column type")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFITSBinaryTableWriteToBuffer(t *testing.T) {
+	table, err := NewFITSBinaryTable("LIGHTCURVE", []FITSColumn{
+		{Name: "TIME", Unit: "d", Data: []float64{2460000.5, 2460000.75}},
+		{Name: "FLUX", Data: []float32{1.5, -2}},
+		{Name: "FLAGS", Data: []int32{0, 3}},
+		{Name: "GOOD", Data: []bool{true, false}},
+		{Name: "FILENAME", Data: []string{"a.fits", "bb.fits"}},
+	})
+
+	if err != nil {
+		t.Fatalf("NewFITSBinaryTable() failed: %v", err)
+	}
+
+	table.Header.Set("OBJECT", "WASP-12", "The target")
+
+	buf, err := table.WriteToBuffer(nil)
+
+	if err != nil {
+		t.Fatalf("WriteToBuffer() failed: %v", err)
+	}
+
+	data := buf.Bytes()
+
+	if len(data) != 3*2880 {
+		t.Fatalf("expected a primary header, table header and table data block of 8640 bytes, but got %d", len(data))
+	}
+
+	primary := getTestRecords(data)
+
+	if !strings.HasPrefix(primary[0], "SIMPLE  =") || !strings.Contains(primary[2], "NAXIS   =                    0") {
+		t.Errorf("expected a primary header without data, but got %v", primary[:3])
+	}
+
+	records := getTestRecords(data[2880:])
+
+	expected := []string{
+		"XTENSION= 'BINTABLE'",
+		"BITPIX  =                    8",
+		"NAXIS   =                    2",
+		"NAXIS1  =                   24",
+		"NAXIS2  =                    2",
+		"PCOUNT  =                    0",
+		"GCOUNT  =                    1",
+		"TFIELDS =                    5",
+		"TTYPE1  = 'TIME'",
+		"TFORM1  = '1D'",
+		"TUNIT1  = 'd'",
+	}
+
+	for i, e := range expected {
+		if !strings.HasPrefix(records[i], e) {
+			t.Errorf("expected record %d to be %q, but got %q", i, e, records[i])
+		}
+	}
+
+	header := strings.Join(records, "")
+
+	for _, e := range []string{"TFORM2  = '1E'", "TFORM3  = '1J'", "TFORM4  = '1L'", "TFORM5  = '7A'", "EXTNAME = 'LIGHTCURVE'", "OBJECT  = 'WASP-12'"} {
+		if !strings.Contains(header, e) {
+			t.Errorf("expected the header to contain %q", e)
+		}
+	}
+
+	// The rows of 24 bytes, in network byte order:
+	rows := data[2*2880:]
+
+	if v := math.Float64frombits(binary.BigEndian.Uint64(rows[24:32])); v != 2460000.75 {
+		t.Errorf("expected the TIME of the second row to be 2460000.75, but got %f", v)
+	}
+
+	if v := math.Float32frombits(binary.BigEndian.Uint32(rows[8:12])); v != 1.5 {
+		t.Errorf("expected the FLUX of the first row to be 1.5, but got %f", v)
+	}
+
+	if v := int32(binary.BigEndian.Uint32(rows[24+12 : 24+16])); v != 3 {
+		t.Errorf("expected the FLAGS of the second row to be 3, but got %d", v)
+	}
+
+	if rows[16] != 'T' || rows[24+16] != 'F' {
+		t.Errorf("expected the GOOD column to be T, F, but got %c, %c", rows[16], rows[24+16])
+	}
+
+	if s := string(rows[17:24]); s != "a.fits " {
+		t.Errorf("expected the FILENAME of the first row to be padded with spaces, but got %q", s)
+	}
+
+	if !bytes.Equal(rows[48:58], make([]byte, 10)) {
+		t.Errorf("expected the data block to be padded with zeros")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFITSBinaryTableAppendToImage(t *testing.T) {
+	f := NewFITSImage(2, 4, 4, 65535)
+
+	f.Data = make([]float32, 16)
+
+	buf, err := f.WriteToBuffer()
+
+	if err != nil {
+		t.Fatalf("WriteToBuffer() failed: %v", err)
+	}
+
+	n := buf.Len()
+
+	table, _ := NewFITSBinaryTable("", []FITSColumn{{Name: "X", Data: []int16{1, 2, 3}}})
+
+	buf, err = table.WriteToBuffer(buf)
+
+	if err != nil {
+		t.Fatalf("WriteToBuffer() failed: %v", err)
+	}
+
+	// The extension follows the image directly, without another primary header:
+	if !strings.HasPrefix(string(buf.Bytes()[n:]), "XTENSION= 'BINTABLE'") {
+		t.Errorf("expected the binary table extension to follow the image")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewFITSBinaryTableFromReader(t *testing.T) {
+	f := NewFITSImage(2, 4, 4, 65535)
+
+	f.Data = make([]float32, 16)
+
+	buf, err := f.WriteToBuffer()
+
+	if err != nil {
+		t.Fatalf("WriteToBuffer() failed: %v", err)
+	}
+
+	first, _ := NewFITSBinaryTable("FIRST", []FITSColumn{{Name: "X", Data: []int16{1, 2, 3}}})
+
+	second, _ := NewFITSBinaryTable("STARS", []FITSColumn{
+		{Name: "RA", Unit: "deg", Data: []float64{10.5, 20.25}},
+		{Name: "MAG", Data: []float32{9.5, 11}},
+		{Name: "ID", Data: []int64{1, 1 << 40}},
+		{Name: "N", Data: []int32{-3, 4}},
+		{Name: "FLAG", Data: []bool{true, false}},
+		{Name: "NAME", Data: []string{"Vega", "Altair"}},
+	})
+
+	second.Header.Set("CATALOG", "test", "The name of the catalogue")
+
+	for _, table := range []*FITSBinaryTable{first, second} {
+		if buf, err = table.WriteToBuffer(buf); err != nil {
+			t.Fatalf("WriteToBuffer() failed: %v", err)
+		}
+	}
+
+	table, err := NewFITSBinaryTableFromReader(bytes.NewReader(buf.Bytes()), "STARS")
+
+	if err != nil {
+		t.Fatalf("NewFITSBinaryTableFromReader() failed: %v", err)
+	}
+
+	if table.Name != "STARS" || table.Rows != 2 || len(table.Columns) != 6 {
+		t.Fatalf("expected the STARS table of 6 columns and 2 rows, but got %q of %d columns and %d rows", table.Name, len(table.Columns), table.Rows)
+	}
+
+	if ra := table.GetColumn("ra"); ra == nil || ra.Unit != "deg" || ra.Data.([]float64)[1] != 20.25 {
+		t.Errorf("expected the RA column to be read, but got %+v", ra)
+	}
+
+	if mag := table.GetColumn("MAG").Data.([]float32); mag[0] != 9.5 {
+		t.Errorf("expected the MAG of the first row to be 9.5, but got %v", mag[0])
+	}
+
+	if id := table.GetColumn("ID").Data.([]int64); id[1] != 1<<40 {
+		t.Errorf("expected the ID of the second row to be %d, but got %d", int64(1<<40), id[1])
+	}
+
+	if n := table.GetColumn("N").Data.([]int32); n[0] != -3 {
+		t.Errorf("expected the N of the first row to be -3, but got %d", n[0])
+	}
+
+	if flag := table.GetColumn("FLAG").Data.([]bool); !flag[0] || flag[1] {
+		t.Errorf("expected the FLAG column to be [true false], but got %v", flag)
+	}
+
+	if name := table.GetColumn("NAME").Data.([]string); name[0] != "Vega" || name[1] != "Altair" {
+		t.Errorf("expected the NAME column to be [Vega Altair], but got %q", name)
+	}
+
+	if table.Header.Strings["CATALOG"].Value != "test" {
+		t.Errorf("expected the CATALOG keyword to be retained")
+	}
+
+	if _, ok := table.Header.Strings["TFORM1"]; ok {
+		t.Errorf("expected the structural keywords not to be retained")
+	}
+
+	// The first binary table is read if no name is given:
+	if table, err := NewFITSBinaryTableFromReader(bytes.NewReader(buf.Bytes()), ""); err != nil || table.Name != "FIRST" {
+		t.Errorf("expected the FIRST table to be read, but got %v", err)
+	}
+
+	if _, err := NewFITSBinaryTableFromReader(bytes.NewReader(buf.Bytes()), "MISSING"); err == nil {
+		t.Errorf("expected an error for a missing extension")
+	}
+}
+
+/*****************************************************************************************************************/
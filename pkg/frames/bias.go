@@ -14,7 +14,6 @@ import (
 	"time"
 
 	"github.com/observerly/iris/pkg/fits"
-	"github.com/observerly/iris/pkg/utils"
 )
 
 /*****************************************************************************************************************/
@@ -24,7 +23,8 @@ import (
 // resolution supported by the camera with no light falling on the sensor. The resulting images are then averaged
 // to produce a master bias frame, which is then subtracted from all subsequent images to remove the bias noise.
 //
-// The master bias frame is then created by taking the mean of all the bias frames.
+// The master bias frame is then created by taking the mean of all the bias frames. The running sum of the frames
+// is retained, such that further frames can be applied (or bad frames removed) incrementally.
 //
 // @see Image Calibration & Stack Woodhouse, C. (2017). The Astrophotography Manual. Taylor & Francis. p.203
 func NewMasterBiasFrame(frames []fits.FITSImage, naxis int32, naxis1 int32, naxis2 int32, adu int32, resolution float32) (*MasterFrame, error) {
@@ -45,20 +45,19 @@ func NewMasterBiasFrame(frames []fits.FITSImage, naxis int32, naxis1 int32, naxi
 		adu,
 	)
 
-	// Combine the data arrays into a single array, by taking
-	// the mean of the total of all the frames for each pixel:
-	if len(frames) > 1 {
-		combined, err := utils.MeanFloat32Arrays(data)
+	// Accumulate the running sum of all the frames for each pixel:
+	integration, err := NewIntegrationFromData(data)
 
-		if err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		f.Data = combined
-	} else {
-		combined := frames[0].Data
+	// Combine the data arrays into a single array, by taking
+	// the mean of the total of all the frames for each pixel:
+	f.Data, err = integration.Mean()
 
-		f.Data = combined
+	if err != nil {
+		return nil, err
 	}
 
 	f.Exposure = resolution
@@ -81,13 +80,11 @@ func NewMasterBiasFrame(frames []fits.FITSImage, naxis int32, naxis1 int32, naxi
 		Comment: "Smallest increment in exposure time (s)",
 	}
 
-	f.Header.Strings["SENSOR"] = struct {
-		Value   string
-		Comment string
-	}{
-		Value:   "Monochrome",
-		Comment: "ASCOM Alpaca Sensor Type",
-	}
+	// Inherit the acquisition metadata of the bias frames, e.g., for matching the master to light frames:
+	inheritCalibrationMetadata(f, frames)
+
+	// Record how the master bias frame was created, once its data is final:
+	NewProvenance("bias", frames).WriteToFITSImage(f)
 
 	return &MasterFrame{
 		Type:             "bias",
@@ -95,6 +92,7 @@ func NewMasterBiasFrame(frames []fits.FITSImage, naxis int32, naxis1 int32, naxi
 		Pixels:           pixels,
 		Frames:           frames,
 		Combined:         f,
+		Integration:      integration,
 		CreatedTimestamp: time.Now().Unix(),
 	}, nil
 }
@@ -164,8 +164,98 @@ func TestApplyFrameToMasterBiasFrame(t *testing.T) {
 		t.Errorf("NewMasterBiasFrame() failed: expected ADU of 255, got %d", masterBias.Combined.ADU)
 	}
 
-	if masterBias.Combined.Data[1] != 4 {
-		t.Errorf("NewMasterBiasFrame() failed: expected data[0] of 6, got %f", masterBias.Combined.Data[0])
+	// The sixth frame is weighted equally with the previous five frames, i.e., (5 * 6 + 2) / 6:
+	if masterBias.Combined.Data[1] != 5.3333335 {
+		t.Errorf("NewMasterBiasFrame() failed: expected data[1] of 5.333333, got %f", masterBias.Combined.Data[1])
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestApplyFrameToMasterBiasFrameMatchesBatch(t *testing.T) {
+	frames := []fits.FITSImage{
+		*fits.NewFITSImageFrom2DData([][]uint32{{1, 2}, {3, 4}}, 2, 2, 2, 255),
+		*fits.NewFITSImageFrom2DData([][]uint32{{5, 6}, {7, 8}}, 2, 2, 2, 255),
+		*fits.NewFITSImageFrom2DData([][]uint32{{2, 2}, {2, 9}}, 2, 2, 2, 255),
+		*fits.NewFITSImageFrom2DData([][]uint32{{9, 1}, {0, 3}}, 2, 2, 2, 255),
+	}
+
+	batch, err := NewMasterBiasFrame(frames, 2, 2, 2, 255, 0.05)
+
+	if err != nil {
+		t.Errorf("NewMasterBiasFrame() failed: %s", err)
+	}
+
+	incremental, err := NewMasterBiasFrame(frames[:1], 2, 2, 2, 255, 0.05)
+
+	if err != nil {
+		t.Errorf("NewMasterBiasFrame() failed: %s", err)
+	}
+
+	for i := 1; i < len(frames); i++ {
+		previous := incremental.Combined.Data[0]
+
+		incremental, err = incremental.ApplyFrame(&frames[i])
+
+		if err != nil {
+			t.Errorf("ApplyFrame() failed: %s", err)
+		}
+
+		if i == 1 && previous != 1 {
+			t.Errorf("ApplyFrame() failed: expected the previous master to be unmodified, got %f", previous)
+		}
+	}
+
+	if incremental.Count != batch.Count {
+		t.Errorf("ApplyFrame() failed: expected count of %d, got %d", batch.Count, incremental.Count)
+	}
+
+	for i := range batch.Combined.Data {
+		if incremental.Combined.Data[i] != batch.Combined.Data[i] {
+			t.Errorf("ApplyFrame() failed: expected data[%d] of %f, got %f", i, batch.Combined.Data[i], incremental.Combined.Data[i])
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRemoveFrameFromMasterBiasFrame(t *testing.T) {
+	frames := []fits.FITSImage{
+		*fits.NewFITSImageFrom2DData([][]uint32{{1, 2}, {3, 4}}, 2, 2, 2, 255),
+		*fits.NewFITSImageFrom2DData([][]uint32{{200, 200}, {200, 200}}, 2, 2, 2, 255),
+		*fits.NewFITSImageFrom2DData([][]uint32{{3, 4}, {5, 6}}, 2, 2, 2, 255),
+	}
+
+	masterBias, err := NewMasterBiasFrame(frames, 2, 2, 2, 255, 0.05)
+
+	if err != nil {
+		t.Errorf("NewMasterBiasFrame() failed: %s", err)
+	}
+
+	cleaned, err := masterBias.RemoveFrame(1)
+
+	if err != nil {
+		t.Errorf("RemoveFrame() failed: %s", err)
+	}
+
+	if cleaned.Count != 2 || len(cleaned.Frames) != 2 {
+		t.Errorf("RemoveFrame() failed: expected count of 2, got %d", cleaned.Count)
+	}
+
+	expected := []float32{2, 3, 4, 5}
+
+	for i, v := range expected {
+		if cleaned.Combined.Data[i] != v {
+			t.Errorf("RemoveFrame() failed: expected data[%d] of %f, got %f", i, v, cleaned.Combined.Data[i])
+		}
+	}
+
+	if masterBias.Count != 3 || masterBias.Combined.Data[0] != 68 {
+		t.Errorf("RemoveFrame() failed: expected the original master to be unmodified, got %f", masterBias.Combined.Data[0])
+	}
+
+	if _, err := cleaned.RemoveFrame(5); err == nil {
+		t.Errorf("RemoveFrame() failed: expected an error for an out of range index")
 	}
 }
 
@@ -0,0 +1,291 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+var (
+	ErrDimensionMismatch   = errors.New("dimension mismatch")
+	ErrTypeMismatch        = errors.New("frame type mismatch")
+	ErrBinningMismatch     = errors.New("binning mismatch")
+	ErrGainMismatch        = errors.New("gain mismatch")
+	ErrOffsetMismatch      = errors.New("offset mismatch")
+	ErrReadoutModeMismatch = errors.New("readout mode mismatch")
+	ErrTemperatureMismatch = errors.New("temperature mismatch")
+	ErrCFAMismatch         = errors.New("colour filter array mismatch")
+)
+
+/*****************************************************************************************************************/
+
+// CompatibilitySeverity determines how an incompatibility between a light frame and a master frame is reported.
+type CompatibilitySeverity int
+
+/*****************************************************************************************************************/
+
+const (
+	// The incompatibility is not checked:
+	SeverityIgnore CompatibilitySeverity = iota
+	// The incompatibility is reported as a warning, and calibration proceeds:
+	SeverityWarning
+	// The incompatibility is reported as an error, and calibration fails:
+	SeverityError
+)
+
+/*****************************************************************************************************************/
+
+// CompatibilityPolicy describes the severity of each check between a light frame and its master frames, and the
+// tolerances within which numeric values are considered compatible. Keywords which are missing from either the
+// light frame or the master frame are not compared.
+type CompatibilityPolicy struct {
+	Dimensions           CompatibilitySeverity // The NAXIS1 and NAXIS2 dimensions (and pixel count)
+	Type                 CompatibilitySeverity // The type of each master frame, e.g., a master flat used as a dark
+	Binning              CompatibilitySeverity // The XBINNING and YBINNING binning factors
+	Gain                 CompatibilitySeverity // The GAIN setting
+	Offset               CompatibilitySeverity // The OFFSET setting
+	ReadoutMode          CompatibilitySeverity // The READOUTM readout mode
+	Temperature          CompatibilitySeverity // The CCD-TEMP sensor temperature, of the master bias and dark only
+	ColourFilterArray    CompatibilitySeverity // The BAYERPAT Colour Filter Array pattern
+	GainTolerance        float32               // The maximum absolute difference in gain setting
+	OffsetTolerance      float32               // The maximum absolute difference in offset setting
+	TemperatureTolerance float32               // The maximum absolute difference in sensor temperature, in °C
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default compatibility policy, i.e., errors for any mismatch in dimensions, frame type, binning,
+// gain, offset, readout mode or CFA pattern, and a warning for sensor temperatures more than 2°C apart.
+func DefaultCompatibilityPolicy() CompatibilityPolicy {
+	return CompatibilityPolicy{
+		Dimensions:           SeverityError,
+		Type:                 SeverityError,
+		Binning:              SeverityError,
+		Gain:                 SeverityError,
+		Offset:               SeverityError,
+		ReadoutMode:          SeverityError,
+		Temperature:          SeverityWarning,
+		ColourFilterArray:    SeverityError,
+		GainTolerance:        0,
+		OffsetTolerance:      0,
+		TemperatureTolerance: 2,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Returns the strict compatibility policy, i.e., errors for any mismatch, including sensor temperatures more
+// than 1°C apart.
+func StrictCompatibilityPolicy() CompatibilityPolicy {
+	policy := DefaultCompatibilityPolicy()
+
+	policy.Temperature = SeverityError
+
+	policy.TemperatureTolerance = 1
+
+	return policy
+}
+
+/*****************************************************************************************************************/
+
+// Returns the permissive compatibility policy, i.e., warnings (but never errors) for any mismatch of the default
+// policy, such that calibration always proceeds.
+func PermissiveCompatibilityPolicy() CompatibilityPolicy {
+	policy := DefaultCompatibilityPolicy()
+
+	policy.Dimensions = SeverityWarning
+
+	policy.Type = SeverityWarning
+
+	policy.Binning = SeverityWarning
+
+	policy.Gain = SeverityWarning
+
+	policy.Offset = SeverityWarning
+
+	policy.ReadoutMode = SeverityWarning
+
+	policy.ColourFilterArray = SeverityWarning
+
+	return policy
+}
+
+/*****************************************************************************************************************/
+
+// CompatibilityError describes an incompatibility between a light frame and one of its master frames. It wraps
+// one of the sentinel errors (e.g., ErrBinningMismatch), such that it can be tested for with errors.Is.
+type CompatibilityError struct {
+	Err      error                 // The sentinel error describing the kind of incompatibility
+	Master   string                // The type of master frame, i.e., bias, dark or flat
+	Keyword  string                // The FITS header keyword (or property) which is incompatible
+	Light    string                // The value for the light frame
+	Value    string                // The value for the master frame
+	Severity CompatibilitySeverity // Whether the incompatibility is a warning or an error
+}
+
+/*****************************************************************************************************************/
+
+// Describes the incompatibility, e.g., "master dark XBINNING 1 does not match light frame 2: binning mismatch".
+func (e *CompatibilityError) Error() string {
+	return fmt.Sprintf("master %s %s %s does not match light frame %s: %s", e.Master, e.Keyword, e.Value, e.Light, e.Err)
+}
+
+/*****************************************************************************************************************/
+
+// Returns the sentinel error describing the kind of incompatibility.
+func (e *CompatibilityError) Unwrap() error {
+	return e.Err
+}
+
+/*****************************************************************************************************************/
+
+/*
+CheckCompatibility()
+
+Checks the compatibility of the light frame with each of the (non-nil) master bias, dark and
+flat frames under the given policy, comparing the NAXIS dimensions, the type of each master,
+and the binning, gain, offset, readout mode, sensor temperature and CFA pattern recorded in
+the FITS headers.
+
+Returns the incompatibilities reported as warnings, and an error joining the incompatibilities
+reported as errors (each a *CompatibilityError), or nil if there are none.
+*/
+func CheckCompatibility(light *fits.FITSImage, masterBias *MasterFrame, masterDark *MasterDarkFrame, masterFlat *MasterFlatFrame, policy CompatibilityPolicy) ([]*CompatibilityError, error) {
+	type master struct {
+		kind        string
+		frameType   string
+		frame       *fits.FITSImage
+		temperature bool
+	}
+
+	masters := make([]master, 0, 3)
+
+	if masterBias != nil {
+		masters = append(masters, master{"bias", masterBias.Type, masterBias.Combined, true})
+	}
+
+	if masterDark != nil {
+		masters = append(masters, master{"dark", masterDark.Type, masterDark.Combined, true})
+	}
+
+	if masterFlat != nil {
+		masters = append(masters, master{"flat", masterFlat.Type, masterFlat.Combined, false})
+	}
+
+	warnings := make([]*CompatibilityError, 0)
+
+	errs := make([]error, 0)
+
+	report := func(e *CompatibilityError) {
+		switch e.Severity {
+		case SeverityWarning:
+			warnings = append(warnings, e)
+		case SeverityError:
+			errs = append(errs, e)
+		}
+	}
+
+	target := NewFrameMetadata(light)
+
+	lxs, lys, lok := light.GetDimensions()
+
+	for _, m := range masters {
+		if m.frame == nil {
+			errs = append(errs, fmt.Errorf("the master %s frame has no combined data", m.kind))
+			continue
+		}
+
+		c := NewFrameMetadata(m.frame)
+
+		newError := func(err error, severity CompatibilitySeverity, keyword string, light string, value string) *CompatibilityError {
+			return &CompatibilityError{
+				Err:      err,
+				Master:   m.kind,
+				Keyword:  keyword,
+				Light:    light,
+				Value:    value,
+				Severity: severity,
+			}
+		}
+
+		if policy.Dimensions != SeverityIgnore {
+			mxs, mys, mok := m.frame.GetDimensions()
+
+			if lok && mok && (lxs != mxs || lys != mys) {
+				report(newError(ErrDimensionMismatch, policy.Dimensions, "NAXIS", fmt.Sprintf("%dx%d", lxs, lys), fmt.Sprintf("%dx%d", mxs, mys)))
+			} else if len(light.Data) != len(m.frame.Data) {
+				report(newError(ErrDimensionMismatch, policy.Dimensions, "pixels", fmt.Sprint(len(light.Data)), fmt.Sprint(len(m.frame.Data))))
+			}
+		}
+
+		if policy.Type != SeverityIgnore && m.frameType != "" && !strings.EqualFold(m.frameType, m.kind) {
+			report(newError(ErrTypeMismatch, policy.Type, "type", m.kind, m.frameType))
+		}
+
+		if policy.Binning != SeverityIgnore {
+			for _, b := range []struct {
+				keyword string
+				light   *int32
+				master  *int32
+			}{
+				{"XBINNING", target.XBinning, c.XBinning},
+				{"YBINNING", target.YBinning, c.YBinning},
+			} {
+				if b.light != nil && b.master != nil && *b.light != *b.master {
+					report(newError(ErrBinningMismatch, policy.Binning, b.keyword, fmt.Sprint(*b.light), fmt.Sprint(*b.master)))
+				}
+			}
+		}
+
+		compareNumber := func(err error, severity CompatibilitySeverity, keyword string, light *float32, value *float32, tolerance float32) {
+			if severity == SeverityIgnore || light == nil || value == nil {
+				return
+			}
+
+			if math.Abs(float64(*light-*value)) > float64(tolerance) {
+				report(newError(err, severity, keyword, fmt.Sprint(*light), fmt.Sprint(*value)))
+			}
+		}
+
+		compareNumber(ErrGainMismatch, policy.Gain, "GAIN", target.Gain, c.Gain, policy.GainTolerance)
+
+		compareNumber(ErrOffsetMismatch, policy.Offset, "OFFSET", target.Offset, c.Offset, policy.OffsetTolerance)
+
+		if m.temperature {
+			compareNumber(ErrTemperatureMismatch, policy.Temperature, "CCD-TEMP", target.Temperature, c.Temperature, policy.TemperatureTolerance)
+		}
+
+		compareString := func(err error, severity CompatibilitySeverity, keyword string, light string, value string) {
+			if severity == SeverityIgnore || light == "" || value == "" {
+				return
+			}
+
+			if !strings.EqualFold(light, value) {
+				report(newError(err, severity, keyword, light, value))
+			}
+		}
+
+		compareString(ErrReadoutModeMismatch, policy.ReadoutMode, "READOUTM", target.ReadoutMode, c.ReadoutMode)
+
+		compareString(ErrCFAMismatch, policy.ColourFilterArray, "BAYERPAT", target.ColourFilterArray, c.ColourFilterArray)
+	}
+
+	return warnings, errors.Join(errs...)
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,186 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+func getCompatibilityTestMasters(t *testing.T) (*MasterFrame, *MasterDarkFrame, *MasterFlatFrame) {
+	bias := getLibraryTestFrame(100, 100, -10, 0, "", "2024-03-01T12:00:00")
+
+	masterBias, err := NewMasterBiasFrame([]fits.FITSImage{*bias}, 2, 4, 4, 65535, 0.001)
+
+	if err != nil {
+		t.Fatalf("NewMasterBiasFrame() failed: %s", err)
+	}
+
+	dark := getLibraryTestFrame(110, 100, -10, 60, "", "2024-03-01T12:00:00")
+
+	masterDark, err := NewMasterDarkFrame([]fits.FITSImage{*dark}, masterBias, 2, 4, 4, 65535, 60)
+
+	if err != nil {
+		t.Fatalf("NewMasterDarkFrame() failed: %s", err)
+	}
+
+	flat := getLibraryTestFrame(30000, 100, -10, 2, "Ha", "2024-03-01T12:00:00")
+
+	masterFlat, err := NewMasterFlatFrame([]fits.FITSImage{*flat}, masterBias, 2, 4, 4, 65535, 2)
+
+	if err != nil {
+		t.Fatalf("NewMasterFlatFrame() failed: %s", err)
+	}
+
+	return masterBias, masterDark, masterFlat
+}
+
+/*****************************************************************************************************************/
+
+func TestCheckCompatibility(t *testing.T) {
+	masterBias, masterDark, masterFlat := getCompatibilityTestMasters(t)
+
+	light := getLibraryTestFrame(1000, 100, -10, 60, "Ha", "2024-03-15T01:00:00")
+
+	warnings, err := CheckCompatibility(light, masterBias, masterDark, masterFlat, DefaultCompatibilityPolicy())
+
+	if err != nil || len(warnings) != 0 {
+		t.Errorf("CheckCompatibility() failed: expected compatible frames, got %v and %v", warnings, err)
+	}
+
+	// A 2x2 binned light frame at a different gain:
+	light.Header.Set("XBINNING", int32(2), "Horizontal binning")
+
+	light.Header.Set("GAIN", int32(0), "Sensor gain")
+
+	_, err = CheckCompatibility(light, masterBias, masterDark, masterFlat, DefaultCompatibilityPolicy())
+
+	if !errors.Is(err, ErrBinningMismatch) || !errors.Is(err, ErrGainMismatch) {
+		t.Errorf("CheckCompatibility() failed: expected binning and gain mismatches, got %v", err)
+	}
+
+	var e *CompatibilityError
+
+	if !errors.As(err, &e) || e.Master != "bias" || e.Keyword != "XBINNING" || e.Light != "2" || e.Value != "1" {
+		t.Errorf("CheckCompatibility() failed: expected a typed master bias XBINNING error, got %+v", e)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCheckCompatibilityPolicy(t *testing.T) {
+	masterBias, masterDark, masterFlat := getCompatibilityTestMasters(t)
+
+	// A light frame 3°C warmer than the master bias and dark:
+	light := getLibraryTestFrame(1000, 100, -7, 60, "Ha", "2024-03-15T01:00:00")
+
+	warnings, err := CheckCompatibility(light, masterBias, masterDark, masterFlat, DefaultCompatibilityPolicy())
+
+	if err != nil {
+		t.Errorf("CheckCompatibility() failed: expected no errors, got %v", err)
+	}
+
+	// The master flat is not compared by temperature:
+	if len(warnings) != 2 || !errors.Is(warnings[0], ErrTemperatureMismatch) {
+		t.Errorf("CheckCompatibility() failed: expected 2 temperature warnings, got %v", warnings)
+	}
+
+	if _, err := CheckCompatibility(light, masterBias, masterDark, masterFlat, StrictCompatibilityPolicy()); !errors.Is(err, ErrTemperatureMismatch) {
+		t.Errorf("CheckCompatibility() failed: expected a temperature error under the strict policy, got %v", err)
+	}
+
+	if warnings, err := CheckCompatibility(light, masterBias, masterDark, masterFlat, CompatibilityPolicy{}); err != nil || len(warnings) != 0 {
+		t.Errorf("CheckCompatibility() failed: expected every check to be ignored, got %v and %v", warnings, err)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewCalibratedLightFrameCompatibility(t *testing.T) {
+	masterBias, masterDark, masterFlat := getCompatibilityTestMasters(t)
+
+	// A light frame of a different size, but the same pixel count, as the master frames:
+	data := make([][]uint32, 2)
+
+	for y := range data {
+		data[y] = make([]uint32, 8)
+
+		for x := range data[y] {
+			data[y][x] = 1000
+		}
+	}
+
+	light := fits.NewFITSImageFrom2DData(data, 2, 8, 2, 65535)
+
+	policy := DefaultCompatibilityPolicy()
+
+	options := CalibrationOptions{Compatibility: &policy}
+
+	if _, err := NewCalibratedLightFrameWithOptions(light, masterBias, masterDark, masterFlat, 2, 8, 2, 65535, 60, options); !errors.Is(err, ErrDimensionMismatch) {
+		t.Errorf("NewCalibratedLightFrameWithOptions() failed: expected a dimension mismatch, got %v", err)
+	}
+
+	// The legacy entry point only warns of the dimension mismatch:
+	calibrated, err := NewCalibratedLightFrame(light, masterBias, masterDark, masterFlat, 2, 8, 2, 65535, 60)
+
+	if err != nil {
+		t.Fatalf("NewCalibratedLightFrame() failed: %s", err)
+	}
+
+	if len(calibrated.Warnings) == 0 || !errors.Is(calibrated.Warnings[0], ErrDimensionMismatch) {
+		t.Errorf("NewCalibratedLightFrame() failed: expected a dimension mismatch warning, got %v", calibrated.Warnings)
+	}
+
+	// The compatibility checks may be disabled entirely:
+	options = CalibrationOptions{Compatibility: &CompatibilityPolicy{}}
+
+	if _, err := NewCalibratedLightFrameWithOptions(light, masterBias, masterDark, masterFlat, 2, 8, 2, 65535, 60, options); err != nil {
+		t.Errorf("NewCalibratedLightFrameWithOptions() failed: expected the checks to be ignored, got %v", err)
+	}
+
+	// A light frame 3°C warmer than the master bias and dark is calibrated, with warnings:
+	warm := getLibraryTestFrame(1000, 100, -7, 60, "Ha", "2024-03-15T01:00:00")
+
+	calibrated, err = NewCalibratedLightFrame(warm, masterBias, masterDark, masterFlat, 2, 4, 4, 65535, 60)
+
+	if err != nil {
+		t.Fatalf("NewCalibratedLightFrame() failed: %s", err)
+	}
+
+	if len(calibrated.Warnings) != 2 {
+		t.Errorf("NewCalibratedLightFrame() failed: expected 2 temperature warnings, got %v", calibrated.Warnings)
+	}
+
+	// A light frame of a different gain is calibrated by the legacy entry point, with warnings:
+	gain := getLibraryTestFrame(1000, 200, -10, 60, "Ha", "2024-03-15T01:00:00")
+
+	calibrated, err = NewCalibratedLightFrame(gain, masterBias, masterDark, masterFlat, 2, 4, 4, 65535, 60)
+
+	if err != nil {
+		t.Fatalf("NewCalibratedLightFrame() failed: %s", err)
+	}
+
+	for _, warning := range calibrated.Warnings {
+		if !errors.Is(warning, ErrGainMismatch) {
+			t.Errorf("NewCalibratedLightFrame() failed: expected only gain mismatch warnings, got %v", warning)
+		}
+	}
+
+	if len(calibrated.Warnings) == 0 {
+		t.Errorf("NewCalibratedLightFrame() failed: expected gain mismatch warnings")
+	}
+}
+
+/*****************************************************************************************************************/
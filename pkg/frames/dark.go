@@ -1,6 +1,7 @@
 package frames
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/observerly/iris/pkg/fits"
@@ -14,6 +15,7 @@ type MasterDarkFrame struct {
 	Frames           []fits.FITSImage // The individual frames used to create the master frame
 	Combined         *fits.FITSImage  // The combined master frame
 	MasterBias       *MasterFrame     // The master bias frame used to create the master dark frame
+	Integration      *Integration     // The running sum of the individual (non bias-subtracted) frames
 	CreatedTimestamp int64
 }
 
@@ -28,7 +30,8 @@ on the sensor, with the same sensor temperature, exposure duration and binning
 level as the associated light frame. The resulting images are then averaged to
 produce a master dark frame.
 
-The master dark frame is then created by taking the mean of all the dark frames.
+The master dark frame is then created by taking the mean of all the dark frames,
+from which the master bias frame is subtracted.
 
 @retuns a new FITSImage containing the master dark frame.
 @see Image Calibration & Stack Woodhouse, C. (2017). The Astrophotography Manual. Taylor & Francis. p.203
@@ -51,32 +54,26 @@ func NewMasterDarkFrame(frames []fits.FITSImage, masterBias *MasterFrame, naxis
 		adu,
 	)
 
-	// Combine the data arrays into a single array, by taking
-	// the mean of the total of all the frames for each pixel:
-	if len(frames) > 1 {
-		combined, err := utils.MeanFloat32Arrays(data)
-
-		if err != nil {
-			return nil, err
-		}
-
-		f.Data, err = utils.SubtractFloat32Array(combined, masterBias.Combined.Data)
+	// Accumulate the running sum of all the frames for each pixel:
+	integration, err := NewIntegrationFromData(data)
 
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		var err error = nil
+	if err != nil {
+		return nil, err
+	}
 
-		combined := frames[0].Data
+	// Combine the data arrays into a single array, by taking
+	// the mean of the total of all the frames for each pixel:
+	combined, err := integration.Mean()
 
-		f.Data = combined
+	if err != nil {
+		return nil, err
+	}
 
-		f.Data, err = utils.SubtractFloat32Array(combined, masterBias.Combined.Data)
+	// Subtract the master bias from the combined master frame:
+	f.Data, err = utils.SubtractFloat32Array(combined, masterBias.Combined.Data)
 
-		if err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return nil, err
 	}
 
 	f.Exposure = exposureTime
@@ -99,13 +96,15 @@ func NewMasterDarkFrame(frames []fits.FITSImage, masterBias *MasterFrame, naxis
 		Comment: "The exposure time (s) of the dark frame",
 	}
 
-	f.Header.Strings["SENSOR"] = struct {
-		Value   string
-		Comment string
-	}{
-		Value:   "Monochrome",
-		Comment: "ASCOM Alpaca Sensor Type",
-	}
+	// Inherit the acquisition metadata of the dark frames, e.g., for matching the master to light frames:
+	inheritCalibrationMetadata(f, frames)
+
+	// Record how the master dark frame was created, once its data is final:
+	provenance := NewProvenance("dark", frames)
+
+	provenance.MasterBias = getFrameID(masterBias.Combined)
+
+	provenance.WriteToFITSImage(f)
 
 	return &MasterDarkFrame{
 		Type:             "dark",
@@ -114,42 +113,113 @@ func NewMasterDarkFrame(frames []fits.FITSImage, masterBias *MasterFrame, naxis
 		Frames:           frames,
 		Combined:         f,
 		MasterBias:       masterBias,
+		Integration:      integration,
 		CreatedTimestamp: time.Now().Unix(),
 	}, nil
 }
 
+/*
+ApplyDarkFrame()
+
+Integrates a further dark frame into the master dark frame, returning a new master dark frame.
+
+The running sum is kept of the individual frames before the master bias is subtracted,
+such that applying frames one at a time yields exactly the same master dark frame as
+creating it from all of the frames at once. The receiver is left unmodified.
+*/
 func (m *MasterDarkFrame) ApplyDarkFrame(frame *fits.FITSImage) (*MasterDarkFrame, error) {
-	// Add the current combined master frame to the master bias before averaging:
+	integration, err := m.getIntegration()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Add the new frame to the running sum of all of the integrated frames:
+	if err := integration.Add(frame.Data); err != nil {
+		return nil, err
+	}
+
+	return m.withIntegration(integration, append(append([]fits.FITSImage{}, m.Frames...), *frame))
+}
+
+/*
+RemoveDarkFrame()
+
+Removes a previously integrated dark frame, e.g., a bad frame, from the master dark frame
+by its index in Frames, returning a new master dark frame. The receiver is left unmodified.
+*/
+func (m *MasterDarkFrame) RemoveDarkFrame(index int) (*MasterDarkFrame, error) {
+	if index < 0 || index >= len(m.Frames) {
+		return nil, fmt.Errorf("frame index %d out of range of %d frames", index, len(m.Frames))
+	}
+
+	if m.Count <= 1 {
+		return nil, fmt.Errorf("cannot remove the only frame of a master %s frame", m.Type)
+	}
+
+	integration, err := m.getIntegration()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the frame from the running sum of all of the integrated frames:
+	if err := integration.Remove(m.Frames[index].Data); err != nil {
+		return nil, err
+	}
+
+	return m.withIntegration(integration, removeFrameAt(m.Frames, index))
+}
+
+// Returns a copy of the running sum of the master dark frame, seeding it from the combined data if needed.
+func (m *MasterDarkFrame) getIntegration() (*Integration, error) {
+	if m.Integration != nil {
+		return m.Integration.Clone(), nil
+	}
+
+	// Add the master bias back to the combined master frame to recover the mean of the individual frames:
 	combined, err := utils.AddFloat32Array(m.Combined.Data, m.MasterBias.Combined.Data)
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Take the current combined master frame and apply the new frame to it:
-	combined, err = utils.MeanFloat32Arrays([][]float32{combined, frame.Data})
+	return newIntegrationFromMean(combined, m.Count), nil
+}
+
+// Returns a new master dark frame, with the combined data recomputed from the given running sum.
+func (m *MasterDarkFrame) withIntegration(integration *Integration, frames []fits.FITSImage) (*MasterDarkFrame, error) {
+	combined, err := integration.Mean()
 
 	if err != nil {
 		return nil, err
 	}
 
+	// Create a new FITSImage from the master data, leaving the current master untouched:
+	f := m.Combined.Copy()
+
 	// Subtract the master bias from the combined master frame:
-	combined, err = utils.SubtractFloat32Array(combined, m.MasterBias.Combined.Data)
+	f.Data, err = utils.SubtractFloat32Array(combined, m.MasterBias.Combined.Data)
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Create a new FITSImage from the master data
-	m.Combined.Data = combined
+	// Record the provenance of the master dark frame, now combined from the given frames:
+	provenance := NewProvenance(m.Type, frames)
+
+	provenance.MasterBias = getFrameID(m.MasterBias.Combined)
+
+	provenance.WriteToFITSImage(f)
 
 	return &MasterDarkFrame{
 		Type:             m.Type,
-		Count:            m.Count + 1,
+		Count:            integration.Count,
 		Pixels:           m.Pixels,
-		Frames:           append(m.Frames, *frame),
-		Combined:         m.Combined,
+		Frames:           frames,
+		Combined:         f,
 		MasterBias:       m.MasterBias,
+		Integration:      integration,
 		CreatedTimestamp: m.CreatedTimestamp,
 	}, nil
 }
@@ -0,0 +1,395 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/iris"
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+const (
+	DefectNone uint8 = 0 // The pixel is not defective
+	DefectHot  uint8 = 1 // The pixel is hot, i.e., significantly brighter than its local neighbourhood
+	DefectCold uint8 = 2 // The pixel is cold, i.e., significantly fainter than its local neighbourhood
+)
+
+/*****************************************************************************************************************/
+
+// DefectMapParams describes the thresholds used to detect defective pixels in master frames.
+type DefectMapParams struct {
+	HotSigma          float32 // The number of standard deviations above the local median for a hot pixel
+	ColdSigma         float32 // The number of standard deviations below the local median for a cold pixel
+	Radius            int     // The radius (in same-colour pixels) of the local median neighbourhood
+	ColourFilterArray string  // The CFA pattern (e.g., "RGGB") for one-shot colour sensors, empty for monochrome
+}
+
+/*****************************************************************************************************************/
+
+// DefectMap records the hot and cold (defective) pixels of a sensor.
+type DefectMap struct {
+	Width  int     // The width of the sensor, in pixels
+	Height int     // The height of the sensor, in pixels
+	Mask   []uint8 // The defect of each pixel, i.e., DefectNone, DefectHot or DefectCold
+	Hot    int     // The number of hot pixels
+	Cold   int     // The number of cold pixels
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default defect map parameters, i.e., 5 sigma thresholds for a 5x5 monochrome neighbourhood.
+func DefaultDefectMapParams() DefectMapParams {
+	return DefectMapParams{
+		HotSigma:          5,
+		ColdSigma:         5,
+		Radius:            2,
+		ColourFilterArray: "",
+	}
+}
+
+/*****************************************************************************************************************/
+
+/*
+NewDefectMap()
+
+Creates a new defect map from a master dark and/or master flat frame.
+
+Each pixel is compared against the median of its local neighbourhood (of the same colour,
+for one-shot colour sensors), and is marked as hot if it exceeds the local median by more
+than HotSigma standard deviations, or cold if it falls below the local median by more than
+ColdSigma standard deviations. The standard deviation is robustly estimated from the median
+absolute deviation of every pixel from its local median.
+
+Hot pixels are typically revealed by the master dark, and cold (dead) pixels by the master
+flat, so either of the master frames may be nil, but not both.
+*/
+func NewDefectMap(masterDark *MasterDarkFrame, masterFlat *MasterFlatFrame, params DefectMapParams) (*DefectMap, error) {
+	sources := make([]*fits.FITSImage, 0, 2)
+
+	if masterDark != nil {
+		sources = append(sources, masterDark.Combined)
+	}
+
+	if masterFlat != nil {
+		sources = append(sources, masterFlat.Combined)
+	}
+
+	if len(sources) == 0 {
+		return nil, errors.New("a master dark or master flat frame is required to create a defect map")
+	}
+
+	step, err := getCFAStep(params.ColourFilterArray)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Radius < 1 {
+		params.Radius = 1
+	}
+
+	xs, ys, ok := sources[0].GetDimensions()
+
+	if !ok {
+		return nil, errors.New("the master frame dimensions are required to create a defect map")
+	}
+
+	d := &DefectMap{
+		Width:  xs,
+		Height: ys,
+		Mask:   make([]uint8, xs*ys),
+	}
+
+	for _, source := range sources {
+		if len(source.Data) != xs*ys {
+			return nil, errors.New("to create a defect map the master frames must be of same dimensions")
+		}
+
+		d.detect(source.Data, params, step)
+	}
+
+	return d, nil
+}
+
+/*****************************************************************************************************************/
+
+// Creates a defect map from a FITS mask image, e.g., as previously created by GetFITSImage().
+func NewDefectMapFromFITSImage(f *fits.FITSImage) (*DefectMap, error) {
+	xs, ys, ok := f.GetDimensions()
+
+	if !ok {
+		return nil, errors.New("the FITS mask dimensions are required to create a defect map")
+	}
+
+	if len(f.Data) != xs*ys {
+		return nil, errors.New("the FITS mask data does not match its dimensions")
+	}
+
+	d := &DefectMap{
+		Width:  xs,
+		Height: ys,
+		Mask:   make([]uint8, xs*ys),
+	}
+
+	for i, v := range f.Data {
+		switch uint8(v) {
+		case DefectHot:
+			d.Mask[i] = DefectHot
+			d.Hot++
+		case DefectCold:
+			d.Mask[i] = DefectCold
+			d.Cold++
+		}
+	}
+
+	return d, nil
+}
+
+/*****************************************************************************************************************/
+
+// Converts the defect map to a FITS mask image, where 0 is a good pixel, 1 is a hot pixel and 2 is a cold pixel.
+func (d *DefectMap) GetFITSImage() *fits.FITSImage {
+	f := fits.NewFITSImage(2, int32(d.Width), int32(d.Height), int32(DefectCold))
+
+	f.Naxisn = []int32{int32(d.Width), int32(d.Height)}
+
+	f.Pixels = int32(d.Width * d.Height)
+
+	f.Data = make([]float32, len(d.Mask))
+
+	for i, v := range d.Mask {
+		f.Data[i] = float32(v)
+	}
+
+	f.Header.Set("IMAGETYP", "Defect Map", "Type of image")
+
+	f.Header.Set("NHOT", d.Hot, "The number of hot pixels (mask value 1)")
+
+	f.Header.Set("NCOLD", d.Cold, "The number of cold pixels (mask value 2)")
+
+	return f
+}
+
+/*****************************************************************************************************************/
+
+/*
+ApplyCosmeticCorrection()
+
+Returns a copy of the light frame, with each defective pixel replaced by the median of
+its neighbouring good pixels of the same colour, as given by the CFA pattern (e.g., "RGGB"),
+or of its immediate neighbours for monochrome sensors (an empty CFA pattern).
+*/
+func (d *DefectMap) ApplyCosmeticCorrection(light *fits.FITSImage, cfa string) (*fits.FITSImage, error) {
+	if len(light.Data) != len(d.Mask) {
+		return nil, errors.New("to apply cosmetic correction the light frame must be of same dimensions as the defect map")
+	}
+
+	step, err := getCFAStep(cfa)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f := light.Copy()
+
+	corrected := d.correct(light.Data, f.Data, step)
+
+	f.Header.History = append(f.Header.History, fmt.Sprintf("Cosmetic correction of %d hot and %d cold pixels", d.Hot, d.Cold))
+
+	f.Header.Set("NCOSMETC", corrected, "The number of cosmetically corrected pixels")
+
+	return f, nil
+}
+
+/*****************************************************************************************************************/
+
+// Applies cosmetic correction to the raw Colour Filter Array data of the exposure in place, before debayering,
+// replacing each defective pixel with the median of its neighbouring good pixels of the same colour.
+func (d *DefectMap) ApplyCosmeticCorrectionToRGGBExposure(exposure *iris.RGGBExposure) error {
+	if exposure.Width != d.Width || exposure.Height != d.Height || len(exposure.Raw) != d.Height {
+		return errors.New("to apply cosmetic correction the exposure must be of same dimensions as the defect map")
+	}
+
+	step, err := getCFAStep(exposure.ColourFilterArray)
+
+	if err != nil {
+		return err
+	}
+
+	data := make([]float32, 0, d.Width*d.Height)
+
+	for _, row := range exposure.Raw {
+		if len(row) != d.Width {
+			return errors.New("to apply cosmetic correction the exposure must be of same dimensions as the defect map")
+		}
+
+		for _, v := range row {
+			data = append(data, float32(v))
+		}
+	}
+
+	corrected := make([]float32, len(data))
+
+	copy(corrected, data)
+
+	d.correct(data, corrected, step)
+
+	for y, row := range exposure.Raw {
+		for x := range row {
+			row[x] = uint32(math.Round(float64(corrected[y*d.Width+x])))
+		}
+	}
+
+	return nil
+}
+
+/*****************************************************************************************************************/
+
+// Marks the hot and cold pixels of the given data, relative to their local same-colour neighbourhood median.
+func (d *DefectMap) detect(data []float32, params DefectMapParams, step int) {
+	residuals := make([]float32, len(data))
+
+	buffer := make([]float32, 0, (2*params.Radius+1)*(2*params.Radius+1))
+
+	for y := 0; y < d.Height; y++ {
+		for x := 0; x < d.Width; x++ {
+			residuals[y*d.Width+x] = data[y*d.Width+x] - d.localMedian(data, x, y, params.Radius, step, buffer, false)
+		}
+	}
+
+	// Robustly estimate the standard deviation of the residuals from their median absolute deviation, ignoring the
+	// residuals of NaN pixels (and of pixels without any non-NaN neighbours):
+	deviations := make([]float32, 0, len(residuals))
+
+	for _, r := range residuals {
+		if !math.IsNaN(float64(r)) {
+			deviations = append(deviations, float32(math.Abs(float64(r))))
+		}
+	}
+
+	sigma := qsort.MedianFloat32(deviations) * 1.4826
+
+	if sigma <= 0 {
+		sigma = math.SmallestNonzeroFloat32
+	}
+
+	for i, r := range residuals {
+		if d.Mask[i] != DefectNone {
+			continue
+		}
+
+		if params.HotSigma > 0 && r > params.HotSigma*sigma {
+			d.Mask[i] = DefectHot
+			d.Hot++
+		} else if params.ColdSigma > 0 && -r > params.ColdSigma*sigma {
+			d.Mask[i] = DefectCold
+			d.Cold++
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Replaces the defective pixels of the source data in the destination data, returning the number replaced.
+func (d *DefectMap) correct(src []float32, dst []float32, step int) int {
+	buffer := make([]float32, 0, 25)
+
+	corrected := 0
+
+	for y := 0; y < d.Height; y++ {
+		for x := 0; x < d.Width; x++ {
+			if d.Mask[y*d.Width+x] == DefectNone {
+				continue
+			}
+
+			// Widen the neighbourhood until good neighbouring pixels of the same colour are found:
+			for radius := 1; radius <= 3; radius++ {
+				median := d.localMedian(src, x, y, radius, step, buffer, true)
+
+				if !math.IsNaN(float64(median)) {
+					dst[y*d.Width+x] = median
+					corrected++
+					break
+				}
+			}
+		}
+	}
+
+	return corrected
+}
+
+/*****************************************************************************************************************/
+
+// Computes the median of the neighbourhood of the pixel { x, y }, excluding the pixel itself, sampling every
+// step pixels such that only pixels of the same colour are sampled for CFA data. If good is true, defective
+// pixels are excluded from the neighbourhood. NaN pixels are always excluded. Returns NaN if the neighbourhood
+// is empty.
+func (d *DefectMap) localMedian(data []float32, x int, y int, radius int, step int, buffer []float32, good bool) float32 {
+	buffer = buffer[:0]
+
+	for j := -radius; j <= radius; j++ {
+		ny := y + j*step
+
+		if ny < 0 || ny >= d.Height {
+			continue
+		}
+
+		for i := -radius; i <= radius; i++ {
+			nx := x + i*step
+
+			if (i == 0 && j == 0) || nx < 0 || nx >= d.Width {
+				continue
+			}
+
+			if good && d.Mask[ny*d.Width+nx] != DefectNone {
+				continue
+			}
+
+			if v := data[ny*d.Width+nx]; !math.IsNaN(float64(v)) {
+				buffer = append(buffer, v)
+			}
+		}
+	}
+
+	if len(buffer) == 0 {
+		return float32(math.NaN())
+	}
+
+	return qsort.QSelectMedianFloat32(buffer)
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the step between neighbouring pixels of the same colour, i.e., 2 for any Bayer CFA pattern and 1 for
+// monochrome sensors (an empty CFA pattern).
+func getCFAStep(cfa string) (int, error) {
+	if strings.TrimSpace(cfa) == "" {
+		return 1, nil
+	}
+
+	// Validate the CFA pattern using the Bayer matrix offsets of the iris RGGB exposure:
+	exposure := iris.RGGBExposure{ColourFilterArray: cfa}
+
+	if _, _, err := exposure.GetBayerMatrixOffset(); err != nil {
+		return 0, err
+	}
+
+	return 2, nil
+}
+
+/*****************************************************************************************************************/
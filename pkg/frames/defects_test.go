@@ -0,0 +1,272 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/iris"
+)
+
+/*****************************************************************************************************************/
+
+func getDefectTestMasters(t *testing.T) (*MasterDarkFrame, *MasterFlatFrame) {
+	bias := make([][]uint32, 16)
+	dark := make([][]uint32, 16)
+	flat := make([][]uint32, 16)
+
+	for y := 0; y < 16; y++ {
+		bias[y] = make([]uint32, 16)
+		dark[y] = make([]uint32, 16)
+		flat[y] = make([]uint32, 16)
+
+		for x := 0; x < 16; x++ {
+			bias[y][x] = 100
+			dark[y][x] = 110 + uint32((x+y)%3)
+			flat[y][x] = 20000 + uint32((x*y)%5)
+		}
+	}
+
+	// A hot pixel in the dark, and a dead (cold) pixel in the flat:
+	dark[4][6] = 4000
+	flat[10][3] = 2000
+
+	biasFrame := fits.NewFITSImageFrom2DData(bias, 2, 16, 16, 65535)
+
+	masterBias, err := NewMasterBiasFrame([]fits.FITSImage{*biasFrame}, 2, 16, 16, 65535, 0.001)
+
+	if err != nil {
+		t.Fatalf("NewMasterBiasFrame() failed: %s", err)
+	}
+
+	darkFrame := fits.NewFITSImageFrom2DData(dark, 2, 16, 16, 65535)
+
+	masterDark, err := NewMasterDarkFrame([]fits.FITSImage{*darkFrame}, masterBias, 2, 16, 16, 65535, 60)
+
+	if err != nil {
+		t.Fatalf("NewMasterDarkFrame() failed: %s", err)
+	}
+
+	flatFrame := fits.NewFITSImageFrom2DData(flat, 2, 16, 16, 65535)
+
+	masterFlat, err := NewMasterFlatFrame([]fits.FITSImage{*flatFrame}, masterBias, 2, 16, 16, 65535, 2)
+
+	if err != nil {
+		t.Fatalf("NewMasterFlatFrame() failed: %s", err)
+	}
+
+	return masterDark, masterFlat
+}
+
+/*****************************************************************************************************************/
+
+func TestNewDefectMap(t *testing.T) {
+	masterDark, masterFlat := getDefectTestMasters(t)
+
+	defects, err := NewDefectMap(masterDark, masterFlat, DefaultDefectMapParams())
+
+	if err != nil {
+		t.Fatalf("NewDefectMap() failed: %s", err)
+	}
+
+	if defects.Mask[4*16+6] != DefectHot {
+		t.Errorf("NewDefectMap() failed: expected pixel { 6, 4 } to be hot, got %d", defects.Mask[4*16+6])
+	}
+
+	if defects.Mask[10*16+3] != DefectCold {
+		t.Errorf("NewDefectMap() failed: expected pixel { 3, 10 } to be cold, got %d", defects.Mask[10*16+3])
+	}
+
+	if defects.Hot != 1 || defects.Cold != 1 {
+		t.Errorf("NewDefectMap() failed: expected 1 hot and 1 cold pixel, got %d hot and %d cold", defects.Hot, defects.Cold)
+	}
+
+	if _, err := NewDefectMap(nil, nil, DefaultDefectMapParams()); err == nil {
+		t.Errorf("NewDefectMap() failed: expected an error without any master frames")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestDefectMapFITSImageRoundTrip(t *testing.T) {
+	masterDark, masterFlat := getDefectTestMasters(t)
+
+	defects, err := NewDefectMap(masterDark, masterFlat, DefaultDefectMapParams())
+
+	if err != nil {
+		t.Fatalf("NewDefectMap() failed: %s", err)
+	}
+
+	f := defects.GetFITSImage()
+
+	if f.Header.Ints["NHOT"].Value != 1 || f.Header.Ints["NCOLD"].Value != 1 {
+		t.Errorf("GetFITSImage() failed: expected NHOT and NCOLD of 1")
+	}
+
+	restored, err := NewDefectMapFromFITSImage(f)
+
+	if err != nil {
+		t.Fatalf("NewDefectMapFromFITSImage() failed: %s", err)
+	}
+
+	for i := range defects.Mask {
+		if restored.Mask[i] != defects.Mask[i] {
+			t.Errorf("NewDefectMapFromFITSImage() failed: expected mask[%d] of %d, got %d", i, defects.Mask[i], restored.Mask[i])
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestApplyCosmeticCorrection(t *testing.T) {
+	masterDark, masterFlat := getDefectTestMasters(t)
+
+	defects, err := NewDefectMap(masterDark, masterFlat, DefaultDefectMapParams())
+
+	if err != nil {
+		t.Fatalf("NewDefectMap() failed: %s", err)
+	}
+
+	light := make([][]uint32, 16)
+
+	for y := range light {
+		light[y] = make([]uint32, 16)
+
+		for x := range light[y] {
+			light[y][x] = 500
+		}
+	}
+
+	light[4][6] = 9000
+	light[10][3] = 50
+
+	lightFrame := fits.NewFITSImageFrom2DData(light, 2, 16, 16, 65535)
+
+	corrected, err := defects.ApplyCosmeticCorrection(lightFrame, "")
+
+	if err != nil {
+		t.Fatalf("ApplyCosmeticCorrection() failed: %s", err)
+	}
+
+	if corrected.Data[4*16+6] != 500 || corrected.Data[10*16+3] != 500 {
+		t.Errorf("ApplyCosmeticCorrection() failed: expected defects to be replaced with 500, got %f and %f", corrected.Data[4*16+6], corrected.Data[10*16+3])
+	}
+
+	if lightFrame.Data[4*16+6] != 9000 {
+		t.Errorf("ApplyCosmeticCorrection() failed: expected the original light frame to be unmodified")
+	}
+
+	if corrected.Header.Ints["NCOSMETC"].Value != 2 {
+		t.Errorf("ApplyCosmeticCorrection() failed: expected NCOSMETC of 2, got %d", corrected.Header.Ints["NCOSMETC"].Value)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestApplyCosmeticCorrectionToRGGBExposure(t *testing.T) {
+	masterDark, masterFlat := getDefectTestMasters(t)
+
+	defects, err := NewDefectMap(masterDark, masterFlat, DefaultDefectMapParams())
+
+	if err != nil {
+		t.Fatalf("NewDefectMap() failed: %s", err)
+	}
+
+	// A colour filter array exposure, where each colour site has a distinct level:
+	raw := make([][]uint32, 16)
+
+	levels := [2][2]uint32{{1000, 600}, {600, 200}}
+
+	for y := range raw {
+		raw[y] = make([]uint32, 16)
+
+		for x := range raw[y] {
+			raw[y][x] = levels[y%2][x%2]
+		}
+	}
+
+	// The hot pixel at { 6, 4 } is a red site, and the cold pixel at { 3, 10 } is a green site:
+	raw[4][6] = 65000
+	raw[10][3] = 0
+
+	exposure := iris.NewRGGBExposure(raw, 65535, 16, 16, "RGGB")
+
+	if err := defects.ApplyCosmeticCorrectionToRGGBExposure(exposure); err != nil {
+		t.Fatalf("ApplyCosmeticCorrectionToRGGBExposure() failed: %s", err)
+	}
+
+	if exposure.Raw[4][6] != 1000 {
+		t.Errorf("ApplyCosmeticCorrectionToRGGBExposure() failed: expected the red site to be replaced with 1000, got %d", exposure.Raw[4][6])
+	}
+
+	if exposure.Raw[10][3] != 600 {
+		t.Errorf("ApplyCosmeticCorrectionToRGGBExposure() failed: expected the green site to be replaced with 600, got %d", exposure.Raw[10][3])
+	}
+
+	exposure.ColourFilterArray = "XYZW"
+
+	if err := defects.ApplyCosmeticCorrectionToRGGBExposure(exposure); err == nil {
+		t.Errorf("ApplyCosmeticCorrectionToRGGBExposure() failed: expected an error for an unknown CFA pattern")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewDefectMapWithNaN(t *testing.T) {
+	masterDark, masterFlat := getDefectTestMasters(t)
+
+	// A NaN pixel in the master dark, e.g., from upstream processing:
+	masterDark.Combined.Data[8*16+8] = float32(math.NaN())
+
+	defects, err := NewDefectMap(masterDark, masterFlat, DefaultDefectMapParams())
+
+	if err != nil {
+		t.Fatalf("NewDefectMap() failed: %s", err)
+	}
+
+	if defects.Mask[8*16+8] != DefectNone {
+		t.Errorf("NewDefectMap() failed: expected the NaN pixel not to be marked, got %d", defects.Mask[8*16+8])
+	}
+
+	if defects.Hot != 1 || defects.Cold != 1 {
+		t.Errorf("NewDefectMap() failed: expected 1 hot and 1 cold pixel, got %d hot and %d cold", defects.Hot, defects.Cold)
+	}
+
+	light := fits.NewFITSImage(2, 16, 16, 65535)
+
+	light.Data = make([]float32, 16*16)
+
+	for i := range light.Data {
+		light.Data[i] = 500
+	}
+
+	// The hot pixel at { 6, 4 } has a NaN neighbour:
+	light.Data[4*16+6] = 9000
+	light.Data[4*16+7] = float32(math.NaN())
+
+	corrected, err := defects.ApplyCosmeticCorrection(light, "")
+
+	if err != nil {
+		t.Fatalf("ApplyCosmeticCorrection() failed: %s", err)
+	}
+
+	if corrected.Data[4*16+6] != 500 {
+		t.Errorf("ApplyCosmeticCorrection() failed: expected the hot pixel to be replaced with 500, got %f", corrected.Data[4*16+6])
+	}
+
+	if !math.IsNaN(float64(corrected.Data[4*16+7])) {
+		t.Errorf("ApplyCosmeticCorrection() failed: expected the NaN pixel to be unchanged, got %f", corrected.Data[4*16+7])
+	}
+}
+
+/*****************************************************************************************************************/
@@ -1,6 +1,8 @@
 package frames
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/observerly/iris/pkg/fits"
@@ -14,6 +16,8 @@ type MasterFlatFrame struct {
 	Frames           []fits.FITSImage // The individual frames used to create the master frame
 	Combined         *fits.FITSImage  // The combined master frame
 	MasterBias       *MasterFrame     // The master bias frame used to create the master flat frame
+	MasterDarkFlat   *MasterDarkFrame // The master dark-flat frame subtracted in place of the master bias, if any
+	Integration      *Integration     // The running sum of the individual (non bias-subtracted) frames
 	CreatedTimestamp int64
 }
 
@@ -30,14 +34,50 @@ background illumination in the exposure environment. Flat-fieds may also reveal
 areas of uneven gains or dark currents in the CCD detector, providing an
 indication of CCD Defects.
 
-The master flat frame is then created by taking the mean of all the flat frames.
+The master flat frame is then created by taking the mean of all the flat frames,
+from which the master bias frame is subtracted.
 
 @retuns a new FITSImage containing the master flat frame.
 @see Image Calibration & Stack Woodhouse, C. (2017). The Astrophotography Manual. Taylor & Francis. p.203
 */
 func NewMasterFlatFrame(frames []fits.FITSImage, masterBias *MasterFrame, naxis int32, naxis1 int32, naxis2 int32, adu int32, exposureTime float32) (*MasterFlatFrame, error) {
+	return NewMasterFlatFrameWithDarkFlat(frames, masterBias, nil, naxis, naxis1, naxis2, adu, exposureTime)
+}
+
+/*
+NewMasterFlatFrameWithDarkFlat()
+
+Creates a new master flat frame from a slice of flat frames, subtracting a master dark-flat
+frame (if given) in place of the master bias frame.
+
+A dark-flat is a dark frame taken with the same exposure time (and sensor settings) as the
+flat frames. For CMOS sensors, and for long flat exposures, the dark current and amplifier
+glow accumulated during the flat exposure are not negligible, and subtracting only the
+master bias would leave them imprinted in the master flat.
+
+As the master dark-flat frame is itself bias-subtracted, the mean of the flat frames has both
+the master dark-flat and its master bias subtracted. The calibration frame used is recorded
+in the HISTORY, and in the FLATCAL keyword, of the master flat frame.
+*/
+func NewMasterFlatFrameWithDarkFlat(frames []fits.FITSImage, masterBias *MasterFrame, masterDarkFlat *MasterDarkFrame, naxis int32, naxis1 int32, naxis2 int32, adu int32, exposureTime float32) (*MasterFlatFrame, error) {
 	pixels := naxis1 * naxis2
 
+	if masterBias == nil && masterDarkFlat == nil {
+		return nil, errors.New("a master bias or master dark-flat frame is required to create a master flat frame")
+	}
+
+	m := &MasterFlatFrame{
+		MasterBias:     masterBias,
+		MasterDarkFlat: masterDarkFlat,
+	}
+
+	// Obtain the level subtracted from the flat frames, i.e., the master bias or master dark-flat:
+	offset, err := m.getOffset()
+
+	if err != nil {
+		return nil, err
+	}
+
 	// Create a slice of 2D data arrays from the slice of FITSImages
 	data := make([][]float32, len(frames))
 
@@ -53,32 +93,26 @@ func NewMasterFlatFrame(frames []fits.FITSImage, masterBias *MasterFrame, naxis
 		adu,
 	)
 
-	// Combine the data arrays into a single array, by taking
-	// the mean of the total of all the frames for each pixel:
-	if len(frames) > 1 {
-		combined, err := utils.MeanFloat32Arrays(data)
-
-		if err != nil {
-			return nil, err
-		}
+	// Accumulate the running sum of all the frames for each pixel:
+	integration, err := NewIntegrationFromData(data)
 
-		f.Data, err = utils.SubtractFloat32Array(combined, masterBias.Combined.Data)
-
-		if err != nil {
-			return nil, err
-		}
-	} else {
-		var err error = nil
+	if err != nil {
+		return nil, err
+	}
 
-		combined := frames[0].Data
+	// Combine the data arrays into a single array, by taking
+	// the mean of the total of all the frames for each pixel:
+	combined, err := integration.Mean()
 
-		f.Data = combined
+	if err != nil {
+		return nil, err
+	}
 
-		f.Data, err = utils.SubtractFloat32Array(combined, masterBias.Combined.Data)
+	// Subtract the master bias (or master dark-flat) from the combined master frame:
+	f.Data, err = utils.SubtractFloat32Array(combined, offset)
 
-		if err != nil {
-			return nil, err
-		}
+	if err != nil {
+		return nil, err
 	}
 
 	f.Exposure = exposureTime
@@ -101,14 +135,31 @@ func NewMasterFlatFrame(frames []fits.FITSImage, masterBias *MasterFrame, naxis
 		Comment: "The exposure time (s) of the flat frame",
 	}
 
-	f.Header.Strings["SENSOR"] = struct {
-		Value   string
-		Comment string
-	}{
-		Value:   "Monochrome",
-		Comment: "ASCOM Alpaca Sensor Type",
+	// Inherit the acquisition metadata of the flat frames, e.g., for matching the master to light frames:
+	inheritCalibrationMetadata(f, frames)
+
+	// Record the calibration frame subtracted from the flat frames:
+	if masterDarkFlat != nil {
+		darkFlatExposure := masterDarkFlat.Combined.GetExposureTime()
+
+		f.Header.Set("FLATCAL", "Dark-Flat", "The calibration frame subtracted from the flat frames")
+
+		f.Header.History = append(
+			f.Header.History,
+			fmt.Sprintf("Flat frames calibrated with a master dark-flat of %d frames (EXPOSURE %gs)", masterDarkFlat.Count, darkFlatExposure),
+		)
+	} else {
+		f.Header.Set("FLATCAL", "Bias", "The calibration frame subtracted from the flat frames")
+
+		f.Header.History = append(
+			f.Header.History,
+			fmt.Sprintf("Flat frames calibrated with a master bias of %d frames", masterBias.Count),
+		)
 	}
 
+	// Record how the master flat frame was created, once its data is final:
+	m.getProvenance(frames).WriteToFITSImage(f)
+
 	return &MasterFlatFrame{
 		Type:             "flat",
 		Count:            len(frames),
@@ -116,42 +167,154 @@ func NewMasterFlatFrame(frames []fits.FITSImage, masterBias *MasterFrame, naxis
 		Frames:           frames,
 		Combined:         f,
 		MasterBias:       masterBias,
+		MasterDarkFlat:   masterDarkFlat,
+		Integration:      integration,
 		CreatedTimestamp: time.Now().Unix(),
 	}, nil
 }
 
+/*
+ApplyFlatFrame()
+
+Integrates a further flat frame into the master flat frame, returning a new master flat frame.
+
+The running sum is kept of the individual frames before the master bias is subtracted,
+such that applying frames one at a time yields exactly the same master flat frame as
+creating it from all of the frames at once. The receiver is left unmodified.
+*/
 func (m *MasterFlatFrame) ApplyFlatFrame(frame *fits.FITSImage) (*MasterFlatFrame, error) {
-	// Add the current combined master frame to the master bias before averaging:
-	combined, err := utils.AddFloat32Array(m.Combined.Data, m.MasterBias.Combined.Data)
+	integration, err := m.getIntegration()
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Take the current combined master frame and apply the new frame to it:
-	combined, err = utils.MeanFloat32Arrays([][]float32{combined, frame.Data})
+	// Add the new frame to the running sum of all of the integrated frames:
+	if err := integration.Add(frame.Data); err != nil {
+		return nil, err
+	}
+
+	return m.withIntegration(integration, append(append([]fits.FITSImage{}, m.Frames...), *frame))
+}
+
+/*
+RemoveFlatFrame()
+
+Removes a previously integrated flat frame, e.g., a bad frame, from the master flat frame
+by its index in Frames, returning a new master flat frame. The receiver is left unmodified.
+*/
+func (m *MasterFlatFrame) RemoveFlatFrame(index int) (*MasterFlatFrame, error) {
+	if index < 0 || index >= len(m.Frames) {
+		return nil, fmt.Errorf("frame index %d out of range of %d frames", index, len(m.Frames))
+	}
+
+	if m.Count <= 1 {
+		return nil, fmt.Errorf("cannot remove the only frame of a master %s frame", m.Type)
+	}
+
+	integration, err := m.getIntegration()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Remove the frame from the running sum of all of the integrated frames:
+	if err := integration.Remove(m.Frames[index].Data); err != nil {
+		return nil, err
+	}
+
+	return m.withIntegration(integration, removeFrameAt(m.Frames, index))
+}
+
+// Returns a copy of the running sum of the master flat frame, seeding it from the combined data if needed.
+func (m *MasterFlatFrame) getIntegration() (*Integration, error) {
+	if m.Integration != nil {
+		return m.Integration.Clone(), nil
+	}
+
+	offset, err := m.getOffset()
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Subtract the master bias from the combined master frame:
-	combined, err = utils.SubtractFloat32Array(combined, m.MasterBias.Combined.Data)
+	// Add the master bias (or dark-flat) back to the combined master frame to recover the mean of the frames:
+	combined, err := utils.AddFloat32Array(m.Combined.Data, offset)
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Create a new FITSImage from the master data
-	m.Combined.Data = combined
+	return newIntegrationFromMean(combined, m.Count), nil
+}
+
+// Returns a new master flat frame, with the combined data recomputed from the given running sum.
+func (m *MasterFlatFrame) withIntegration(integration *Integration, frames []fits.FITSImage) (*MasterFlatFrame, error) {
+	combined, err := integration.Mean()
+
+	if err != nil {
+		return nil, err
+	}
+
+	offset, err := m.getOffset()
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Create a new FITSImage from the master data, leaving the current master untouched:
+	f := m.Combined.Copy()
+
+	// Subtract the master bias (or master dark-flat) from the combined master frame:
+	f.Data, err = utils.SubtractFloat32Array(combined, offset)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Record the provenance of the master flat frame, now combined from the given frames:
+	m.getProvenance(frames).WriteToFITSImage(f)
 
 	return &MasterFlatFrame{
 		Type:             m.Type,
-		Count:            m.Count + 1,
+		Count:            integration.Count,
 		Pixels:           m.Pixels,
-		Frames:           append(m.Frames, *frame),
-		Combined:         m.Combined,
+		Frames:           frames,
+		Combined:         f,
 		MasterBias:       m.MasterBias,
+		MasterDarkFlat:   m.MasterDarkFlat,
+		Integration:      integration,
 		CreatedTimestamp: m.CreatedTimestamp,
 	}, nil
 }
+
+// Returns the level subtracted from the flat frames, i.e., the master dark-flat (with its master bias added back,
+// as the master dark-flat is bias-subtracted) if there is one, or else the master bias.
+func (m *MasterFlatFrame) getOffset() ([]float32, error) {
+	if m.MasterDarkFlat == nil {
+		if m.MasterBias == nil {
+			return nil, errors.New("a master bias or master dark-flat frame is required to calibrate the flat frames")
+		}
+
+		return m.MasterBias.Combined.Data, nil
+	}
+
+	if m.MasterDarkFlat.MasterBias == nil {
+		return m.MasterDarkFlat.Combined.Data, nil
+	}
+
+	return utils.AddFloat32Array(m.MasterDarkFlat.Combined.Data, m.MasterDarkFlat.MasterBias.Combined.Data)
+}
+
+// Returns the provenance of the master flat frame combined from the given frames, with the master frame used.
+func (m *MasterFlatFrame) getProvenance(frames []fits.FITSImage) *Provenance {
+	provenance := NewProvenance("flat", frames)
+
+	if m.MasterDarkFlat != nil {
+		provenance.MasterDark = getFrameID(m.MasterDarkFlat.Combined)
+	} else if m.MasterBias != nil {
+		provenance.MasterBias = getFrameID(m.MasterBias.Combined)
+	}
+
+	return provenance
+}
@@ -1,6 +1,7 @@
 package frames
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/observerly/iris/pkg/fits"
@@ -212,7 +213,94 @@ func TestApplyFrameToNewMasterFlatFrame(t *testing.T) {
 		t.Errorf("NewmasterFlatFrame() failed: expected ADU of 255, got %d", masterFlat.Combined.ADU)
 	}
 
-	if masterFlat.Combined.Data[1] != 251 {
-		t.Errorf("NewmasterFlatFrame() failed: expected data[0] of 251, got %f", masterFlat.Combined.Data[1])
+	// The sixth frame is weighted equally with the previous five frames, i.e., (5 * 254 + 250) / 6 - 1:
+	if masterFlat.Combined.Data[1] != 252.33333 {
+		t.Errorf("NewmasterFlatFrame() failed: expected data[1] of 252.333333, got %f", masterFlat.Combined.Data[1])
+	}
+}
+
+func TestNewMasterFlatFrameWithDarkFlat(t *testing.T) {
+	var bias = [][]uint32{
+		{100, 100, 100, 100},
+		{100, 100, 100, 100},
+		{100, 100, 100, 100},
+		{100, 100, 100, 100},
+	}
+
+	var darkFlat = [][]uint32{
+		{140, 140, 140, 140},
+		{140, 140, 140, 140},
+		{140, 140, 140, 140},
+		{140, 140, 140, 140},
+	}
+
+	var flat = [][]uint32{
+		{20140, 20140, 20140, 20140},
+		{20140, 20140, 20140, 20140},
+		{20140, 20140, 20140, 20140},
+		{20140, 20140, 20140, 20140},
+	}
+
+	var biasFrame = fits.NewFITSImageFrom2DData(bias, 2, 4, 4, 65535)
+
+	var darkFlatFrame = fits.NewFITSImageFrom2DData(darkFlat, 2, 4, 4, 65535)
+
+	var flatFrame = fits.NewFITSImageFrom2DData(flat, 2, 4, 4, 65535)
+
+	masterBias, err := NewMasterBiasFrame([]fits.FITSImage{*biasFrame}, 2, 4, 4, 65535, 0.001)
+
+	if err != nil {
+		t.Errorf("Error creating master bias frame: %v", err)
+	}
+
+	masterDarkFlat, err := NewMasterDarkFrame([]fits.FITSImage{*darkFlatFrame}, masterBias, 2, 4, 4, 65535, 30)
+
+	if err != nil {
+		t.Errorf("Error creating master dark-flat frame: %v", err)
+	}
+
+	masterFlat, err := NewMasterFlatFrameWithDarkFlat([]fits.FITSImage{*flatFrame, *flatFrame}, masterBias, masterDarkFlat, 2, 4, 4, 65535, 30)
+
+	if err != nil {
+		t.Errorf("Error creating master flat frame: %v", err)
+	}
+
+	// The dark current and bias, i.e., the raw dark-flat level of 140 ADU, is subtracted:
+	if masterFlat.Combined.Data[0] != 20000 {
+		t.Errorf("Expected 20000, got %v", masterFlat.Combined.Data[0])
+	}
+
+	if masterFlat.Combined.Header.Strings["FLATCAL"].Value != "Dark-Flat" {
+		t.Errorf("Expected FLATCAL of Dark-Flat, got %v", masterFlat.Combined.Header.Strings["FLATCAL"].Value)
+	}
+
+	if !strings.Contains(masterFlat.Combined.Header.History[0], "master dark-flat") {
+		t.Errorf("Expected a HISTORY entry recording the master dark-flat, got %v", masterFlat.Combined.Header.History)
+	}
+
+	// Integrating a further flat frame retains the master dark-flat subtraction:
+	masterFlat, err = masterFlat.ApplyFlatFrame(flatFrame)
+
+	if err != nil {
+		t.Errorf("Error applying flat frame: %v", err)
+	}
+
+	if masterFlat.Combined.Data[0] != 20000 {
+		t.Errorf("Expected 20000, got %v", masterFlat.Combined.Data[0])
+	}
+
+	// Without a master dark-flat, only the master bias is subtracted:
+	masterFlat, err = NewMasterFlatFrame([]fits.FITSImage{*flatFrame}, masterBias, 2, 4, 4, 65535, 30)
+
+	if err != nil {
+		t.Errorf("Error creating master flat frame: %v", err)
+	}
+
+	if masterFlat.Combined.Data[0] != 20040 {
+		t.Errorf("Expected 20040, got %v", masterFlat.Combined.Data[0])
+	}
+
+	if masterFlat.Combined.Header.Strings["FLATCAL"].Value != "Bias" {
+		t.Errorf("Expected FLATCAL of Bias, got %v", masterFlat.Combined.Header.Strings["FLATCAL"].Value)
 	}
 }
@@ -0,0 +1,118 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+// Computes the average of a numeric FITS header value across all of the frames, e.g., the average sensor
+// temperature, returning false if none of the frames record the given key.
+func averageHeaderFloat(frames []fits.FITSImage, key string) (float32, bool) {
+	sum, count := float32(0), 0
+
+	for _, frame := range frames {
+		if v, ok := frame.Header.GetFloat(key); ok {
+			sum += float32(v)
+			count++
+		}
+	}
+
+	if count == 0 {
+		return 0, false
+	}
+
+	return sum / float32(count), true
+}
+
+/*****************************************************************************************************************/
+
+// The FITS header keywords describing the acquisition of a frame, which are inherited by master frames so that
+// they can be matched to the light frames they calibrate:
+var calibrationMetadataKeys = []string{
+	"INSTRUME",
+	"FILTER",
+	"GAIN",
+	"OFFSET",
+	"XBINNING",
+	"YBINNING",
+	"READOUTM",
+	"BAYERPAT",
+	"SENSOR",
+	"DATE-OBS",
+}
+
+/*****************************************************************************************************************/
+
+// Copies the FITS header value for the given key from the source header to the destination header, whatever
+// its type, returning false if the source header does not record the given key.
+func copyHeaderValue(dst *fits.FITSHeader, src fits.FITSHeader, key string) bool {
+	if v, ok := src.Bools[key]; ok {
+		dst.Bools[key] = v
+		return true
+	}
+
+	if v, ok := src.Ints[key]; ok {
+		dst.Ints[key] = v
+		return true
+	}
+
+	if v, ok := src.Floats[key]; ok {
+		dst.Floats[key] = v
+		return true
+	}
+
+	if v, ok := src.Strings[key]; ok {
+		dst.Strings[key] = v
+		return true
+	}
+
+	if v, ok := src.Dates[key]; ok {
+		dst.Dates[key] = v
+		return true
+	}
+
+	return false
+}
+
+/*****************************************************************************************************************/
+
+// Inherits the acquisition metadata of the individual frames onto the combined master frame, e.g., the
+// instrument, gain, offset, binning and filter of the first frame recording them, and the average sensor
+// temperature of all of the frames. A monochrome sensor is assumed if none of the frames record the SENSOR.
+func inheritCalibrationMetadata(f *fits.FITSImage, frames []fits.FITSImage) {
+	for _, key := range calibrationMetadataKeys {
+		for _, frame := range frames {
+			if copyHeaderValue(&f.Header, frame.Header, key) {
+				break
+			}
+		}
+	}
+
+	if _, ok := f.Header.Strings["SENSOR"]; !ok {
+		f.Header.Set("SENSOR", "Monochrome", "ASCOM Alpaca Sensor Type")
+	}
+
+	// Record the average sensor temperature of the frames, e.g., for the dark current temperature model:
+	if temperature, ok := averageHeaderFloat(frames, "CCD-TEMP"); ok {
+		f.Header.Floats["CCD-TEMP"] = struct {
+			Value   float32
+			Comment string
+		}{
+			Value:   temperature,
+			Comment: "The average sensor temperature (C) of the frames",
+		}
+	}
+}
+
+/*****************************************************************************************************************/
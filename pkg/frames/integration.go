@@ -0,0 +1,160 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+// Integration keeps a count-aware running sum of every frame that has been added to a master frame, so that
+// frames may be added (or removed) one at a time whilst yielding exactly the same mean as a batch integration.
+//
+// The per-pixel sums are accumulated in float64 precision, which represents the sum of float32 ADU values
+// exactly for any realistic number of frames, such that adding and then removing a frame is lossless.
+type Integration struct {
+	Count  int       // The number of frames currently integrated
+	Pixels int       // The number of pixels in each integrated frame
+	Sum    []float64 // The per-pixel running sum of all of the integrated frames
+}
+
+/*****************************************************************************************************************/
+
+// Creates a new, empty integration for frames of the given number of pixels.
+func NewIntegration(pixels int) *Integration {
+	return &Integration{
+		Count:  0,
+		Pixels: pixels,
+		Sum:    make([]float64, pixels),
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Creates a new integration from a slice of data arrays, e.g., the data of each individual frame.
+func NewIntegrationFromData(data [][]float32) (*Integration, error) {
+	if len(data) == 0 {
+		return nil, errors.New("to integrate frames there must be at least one frame")
+	}
+
+	integration := NewIntegration(len(data[0]))
+
+	for i, d := range data {
+		if err := integration.Add(d); err != nil {
+			return nil, fmt.Errorf("issue at frame input %v: %w", i, err)
+		}
+	}
+
+	return integration, nil
+}
+
+/*****************************************************************************************************************/
+
+// Adds the data of a single frame to the running sum.
+func (n *Integration) Add(data []float32) error {
+	if len(data) != n.Pixels {
+		return errors.New("to integrate frames they must be of same length")
+	}
+
+	for i, v := range data {
+		n.Sum[i] += float64(v)
+	}
+
+	n.Count++
+
+	return nil
+}
+
+/*****************************************************************************************************************/
+
+// Removes the data of a single, previously added frame from the running sum.
+func (n *Integration) Remove(data []float32) error {
+	if len(data) != n.Pixels {
+		return errors.New("to remove a frame from an integration it must be of same length")
+	}
+
+	if n.Count == 0 {
+		return errors.New("cannot remove a frame from an empty integration")
+	}
+
+	for i, v := range data {
+		n.Sum[i] -= float64(v)
+	}
+
+	n.Count--
+
+	return nil
+}
+
+/*****************************************************************************************************************/
+
+// Computes the per-pixel mean of all of the integrated frames.
+func (n *Integration) Mean() ([]float32, error) {
+	if n.Count == 0 {
+		return nil, errors.New("cannot compute the mean of an empty integration")
+	}
+
+	m := make([]float32, n.Pixels)
+
+	for i, s := range n.Sum {
+		m[i] = float32(s / float64(n.Count))
+	}
+
+	return m, nil
+}
+
+/*****************************************************************************************************************/
+
+// Returns a deep copy of the integration, such that it can be modified without side effects.
+func (n *Integration) Clone() *Integration {
+	sum := make([]float64, len(n.Sum))
+
+	copy(sum, n.Sum)
+
+	return &Integration{
+		Count:  n.Count,
+		Pixels: n.Pixels,
+		Sum:    sum,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Seeds an integration from an existing combined mean of count frames, for master frames which were
+// constructed without an accumulated running sum.
+func newIntegrationFromMean(mean []float32, count int) *Integration {
+	integration := NewIntegration(len(mean))
+
+	for i, v := range mean {
+		integration.Sum[i] = float64(v) * float64(count)
+	}
+
+	integration.Count = count
+
+	return integration
+}
+
+/*****************************************************************************************************************/
+
+// Returns a copy of the slice of frames with the frame at the given index removed.
+func removeFrameAt(frames []fits.FITSImage, index int) []fits.FITSImage {
+	remaining := make([]fits.FITSImage, 0, len(frames)-1)
+
+	remaining = append(remaining, frames[:index]...)
+
+	return append(remaining, frames[index+1:]...)
+}
+
+/*****************************************************************************************************************/
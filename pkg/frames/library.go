@@ -0,0 +1,496 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+// MatchTolerances describes how closely the metadata of a master frame must match that of a light frame.
+type MatchTolerances struct {
+	Gain        float32       // The maximum absolute difference in gain setting (GAIN)
+	Offset      float32       // The maximum absolute difference in offset setting (OFFSET)
+	Temperature float32       // The maximum absolute difference in sensor temperature (CCD-TEMP), in °C
+	Exposure    float32       // The maximum absolute difference in dark exposure time (EXPOSURE), in seconds
+	MaxAge      time.Duration // The maximum time between the master and light frame (DATE-OBS), zero is unlimited
+	ScaleDarks  bool          // Whether darks of any exposure time may match, e.g., when the dark will be scaled
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default match tolerances, i.e., identical gain, offset and dark exposure time, with a sensor
+// temperature within 2°C, irrespective of the age of the master frame.
+func DefaultMatchTolerances() MatchTolerances {
+	return MatchTolerances{
+		Gain:        0,
+		Offset:      0,
+		Temperature: 2,
+		Exposure:    0,
+		MaxAge:      0,
+		ScaleDarks:  false,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// CalibrationLibrary indexes master bias, dark and flat frames by their FITS header keywords (GAIN, OFFSET,
+// CCD-TEMP, XBINNING, YBINNING, FILTER, EXPOSURE, INSTRUME and DATE-OBS), such that the best matching masters
+// can be selected for any given light frame.
+type CalibrationLibrary struct {
+	Biases     []*MasterFrame     // The master bias frames in the library
+	Darks      []*MasterDarkFrame // The master dark frames in the library
+	Flats      []*MasterFlatFrame // The master flat frames in the library
+	DarkFlats  []*MasterDarkFrame // The master dark-flat frames in the library, e.g., for calibrating flat frames
+	Tolerances MatchTolerances    // The tolerances within which masters are matched to light frames
+}
+
+/*****************************************************************************************************************/
+
+// CalibrationMatch is the selection of master frames for a light frame, with the reason each was chosen.
+type CalibrationMatch struct {
+	Bias       *MasterFrame     // The best matching master bias frame, if any
+	Dark       *MasterDarkFrame // The best matching master dark frame, if any
+	Flat       *MasterFlatFrame // The best matching master flat frame, if any
+	BiasReason string           // The explanation of why the master bias frame was chosen
+	DarkReason string           // The explanation of why the master dark frame was chosen
+	FlatReason string           // The explanation of why the master flat frame was chosen
+}
+
+/*****************************************************************************************************************/
+
+// The metadata criteria considered when matching a type of master frame to a light frame:
+type matchCriteria struct {
+	temperature bool // Whether the sensor temperature must match, e.g., for bias and dark frames
+	exposure    bool // Whether the exposure time must match, e.g., for dark frames
+	filter      bool // Whether the filter must match, e.g., for flat frames
+	scalable    bool // Whether the exposure time may differ when darks are scaled, e.g., not for dark-flats
+}
+
+/*****************************************************************************************************************/
+
+// Creates a new, empty calibration library with the given match tolerances.
+func NewCalibrationLibrary(tolerances MatchTolerances) *CalibrationLibrary {
+	return &CalibrationLibrary{
+		Biases:     make([]*MasterFrame, 0),
+		Darks:      make([]*MasterDarkFrame, 0),
+		Flats:      make([]*MasterFlatFrame, 0),
+		DarkFlats:  make([]*MasterDarkFrame, 0),
+		Tolerances: tolerances,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Adds a master bias frame to the calibration library.
+func (l *CalibrationLibrary) AddMasterBias(m *MasterFrame) {
+	l.Biases = append(l.Biases, m)
+}
+
+/*****************************************************************************************************************/
+
+// Adds a master dark frame to the calibration library.
+func (l *CalibrationLibrary) AddMasterDark(m *MasterDarkFrame) {
+	l.Darks = append(l.Darks, m)
+}
+
+/*****************************************************************************************************************/
+
+// Adds a master flat frame to the calibration library.
+func (l *CalibrationLibrary) AddMasterFlat(m *MasterFlatFrame) {
+	l.Flats = append(l.Flats, m)
+}
+
+/*****************************************************************************************************************/
+
+// Adds a master dark-flat frame, i.e., a master dark taken at the exposure time of flat frames, to the library.
+func (l *CalibrationLibrary) AddMasterDarkFlat(m *MasterDarkFrame) {
+	l.DarkFlats = append(l.DarkFlats, m)
+}
+
+/*****************************************************************************************************************/
+
+/*
+Match()
+
+Selects the best matching master bias, dark and flat frames for the given light frame.
+
+Candidates must share the instrument, binning, gain and offset of the light frame (and
+the filter for flats), with the sensor temperature (for biases and darks) and exposure
+time (for darks) within the configured tolerances. Of the remaining candidates, the one
+with the closest temperature and exposure time is chosen, followed by the one taken
+closest in time to the light frame. Keywords missing from either frame are not compared.
+
+Returns the (possibly partial) match, and an error describing why each candidate was
+rejected for any type of master frame which could not be matched.
+*/
+func (l *CalibrationLibrary) Match(light *fits.FITSImage) (*CalibrationMatch, error) {
+	target := NewFrameMetadata(light)
+
+	match := &CalibrationMatch{}
+
+	errs := make([]error, 0)
+
+	biases := make([]FrameMetadata, len(l.Biases))
+
+	for i, m := range l.Biases {
+		biases[i] = NewFrameMetadata(m.Combined)
+	}
+
+	if i, reason, err := l.selectMaster("bias", target, biases, matchCriteria{temperature: true}); err != nil {
+		errs = append(errs, err)
+	} else {
+		match.Bias, match.BiasReason = l.Biases[i], reason
+	}
+
+	darks := make([]FrameMetadata, len(l.Darks))
+
+	for i, m := range l.Darks {
+		darks[i] = NewFrameMetadata(m.Combined)
+	}
+
+	if i, reason, err := l.selectMaster("dark", target, darks, matchCriteria{temperature: true, exposure: true, scalable: true}); err != nil {
+		errs = append(errs, err)
+	} else {
+		match.Dark, match.DarkReason = l.Darks[i], reason
+	}
+
+	flats := make([]FrameMetadata, len(l.Flats))
+
+	for i, m := range l.Flats {
+		flats[i] = NewFrameMetadata(m.Combined)
+	}
+
+	if i, reason, err := l.selectMaster("flat", target, flats, matchCriteria{filter: true}); err != nil {
+		errs = append(errs, err)
+	} else {
+		match.Flat, match.FlatReason = l.Flats[i], reason
+	}
+
+	return match, errors.Join(errs...)
+}
+
+/*****************************************************************************************************************/
+
+/*
+Calibrate()
+
+Calibrates the light frame with the best matching master bias, dark and flat frames in the library.
+
+If the matched master dark differs in exposure time from the light frame (i.e., when darks may be
+scaled), the master dark is scaled by the ratio of the exposure times, unless the options already
+scale it (e.g., by optimisation).
+*/
+func (l *CalibrationLibrary) Calibrate(light *fits.FITSImage, options CalibrationOptions) (*CalibratedLightFrame, *CalibrationMatch, error) {
+	match, err := l.Match(light)
+
+	if err != nil {
+		return nil, match, err
+	}
+
+	xs, ys, ok := light.GetDimensions()
+
+	if !ok {
+		return nil, match, errors.New("the light frame dimensions are required to calibrate the light frame")
+	}
+
+	exposure := float32(light.GetExposureTime())
+
+	if match.Dark != nil && options.DarkScaling.Mode == DarkScalingNone {
+		darkExposure := float32(match.Dark.Combined.GetExposureTime())
+
+		known := exposure > 0 && darkExposure > 0
+
+		if l.Tolerances.ScaleDarks && !known {
+			return nil, match, errors.New("the exposure times of the light frame and master dark are required to scale the master dark")
+		}
+
+		if known && darkExposure != exposure {
+			options.DarkScaling.Mode = DarkScalingExposure
+		}
+	}
+
+	calibrated, err := NewCalibratedLightFrameWithOptions(
+		light,
+		match.Bias,
+		match.Dark,
+		match.Flat,
+		2,
+		int32(xs),
+		int32(ys),
+		light.ADU,
+		exposure,
+		options,
+	)
+
+	return calibrated, match, err
+}
+
+/*****************************************************************************************************************/
+
+/*
+MatchFlatCalibration()
+
+Selects the calibration frame to subtract from the given flat frame, preferring a master
+dark-flat with the same exposure time, gain, offset and sensor temperature (within the
+configured tolerances), and otherwise falling back to the best matching master bias.
+
+Returns either the master dark-flat or the master bias (the other being nil), with the
+reason it was chosen, or an error describing why each candidate was rejected.
+*/
+func (l *CalibrationLibrary) MatchFlatCalibration(flat *fits.FITSImage) (*MasterFrame, *MasterDarkFrame, string, error) {
+	return l.matchFlatCalibration(NewFrameMetadata(flat))
+}
+
+/*****************************************************************************************************************/
+
+/*
+NewMasterFlatFrame()
+
+Creates a new master flat frame from a slice of flat frames, subtracting the master dark-flat
+(or otherwise the master bias) in the library which best matches the metadata of the flat
+frames, as selected by MatchFlatCalibration().
+*/
+func (l *CalibrationLibrary) NewMasterFlatFrame(frames []fits.FITSImage, naxis int32, naxis1 int32, naxis2 int32, adu int32, exposureTime float32) (*MasterFlatFrame, string, error) {
+	if len(frames) == 0 {
+		return nil, "", errors.New("at least one flat frame is required to create a master flat frame")
+	}
+
+	target := NewFrameMetadata(&frames[0])
+
+	if exposureTime > 0 {
+		target.Exposure = &exposureTime
+	}
+
+	masterBias, masterDarkFlat, reason, err := l.matchFlatCalibration(target)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	m, err := NewMasterFlatFrameWithDarkFlat(frames, masterBias, masterDarkFlat, naxis, naxis1, naxis2, adu, exposureTime)
+
+	return m, reason, err
+}
+
+/*****************************************************************************************************************/
+
+// Selects the master dark-flat, or otherwise the master bias, which best matches the target flat metadata.
+func (l *CalibrationLibrary) matchFlatCalibration(target FrameMetadata) (*MasterFrame, *MasterDarkFrame, string, error) {
+	errs := make([]error, 0)
+
+	if len(l.DarkFlats) > 0 {
+		darkFlats := make([]FrameMetadata, len(l.DarkFlats))
+
+		for i, m := range l.DarkFlats {
+			darkFlats[i] = NewFrameMetadata(m.Combined)
+		}
+
+		i, reason, err := l.selectMaster("dark-flat", target, darkFlats, matchCriteria{temperature: true, exposure: true})
+
+		if err == nil {
+			return nil, l.DarkFlats[i], reason, nil
+		}
+
+		errs = append(errs, err)
+	}
+
+	biases := make([]FrameMetadata, len(l.Biases))
+
+	for i, m := range l.Biases {
+		biases[i] = NewFrameMetadata(m.Combined)
+	}
+
+	i, reason, err := l.selectMaster("bias", target, biases, matchCriteria{temperature: true})
+
+	if err != nil {
+		return nil, nil, "", errors.Join(append(errs, err)...)
+	}
+
+	// Explain why none of the master dark-flats were chosen in favour of the master bias:
+	if len(errs) > 0 {
+		reason = fmt.Sprintf("%s (%s)", reason, errs[0])
+	}
+
+	return l.Biases[i], nil, reason, nil
+}
+
+/*****************************************************************************************************************/
+
+// Selects the index of the best matching candidate for the target metadata, and explains why it was chosen.
+func (l *CalibrationLibrary) selectMaster(kind string, target FrameMetadata, candidates []FrameMetadata, criteria matchCriteria) (int, string, error) {
+	if len(candidates) == 0 {
+		return -1, "", fmt.Errorf("no master %s frames in the calibration library", kind)
+	}
+
+	best, bestCost, bestAge := -1, math.Inf(1), time.Duration(math.MaxInt64)
+
+	bestReasons := make([]string, 0)
+
+	rejections := make([]string, 0)
+
+	for i, c := range candidates {
+		reasons, rejection, cost := l.compare(target, c, criteria)
+
+		if rejection != "" {
+			rejections = append(rejections, fmt.Sprintf("%s %d: %s", kind, i, rejection))
+			continue
+		}
+
+		age := time.Duration(math.MaxInt64)
+
+		if !target.DateObs.IsZero() && !c.DateObs.IsZero() {
+			age = target.DateObs.Sub(c.DateObs)
+
+			if age < 0 {
+				age = -age
+			}
+
+			if l.Tolerances.MaxAge > 0 && age > l.Tolerances.MaxAge {
+				rejections = append(rejections, fmt.Sprintf("%s %d: DATE-OBS differs by %s, exceeding %s", kind, i, age, l.Tolerances.MaxAge))
+				continue
+			}
+
+			reasons = append(reasons, fmt.Sprintf("DATE-OBS differs by %s", age))
+		}
+
+		if cost < bestCost || (cost == bestCost && age < bestAge) {
+			best, bestCost, bestAge, bestReasons = i, cost, age, reasons
+		}
+	}
+
+	if best < 0 {
+		return -1, "", fmt.Errorf("no master %s frame matches the light frame: %s", kind, strings.Join(rejections, "; "))
+	}
+
+	reason := fmt.Sprintf("master %s %d of %d chosen: %s", kind, best, len(candidates), strings.Join(bestReasons, ", "))
+
+	return best, reason, nil
+}
+
+/*****************************************************************************************************************/
+
+// Compares the candidate metadata against the target metadata, returning the reasons the candidate matches, or
+// the reason it was rejected, and the cost of the match (lower is better) within the tolerances.
+func (l *CalibrationLibrary) compare(target FrameMetadata, c FrameMetadata, criteria matchCriteria) ([]string, string, float64) {
+	reasons := make([]string, 0)
+
+	cost := 0.0
+
+	matchString := func(key string, want string, got string) string {
+		if want == "" || got == "" {
+			reasons = append(reasons, key+" not compared")
+			return ""
+		}
+
+		if !strings.EqualFold(want, got) {
+			return fmt.Sprintf("%s %q does not match %q", key, got, want)
+		}
+
+		reasons = append(reasons, fmt.Sprintf("%s %q matches", key, got))
+
+		return ""
+	}
+
+	matchNumber := func(key string, want *float32, got *float32, tolerance float32, unit string) string {
+		if want == nil || got == nil {
+			reasons = append(reasons, key+" not compared")
+			return ""
+		}
+
+		difference := math.Abs(float64(*want - *got))
+
+		if difference > float64(tolerance) {
+			return fmt.Sprintf("%s %g%s differs from %g%s by more than %g%s", key, *got, unit, *want, unit, tolerance, unit)
+		}
+
+		if difference == 0 {
+			reasons = append(reasons, fmt.Sprintf("%s %g%s matches", key, *got, unit))
+		} else {
+			reasons = append(reasons, fmt.Sprintf("%s %g%s within %g%s of %g%s", key, *got, unit, tolerance, unit, *want, unit))
+		}
+
+		if tolerance > 0 {
+			cost += difference / float64(tolerance)
+		}
+
+		return ""
+	}
+
+	matchBinning := func(key string, want *int32, got *int32) string {
+		if want == nil || got == nil {
+			reasons = append(reasons, key+" not compared")
+			return ""
+		}
+
+		if *want != *got {
+			return fmt.Sprintf("%s %d does not match %d", key, *got, *want)
+		}
+
+		reasons = append(reasons, fmt.Sprintf("%s %d matches", key, *got))
+
+		return ""
+	}
+
+	checks := []func() string{
+		func() string { return matchString("INSTRUME", target.Instrument, c.Instrument) },
+		func() string { return matchBinning("XBINNING", target.XBinning, c.XBinning) },
+		func() string { return matchBinning("YBINNING", target.YBinning, c.YBinning) },
+		func() string { return matchNumber("GAIN", target.Gain, c.Gain, l.Tolerances.Gain, "") },
+		func() string { return matchNumber("OFFSET", target.Offset, c.Offset, l.Tolerances.Offset, "") },
+	}
+
+	if criteria.filter {
+		checks = append(checks, func() string { return matchString("FILTER", target.Filter, c.Filter) })
+	}
+
+	if criteria.temperature {
+		checks = append(checks, func() string {
+			return matchNumber("CCD-TEMP", target.Temperature, c.Temperature, l.Tolerances.Temperature, "C")
+		})
+	}
+
+	if criteria.exposure {
+		checks = append(checks, func() string {
+			if !criteria.scalable || !l.Tolerances.ScaleDarks {
+				return matchNumber("EXPOSURE", target.Exposure, c.Exposure, l.Tolerances.Exposure, "s")
+			}
+
+			if target.Exposure == nil || c.Exposure == nil || *target.Exposure <= 0 || *c.Exposure <= 0 {
+				return matchNumber("EXPOSURE", target.Exposure, c.Exposure, l.Tolerances.Exposure, "s")
+			}
+
+			// When the dark will be scaled any exposure time matches, although the closest ratio is preferred:
+			reasons = append(reasons, fmt.Sprintf("EXPOSURE %gs to be scaled to %gs", *c.Exposure, *target.Exposure))
+
+			cost += math.Abs(math.Log(float64(*target.Exposure / *c.Exposure)))
+
+			return ""
+		})
+	}
+
+	for _, check := range checks {
+		if rejection := check(); rejection != "" {
+			return nil, rejection, math.Inf(1)
+		}
+	}
+
+	return reasons, "", cost
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,344 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+func getLibraryTestFrame(value uint32, gain int32, temperature float32, exposure float32, filter string, date string) *fits.FITSImage {
+	data := [][]uint32{
+		{value, value, value, value},
+		{value, value, value, value},
+		{value, value, value, value},
+		{value, value, value, value},
+	}
+
+	f := fits.NewFITSImageFrom2DData(data, 2, 4, 4, 65535)
+
+	f.Header.Set("INSTRUME", "ZWO ASI2600MM Pro", "The name of the instrument")
+	f.Header.Set("GAIN", gain, "Sensor gain")
+	f.Header.Set("OFFSET", int32(50), "Sensor offset")
+	f.Header.Set("XBINNING", int32(1), "Horizontal binning")
+	f.Header.Set("YBINNING", int32(1), "Vertical binning")
+	f.Header.Set("CCD-TEMP", temperature, "Sensor temperature (C)")
+	f.Header.Set("EXPOSURE", exposure, "Exposure time (s)")
+	f.Header.Set("DATE-OBS", date, "Date of observation")
+
+	if filter != "" {
+		f.Header.Set("FILTER", filter, "The name of the filter")
+	}
+
+	return f
+}
+
+/*****************************************************************************************************************/
+
+func getTestCalibrationLibrary(t *testing.T) *CalibrationLibrary {
+	library := NewCalibrationLibrary(DefaultMatchTolerances())
+
+	for _, gain := range []int32{0, 100} {
+		bias := getLibraryTestFrame(100, gain, -10, 0, "", "2024-03-01T12:00:00")
+
+		masterBias, err := NewMasterBiasFrame([]fits.FITSImage{*bias}, 2, 4, 4, 65535, 0.001)
+
+		if err != nil {
+			t.Fatalf("NewMasterBiasFrame() failed: %s", err)
+		}
+
+		library.AddMasterBias(masterBias)
+	}
+
+	masterBias := library.Biases[1]
+
+	for _, d := range []struct {
+		temperature float32
+		exposure    float32
+	}{
+		{-10, 60},
+		{-10, 300},
+		{-9, 300},
+		{0, 300},
+	} {
+		dark := getLibraryTestFrame(110, 100, d.temperature, d.exposure, "", "2024-03-01T12:00:00")
+
+		masterDark, err := NewMasterDarkFrame([]fits.FITSImage{*dark}, masterBias, 2, 4, 4, 65535, d.exposure)
+
+		if err != nil {
+			t.Fatalf("NewMasterDarkFrame() failed: %s", err)
+		}
+
+		library.AddMasterDark(masterDark)
+	}
+
+	for _, f := range []struct {
+		filter string
+		date   string
+	}{
+		{"Ha", "2024-03-10T20:00:00"},
+		{"OIII", "2024-03-14T20:00:00"},
+		{"Ha", "2024-03-14T20:00:00"},
+	} {
+		flat := getLibraryTestFrame(30000, 100, -10, 2, f.filter, f.date)
+
+		masterFlat, err := NewMasterFlatFrame([]fits.FITSImage{*flat}, masterBias, 2, 4, 4, 65535, 2)
+
+		if err != nil {
+			t.Fatalf("NewMasterFlatFrame() failed: %s", err)
+		}
+
+		library.AddMasterFlat(masterFlat)
+	}
+
+	return library
+}
+
+/*****************************************************************************************************************/
+
+func TestMasterFramesInheritMetadata(t *testing.T) {
+	library := getTestCalibrationLibrary(t)
+
+	m := NewFrameMetadata(library.Darks[2].Combined)
+
+	if m.Instrument != "ZWO ASI2600MM Pro" {
+		t.Errorf("NewFrameMetadata() failed: expected instrument of ZWO ASI2600MM Pro, got %s", m.Instrument)
+	}
+
+	if m.Gain == nil || *m.Gain != 100 {
+		t.Errorf("NewFrameMetadata() failed: expected gain of 100")
+	}
+
+	if m.Temperature == nil || *m.Temperature != -9 {
+		t.Errorf("NewFrameMetadata() failed: expected temperature of -9")
+	}
+
+	if m.Exposure == nil || *m.Exposure != 300 {
+		t.Errorf("NewFrameMetadata() failed: expected exposure of 300")
+	}
+
+	if m.DateObs.IsZero() {
+		t.Errorf("NewFrameMetadata() failed: expected the date of observation to be inherited")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCalibrationLibraryMatch(t *testing.T) {
+	library := getTestCalibrationLibrary(t)
+
+	light := getLibraryTestFrame(1000, 100, -9.5, 300, "Ha", "2024-03-15T01:00:00")
+
+	match, err := library.Match(light)
+
+	if err != nil {
+		t.Fatalf("Match() failed: %s", err)
+	}
+
+	if match.Bias != library.Biases[1] {
+		t.Errorf("Match() failed: expected the gain 100 master bias, got %s", match.BiasReason)
+	}
+
+	// Both the -10°C and -9°C 300s darks are within tolerance, and are equidistant in temperature:
+	if match.Dark != library.Darks[1] && match.Dark != library.Darks[2] {
+		t.Errorf("Match() failed: expected a 300s master dark, got %s", match.DarkReason)
+	}
+
+	// The most recent Ha flat is preferred:
+	if match.Flat != library.Flats[2] {
+		t.Errorf("Match() failed: expected the most recent Ha master flat, got %s", match.FlatReason)
+	}
+
+	if !strings.Contains(match.FlatReason, `FILTER "Ha" matches`) {
+		t.Errorf("Match() failed: expected the flat reason to explain the filter match, got %s", match.FlatReason)
+	}
+
+	if !strings.Contains(match.DarkReason, "EXPOSURE 300s matches") {
+		t.Errorf("Match() failed: expected the dark reason to explain the exposure match, got %s", match.DarkReason)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCalibrationLibraryMatchNoCandidates(t *testing.T) {
+	library := getTestCalibrationLibrary(t)
+
+	light := getLibraryTestFrame(1000, 100, -10, 120, "SII", "2024-03-15T01:00:00")
+
+	match, err := library.Match(light)
+
+	if err == nil {
+		t.Fatalf("Match() failed: expected an error for a light frame without a matching dark or flat")
+	}
+
+	if match.Bias == nil {
+		t.Errorf("Match() failed: expected the master bias to still be matched")
+	}
+
+	if !strings.Contains(err.Error(), "no master dark frame matches") || !strings.Contains(err.Error(), "no master flat frame matches") {
+		t.Errorf("Match() failed: expected the error to explain the rejections, got %s", err)
+	}
+
+	// Allowing the darks to be scaled, the closest exposure time at a matching temperature is chosen:
+	library.Tolerances.ScaleDarks = true
+
+	match, _ = library.Match(light)
+
+	if match.Dark != library.Darks[0] {
+		t.Errorf("Match() failed: expected the 60s master dark, got %s", match.DarkReason)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCalibrationLibraryCalibrate(t *testing.T) {
+	library := getTestCalibrationLibrary(t)
+
+	light := getLibraryTestFrame(1000, 100, -10, 60, "Ha", "2024-03-15T01:00:00")
+
+	calibrated, match, err := library.Calibrate(light, CalibrationOptions{})
+
+	if err != nil {
+		t.Fatalf("Calibrate() failed: %s", err)
+	}
+
+	if match.Dark != library.Darks[0] {
+		t.Errorf("Calibrate() failed: expected the 60s master dark, got %s", match.DarkReason)
+	}
+
+	// 1000 - 100 (bias) - 10 (dark), with a uniform flat:
+	if calibrated.Combined.Data[0] != 890 {
+		t.Errorf("Calibrate() failed: expected data[0] of 890, got %f", calibrated.Combined.Data[0])
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCalibrationLibraryCalibrateWithoutAxisDimensions(t *testing.T) {
+	library := getTestCalibrationLibrary(t)
+
+	light := getLibraryTestFrame(1000, 100, -10, 60, "Ha", "2024-03-15T01:00:00")
+
+	// Frames created by fits.NewFITSImage() record their dimensions only in the NAXIS1 and NAXIS2 header values:
+	light.Naxisn = nil
+
+	calibrated, _, err := library.Calibrate(light, CalibrationOptions{})
+
+	if err != nil {
+		t.Fatalf("Calibrate() failed: %s", err)
+	}
+
+	if calibrated.Combined.Data[0] != 890 {
+		t.Errorf("Calibrate() failed: expected data[0] of 890, got %f", calibrated.Combined.Data[0])
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCalibrationLibraryNewMasterFlatFrame(t *testing.T) {
+	library := getTestCalibrationLibrary(t)
+
+	flat := getLibraryTestFrame(30040, 100, -10, 2, "Ha", "2024-03-15T01:00:00")
+
+	// Without any master dark-flats in the library, the master bias is subtracted:
+	masterFlat, reason, err := library.NewMasterFlatFrame([]fits.FITSImage{*flat}, 2, 4, 4, 65535, 2)
+
+	if err != nil {
+		t.Fatalf("NewMasterFlatFrame() failed: %s", err)
+	}
+
+	if masterFlat.MasterDarkFlat != nil || masterFlat.MasterBias != library.Biases[1] {
+		t.Errorf("NewMasterFlatFrame() failed: expected the gain 100 master bias, got %s", reason)
+	}
+
+	if masterFlat.Combined.Data[0] != 29940 {
+		t.Errorf("NewMasterFlatFrame() failed: expected data[0] of 29940, got %f", masterFlat.Combined.Data[0])
+	}
+
+	for _, exposure := range []float32{2, 5} {
+		darkFlat := getLibraryTestFrame(140, 100, -10, exposure, "", "2024-03-15T01:00:00")
+
+		masterDarkFlat, err := NewMasterDarkFrame([]fits.FITSImage{*darkFlat}, library.Biases[1], 2, 4, 4, 65535, exposure)
+
+		if err != nil {
+			t.Fatalf("NewMasterDarkFrame() failed: %s", err)
+		}
+
+		library.AddMasterDarkFlat(masterDarkFlat)
+	}
+
+	// With master dark-flats in the library, the one matching the exposure time of the flats is subtracted:
+	masterFlat, reason, err = library.NewMasterFlatFrame([]fits.FITSImage{*flat}, 2, 4, 4, 65535, 2)
+
+	if err != nil {
+		t.Fatalf("NewMasterFlatFrame() failed: %s", err)
+	}
+
+	if masterFlat.MasterDarkFlat != library.DarkFlats[0] {
+		t.Errorf("NewMasterFlatFrame() failed: expected the 2s master dark-flat, got %s", reason)
+	}
+
+	if masterFlat.Combined.Data[0] != 29900 {
+		t.Errorf("NewMasterFlatFrame() failed: expected data[0] of 29900, got %f", masterFlat.Combined.Data[0])
+	}
+
+	// Even when darks may be scaled, dark-flats must match the exposure time of the flats:
+	library.Tolerances.ScaleDarks = true
+
+	flat = getLibraryTestFrame(30040, 100, -10, 3, "Ha", "2024-03-15T01:00:00")
+
+	masterFlat, reason, err = library.NewMasterFlatFrame([]fits.FITSImage{*flat}, 2, 4, 4, 65535, 3)
+
+	if err != nil {
+		t.Fatalf("NewMasterFlatFrame() failed: %s", err)
+	}
+
+	if masterFlat.MasterDarkFlat != nil || !strings.Contains(reason, "no master dark-flat frame matches") {
+		t.Errorf("NewMasterFlatFrame() failed: expected to fall back to the master bias, got %s", reason)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCalibrationLibraryCalibrateScalesDarks(t *testing.T) {
+	library := getTestCalibrationLibrary(t)
+
+	library.Darks = library.Darks[:1]
+
+	library.Tolerances.ScaleDarks = true
+
+	light := getLibraryTestFrame(1000, 100, -10, 300, "Ha", "2024-03-15T01:00:00")
+
+	calibrated, match, err := library.Calibrate(light, CalibrationOptions{})
+
+	if err != nil {
+		t.Fatalf("Calibrate() failed: %s", err)
+	}
+
+	if match.Dark != library.Darks[0] {
+		t.Errorf("Calibrate() failed: expected the 60s master dark, got %s", match.DarkReason)
+	}
+
+	// The 60s master dark is scaled to the 300s light frame, i.e., 1000 - 100 (bias) - 5 × 10 (dark):
+	if calibrated.DarkScale != 5 {
+		t.Errorf("Calibrate() failed: expected dark scale of 5, got %f", calibrated.DarkScale)
+	}
+
+	if calibrated.Combined.Data[0] != 850 {
+		t.Errorf("Calibrate() failed: expected data[0] of 850, got %f", calibrated.Combined.Data[0])
+	}
+}
+
+/*****************************************************************************************************************/
@@ -1,6 +1,9 @@
 package frames
 
 import (
+	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/observerly/iris/pkg/fits"
@@ -8,17 +11,27 @@ import (
 )
 
 type CalibratedLightFrame struct {
-	Type             string           // The type of master frame (e.g., bias, dark, flat)
-	Count            int              // The number of frames used to create the master frame
-	Pixels           int32            // The number of pixels in the master frame
-	Frames           []fits.FITSImage // The individual frames used to create the master frame
-	Combined         *fits.FITSImage  // The calibrated master light frame
-	MaterBias        *MasterFrame     // The master bias frame used to create the master flat frame
-	MasterFlat       *MasterFlatFrame // The master flat frame used to create the master light frame
-	MasterDark       *MasterDarkFrame // The master dark frame used to create the master light frame
+	Type             string                // The type of master frame (e.g., bias, dark, flat)
+	Count            int                   // The number of frames used to create the master frame
+	Pixels           int32                 // The number of pixels in the master frame
+	Frames           []fits.FITSImage      // The individual frames used to create the master frame
+	Combined         *fits.FITSImage       // The calibrated master light frame
+	MaterBias        *MasterFrame          // The master bias frame used to create the master flat frame
+	MasterFlat       *MasterFlatFrame      // The master flat frame used to create the master light frame
+	MasterDark       *MasterDarkFrame      // The master dark frame used to create the master light frame
+	DarkScale        float32               // The factor by which the master dark frame was scaled before subtraction
+	Warnings         []*CompatibilityError // The incompatibilities between the light and master frames reported as warnings
 	CreatedTimestamp int64
 }
 
+// CalibrationOptions describes the optional steps applied when calibrating a light frame.
+type CalibrationOptions struct {
+	DarkScaling       DarkScaling           // How the master dark frame is scaled to match the light frame
+	FlatNormalisation FlatNormalisationMode // How the master flat frame is normalised before division
+	ColourFilterArray string                // The CFA pattern (e.g., "RGGB"), defaults to the BAYERPAT keyword
+	Compatibility     *CompatibilityPolicy  // The compatibility checks of the light and master frames, nil for warnings only
+}
+
 /*
 NewCalibratedLightFrame()
 
@@ -28,6 +41,9 @@ master dark, and master flat.
 A calibrated light frame is a light frame that has been calibrated by
 subtracting the master bias, master dark, and then multiplying by the
 averaged master flat divided by the master flat.
+
+Any incompatibility between the light frame and the master frames is
+reported as a warning, rather than an error.
 */
 func NewCalibratedLightFrame(
 	frame *fits.FITSImage,
@@ -39,37 +55,139 @@ func NewCalibratedLightFrame(
 	naxis2 int32,
 	adu int32,
 	exposureTime float32,
+) (*CalibratedLightFrame, error) {
+	return NewCalibratedLightFrameWithOptions(
+		frame,
+		masterBias,
+		masterDark,
+		masterFlat,
+		naxis,
+		naxis1,
+		naxis2,
+		adu,
+		exposureTime,
+		CalibrationOptions{},
+	)
+}
+
+/*
+NewCalibratedLightFrameWithOptions()
+
+Creates a new calibrated light frame from a light frame, master bias,
+master dark, and master flat, applying the given calibration options.
+
+The master dark frame (which is bias-subtracted) may be scaled to the
+exposure time and sensor temperature of the light frame, or by the
+factor which minimises the noise in the calibrated light frame, such
+that a single master dark can calibrate lights of any exposure time.
+
+For one-shot colour sensors, the master flat frame may be normalised by
+the average of each colour channel of the Colour Filter Array separately
+(rather than by its global average), to preserve the colour balance.
+
+Before calibrating, the light frame is checked for compatibility with the master frames
+(e.g., dimensions, binning, gain and offset) under the given policy, or otherwise under the
+permissive policy (which only reports warnings).
+*/
+func NewCalibratedLightFrameWithOptions(
+	frame *fits.FITSImage,
+	masterBias *MasterFrame,
+	masterDark *MasterDarkFrame,
+	masterFlat *MasterFlatFrame,
+	naxis int32,
+	naxis1 int32,
+	naxis2 int32,
+	adu int32,
+	exposureTime float32,
+	options CalibrationOptions,
 ) (*CalibratedLightFrame, error) {
 	pixels := naxis1 * naxis2
 
-	// Subtract the master bias from the light frame:
-	light, err := utils.SubtractFloat32Array(frame.Data, masterBias.Combined.Data)
+	policy := PermissiveCompatibilityPolicy()
+
+	if options.Compatibility != nil {
+		policy = *options.Compatibility
+	}
+
+	// Check the light frame is compatible with the master frames, before calibrating:
+	warnings, err := CheckCompatibility(frame, masterBias, masterDark, masterFlat, policy)
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Subtract the master dark from the light frame:
-	light, err = utils.SubtractFloat32Array(light, masterDark.Combined.Data)
+	// Subtract the master bias from the light frame:
+	light, err := utils.SubtractFloat32Array(frame.Data, masterBias.Combined.Data)
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Obtain the average master flat:
-	averageMasterFlat, err := utils.AverageFloat32Array(masterFlat.Combined.Data)
+	// Obtain the factor by which to scale the master dark frame:
+	darkScale, err := masterDark.GetScaleFactor(frame, exposureTime, masterBias, options.DarkScaling)
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Divide every pixel in the master flat by the average master flat:
-	light, err = utils.DivideFloat32Array(light, masterFlat.Combined.Data, averageMasterFlat)
+	dark := masterDark.Combined.Data
+
+	if darkScale != 1 {
+		dark = make([]float32, len(masterDark.Combined.Data))
+
+		for i, v := range masterDark.Combined.Data {
+			dark[i] = v * darkScale
+		}
+	}
+
+	// Subtract the (scaled) master dark from the light frame:
+	light, err = utils.SubtractFloat32Array(light, dark)
 
 	if err != nil {
 		return nil, err
 	}
 
+	cfa := ""
+
+	switch options.FlatNormalisation {
+	case FlatNormalisationGlobal:
+		// Obtain the average master flat:
+		averageMasterFlat, err := utils.AverageFloat32Array(masterFlat.Combined.Data)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Divide every pixel in the master flat by the average master flat:
+		light, err = utils.DivideFloat32Array(light, masterFlat.Combined.Data, averageMasterFlat)
+
+		if err != nil {
+			return nil, err
+		}
+	case FlatNormalisationCFA:
+		cfa = getColourFilterArray(options.ColourFilterArray, frame, masterFlat.Combined)
+
+		if cfa == "" {
+			return nil, errors.New("a CFA pattern (or BAYERPAT keyword) is required for CFA flat normalisation")
+		}
+
+		// Normalise the master flat by the average of each colour channel separately:
+		normalisedMasterFlat, err := masterFlat.GetCFANormalisedFlat(cfa)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// Divide every pixel in the light frame by the normalised master flat:
+		light, err = utils.DivideFloat32Array(light, normalisedMasterFlat, 1)
+
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown flat normalisation mode: %d", options.FlatNormalisation)
+	}
+
 	// Create a new FITSImage from the master bias data
 	f := fits.NewFITSImage(
 		naxis,
@@ -98,14 +216,38 @@ func NewCalibratedLightFrame(
 		Comment: "The exposure time (s) of the flat frame",
 	}
 
-	f.Header.Strings["SENSOR"] = struct {
-		Value   string
+	f.Header.Floats["DARKSCAL"] = struct {
+		Value   float32
 		Comment string
 	}{
-		Value:   "Monochrome",
-		Comment: "ASCOM Alpaca Sensor Type",
+		Value:   darkScale,
+		Comment: "The scale factor applied to the master dark",
 	}
 
+	// Inherit the sensor type of the light frame, assuming a monochrome sensor only if it is not recorded:
+	if !copyHeaderValue(&f.Header, frame.Header, "SENSOR") {
+		f.Header.Set("SENSOR", "Monochrome", "ASCOM Alpaca Sensor Type")
+	}
+
+	if cfa != "" {
+		f.Header.Set("SENSOR", "RGGB", "ASCOM Alpaca Sensor Type")
+
+		f.Header.Set("BAYERPAT", strings.ToUpper(cfa), "The Bayer Colour Filter Array pattern")
+
+		f.Header.Set("FLATNORM", "CFA", "The master flat was normalised per CFA channel")
+	}
+
+	// Record how the light frame was calibrated, once its data is final:
+	provenance := NewProvenance("light", []fits.FITSImage{*frame})
+
+	provenance.MasterBias = getFrameID(masterBias.Combined)
+
+	provenance.MasterDark = getFrameID(masterDark.Combined)
+
+	provenance.MasterFlat = getFrameID(masterFlat.Combined)
+
+	provenance.WriteToFITSImage(f)
+
 	// Create the new calibrated light frame:
 	return &CalibratedLightFrame{
 		Type:             "light",
@@ -116,6 +258,8 @@ func NewCalibratedLightFrame(
 		MaterBias:        masterBias,
 		MasterFlat:       masterFlat,
 		MasterDark:       masterDark,
+		DarkScale:        darkScale,
+		Warnings:         warnings,
 		CreatedTimestamp: time.Now().Unix(),
 	}, nil
 }
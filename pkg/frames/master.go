@@ -1,8 +1,9 @@
 package frames
 
 import (
+	"fmt"
+
 	"github.com/observerly/iris/pkg/fits"
-	"github.com/observerly/iris/pkg/utils"
 )
 
 type MasterFrame struct {
@@ -11,26 +12,88 @@ type MasterFrame struct {
 	Pixels           int32            // The number of pixels in the master frame
 	Frames           []fits.FITSImage // The individual frames used to create the master frame
 	Combined         *fits.FITSImage  // The combined master frame
+	Integration      *Integration     // The running sum of the individual frames used to create the master frame
 	CreatedTimestamp int64
 }
 
+/*
+ApplyFrame()
+
+Integrates a further frame into the master frame, returning a new master frame.
+
+The combined master frame is recomputed from the count-aware running sum of all of
+the integrated frames, such that applying frames one at a time yields exactly the
+same master frame as creating it from all of the frames at once. The receiver is
+left unmodified.
+*/
 func (m *MasterFrame) ApplyFrame(frame *fits.FITSImage) (*MasterFrame, error) {
-	// Take the current combined master frame and apply the new frame to it:
-	combined, err := utils.MeanFloat32Arrays([][]float32{m.Combined.Data, frame.Data})
+	integration := m.getIntegration()
+
+	// Add the new frame to the running sum of all of the integrated frames:
+	if err := integration.Add(frame.Data); err != nil {
+		return nil, err
+	}
+
+	return m.withIntegration(integration, append(append([]fits.FITSImage{}, m.Frames...), *frame))
+}
+
+/*
+RemoveFrame()
+
+Removes a previously integrated frame, e.g., a bad frame, from the master frame by its
+index in Frames, returning a new master frame. The receiver is left unmodified.
+*/
+func (m *MasterFrame) RemoveFrame(index int) (*MasterFrame, error) {
+	if index < 0 || index >= len(m.Frames) {
+		return nil, fmt.Errorf("frame index %d out of range of %d frames", index, len(m.Frames))
+	}
+
+	if m.Count <= 1 {
+		return nil, fmt.Errorf("cannot remove the only frame of a master %s frame", m.Type)
+	}
+
+	integration := m.getIntegration()
+
+	// Remove the frame from the running sum of all of the integrated frames:
+	if err := integration.Remove(m.Frames[index].Data); err != nil {
+		return nil, err
+	}
+
+	return m.withIntegration(integration, removeFrameAt(m.Frames, index))
+}
+
+// Returns a copy of the running sum of the master frame, seeding it from the combined data if needed.
+func (m *MasterFrame) getIntegration() *Integration {
+	if m.Integration != nil {
+		return m.Integration.Clone()
+	}
+
+	return newIntegrationFromMean(m.Combined.Data, m.Count)
+}
+
+// Returns a new master frame, with the combined data recomputed from the given running sum.
+func (m *MasterFrame) withIntegration(integration *Integration, frames []fits.FITSImage) (*MasterFrame, error) {
+	combined, err := integration.Mean()
 
 	if err != nil {
 		return nil, err
 	}
 
-	// Create a new FITSImage from the master data
-	m.Combined.Data = combined
+	// Create a new FITSImage from the master data, leaving the current master untouched:
+	f := m.Combined.Copy()
+
+	f.Data = combined
+
+	// Record the provenance of the master frame, now combined from the given frames:
+	NewProvenance(m.Type, frames).WriteToFITSImage(f)
 
 	return &MasterFrame{
 		Type:             m.Type,
-		Count:            m.Count + 1,
+		Count:            integration.Count,
 		Pixels:           m.Pixels,
-		Frames:           append(m.Frames, *frame),
-		Combined:         m.Combined,
+		Frames:           frames,
+		Combined:         f,
+		Integration:      integration,
 		CreatedTimestamp: m.CreatedTimestamp,
 	}, nil
 }
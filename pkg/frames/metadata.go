@@ -0,0 +1,85 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"strings"
+	"time"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+// FrameMetadata describes the acquisition of a frame, as recorded by its FITS header keywords. Numeric values
+// which are not recorded in the header are nil, and strings which are not recorded are empty.
+type FrameMetadata struct {
+	Instrument        string    // The name of the instrument (INSTRUME)
+	Filter            string    // The name of the filter (FILTER)
+	ReadoutMode       string    // The sensor readout mode (READOUTM)
+	ColourFilterArray string    // The Bayer Colour Filter Array pattern (BAYERPAT), empty for monochrome
+	Gain              *float32  // The sensor gain setting (GAIN)
+	Offset            *float32  // The sensor offset setting (OFFSET)
+	Temperature       *float32  // The sensor temperature in °C (CCD-TEMP)
+	XBinning          *int32    // The horizontal binning factor (XBINNING)
+	YBinning          *int32    // The vertical binning factor (YBINNING)
+	Exposure          *float32  // The exposure time in seconds (EXPOSURE)
+	DateObs           time.Time // The date and time of the observation (DATE-OBS), zero if not known
+}
+
+/*****************************************************************************************************************/
+
+// Creates the frame metadata from the FITS header keywords (and the Exposure field) of the given frame.
+func NewFrameMetadata(f *fits.FITSImage) FrameMetadata {
+	m := FrameMetadata{
+		Instrument:        strings.TrimSpace(f.Header.Strings["INSTRUME"].Value),
+		Filter:            strings.TrimSpace(f.Header.Strings["FILTER"].Value),
+		ReadoutMode:       strings.TrimSpace(f.Header.Strings["READOUTM"].Value),
+		ColourFilterArray: strings.TrimSpace(f.Header.Strings["BAYERPAT"].Value),
+	}
+
+	if v, ok := f.Header.GetFloat("GAIN"); ok {
+		value := float32(v)
+		m.Gain = &value
+	}
+
+	if v, ok := f.Header.GetFloat("OFFSET"); ok {
+		value := float32(v)
+		m.Offset = &value
+	}
+
+	if v, ok := f.Header.GetFloat("CCD-TEMP"); ok {
+		value := float32(v)
+		m.Temperature = &value
+	}
+
+	if v, ok := f.Header.GetFloat("XBINNING"); ok {
+		b := int32(v)
+		m.XBinning = &b
+	}
+
+	if v, ok := f.Header.GetFloat("YBINNING"); ok {
+		b := int32(v)
+		m.YBinning = &b
+	}
+
+	if v := float32(f.GetExposureTime()); v > 0 {
+		m.Exposure = &v
+	}
+
+	if d, err := f.Header.GetDateObs(); err == nil {
+		m.DateObs = d
+	}
+
+	return m
+}
+
+/*****************************************************************************************************************/
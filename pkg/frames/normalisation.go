@@ -0,0 +1,156 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/iris"
+)
+
+/*****************************************************************************************************************/
+
+// FlatNormalisationMode determines how the master flat is normalised before a light frame is divided by it.
+type FlatNormalisationMode int
+
+/*****************************************************************************************************************/
+
+const (
+	// The master flat is normalised by its global average, e.g., for monochrome sensors:
+	FlatNormalisationGlobal FlatNormalisationMode = iota
+	// The master flat is normalised by the average of each colour channel of the Colour Filter Array separately,
+	// e.g., for one-shot colour sensors, such that the colour balance of the light frame is preserved:
+	FlatNormalisationCFA
+)
+
+/*****************************************************************************************************************/
+
+/*
+GetCFANormalisedFlat()
+
+Normalises the master flat by the average of each colour channel of the Colour Filter Array
+(e.g., "RGGB") separately, such that each normalised channel has a mean of one.
+
+For one-shot colour sensors the red, green and blue photosites record very different flat
+levels, so normalising by a single global average would shift the colour balance of every
+calibrated light frame towards the colour of the flat field illumination.
+
+Pixels with a non-positive flat value are excluded from the channel averages, and are left
+as zero in the normalised flat.
+*/
+func (m *MasterFlatFrame) GetCFANormalisedFlat(cfa string) ([]float32, error) {
+	xs, ys, ok := m.Combined.GetDimensions()
+
+	if !ok {
+		return nil, errors.New("the master flat dimensions are required to normalise the master flat")
+	}
+
+	if len(m.Combined.Data) != xs*ys {
+		return nil, errors.New("the master flat data does not match its dimensions")
+	}
+
+	exposure := iris.RGGBExposure{ColourFilterArray: cfa}
+
+	xOffset, yOffset, err := exposure.GetBayerMatrixOffset()
+
+	if err != nil {
+		return nil, err
+	}
+
+	var sums [3]float64
+
+	var counts [3]int
+
+	for y := 0; y < ys; y++ {
+		for x := 0; x < xs; x++ {
+			v := m.Combined.Data[y*xs+x]
+
+			if v <= 0 {
+				continue
+			}
+
+			c := getCFAChannel(x, y, xOffset, yOffset)
+
+			sums[c] += float64(v)
+			counts[c]++
+		}
+	}
+
+	var averages [3]float32
+
+	for c := range averages {
+		if counts[c] == 0 {
+			return nil, fmt.Errorf("the master flat has no positive pixels in CFA channel %d", c)
+		}
+
+		averages[c] = float32(sums[c] / float64(counts[c]))
+	}
+
+	normalised := make([]float32, len(m.Combined.Data))
+
+	for y := 0; y < ys; y++ {
+		for x := 0; x < xs; x++ {
+			v := m.Combined.Data[y*xs+x]
+
+			if v <= 0 {
+				continue
+			}
+
+			normalised[y*xs+x] = v / averages[getCFAChannel(x, y, xOffset, yOffset)]
+		}
+	}
+
+	return normalised, nil
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the colour channel (0 for red, 1 for green and 2 for blue) of the pixel { x, y } of a Colour Filter
+// Array, where the red photosite of each 2x2 Bayer matrix is at the given offset.
+func getCFAChannel(x int, y int, xOffset int, yOffset int) int {
+	dx, dy := (x+xOffset)&1, (y+yOffset)&1
+
+	switch {
+	case dx == 0 && dy == 0:
+		return 0
+	case dx == 1 && dy == 1:
+		return 2
+	default:
+		return 1
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the Colour Filter Array pattern from the first non-empty value of the given pattern, or the BAYERPAT
+// keyword of the given frames' headers.
+func getColourFilterArray(cfa string, frames ...*fits.FITSImage) string {
+	if strings.TrimSpace(cfa) != "" {
+		return strings.TrimSpace(cfa)
+	}
+
+	for _, f := range frames {
+		if f == nil {
+			continue
+		}
+
+		if v, ok := f.Header.Strings["BAYERPAT"]; ok && strings.TrimSpace(v.Value) != "" {
+			return strings.TrimSpace(v.Value)
+		}
+	}
+
+	return ""
+}
+
+/*****************************************************************************************************************/
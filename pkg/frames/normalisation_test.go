@@ -0,0 +1,153 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+func getCFATestFrame(red uint32, green uint32, blue uint32) *fits.FITSImage {
+	data := make([][]uint32, 4)
+
+	for y := range data {
+		data[y] = make([]uint32, 4)
+
+		for x := range data[y] {
+			switch getCFAChannel(x, y, 0, 0) {
+			case 0:
+				data[y][x] = red
+			case 1:
+				data[y][x] = green
+			default:
+				data[y][x] = blue
+			}
+		}
+	}
+
+	return fits.NewFITSImageFrom2DData(data, 2, 4, 4, 65535)
+}
+
+/*****************************************************************************************************************/
+
+func getCFATestMasters(t *testing.T) (*MasterFrame, *MasterDarkFrame, *MasterFlatFrame) {
+	bias := getCFATestFrame(0, 0, 0)
+
+	masterBias, err := NewMasterBiasFrame([]fits.FITSImage{*bias}, 2, 4, 4, 65535, 0.001)
+
+	if err != nil {
+		t.Fatalf("NewMasterBiasFrame() failed: %s", err)
+	}
+
+	dark := getCFATestFrame(0, 0, 0)
+
+	masterDark, err := NewMasterDarkFrame([]fits.FITSImage{*dark}, masterBias, 2, 4, 4, 65535, 60)
+
+	if err != nil {
+		t.Fatalf("NewMasterDarkFrame() failed: %s", err)
+	}
+
+	flat := getCFATestFrame(2000, 1000, 500)
+
+	masterFlat, err := NewMasterFlatFrame([]fits.FITSImage{*flat}, masterBias, 2, 4, 4, 65535, 2)
+
+	if err != nil {
+		t.Fatalf("NewMasterFlatFrame() failed: %s", err)
+	}
+
+	return masterBias, masterDark, masterFlat
+}
+
+/*****************************************************************************************************************/
+
+func TestGetCFAChannel(t *testing.T) {
+	// For an RGGB pattern the red photosite is at { 0, 0 } and the blue photosite at { 1, 1 }:
+	if getCFAChannel(0, 0, 0, 0) != 0 || getCFAChannel(1, 0, 0, 0) != 1 || getCFAChannel(0, 1, 0, 0) != 1 || getCFAChannel(1, 1, 0, 0) != 2 {
+		t.Errorf("getCFAChannel() failed: expected an RGGB channel layout")
+	}
+
+	// For a BGGR pattern the red photosite is at { 1, 1 } and the blue photosite at { 0, 0 }:
+	if getCFAChannel(1, 1, 1, 1) != 0 || getCFAChannel(0, 0, 1, 1) != 2 {
+		t.Errorf("getCFAChannel() failed: expected a BGGR channel layout")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetCFANormalisedFlat(t *testing.T) {
+	_, _, masterFlat := getCFATestMasters(t)
+
+	normalised, err := masterFlat.GetCFANormalisedFlat("RGGB")
+
+	if err != nil {
+		t.Fatalf("GetCFANormalisedFlat() failed: %s", err)
+	}
+
+	for i, v := range normalised {
+		if v != 1 {
+			t.Errorf("GetCFANormalisedFlat() failed: expected normalised[%d] of 1, got %f", i, v)
+		}
+	}
+
+	if _, err := masterFlat.GetCFANormalisedFlat("XYZW"); err == nil {
+		t.Errorf("GetCFANormalisedFlat() failed: expected an error for an unknown CFA pattern")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewCalibratedLightFrameWithCFAFlatNormalisation(t *testing.T) {
+	masterBias, masterDark, masterFlat := getCFATestMasters(t)
+
+	light := getCFATestFrame(800, 400, 200)
+
+	light.Header.Set("BAYERPAT", "RGGB", "The Bayer Colour Filter Array pattern")
+
+	options := CalibrationOptions{FlatNormalisation: FlatNormalisationCFA}
+
+	calibrated, err := NewCalibratedLightFrameWithOptions(light, masterBias, masterDark, masterFlat, 2, 4, 4, 65535, 60, options)
+
+	if err != nil {
+		t.Fatalf("NewCalibratedLightFrameWithOptions() failed: %s", err)
+	}
+
+	// Each channel is flat in its own colour, so the colour balance of the light frame is preserved:
+	if calibrated.Combined.Data[0] != 800 || calibrated.Combined.Data[1] != 400 || calibrated.Combined.Data[5] != 200 {
+		t.Errorf("NewCalibratedLightFrameWithOptions() failed: expected R, G, B of 800, 400, 200, got %f, %f, %f", calibrated.Combined.Data[0], calibrated.Combined.Data[1], calibrated.Combined.Data[5])
+	}
+
+	if calibrated.Combined.Header.Strings["BAYERPAT"].Value != "RGGB" {
+		t.Errorf("NewCalibratedLightFrameWithOptions() failed: expected BAYERPAT of RGGB")
+	}
+
+	// The global normalisation equalises the channels, shifting the colour balance of the light frame:
+	calibrated, err = NewCalibratedLightFrameWithOptions(light, masterBias, masterDark, masterFlat, 2, 4, 4, 65535, 60, CalibrationOptions{})
+
+	if err != nil {
+		t.Fatalf("NewCalibratedLightFrameWithOptions() failed: %s", err)
+	}
+
+	if calibrated.Combined.Data[0] != calibrated.Combined.Data[5] {
+		t.Errorf("NewCalibratedLightFrameWithOptions() failed: expected equal R and B with global normalisation, got %f and %f", calibrated.Combined.Data[0], calibrated.Combined.Data[5])
+	}
+
+	// Without a CFA pattern or BAYERPAT keyword, the CFA normalisation cannot be applied:
+	delete(light.Header.Strings, "BAYERPAT")
+
+	if _, err := NewCalibratedLightFrameWithOptions(light, masterBias, masterDark, masterFlat, 2, 4, 4, 65535, 60, options); err == nil {
+		t.Errorf("NewCalibratedLightFrameWithOptions() failed: expected an error without a CFA pattern")
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,463 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// Region describes a rectangular region of a frame, as 0-based pixel coordinates where X2 and Y2 are exclusive.
+type Region struct {
+	X1 int // The first column of the region
+	X2 int // The column after the last column of the region
+	Y1 int // The first row of the region
+	Y2 int // The row after the last row of the region
+}
+
+/*****************************************************************************************************************/
+
+// OverscanMode determines how the bias level is estimated from the overscan (or prescan) region.
+type OverscanMode int
+
+/*****************************************************************************************************************/
+
+const (
+	// The bias level is the median of the entire overscan region, subtracted from every pixel:
+	OverscanGlobalMedian OverscanMode = iota
+	// The bias level is the median of the overscan region of each row, smoothed by a least-squares polynomial
+	// fit over the rows, such that any gradient of the bias level along the columns is removed:
+	OverscanRowFit
+)
+
+/*****************************************************************************************************************/
+
+// OverscanParams describes how the overscan correction is applied to a frame. Any region which is not given
+// explicitly is obtained from the BIASSEC, DATASEC and TRIMSEC keywords of the frame's header.
+type OverscanParams struct {
+	Mode        OverscanMode // The overscan level estimation mode
+	Order       int          // The polynomial order of the row fit, e.g., 0 for a constant and 1 for a linear fit
+	BiasSection *Region      // The overscan (or prescan) region, defaults to the BIASSEC keyword
+	DataSection *Region      // The region of the sensor exposed to light, defaults to the DATASEC keyword
+	TrimSection *Region      // The region retained after trimming, defaults to the TRIMSEC keyword or DATASEC
+}
+
+/*****************************************************************************************************************/
+
+var sectionRegex = regexp.MustCompile(`^\[\s*(\d+)\s*:\s*(\d+)\s*,\s*(\d+)\s*:\s*(\d+)\s*\]$`)
+
+/*****************************************************************************************************************/
+
+// Parses an IRAF-style section, e.g., "[1:4096,1:4096]", of 1-based inclusive column and row ranges, into a
+// region of 0-based pixel coordinates. Reversed ranges, e.g., "[4096:1,1:4096]", are normalised.
+func ParseRegion(section string) (Region, error) {
+	matches := sectionRegex.FindStringSubmatch(strings.TrimSpace(section))
+
+	if matches == nil {
+		return Region{}, fmt.Errorf("invalid section: %q", section)
+	}
+
+	v := [4]int{}
+
+	for i := range v {
+		n, err := strconv.Atoi(matches[i+1])
+
+		if err != nil {
+			return Region{}, err
+		}
+
+		if n < 1 {
+			return Region{}, fmt.Errorf("invalid section: %q, ranges are 1-based", section)
+		}
+
+		v[i] = n
+	}
+
+	return Region{
+		X1: min(v[0], v[1]) - 1,
+		X2: max(v[0], v[1]),
+		Y1: min(v[2], v[3]) - 1,
+		Y2: max(v[2], v[3]),
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+// Formats the region as an IRAF-style section, e.g., "[1:4096,1:4096]", of 1-based inclusive ranges.
+func (r Region) String() string {
+	return fmt.Sprintf("[%d:%d,%d:%d]", r.X1+1, r.X2, r.Y1+1, r.Y2)
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the width of the region, in pixels.
+func (r Region) Width() int {
+	return r.X2 - r.X1
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the height of the region, in pixels.
+func (r Region) Height() int {
+	return r.Y2 - r.Y1
+}
+
+/*****************************************************************************************************************/
+
+// Determines whether the region is non-empty and lies within a frame of the given width and height.
+func (r Region) IsWithin(xs int, ys int) bool {
+	return r.X1 >= 0 && r.Y1 >= 0 && r.X2 <= xs && r.Y2 <= ys && r.X1 < r.X2 && r.Y1 < r.Y2
+}
+
+/*****************************************************************************************************************/
+
+/*
+ApplyOverscanCorrection()
+
+Returns a copy of the frame with the bias level, as estimated from the overscan (or prescan)
+region, subtracted from every pixel, and then trimmed to the trim (or data) section.
+
+Overscan columns are read out after (or prescan columns before) the exposed pixels of each
+row, so they record the bias level of the readout of that row without any signal. This
+should be applied to every bias, dark, flat and light frame before creating the master
+frames with NewMasterBiasFrame() and calibrating with NewCalibratedLightFrame().
+
+The BIASSEC, DATASEC and TRIMSEC keywords are removed from the corrected frame, the OVERSCAN
+keyword records the mean bias level subtracted, and a HISTORY entry records the correction.
+
+@see https://iraf.net/irafdocs/ccdproc.html
+*/
+func ApplyOverscanCorrection(frame *fits.FITSImage, params OverscanParams) (*fits.FITSImage, error) {
+	xs, ys, ok := frame.GetDimensions()
+
+	if !ok {
+		return nil, errors.New("the frame dimensions are required to apply overscan correction")
+	}
+
+	if len(frame.Data) != xs*ys {
+		return nil, errors.New("the frame data does not match its dimensions")
+	}
+
+	bias, err := getRegion(frame.Header, params.BiasSection, "BIASSEC")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if bias == nil {
+		return nil, errors.New("a bias section (or BIASSEC keyword) is required to apply overscan correction")
+	}
+
+	if !bias.IsWithin(xs, ys) {
+		return nil, fmt.Errorf("the bias section %s is outside of the frame", bias)
+	}
+
+	trim, err := getRegion(frame.Header, params.TrimSection, "TRIMSEC")
+
+	if err != nil {
+		return nil, err
+	}
+
+	if trim == nil {
+		trim, err = getRegion(frame.Header, params.DataSection, "DATASEC")
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Without a trim or data section the entire frame is retained:
+	if trim == nil {
+		trim = &Region{X1: 0, X2: xs, Y1: 0, Y2: ys}
+	}
+
+	if !trim.IsWithin(xs, ys) {
+		return nil, fmt.Errorf("the trim section %s is outside of the frame", trim)
+	}
+
+	// Estimate the bias level for each row of the frame:
+	levels, err := getOverscanLevels(frame.Data, xs, ys, *bias, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	f := frame.Copy()
+
+	w, h := trim.Width(), trim.Height()
+
+	f.Data = make([]float32, w*h)
+
+	level := float64(0)
+
+	for y := trim.Y1; y < trim.Y2; y++ {
+		level += float64(levels[y])
+
+		for x := trim.X1; x < trim.X2; x++ {
+			f.Data[(y-trim.Y1)*w+(x-trim.X1)] = frame.Data[y*xs+x] - levels[y]
+		}
+	}
+
+	level /= float64(h)
+
+	f.Naxisn = []int32{int32(w), int32(h)}
+
+	f.Pixels = int32(w * h)
+
+	f.Header.Naxis1 = int32(w)
+
+	f.Header.Naxis2 = int32(h)
+
+	for _, key := range []string{"BIASSEC", "DATASEC", "TRIMSEC"} {
+		delete(f.Header.Strings, key)
+	}
+
+	f.Header.Set("OVERSCAN", float32(level), "The mean overscan bias level subtracted (ADU)")
+
+	f.Header.History = append(
+		f.Header.History,
+		fmt.Sprintf("Overscan corrected from %s, trimmed to %s", bias, trim),
+	)
+
+	return f, nil
+}
+
+/*****************************************************************************************************************/
+
+// Applies the overscan correction to each of the frames, e.g., before creating a master frame.
+func ApplyOverscanCorrectionToFrames(frames []fits.FITSImage, params OverscanParams) ([]fits.FITSImage, error) {
+	corrected := make([]fits.FITSImage, len(frames))
+
+	for i := range frames {
+		f, err := ApplyOverscanCorrection(&frames[i], params)
+
+		if err != nil {
+			return nil, fmt.Errorf("frame %d: %w", i, err)
+		}
+
+		corrected[i] = *f
+	}
+
+	return corrected, nil
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the explicit region, if given, or else parses the region from the header keyword, returning nil if
+// neither is present.
+func getRegion(h fits.FITSHeader, region *Region, key string) (*Region, error) {
+	if region != nil {
+		return region, nil
+	}
+
+	v, ok := h.Strings[key]
+
+	if !ok || strings.TrimSpace(v.Value) == "" {
+		return nil, nil
+	}
+
+	r, err := ParseRegion(v.Value)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", key, err)
+	}
+
+	return &r, nil
+}
+
+/*****************************************************************************************************************/
+
+// Estimates the bias level of each row of the frame from the overscan region.
+func getOverscanLevels(data []float32, xs int, ys int, bias Region, params OverscanParams) ([]float32, error) {
+	levels := make([]float32, ys)
+
+	switch params.Mode {
+	case OverscanGlobalMedian:
+		values := make([]float32, 0, bias.Width()*bias.Height())
+
+		for y := bias.Y1; y < bias.Y2; y++ {
+			values = append(values, data[y*xs+bias.X1:y*xs+bias.X2]...)
+		}
+
+		median := qsort.QSelectMedianFloat32(values)
+
+		for y := range levels {
+			levels[y] = median
+		}
+
+		return levels, nil
+	case OverscanRowFit:
+		if params.Order < 0 {
+			return nil, errors.New("the overscan row fit order must be non-negative")
+		}
+
+		if params.Order >= bias.Height() {
+			return nil, fmt.Errorf("the overscan row fit order %d requires more than %d rows", params.Order, bias.Height())
+		}
+
+		rows := make([]float64, 0, bias.Height())
+
+		medians := make([]float64, 0, bias.Height())
+
+		values := make([]float32, bias.Width())
+
+		for y := bias.Y1; y < bias.Y2; y++ {
+			copy(values, data[y*xs+bias.X1:y*xs+bias.X2])
+
+			rows = append(rows, float64(y))
+
+			medians = append(medians, float64(qsort.QSelectMedianFloat32(values)))
+		}
+
+		coefficients, err := fitPolynomial(rows, medians, params.Order)
+
+		if err != nil {
+			return nil, err
+		}
+
+		for y := range levels {
+			levels[y] = float32(evaluatePolynomial(coefficients, float64(y)))
+		}
+
+		return levels, nil
+	default:
+		return nil, fmt.Errorf("unknown overscan mode: %d", params.Mode)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Fits a polynomial of the given order to the points { x, y } by least squares, returning the coefficients in
+// ascending order of power.
+func fitPolynomial(x []float64, y []float64, order int) ([]float64, error) {
+	n := order + 1
+
+	// Centre and scale the abscissae to keep the normal equations well conditioned:
+	centre, scale := 0.0, 1.0
+
+	if len(x) > 0 {
+		lo, hi := x[0], x[0]
+
+		for _, v := range x {
+			lo, hi = math.Min(lo, v), math.Max(hi, v)
+		}
+
+		centre = (lo + hi) / 2
+
+		if hi > lo {
+			scale = (hi - lo) / 2
+		}
+	}
+
+	// Construct the augmented normal equations, i.e., (AᵀA | Aᵀy):
+	a := make([][]float64, n)
+
+	for i := range a {
+		a[i] = make([]float64, n+1)
+	}
+
+	for k := range x {
+		t := (x[k] - centre) / scale
+
+		powers := make([]float64, 2*n-1)
+
+		powers[0] = 1
+
+		for p := 1; p < len(powers); p++ {
+			powers[p] = powers[p-1] * t
+		}
+
+		for i := 0; i < n; i++ {
+			for j := 0; j < n; j++ {
+				a[i][j] += powers[i+j]
+			}
+
+			a[i][n] += powers[i] * y[k]
+		}
+	}
+
+	// Solve the normal equations by Gaussian elimination with partial pivoting:
+	for i := 0; i < n; i++ {
+		pivot := i
+
+		for j := i + 1; j < n; j++ {
+			if math.Abs(a[j][i]) > math.Abs(a[pivot][i]) {
+				pivot = j
+			}
+		}
+
+		if math.Abs(a[pivot][i]) < 1e-12 {
+			return nil, errors.New("the polynomial fit is singular")
+		}
+
+		a[i], a[pivot] = a[pivot], a[i]
+
+		for j := i + 1; j < n; j++ {
+			factor := a[j][i] / a[i][i]
+
+			for k := i; k <= n; k++ {
+				a[j][k] -= factor * a[i][k]
+			}
+		}
+	}
+
+	c := make([]float64, n)
+
+	for i := n - 1; i >= 0; i-- {
+		sum := a[i][n]
+
+		for j := i + 1; j < n; j++ {
+			sum -= a[i][j] * c[j]
+		}
+
+		c[i] = sum / a[i][i]
+	}
+
+	// Expand the coefficients of the centred and scaled polynomial, i.e., in powers of (x - centre) / scale:
+	coefficients := make([]float64, n)
+
+	for i := 0; i < n; i++ {
+		// The binomial expansion of c[i] * ((x - centre) / scale)^i:
+		binomial := 1.0
+
+		for k := 0; k <= i; k++ {
+			coefficients[k] += c[i] * binomial * math.Pow(-centre, float64(i-k)) / math.Pow(scale, float64(i))
+
+			binomial = binomial * float64(i-k) / float64(k+1)
+		}
+	}
+
+	return coefficients, nil
+}
+
+/*****************************************************************************************************************/
+
+// Evaluates the polynomial with the coefficients in ascending order of power at x, by Horner's method.
+func evaluatePolynomial(coefficients []float64, x float64) float64 {
+	v := 0.0
+
+	for i := len(coefficients) - 1; i >= 0; i-- {
+		v = v*x + coefficients[i]
+	}
+
+	return v
+}
+
+/*****************************************************************************************************************/
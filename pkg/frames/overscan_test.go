@@ -0,0 +1,170 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+// Creates a 10x8 frame, where the last two columns are overscan, with a bias level which increases by one ADU
+// per row on top of 100 ADU, and a signal of 500 ADU in the exposed pixels.
+func getOverscanTestFrame() *fits.FITSImage {
+	data := make([][]uint32, 8)
+
+	for y := range data {
+		data[y] = make([]uint32, 10)
+
+		for x := range data[y] {
+			data[y][x] = 100 + uint32(y)
+
+			if x < 8 {
+				data[y][x] += 500
+			}
+		}
+	}
+
+	f := fits.NewFITSImageFrom2DData(data, 2, 10, 8, 65535)
+
+	f.Header.Set("BIASSEC", "[9:10,1:8]", "The overscan region")
+
+	f.Header.Set("DATASEC", "[1:8,1:8]", "The exposed region")
+
+	return f
+}
+
+/*****************************************************************************************************************/
+
+func TestParseRegion(t *testing.T) {
+	r, err := ParseRegion("[1:4096, 2:10]")
+
+	if err != nil {
+		t.Fatalf("ParseRegion() failed: %s", err)
+	}
+
+	if r.X1 != 0 || r.X2 != 4096 || r.Y1 != 1 || r.Y2 != 10 {
+		t.Errorf("ParseRegion() failed: expected { 0, 4096, 1, 10 }, got %+v", r)
+	}
+
+	if r.String() != "[1:4096,2:10]" {
+		t.Errorf("String() failed: expected [1:4096,2:10], got %s", r.String())
+	}
+
+	r, err = ParseRegion("[10:9,1:8]")
+
+	if err != nil {
+		t.Fatalf("ParseRegion() failed: %s", err)
+	}
+
+	if r.X1 != 8 || r.X2 != 10 {
+		t.Errorf("ParseRegion() failed: expected a normalised reversed range, got %+v", r)
+	}
+
+	if _, err := ParseRegion("1:10,1:10"); err == nil {
+		t.Errorf("ParseRegion() failed: expected an error for an invalid section")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestApplyOverscanCorrectionGlobalMedian(t *testing.T) {
+	f := getOverscanTestFrame()
+
+	corrected, err := ApplyOverscanCorrection(f, OverscanParams{Mode: OverscanGlobalMedian})
+
+	if err != nil {
+		t.Fatalf("ApplyOverscanCorrection() failed: %s", err)
+	}
+
+	if corrected.Naxisn[0] != 8 || corrected.Naxisn[1] != 8 || len(corrected.Data) != 64 {
+		t.Fatalf("ApplyOverscanCorrection() failed: expected an 8x8 trimmed frame, got %v", corrected.Naxisn)
+	}
+
+	if corrected.Header.Naxis1 != 8 || corrected.Header.Naxis2 != 8 {
+		t.Errorf("ApplyOverscanCorrection() failed: expected NAXIS1 and NAXIS2 of 8")
+	}
+
+	// The median of the overscan region, i.e., 103.5, is subtracted from every pixel:
+	if corrected.Data[0] != 496.5 {
+		t.Errorf("ApplyOverscanCorrection() failed: expected data[0] of 496.5, got %f", corrected.Data[0])
+	}
+
+	if _, ok := corrected.Header.Strings["BIASSEC"]; ok {
+		t.Errorf("ApplyOverscanCorrection() failed: expected the BIASSEC keyword to be removed")
+	}
+
+	if len(corrected.Header.History) == 0 {
+		t.Errorf("ApplyOverscanCorrection() failed: expected a HISTORY entry")
+	}
+
+	if len(f.Data) != 80 {
+		t.Errorf("ApplyOverscanCorrection() failed: expected the original frame to be unmodified")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestApplyOverscanCorrectionRowFit(t *testing.T) {
+	f := getOverscanTestFrame()
+
+	corrected, err := ApplyOverscanCorrection(f, OverscanParams{Mode: OverscanRowFit, Order: 1})
+
+	if err != nil {
+		t.Fatalf("ApplyOverscanCorrection() failed: %s", err)
+	}
+
+	// The linear bias gradient is removed entirely, leaving only the signal:
+	for i, v := range corrected.Data {
+		if math.Abs(float64(v-500)) > 1e-3 {
+			t.Errorf("ApplyOverscanCorrection() failed: expected data[%d] of 500, got %f", i, v)
+		}
+	}
+
+	if math.Abs(float64(corrected.Header.Floats["OVERSCAN"].Value-103.5)) > 1e-3 {
+		t.Errorf("ApplyOverscanCorrection() failed: expected OVERSCAN of 103.5, got %f", corrected.Header.Floats["OVERSCAN"].Value)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestApplyOverscanCorrectionToFrames(t *testing.T) {
+	frames := []fits.FITSImage{*getOverscanTestFrame(), *getOverscanTestFrame()}
+
+	corrected, err := ApplyOverscanCorrectionToFrames(frames, OverscanParams{Mode: OverscanRowFit, Order: 1})
+
+	if err != nil {
+		t.Fatalf("ApplyOverscanCorrectionToFrames() failed: %s", err)
+	}
+
+	// The corrected frames may then be combined into a master frame of the trimmed dimensions:
+	masterBias, err := NewMasterBiasFrame(corrected, 2, 8, 8, 65535, 0.001)
+
+	if err != nil {
+		t.Fatalf("NewMasterBiasFrame() failed: %s", err)
+	}
+
+	if len(masterBias.Combined.Data) != 64 {
+		t.Errorf("NewMasterBiasFrame() failed: expected 64 pixels, got %d", len(masterBias.Combined.Data))
+	}
+
+	// Without a BIASSEC keyword or explicit bias section, the overscan correction cannot be applied:
+	delete(frames[0].Header.Strings, "BIASSEC")
+
+	if _, err := ApplyOverscanCorrectionToFrames(frames, OverscanParams{}); err == nil {
+		t.Errorf("ApplyOverscanCorrectionToFrames() failed: expected an error without a bias section")
+	}
+}
+
+/*****************************************************************************************************************/
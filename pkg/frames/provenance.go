@@ -0,0 +1,347 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+// The prefix of the HISTORY records holding the structured provenance of a frame:
+const provenanceHistoryPrefix = "PROV "
+
+/*****************************************************************************************************************/
+
+// The maximum length of a provenance HISTORY record, i.e., the 72 characters of a single FITS commentary record:
+const provenanceRecordLength = 72
+
+/*****************************************************************************************************************/
+
+// The format of the DATE keyword, i.e., the UTC timestamp at which the frame was created:
+const provenanceDateFormat = "2006-01-02T15:04:05"
+
+/*****************************************************************************************************************/
+
+// ProvenanceInput describes one of the input frames from which a frame was created.
+type ProvenanceInput struct {
+	Filename string // The (base) file name of the input frame, if any
+	Checksum string // The checksum of the input frame's data, i.e., the leading 16 hex digits of its SHA-256
+}
+
+/*****************************************************************************************************************/
+
+// Provenance describes how a master or calibrated frame was created. It is written into the FITS header as the
+// DATE, NCOMBINE, PROVID, PROVTYPE, PROVREJ, PROVSW, PROVBIAS, PROVDARK and PROVFLAT keywords, with the inputs,
+// the software and the rejection parameters recorded as "PROV" HISTORY records. Records longer than a single FITS
+// commentary record are continued over further records of the same kind followed by a "+", e.g., "PROV INPUT+".
+type Provenance struct {
+	ID                  string            // The ID of the frame, i.e., the checksum of its data
+	Type                string            // The type of the frame (e.g., bias, dark, flat or light)
+	Count               int               // The number of input frames combined to create the frame
+	Inputs              []ProvenanceInput // The input frames, in the order they were combined
+	Rejection           string            // The pixel rejection method used when combining, e.g., "none"
+	RejectionParameters string            // The parameters of the pixel rejection method, if any
+	MasterBias          string            // The ID of the master bias frame used, if any
+	MasterDark          string            // The ID of the master dark (or dark-flat) frame used, if any
+	MasterFlat          string            // The ID of the master flat frame used, if any
+	Software            string            // The name and version of the software which created the frame
+	Timestamp           time.Time         // The UTC timestamp at which the frame was created
+}
+
+/*****************************************************************************************************************/
+
+// Creates the provenance of a frame of the given type, combined from the given input frames.
+func NewProvenance(kind string, frames []fits.FITSImage) *Provenance {
+	inputs := make([]ProvenanceInput, len(frames))
+
+	for i := range frames {
+		filename := ""
+
+		if frames[i].Filename != "" {
+			filename = filepath.Base(frames[i].Filename)
+		}
+
+		inputs[i] = ProvenanceInput{
+			Filename: filename,
+			Checksum: getChecksum(frames[i].Data),
+		}
+	}
+
+	return &Provenance{
+		Type:      kind,
+		Count:     len(frames),
+		Inputs:    inputs,
+		Rejection: "none",
+		Software:  getSoftwareVersion(),
+		Timestamp: time.Now().UTC().Truncate(time.Second),
+	}
+}
+
+/*****************************************************************************************************************/
+
+/*
+WriteToFITSImage()
+
+Writes the provenance into the header of the frame, replacing any previous provenance.
+
+The ID of the frame is computed as the checksum of its data, such that the frame can be
+referred to by the provenance of any frames subsequently calibrated with it, and so the
+provenance must be written once the data of the frame is final.
+*/
+func (p *Provenance) WriteToFITSImage(f *fits.FITSImage) {
+	p.ID = getChecksum(f.Data)
+
+	f.Header.Set("DATE", p.Timestamp.UTC().Format(provenanceDateFormat), "UTC date the frame was created")
+
+	f.Header.Set("NCOMBINE", p.Count, "The number of frames combined")
+
+	f.Header.Set("PROVID", p.ID, "The checksum ID of the frame data")
+
+	f.Header.Set("PROVTYPE", p.Type, "The type of the frame")
+
+	f.Header.Set("PROVREJ", p.Rejection, "The pixel rejection method")
+
+	f.Header.Set("PROVSW", p.Software, "The software which created the frame")
+
+	for key, id := range map[string]string{
+		"PROVBIAS": p.MasterBias,
+		"PROVDARK": p.MasterDark,
+		"PROVFLAT": p.MasterFlat,
+	} {
+		delete(f.Header.Strings, key)
+
+		if id != "" {
+			f.Header.Set(key, id, "The checksum ID of the master frame used")
+		}
+	}
+
+	// Replace any previous provenance HISTORY records, e.g., when a frame is integrated into a master frame:
+	history := make([]string, 0, len(f.Header.History)+len(p.Inputs)+1)
+
+	for _, h := range f.Header.History {
+		if !strings.HasPrefix(h, provenanceHistoryPrefix) {
+			history = append(history, h)
+		}
+	}
+
+	// The software version may exceed the length of a FITS string value, so is also recorded in full here:
+	history = append(history, getProvenanceRecords("SOFTWARE", p.Software)...)
+
+	if p.RejectionParameters != "" {
+		history = append(history, getProvenanceRecords("REJECT", p.RejectionParameters)...)
+	}
+
+	for i, input := range p.Inputs {
+		record := fmt.Sprintf("%d %s %s", i+1, input.Checksum, input.Filename)
+
+		history = append(history, getProvenanceRecords("INPUT", strings.TrimSpace(record))...)
+	}
+
+	f.Header.History = history
+}
+
+/*****************************************************************************************************************/
+
+/*
+GetProvenance()
+
+Reads the provenance of a master or calibrated frame back from its FITS header, as written
+by WriteToFITSImage().
+
+Returns an error if the frame has no provenance, or if any of its records are malformed.
+*/
+func GetProvenance(f *fits.FITSImage) (*Provenance, error) {
+	id, ok := f.Header.Strings["PROVID"]
+
+	if !ok {
+		return nil, errors.New("the frame has no provenance")
+	}
+
+	p := &Provenance{
+		ID:                  id.Value,
+		Type:                f.Header.Strings["PROVTYPE"].Value,
+		Count:               int(f.Header.Ints["NCOMBINE"].Value),
+		Inputs:              make([]ProvenanceInput, 0),
+		Rejection:           f.Header.Strings["PROVREJ"].Value,
+		RejectionParameters: "",
+		MasterBias:          f.Header.Strings["PROVBIAS"].Value,
+		MasterDark:          f.Header.Strings["PROVDARK"].Value,
+		MasterFlat:          f.Header.Strings["PROVFLAT"].Value,
+		Software:            f.Header.Strings["PROVSW"].Value,
+	}
+
+	date, ok := f.Header.Strings["DATE"]
+
+	if !ok {
+		date, ok = f.Header.Dates["DATE"]
+	}
+
+	if ok {
+		timestamp, err := time.Parse(provenanceDateFormat, strings.TrimSuffix(date.Value, "Z"))
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid DATE: %w", err)
+		}
+
+		p.Timestamp = timestamp
+	}
+
+	records := make([]string, 0)
+
+	for _, h := range f.Header.History {
+		if !strings.HasPrefix(h, provenanceHistoryPrefix) {
+			continue
+		}
+
+		record := strings.TrimPrefix(h, provenanceHistoryPrefix)
+
+		// A continuation record, e.g., "INPUT+ ...", continues the previous record of the same kind:
+		if kind, value, ok := strings.Cut(record, "+ "); ok && !strings.Contains(kind, " ") {
+			if len(records) == 0 || !strings.HasPrefix(records[len(records)-1], kind+" ") {
+				return nil, fmt.Errorf("invalid provenance continuation record: %q", h)
+			}
+
+			records[len(records)-1] += value
+
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	for _, record := range records {
+		switch {
+		case strings.HasPrefix(record, "SOFTWARE "):
+			p.Software = strings.TrimPrefix(record, "SOFTWARE ")
+		case strings.HasPrefix(record, "REJECT "):
+			p.RejectionParameters = strings.TrimPrefix(record, "REJECT ")
+		case strings.HasPrefix(record, "INPUT "):
+			fields := strings.SplitN(strings.TrimPrefix(record, "INPUT "), " ", 3)
+
+			if len(fields) < 2 {
+				return nil, fmt.Errorf("invalid provenance input record: %q", record)
+			}
+
+			if _, err := strconv.Atoi(fields[0]); err != nil {
+				return nil, fmt.Errorf("invalid provenance input record: %q", record)
+			}
+
+			input := ProvenanceInput{Checksum: fields[1]}
+
+			if len(fields) == 3 {
+				input.Filename = strings.TrimSpace(fields[2])
+			}
+
+			p.Inputs = append(p.Inputs, input)
+		}
+	}
+
+	return p, nil
+}
+
+/*****************************************************************************************************************/
+
+// Splits the value of a provenance record of the given kind (e.g., "INPUT") over as many HISTORY records as needed
+// for each to fit in a single FITS commentary record, continuing it with records of the kind followed by a "+". The
+// value is never split next to a space, as leading and trailing spaces are not preserved by a FITS round trip.
+func getProvenanceRecords(kind string, value string) []string {
+	prefix := provenanceHistoryPrefix + kind + " "
+
+	records := make([]string, 0, 1)
+
+	for {
+		n := provenanceRecordLength - len(prefix)
+
+		if len(value) <= n {
+			return append(records, prefix+value)
+		}
+
+		for n > 1 && (value[n-1] == ' ' || value[n] == ' ') {
+			n--
+		}
+
+		records = append(records, prefix+value[:n])
+
+		value = value[n:]
+
+		prefix = provenanceHistoryPrefix + kind + "+ "
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the ID of a master frame from its provenance, or otherwise computes it as the checksum of its data.
+func getFrameID(f *fits.FITSImage) string {
+	if f == nil {
+		return ""
+	}
+
+	if id, ok := f.Header.Strings["PROVID"]; ok && id.Value != "" {
+		return id.Value
+	}
+
+	return getChecksum(f.Data)
+}
+
+/*****************************************************************************************************************/
+
+// Computes the checksum of the frame data, i.e., the leading 16 hex digits of the SHA-256 of its (little-endian)
+// IEEE 754 representation.
+func getChecksum(data []float32) string {
+	h := sha256.New()
+
+	buf := make([]byte, 4)
+
+	for _, v := range data {
+		binary.LittleEndian.PutUint32(buf, math.Float32bits(v))
+		h.Write(buf)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the name and version of this module, e.g., "iris v0.1.0", from the build information of the binary.
+func getSoftwareVersion() string {
+	info, ok := debug.ReadBuildInfo()
+
+	if !ok {
+		return "iris"
+	}
+
+	if info.Main.Path == "github.com/observerly/iris" {
+		return "iris " + info.Main.Version
+	}
+
+	for _, dep := range info.Deps {
+		if dep.Path == "github.com/observerly/iris" {
+			return "iris " + dep.Version
+		}
+	}
+
+	return "iris"
+}
+
+/*****************************************************************************************************************/
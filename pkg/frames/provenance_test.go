@@ -0,0 +1,297 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"testing"
+	"time"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+func getProvenanceTestFrame(value uint32, filename string) *fits.FITSImage {
+	data := [][]uint32{
+		{value, value, value, value},
+		{value, value, value, value},
+		{value, value, value, value},
+		{value, value, value, value},
+	}
+
+	f := fits.NewFITSImageFrom2DData(data, 2, 4, 4, 65535)
+
+	f.Filename = filename
+
+	f.Header.Set("SENSOR", "Monochrome", "ASCOM Alpaca Sensor Type")
+
+	return f
+}
+
+/*****************************************************************************************************************/
+
+func TestMasterBiasFrameProvenance(t *testing.T) {
+	frames := []fits.FITSImage{
+		*getProvenanceTestFrame(100, "/data/bias_001.fits"),
+		*getProvenanceTestFrame(102, "/data/bias_002.fits"),
+	}
+
+	masterBias, err := NewMasterBiasFrame(frames, 2, 4, 4, 65535, 0.001)
+
+	if err != nil {
+		t.Fatalf("NewMasterBiasFrame() failed: %s", err)
+	}
+
+	p, err := GetProvenance(masterBias.Combined)
+
+	if err != nil {
+		t.Fatalf("GetProvenance() failed: %s", err)
+	}
+
+	if p.Type != "bias" || p.Count != 2 || p.Rejection != "none" {
+		t.Errorf("GetProvenance() failed: expected a bias of 2 frames without rejection, got %+v", p)
+	}
+
+	if len(p.Inputs) != 2 || p.Inputs[0].Filename != "bias_001.fits" || p.Inputs[1].Filename != "bias_002.fits" {
+		t.Errorf("GetProvenance() failed: expected the input file names, got %+v", p.Inputs)
+	}
+
+	if p.Inputs[0].Checksum != getChecksum(frames[0].Data) || p.Inputs[0].Checksum == p.Inputs[1].Checksum {
+		t.Errorf("GetProvenance() failed: expected the input checksums, got %+v", p.Inputs)
+	}
+
+	if p.ID != getChecksum(masterBias.Combined.Data) {
+		t.Errorf("GetProvenance() failed: expected the ID to be the checksum of the master data, got %s", p.ID)
+	}
+
+	if p.Software == "" || p.Timestamp.IsZero() {
+		t.Errorf("GetProvenance() failed: expected the software and timestamp, got %+v", p)
+	}
+
+	if masterBias.Combined.Header.Strings["SENSOR"].Value != "Monochrome" {
+		t.Errorf("NewMasterBiasFrame() failed: expected the SENSOR to be inherited from the bias frames")
+	}
+
+	// Applying a further frame records the provenance of all of the integrated frames:
+	masterBias, err = masterBias.ApplyFrame(getProvenanceTestFrame(104, "/data/bias_003.fits"))
+
+	if err != nil {
+		t.Fatalf("ApplyFrame() failed: %s", err)
+	}
+
+	p, err = GetProvenance(masterBias.Combined)
+
+	if err != nil {
+		t.Fatalf("GetProvenance() failed: %s", err)
+	}
+
+	if p.Count != 3 || len(p.Inputs) != 3 || p.Inputs[2].Filename != "bias_003.fits" {
+		t.Errorf("GetProvenance() failed: expected the provenance of 3 frames, got %+v", p)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestCalibratedLightFrameProvenanceRoundTrip(t *testing.T) {
+	masterBias, err := NewMasterBiasFrame([]fits.FITSImage{*getProvenanceTestFrame(100, "bias.fits")}, 2, 4, 4, 65535, 0.001)
+
+	if err != nil {
+		t.Fatalf("NewMasterBiasFrame() failed: %s", err)
+	}
+
+	masterDark, err := NewMasterDarkFrame([]fits.FITSImage{*getProvenanceTestFrame(110, "dark.fits")}, masterBias, 2, 4, 4, 65535, 60)
+
+	if err != nil {
+		t.Fatalf("NewMasterDarkFrame() failed: %s", err)
+	}
+
+	masterFlat, err := NewMasterFlatFrame([]fits.FITSImage{*getProvenanceTestFrame(20000, "flat.fits")}, masterBias, 2, 4, 4, 65535, 2)
+
+	if err != nil {
+		t.Fatalf("NewMasterFlatFrame() failed: %s", err)
+	}
+
+	light := getProvenanceTestFrame(1000, "light.fits")
+
+	calibrated, err := NewCalibratedLightFrame(light, masterBias, masterDark, masterFlat, 2, 4, 4, 65535, 60)
+
+	if err != nil {
+		t.Fatalf("NewCalibratedLightFrame() failed: %s", err)
+	}
+
+	// Write the calibrated light frame to a FITS buffer, and read it back:
+	buf, err := calibrated.Combined.WriteToBuffer()
+
+	if err != nil {
+		t.Fatalf("WriteToBuffer() failed: %s", err)
+	}
+
+	f := fits.NewFITSImage(2, 4, 4, 65535)
+
+	if err := f.Read(buf); err != nil {
+		t.Fatalf("Read() failed: %s", err)
+	}
+
+	p, err := GetProvenance(f)
+
+	if err != nil {
+		t.Fatalf("GetProvenance() failed: %s", err)
+	}
+
+	if p.MasterBias != masterBias.Combined.Header.Strings["PROVID"].Value {
+		t.Errorf("GetProvenance() failed: expected the master bias ID, got %s", p.MasterBias)
+	}
+
+	if p.MasterDark != masterDark.Combined.Header.Strings["PROVID"].Value {
+		t.Errorf("GetProvenance() failed: expected the master dark ID, got %s", p.MasterDark)
+	}
+
+	if p.MasterFlat != masterFlat.Combined.Header.Strings["PROVID"].Value {
+		t.Errorf("GetProvenance() failed: expected the master flat ID, got %s", p.MasterFlat)
+	}
+
+	if p.Type != "light" || len(p.Inputs) != 1 || p.Inputs[0].Filename != "light.fits" {
+		t.Errorf("GetProvenance() failed: expected the light frame input, got %+v", p)
+	}
+
+	if !p.Timestamp.Equal(calibratedTimestamp(t, calibrated.Combined)) {
+		t.Errorf("GetProvenance() failed: expected the timestamp to survive the round trip, got %s", p.Timestamp)
+	}
+
+	if _, err := GetProvenance(light); err == nil {
+		t.Errorf("GetProvenance() failed: expected an error for a frame without provenance")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func calibratedTimestamp(t *testing.T, f *fits.FITSImage) (timestamp time.Time) {
+	p, err := GetProvenance(f)
+
+	if err != nil {
+		t.Fatalf("GetProvenance() failed: %s", err)
+	}
+
+	return p.Timestamp
+}
+
+/*****************************************************************************************************************/
+
+func TestProvenanceLongRecordsRoundTrip(t *testing.T) {
+	filename := "M42_Orion_Nebula_2024-03-15T01-23-45_Ha_7nm_300s_gain100_offset10_temp-10C_bin1x1_0001.fits"
+
+	light := getProvenanceTestFrame(1000, "/data/observations/2024-03-15/M42/"+filename)
+
+	p := NewProvenance("light", []fits.FITSImage{*light})
+
+	p.Software = "iris v0.1.0-0.20240315012345-0123456789ab (with a long description of the build of the software)"
+
+	p.RejectionParameters = "sigma clipping with a lower threshold of 3.0 and an upper threshold of 3.0 over 5 iterations"
+
+	p.WriteToFITSImage(light)
+
+	for _, h := range light.Header.History {
+		if len(h) > 72 {
+			t.Errorf("WriteToFITSImage() failed: expected each HISTORY record to fit in 72 characters, got %q", h)
+		}
+	}
+
+	// Write the light frame to a FITS buffer, and read it back:
+	buf, err := light.WriteToBuffer()
+
+	if err != nil {
+		t.Fatalf("WriteToBuffer() failed: %s", err)
+	}
+
+	f := fits.NewFITSImage(2, 4, 4, 65535)
+
+	if err := f.Read(buf); err != nil {
+		t.Fatalf("Read() failed: %s", err)
+	}
+
+	read, err := GetProvenance(f)
+
+	if err != nil {
+		t.Fatalf("GetProvenance() failed: %s", err)
+	}
+
+	if len(read.Inputs) != 1 || read.Inputs[0].Filename != filename || read.Inputs[0].Checksum != p.Inputs[0].Checksum {
+		t.Errorf("GetProvenance() failed: expected the input %q to survive the round trip, got %+v", filename, read.Inputs)
+	}
+
+	if read.Software != p.Software {
+		t.Errorf("GetProvenance() failed: expected the software %q, got %q", p.Software, read.Software)
+	}
+
+	if read.RejectionParameters != p.RejectionParameters {
+		t.Errorf("GetProvenance() failed: expected the rejection parameters %q, got %q", p.RejectionParameters, read.RejectionParameters)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestSensorDefaultsToMonochrome(t *testing.T) {
+	bias := getProvenanceTestFrame(100, "bias.fits")
+
+	delete(bias.Header.Strings, "SENSOR")
+
+	masterBias, err := NewMasterBiasFrame([]fits.FITSImage{*bias}, 2, 4, 4, 65535, 0.001)
+
+	if err != nil {
+		t.Fatalf("NewMasterBiasFrame() failed: %s", err)
+	}
+
+	if masterBias.Combined.Header.Strings["SENSOR"].Value != "Monochrome" {
+		t.Errorf("NewMasterBiasFrame() failed: expected a SENSOR of Monochrome, got %q", masterBias.Combined.Header.Strings["SENSOR"].Value)
+	}
+
+	dark := getProvenanceTestFrame(110, "dark.fits")
+
+	delete(dark.Header.Strings, "SENSOR")
+
+	masterDark, err := NewMasterDarkFrame([]fits.FITSImage{*dark}, masterBias, 2, 4, 4, 65535, 60)
+
+	if err != nil {
+		t.Fatalf("NewMasterDarkFrame() failed: %s", err)
+	}
+
+	flat := getProvenanceTestFrame(20000, "flat.fits")
+
+	delete(flat.Header.Strings, "SENSOR")
+
+	masterFlat, err := NewMasterFlatFrame([]fits.FITSImage{*flat}, masterBias, 2, 4, 4, 65535, 2)
+
+	if err != nil {
+		t.Fatalf("NewMasterFlatFrame() failed: %s", err)
+	}
+
+	light := getProvenanceTestFrame(1000, "light.fits")
+
+	delete(light.Header.Strings, "SENSOR")
+
+	calibrated, err := NewCalibratedLightFrame(light, masterBias, masterDark, masterFlat, 2, 4, 4, 65535, 60)
+
+	if err != nil {
+		t.Fatalf("NewCalibratedLightFrame() failed: %s", err)
+	}
+
+	for name, f := range map[string]*fits.FITSImage{
+		"master dark":      masterDark.Combined,
+		"master flat":      masterFlat.Combined,
+		"calibrated light": calibrated.Combined,
+	} {
+		if f.Header.Strings["SENSOR"].Value != "Monochrome" {
+			t.Errorf("expected the %s to have a SENSOR of Monochrome, got %q", name, f.Header.Strings["SENSOR"].Value)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,185 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/iris/pkg/utils"
+)
+
+/*****************************************************************************************************************/
+
+// DarkScalingMode determines how the (bias-subtracted) master dark is scaled before it is subtracted from a
+// light frame.
+type DarkScalingMode int
+
+/*****************************************************************************************************************/
+
+const (
+	// The master dark is subtracted as-is, i.e., it must match the exposure time of the light frame:
+	DarkScalingNone DarkScalingMode = iota
+	// The master dark is scaled by the ratio of the light frame exposure time to the dark frame exposure time:
+	DarkScalingExposure
+	// The master dark is scaled by the factor which minimises the noise in the calibrated light frame:
+	DarkScalingOptimise
+)
+
+/*****************************************************************************************************************/
+
+// The default number of iterations of the golden-section search used to optimise the dark scale factor:
+const darkScalingOptimisationIterations = 40
+
+/*****************************************************************************************************************/
+
+// DarkScaling describes how the master dark should be scaled to match a light frame.
+type DarkScaling struct {
+	Mode                DarkScalingMode // The dark scaling mode
+	DoublingTemperature float32         // The temperature change (°C) over which the dark current doubles, zero disables
+	MinimumScale        float32         // The lower bound of the optimised scale factor (defaults to zero)
+	MaximumScale        float32         // The upper bound of the optimised scale factor (defaults to twice the nominal)
+}
+
+/*****************************************************************************************************************/
+
+/*
+GetScaleFactor()
+
+Obtains the factor by which the (bias-subtracted) master dark frame should be multiplied
+before it is subtracted from the given light frame.
+
+The dark current (thermal signal) accumulates linearly with exposure time, so the nominal
+scale factor is the ratio of the light frame exposure time to the master dark exposure
+time (from the Exposure field or EXPOSURE keyword). Optionally, the dark current is also
+modelled as doubling for every DoublingTemperature (°C) rise in sensor temperature, using
+the CCD-TEMP keywords of the light frame and the master dark frame.
+
+In the optimise mode the nominal scale factor seeds a golden-section search for the scale
+factor which minimises the Gaussian noise of the calibrated light frame.
+
+@see Image Calibration & Stack Woodhouse, C. (2017). The Astrophotography Manual. Taylor & Francis. p.203
+*/
+func (m *MasterDarkFrame) GetScaleFactor(light *fits.FITSImage, lightExposure float32, masterBias *MasterFrame, scaling DarkScaling) (float32, error) {
+	if scaling.Mode == DarkScalingNone {
+		return 1, nil
+	}
+
+	if lightExposure <= 0 {
+		lightExposure = float32(light.GetExposureTime())
+
+		if lightExposure <= 0 {
+			return 0, errors.New("the light frame exposure time is required to scale the master dark")
+		}
+	}
+
+	darkExposure := m.Combined.GetExposureTime()
+
+	if darkExposure <= 0 {
+		return 0, errors.New("the master dark exposure time is required to scale the master dark")
+	}
+
+	// The dark current accumulates linearly with the exposure time:
+	scale := float64(lightExposure) / darkExposure
+
+	// Optionally, the dark current doubles for every doubling temperature rise in sensor temperature:
+	if scaling.DoublingTemperature > 0 {
+		lightTemperature, lok := light.Header.GetFloat("CCD-TEMP")
+
+		darkTemperature, dok := m.Combined.Header.GetFloat("CCD-TEMP")
+
+		if !lok || !dok {
+			return 0, errors.New("the CCD-TEMP of both the light and master dark is required for the temperature model")
+		}
+
+		scale *= math.Pow(2, (lightTemperature-darkTemperature)/float64(scaling.DoublingTemperature))
+	}
+
+	if scaling.Mode == DarkScalingExposure {
+		return float32(scale), nil
+	}
+
+	if scaling.Mode != DarkScalingOptimise {
+		return 0, fmt.Errorf("unknown dark scaling mode: %d", scaling.Mode)
+	}
+
+	xs, ys, ok := light.GetDimensions()
+
+	if !ok {
+		return 0, errors.New("the light frame dimensions are required to optimise the dark scale factor")
+	}
+
+	// Subtract the master bias from the light frame, before optimising the dark scale:
+	signal, err := utils.SubtractFloat32Array(light.Data, masterBias.Combined.Data)
+
+	if err != nil {
+		return 0, err
+	}
+
+	lower, upper := float64(scaling.MinimumScale), float64(scaling.MaximumScale)
+
+	if upper <= lower {
+		upper = math.Max(lower, 0) + 2*scale
+	}
+
+	return optimiseDarkScaleFactor(signal, m.Combined.Data, xs, ys, lower, upper)
+}
+
+/*****************************************************************************************************************/
+
+// Finds the dark scale factor k, within the lower and upper bounds, which minimises the Gaussian noise of the
+// bias-subtracted light frame minus k times the bias-subtracted master dark, by golden-section search.
+func optimiseDarkScaleFactor(light []float32, dark []float32, xs int, ys int, lower float64, upper float64) (float32, error) {
+	if len(light) != len(dark) {
+		return 0, errors.New("to optimise the dark scale factor the light and dark must be of same length")
+	}
+
+	if xs < 3 || ys < 3 {
+		return 0, errors.New("to optimise the dark scale factor the frames must be at least 3x3 pixels")
+	}
+
+	buffer := make([]float32, len(light))
+
+	noise := func(k float64) float64 {
+		for i := range buffer {
+			buffer[i] = light[i] - float32(k)*dark[i]
+		}
+
+		return photometry.NewNoiseExtractor(buffer, xs, ys).GetGaussianNoise()
+	}
+
+	phi := (math.Sqrt(5) - 1) / 2
+
+	a, b := lower, upper
+
+	c, d := b-phi*(b-a), a+phi*(b-a)
+
+	fc, fd := noise(c), noise(d)
+
+	for i := 0; i < darkScalingOptimisationIterations; i++ {
+		if fc < fd {
+			b, d, fd = d, c, fc
+			c = b - phi*(b-a)
+			fc = noise(c)
+		} else {
+			a, c, fc = c, d, fd
+			d = a + phi*(b-a)
+			fd = noise(d)
+		}
+	}
+
+	return float32((a + b) / 2), nil
+}
+
+/*****************************************************************************************************************/
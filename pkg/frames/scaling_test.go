@@ -0,0 +1,230 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/frames
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package frames
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/utils"
+)
+
+/*****************************************************************************************************************/
+
+func getScalingTestFrames(t *testing.T, xs int, ys int, darkScale float32) (*fits.FITSImage, *MasterFrame, *MasterDarkFrame) {
+	bias := make([][]uint32, ys)
+	dark := make([][]uint32, ys)
+	light := make([][]uint32, ys)
+
+	rng := utils.RNG{}
+
+	for y := 0; y < ys; y++ {
+		bias[y] = make([]uint32, xs)
+		dark[y] = make([]uint32, xs)
+		light[y] = make([]uint32, xs)
+
+		for x := 0; x < xs; x++ {
+			// A strongly structured dark current pattern, with hot columns:
+			current := uint32(10 + 40*((x*7+y*3)%5))
+
+			bias[y][x] = 100
+			dark[y][x] = 100 + current
+			light[y][x] = 100 + 500 + uint32(float32(current)*darkScale) + rng.Uint32n(3)
+		}
+	}
+
+	biasFrame := fits.NewFITSImageFrom2DData(bias, 2, int32(xs), int32(ys), 65535)
+
+	masterBias, err := NewMasterBiasFrame([]fits.FITSImage{*biasFrame}, 2, int32(xs), int32(ys), 65535, 0.001)
+
+	if err != nil {
+		t.Fatalf("NewMasterBiasFrame() failed: %s", err)
+	}
+
+	darkFrame := fits.NewFITSImageFrom2DData(dark, 2, int32(xs), int32(ys), 65535)
+
+	darkFrame.Header.Set("CCD-TEMP", float32(-10), "Sensor temperature (C)")
+
+	masterDark, err := NewMasterDarkFrame([]fits.FITSImage{*darkFrame}, masterBias, 2, int32(xs), int32(ys), 65535, 60)
+
+	if err != nil {
+		t.Fatalf("NewMasterDarkFrame() failed: %s", err)
+	}
+
+	lightFrame := fits.NewFITSImageFrom2DData(light, 2, int32(xs), int32(ys), 65535)
+
+	return lightFrame, masterBias, masterDark
+}
+
+/*****************************************************************************************************************/
+
+func TestMasterDarkFrameRecordsSensorTemperature(t *testing.T) {
+	_, _, masterDark := getScalingTestFrames(t, 16, 16, 1)
+
+	if masterDark.Combined.Header.Floats["CCD-TEMP"].Value != -10 {
+		t.Errorf("NewMasterDarkFrame() failed: expected CCD-TEMP of -10, got %f", masterDark.Combined.Header.Floats["CCD-TEMP"].Value)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetScaleFactorNone(t *testing.T) {
+	light, masterBias, masterDark := getScalingTestFrames(t, 16, 16, 1)
+
+	scale, err := masterDark.GetScaleFactor(light, 120, masterBias, DarkScaling{Mode: DarkScalingNone})
+
+	if err != nil {
+		t.Errorf("GetScaleFactor() failed: %s", err)
+	}
+
+	if scale != 1 {
+		t.Errorf("GetScaleFactor() failed: expected scale of 1, got %f", scale)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetScaleFactorExposure(t *testing.T) {
+	light, masterBias, masterDark := getScalingTestFrames(t, 16, 16, 2)
+
+	scale, err := masterDark.GetScaleFactor(light, 120, masterBias, DarkScaling{Mode: DarkScalingExposure})
+
+	if err != nil {
+		t.Errorf("GetScaleFactor() failed: %s", err)
+	}
+
+	if scale != 2 {
+		t.Errorf("GetScaleFactor() failed: expected scale of 2, got %f", scale)
+	}
+
+	// The light frame exposure time falls back to the EXPOSURE keyword:
+	light.Header.Set("EXPOSURE", float32(30), "Exposure time (s)")
+
+	scale, err = masterDark.GetScaleFactor(light, 0, masterBias, DarkScaling{Mode: DarkScalingExposure})
+
+	if err != nil {
+		t.Errorf("GetScaleFactor() failed: %s", err)
+	}
+
+	if scale != 0.5 {
+		t.Errorf("GetScaleFactor() failed: expected scale of 0.5, got %f", scale)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetScaleFactorExposureWithTemperatureModel(t *testing.T) {
+	light, masterBias, masterDark := getScalingTestFrames(t, 16, 16, 1)
+
+	scaling := DarkScaling{Mode: DarkScalingExposure, DoublingTemperature: 6}
+
+	if _, err := masterDark.GetScaleFactor(light, 60, masterBias, scaling); err == nil {
+		t.Errorf("GetScaleFactor() failed: expected an error when the light CCD-TEMP is missing")
+	}
+
+	light.Header.Set("CCD-TEMP", float32(2), "Sensor temperature (C)")
+
+	scale, err := masterDark.GetScaleFactor(light, 60, masterBias, scaling)
+
+	if err != nil {
+		t.Errorf("GetScaleFactor() failed: %s", err)
+	}
+
+	// A 12°C rise in temperature, with a doubling temperature of 6°C, quadruples the dark current:
+	if math.Abs(float64(scale)-4) > 1e-5 {
+		t.Errorf("GetScaleFactor() failed: expected scale of 4, got %f", scale)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetScaleFactorOptimise(t *testing.T) {
+	light, masterBias, masterDark := getScalingTestFrames(t, 64, 64, 1.5)
+
+	scale, err := masterDark.GetScaleFactor(light, 60, masterBias, DarkScaling{Mode: DarkScalingOptimise})
+
+	if err != nil {
+		t.Errorf("GetScaleFactor() failed: %s", err)
+	}
+
+	if math.Abs(float64(scale)-1.5) > 0.05 {
+		t.Errorf("GetScaleFactor() failed: expected scale of approximately 1.5, got %f", scale)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewCalibratedLightFrameWithDarkScaling(t *testing.T) {
+	light, masterBias, masterDark := getScalingTestFrames(t, 16, 16, 2)
+
+	flat := make([][]uint32, 16)
+
+	for y := range flat {
+		flat[y] = make([]uint32, 16)
+
+		for x := range flat[y] {
+			flat[y][x] = 1100
+		}
+	}
+
+	flatFrame := fits.NewFITSImageFrom2DData(flat, 2, 16, 16, 65535)
+
+	masterFlat, err := NewMasterFlatFrame([]fits.FITSImage{*flatFrame}, masterBias, 2, 16, 16, 65535, 1)
+
+	if err != nil {
+		t.Fatalf("NewMasterFlatFrame() failed: %s", err)
+	}
+
+	calibrated, err := NewCalibratedLightFrameWithOptions(light, masterBias, masterDark, masterFlat, 2, 16, 16, 65535, 120, CalibrationOptions{
+		DarkScaling: DarkScaling{Mode: DarkScalingExposure},
+	})
+
+	if err != nil {
+		t.Fatalf("NewCalibratedLightFrameWithOptions() failed: %s", err)
+	}
+
+	if calibrated.DarkScale != 2 {
+		t.Errorf("NewCalibratedLightFrameWithOptions() failed: expected dark scale of 2, got %f", calibrated.DarkScale)
+	}
+
+	if calibrated.Combined.Header.Floats["DARKSCAL"].Value != 2 {
+		t.Errorf("NewCalibratedLightFrameWithOptions() failed: expected DARKSCAL of 2, got %f", calibrated.Combined.Header.Floats["DARKSCAL"].Value)
+	}
+
+	// With the dark current fully removed, only the 500 ADU signal (plus up to 2 ADU of noise) remains:
+	for i, v := range calibrated.Combined.Data {
+		if v < 500 || v > 502 {
+			t.Errorf("NewCalibratedLightFrameWithOptions() failed: expected data[%d] of 500-502, got %f", i, v)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetScaleFactorOptimiseWithoutAxisDimensions(t *testing.T) {
+	light, masterBias, masterDark := getScalingTestFrames(t, 64, 64, 1.5)
+
+	// Frames created by fits.NewFITSImage() record their dimensions only in the NAXIS1 and NAXIS2 header values:
+	light.Naxisn = nil
+
+	scale, err := masterDark.GetScaleFactor(light, 60, masterBias, DarkScaling{Mode: DarkScalingOptimise})
+
+	if err != nil {
+		t.Fatalf("GetScaleFactor() failed: %s", err)
+	}
+
+	if math.Abs(float64(scale)-1.5) > 0.05 {
+		t.Errorf("GetScaleFactor() failed: expected scale of approximately 1.5, got %f", scale)
+	}
+}
+
+/*****************************************************************************************************************/
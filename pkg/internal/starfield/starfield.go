@@ -0,0 +1,52 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/starfield
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+// Package starfield renders synthetic star fields, e.g., for the tests of the packages which detect and measure
+// the stars of an image.
+package starfield
+
+/*****************************************************************************************************************/
+
+import "math"
+
+/*****************************************************************************************************************/
+
+// The standard deviation of the Gaussian profile of the stars, in pixels, i.e., a FWHM of 3.53 pixels:
+const Sigma = 1.5
+
+/*****************************************************************************************************************/
+
+// The half-width of the box within which each star is rendered, in pixels (i.e., more than 6σ):
+const Radius = 10
+
+/*****************************************************************************************************************/
+
+/*
+AddStar()
+
+Adds a Gaussian star (σ of 1.5 pixels) of the given total flux, in ADU, centred on
+the given position to the data of an image of the given dimensions. The star is
+rendered within a box of 21 x 21 pixels, clipped to the bounds of the image.
+*/
+func AddStar(data []float32, xs int, ys int, x float64, y float64, flux float64) {
+	v := 2 * Sigma * Sigma
+
+	for j := int(y) - Radius; j <= int(y)+Radius; j++ {
+		for i := int(x) - Radius; i <= int(x)+Radius; i++ {
+			if i < 0 || j < 0 || i >= xs || j >= ys {
+				continue
+			}
+
+			r2 := (float64(i)-x)*(float64(i)-x) + (float64(j)-y)*(float64(j)-y)
+
+			data[j*xs+i] += float32(flux / (math.Pi * v) * math.Exp(-r2/v))
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,68 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/starfield
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package starfield
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+func TestAddStar(t *testing.T) {
+	xs, ys := 32, 32
+
+	data := make([]float32, xs*ys)
+
+	AddStar(data, xs, ys, 16.3, 15.6, 10000)
+
+	sum, peak := 0.0, 0
+
+	for i, v := range data {
+		sum += float64(v)
+
+		if v > data[peak] {
+			peak = i
+		}
+	}
+
+	if math.Abs(sum-10000) > 1 {
+		t.Errorf("AddStar() expected a total flux of 10000, got %f", sum)
+	}
+
+	if peak%xs != 16 || peak/xs != 16 {
+		t.Errorf("AddStar() expected the peak at (16, 16), got (%d, %d)", peak%xs, peak/xs)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestAddStarClipped(t *testing.T) {
+	xs, ys := 16, 16
+
+	data := make([]float32, xs*ys)
+
+	// A star in the corner of the image is clipped to its bounds, with a quarter of its flux (and half of the flux of
+	// its central row and column) rendered:
+	AddStar(data, xs, ys, 0, 0, 10000)
+
+	sum := 0.0
+
+	for _, v := range data {
+		sum += float64(v)
+	}
+
+	if sum < 2500 || sum > 4500 {
+		t.Errorf("AddStar() expected around a quarter of the flux to be rendered, got %f", sum)
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,532 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/lightcurve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package lightcurve
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/iris/pkg/qsort"
+	"github.com/observerly/iris/pkg/registration"
+	stats "github.com/observerly/iris/pkg/statistics"
+	"github.com/observerly/iris/pkg/wcs"
+)
+
+/*****************************************************************************************************************/
+
+// Tracking describes how the positions of the stars are found in each frame of the series.
+type Tracking int
+
+/*****************************************************************************************************************/
+
+const (
+	// The pixel positions of the stars in the reference frame are transformed onto each frame, by registering the
+	// stars detected in the frame onto those of the reference frame:
+	TrackRegistration Tracking = iota
+	// The equatorial coordinates of the stars are projected onto each frame by its world coordinate system:
+	TrackWCS
+)
+
+/*****************************************************************************************************************/
+
+// Returns the name of the tracking method, e.g., "registration".
+func (t Tracking) String() string {
+	switch t {
+	case TrackRegistration:
+		return "registration"
+	case TrackWCS:
+		return "wcs"
+	default:
+		return "unknown"
+	}
+}
+
+/*****************************************************************************************************************/
+
+// The flags of the photometry of a comparison star which exclude it from the ensemble of a frame:
+const rejectedFlags = photometry.FlagSaturated | photometry.FlagEdge | photometry.FlagBadPixel | photometry.FlagNoSky | photometry.FlagNonPositiveFlux
+
+/*****************************************************************************************************************/
+
+// Star is a target or comparison star of the light curve.
+type Star struct {
+	Name string  // The name of the star, e.g., "WASP-12"
+	X    float64 // The x position of the star in the reference frame, in pixels (for registration tracking)
+	Y    float64 // The y position of the star in the reference frame, in pixels (for registration tracking)
+	RA   float64 // The Right Ascension of the star, in degrees (for WCS tracking and barycentric times)
+	Dec  float64 // The Declination of the star, in degrees (for WCS tracking and barycentric times)
+}
+
+/*****************************************************************************************************************/
+
+// Params describes how the light curve is generated from the series of frames.
+type Params struct {
+	Tracking     Tracking                  // How the positions of the stars are found in each frame
+	Reference    int                       // The index of the reference frame for registration tracking
+	Recentre     bool                      // Whether each aperture is recentred on the centroid of its star
+	Aperture     photometry.ApertureParams // The aperture photometry of each star (a zero exposure is that of the frame)
+	StarRadius   float32                   // The radius of the star extraction, for registration tracking
+	StarSigma    float32                   // The detection threshold of the star extraction, for registration tracking
+	StarInOut    float32                   // The HFR in-out ratio of the star extraction, for registration tracking
+	Registration registration.Params       // The registration of each frame onto the reference frame
+	TimeSystem   TimeSystem                // The time system of the light curve
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default light curve parameters, i.e., registration tracking onto the first frame, with recentred
+// apertures and barycentric times.
+func DefaultParams() Params {
+	return Params{
+		Tracking:     TrackRegistration,
+		Reference:    0,
+		Recentre:     true,
+		Aperture:     photometry.DefaultApertureParams(),
+		StarRadius:   16,
+		StarSigma:    5,
+		StarInOut:    2,
+		Registration: registration.DefaultParams(),
+		TimeSystem:   TimeBJD,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Point is a point of the light curve, i.e., the differential photometry of the target in a frame.
+type Point struct {
+	Index          int                             // The index of the frame in the series
+	Filename       string                          // The filename of the frame, if any
+	Time           float64                         // The mid-exposure time of the frame, in the time system of the light curve
+	Magnitude      float64                         // The differential magnitude of the target, i.e., target - ensemble
+	MagnitudeError float64                         // The uncertainty of the differential magnitude
+	Ensemble       float64                         // The instrumental magnitude of the ensemble of comparison stars
+	EnsembleError  float64                         // The uncertainty of the ensemble magnitude
+	Used           int                             // The number of comparison stars in the ensemble of the frame
+	Target         photometry.AperturePhotometry   // The aperture photometry of the target
+	Comparisons    []photometry.AperturePhotometry // The aperture photometry of each comparison star (NaN if not measured)
+}
+
+/*****************************************************************************************************************/
+
+// Skipped is a frame of the series for which no point of the light curve could be measured.
+type Skipped struct {
+	Index    int    // The index of the frame in the series
+	Filename string // The filename of the frame, if any
+	Err      error  // Why the frame was skipped
+}
+
+/*****************************************************************************************************************/
+
+// LightCurve is the differential photometry of a target across a series of frames.
+type LightCurve struct {
+	Target      Star       // The target star
+	Comparisons []Star     // The comparison stars
+	TimeSystem  TimeSystem // The time system of the points
+	Points      []Point    // The points of the light curve, in time order
+	Skipped     []Skipped  // The frames of the series which were skipped, in series order
+}
+
+/*****************************************************************************************************************/
+
+/*
+Generate()
+
+Generates the light curve of the target from the series of frames, by differential aperture
+photometry against an ensemble of comparison stars.
+
+The stars are tracked across the frames either by registration onto the reference frame, or by
+the world coordinate system of each frame, and measured by aperture photometry. For each frame,
+the ensemble is the weighted mean offset of the comparison stars from their median magnitude
+across the series, such that a comparison star missing from (or flagged in) some frames does
+not bias the ensemble. The differential magnitude of the target is relative to the combined
+magnitude of the comparison stars.
+
+The time of each point is the mid-exposure time (from DATE-OBS and EXPOSURE) as a Julian Date,
+or a Barycentric Julian Date in the direction of the target.
+*/
+func Generate(frames []fits.FITSImage, target Star, comparisons []Star, params Params) (*LightCurve, error) {
+	if len(frames) == 0 {
+		return nil, errors.New("at least one frame is required to generate a light curve")
+	}
+
+	if len(comparisons) == 0 {
+		return nil, errors.New("at least one comparison star is required for differential photometry")
+	}
+
+	if params.Reference < 0 || params.Reference >= len(frames) {
+		return nil, fmt.Errorf("the reference frame %d is not in the series of %d frames", params.Reference, len(frames))
+	}
+
+	stars := append([]Star{target}, comparisons...)
+
+	track, err := getTracker(frames, stars, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ra, dec := target.RA, target.Dec
+
+	// Barycentric times require the equatorial coordinates of the target, e.g., from the reference frame:
+	if params.TimeSystem == TimeBJD && ra == 0 && dec == 0 {
+		w, err := wcs.NewWCSFromHeader(frames[params.Reference].Header)
+
+		if err != nil {
+			return nil, fmt.Errorf("the equatorial coordinates of the target are required for barycentric times: %w", err)
+		}
+
+		ra, dec = w.PixelToEquatorial(target.X, target.Y)
+	}
+
+	lc := &LightCurve{
+		Target:      target,
+		Comparisons: comparisons,
+		TimeSystem:  params.TimeSystem,
+		Points:      make([]Point, 0, len(frames)),
+		Skipped:     make([]Skipped, 0),
+	}
+
+	for i := range frames {
+		f := &frames[i]
+
+		point, err := measureFrame(f, i, track, ra, dec, params)
+
+		if err != nil {
+			lc.Skipped = append(lc.Skipped, Skipped{Index: i, Filename: f.Filename, Err: err})
+			continue
+		}
+
+		lc.Points = append(lc.Points, *point)
+	}
+
+	lc.computeEnsemble()
+
+	sort.SliceStable(lc.Points, func(a, b int) bool {
+		return lc.Points[a].Time < lc.Points[b].Time
+	})
+
+	sort.SliceStable(lc.Skipped, func(a, b int) bool {
+		return lc.Skipped[a].Index < lc.Skipped[b].Index
+	})
+
+	if len(lc.Points) == 0 {
+		return lc, errors.New("no point of the light curve could be measured")
+	}
+
+	return lc, nil
+}
+
+/*****************************************************************************************************************/
+
+// Measures the aperture photometry of the target and comparison stars in the frame, at the mid-exposure time.
+func measureFrame(f *fits.FITSImage, index int, track tracker, ra float64, dec float64, params Params) (*Point, error) {
+	mid, err := f.GetMidExposureTime()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain the mid-exposure time: %w", err)
+	}
+
+	xs, ys, ok := f.GetDimensions()
+
+	if !ok || len(f.Data) != xs*ys {
+		return nil, fmt.Errorf("the frame data of %d pixels does not match the dimensions %dx%d", len(f.Data), xs, ys)
+	}
+
+	positions, err := track(index, f, xs, ys)
+
+	if err != nil {
+		return nil, err
+	}
+
+	aperture := params.Aperture
+
+	if aperture.Exposure <= 0 {
+		aperture.Exposure = f.GetExposureTime()
+	}
+
+	point := &Point{
+		Index:       index,
+		Filename:    f.Filename,
+		Time:        GetJulianDate(mid),
+		Comparisons: make([]photometry.AperturePhotometry, len(positions)-1),
+	}
+
+	if params.TimeSystem == TimeBJD {
+		point.Time = GetBarycentricJulianDate(mid, ra, dec)
+	}
+
+	for j, p := range positions {
+		x, y := p.X, p.Y
+
+		if params.Recentre {
+			x, y = recentre(f.Data, xs, ys, x, y, aperture)
+		}
+
+		m, err := photometry.MeasureAperture(f.Data, xs, ys, x, y, aperture)
+
+		if j == 0 {
+			if err != nil {
+				return nil, fmt.Errorf("failed to measure the target: %w", err)
+			}
+
+			if math.IsNaN(m.Magnitude) {
+				return nil, fmt.Errorf("the magnitude of the target is undefined (flags %b)", m.Flags)
+			}
+
+			point.Target = *m
+
+			continue
+		}
+
+		if err != nil {
+			nan := math.NaN()
+
+			m = &photometry.AperturePhotometry{X: x, Y: y, RA: nan, Dec: nan, Flux: nan, FluxError: nan, Magnitude: nan, MagnitudeError: nan}
+		}
+
+		point.Comparisons[j-1] = *m
+	}
+
+	return point, nil
+}
+
+/*****************************************************************************************************************/
+
+// Computes the ensemble of comparison stars for each point, and the differential magnitude of the target, skipping
+// the points without any valid comparison star.
+func (lc *LightCurve) computeEnsemble() {
+	isValid := func(p photometry.AperturePhotometry) bool {
+		return p.Flags&rejectedFlags == 0 && !math.IsNaN(p.Magnitude) && p.MagnitudeError > 0
+	}
+
+	// The median magnitude of each comparison star across the series:
+	medians := make([]float64, len(lc.Comparisons))
+
+	combined := 0.0
+
+	for j := range lc.Comparisons {
+		magnitudes := make([]float64, 0, len(lc.Points))
+
+		for _, p := range lc.Points {
+			if isValid(p.Comparisons[j]) {
+				magnitudes = append(magnitudes, p.Comparisons[j].Magnitude)
+			}
+		}
+
+		medians[j] = math.NaN()
+
+		if len(magnitudes) > 0 {
+			medians[j] = qsort.MedianFloat64(magnitudes)
+
+			combined += math.Pow(10, -0.4*medians[j])
+		}
+	}
+
+	// The combined magnitude of the comparison stars, i.e., of the sum of their median fluxes:
+	reference := -2.5 * math.Log10(combined)
+
+	points := lc.Points[:0]
+
+	for _, p := range lc.Points {
+		weights, offset := 0.0, 0.0
+
+		for j, c := range p.Comparisons {
+			if math.IsNaN(medians[j]) || !isValid(c) {
+				continue
+			}
+
+			w := 1 / (c.MagnitudeError * c.MagnitudeError)
+
+			weights += w
+
+			offset += w * (c.Magnitude - medians[j])
+
+			p.Used++
+		}
+
+		if weights == 0 {
+			lc.Skipped = append(lc.Skipped, Skipped{Index: p.Index, Filename: p.Filename, Err: errors.New("no comparison star could be measured")})
+			continue
+		}
+
+		p.Ensemble = reference + offset/weights
+
+		p.EnsembleError = math.Sqrt(1 / weights)
+
+		p.Magnitude = p.Target.Magnitude - p.Ensemble
+
+		p.MagnitudeError = math.Hypot(p.Target.MagnitudeError, p.EnsembleError)
+
+		points = append(points, p)
+	}
+
+	lc.Points = points
+}
+
+/*****************************************************************************************************************/
+
+// A tracker obtains the pixel positions of the stars in the frame with the given index.
+type tracker func(index int, f *fits.FITSImage, xs int, ys int) ([]registration.Point, error)
+
+/*****************************************************************************************************************/
+
+// Obtains the tracker of the stars across the frames.
+func getTracker(frames []fits.FITSImage, stars []Star, params Params) (tracker, error) {
+	switch params.Tracking {
+	case TrackRegistration:
+		reference := &frames[params.Reference]
+
+		xs, ys, ok := reference.GetDimensions()
+
+		if !ok || len(reference.Data) != xs*ys {
+			return nil, fmt.Errorf("the reference frame data of %d pixels does not match the dimensions %dx%d", len(reference.Data), xs, ys)
+		}
+
+		referenceStars := findStars(reference, xs, ys, params)
+
+		positions := make([]registration.Point, len(stars))
+
+		for i, s := range stars {
+			positions[i] = registration.Point{X: s.X, Y: s.Y}
+		}
+
+		return func(index int, f *fits.FITSImage, xs int, ys int) ([]registration.Point, error) {
+			if index == params.Reference {
+				return positions, nil
+			}
+
+			result, err := registration.Register(referenceStars, findStars(f, xs, ys, params), params.Registration)
+
+			if err != nil {
+				return nil, fmt.Errorf("failed to register onto the reference frame: %w", err)
+			}
+
+			// The registration maps the frame onto the reference frame, so its inverse locates the stars in the frame:
+			inverse, err := result.Transform.Inverse()
+
+			if err != nil {
+				return nil, err
+			}
+
+			tracked := make([]registration.Point, len(positions))
+
+			for i, p := range positions {
+				tracked[i] = inverse.ApplyPoint(p)
+			}
+
+			return tracked, nil
+		}, nil
+	case TrackWCS:
+		return func(index int, f *fits.FITSImage, xs int, ys int) ([]registration.Point, error) {
+			w, err := wcs.NewWCSFromHeader(f.Header)
+
+			if err != nil {
+				return nil, err
+			}
+
+			tracked := make([]registration.Point, len(stars))
+
+			for i, s := range stars {
+				x, y, err := w.EquatorialToPixel(s.RA, s.Dec)
+
+				if err != nil {
+					return nil, err
+				}
+
+				tracked[i] = registration.Point{X: x, Y: y}
+			}
+
+			return tracked, nil
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown tracking method: %d", params.Tracking)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Finds the stars of the frame, for registration.
+func findStars(f *fits.FITSImage, xs int, ys int, params Params) []photometry.Star {
+	adu := f.ADU
+
+	if adu <= 0 {
+		adu = 65535
+	}
+
+	extractor := photometry.NewStarsExtractor(f.Data, xs, ys, params.StarRadius, adu)
+
+	return extractor.FindStars(stats.NewStats(f.Data, adu, xs), params.StarSigma, params.StarInOut)
+}
+
+/*****************************************************************************************************************/
+
+// Recentres the aperture on the centroid of the sky-subtracted pixels within it, returning the original position
+// if the centroid wanders beyond the aperture, e.g., towards a brighter neighbour.
+func recentre(data []float32, xs int, ys int, x float64, y float64, params photometry.ApertureParams) (float64, float64) {
+	radius := params.Aperture.Radius
+
+	cx, cy := x, y
+
+	for iteration := 0; iteration < 5; iteration++ {
+		m, err := photometry.MeasureAperture(data, xs, ys, cx, cy, params)
+
+		if err != nil {
+			return x, y
+		}
+
+		sum, sx, sy := 0.0, 0.0, 0.0
+
+		for j := max(int(cy-radius), 0); j <= min(int(cy+radius)+1, ys-1); j++ {
+			for i := max(int(cx-radius), 0); i <= min(int(cx+radius)+1, xs-1); i++ {
+				if math.Hypot(float64(i)-cx, float64(j)-cy) > radius {
+					continue
+				}
+
+				v := float64(data[j*xs+i]) - m.Sky
+
+				// Only pixels significantly above the sky contribute, such that the noise does not pull the centroid:
+				if math.IsNaN(v) || v <= 3*m.SkySigma {
+					continue
+				}
+
+				sum += v
+				sx += v * float64(i)
+				sy += v * float64(j)
+			}
+		}
+
+		if sum == 0 {
+			return x, y
+		}
+
+		nx, ny := sx/sum, sy/sum
+
+		if math.Hypot(nx-x, ny-y) > radius {
+			return x, y
+		}
+
+		shift := math.Hypot(nx-cx, ny-cy)
+
+		cx, cy = nx, ny
+
+		if shift < 0.01 {
+			break
+		}
+	}
+
+	return cx, cy
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,303 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/lightcurve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package lightcurve
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/internal/starfield"
+)
+
+/*****************************************************************************************************************/
+
+// The start of the test series of frames:
+var start = time.Date(2024, 10, 1, 22, 0, 0, 0, time.UTC)
+
+/*****************************************************************************************************************/
+
+// Creates the test series of frames: a field of stars shifted by up to a few pixels in each frame, observed through
+// varying transparency, where the target (the first star) dims by the given fraction in each frame, and each frame
+// has a world coordinate system consistent with its shift.
+func getTestSeries(dims []float64) ([]fits.FITSImage, []Star) {
+	xs, ys := 200, 200
+
+	field := rand.New(rand.NewSource(42))
+
+	stars := make([]Star, 40)
+
+	fluxes := make([]float64, len(stars))
+
+	for i := range stars {
+		stars[i] = Star{X: 20 + field.Float64()*160, Y: 20 + field.Float64()*160}
+
+		fluxes[i] = 20000 + field.Float64()*80000
+	}
+
+	// The target and comparison stars are well separated from the edges and each other:
+	stars[0], stars[1], stars[2], stars[3] = Star{Name: "target", X: 100, Y: 100}, Star{X: 50, Y: 60}, Star{X: 150, Y: 50}, Star{X: 60, Y: 150}
+
+	fluxes[0], fluxes[1], fluxes[2], fluxes[3] = 60000, 80000, 70000, 90000
+
+	frames := make([]fits.FITSImage, len(dims))
+
+	noise := rand.New(rand.NewSource(7))
+
+	for n, dim := range dims {
+		dx, dy := float64(n%3)-1, float64(n%2)*2-1
+
+		transparency := 1 - 0.1*float64(n%4)
+
+		data := make([]float32, xs*ys)
+
+		for i := range data {
+			data[i] = float32(1000 + noise.NormFloat64()*5)
+		}
+
+		for i, s := range stars {
+			flux := fluxes[i] * transparency
+
+			if i == 0 {
+				flux *= 1 - dim
+			}
+
+			starfield.AddStar(data, xs, ys, s.X+dx, s.Y+dy, flux)
+		}
+
+		f := fits.NewFITSImage(2, int32(xs), int32(ys), 65535)
+
+		f.Data = data
+
+		f.Naxisn = []int32{int32(xs), int32(ys)}
+
+		f.Filename = "light_" + string(rune('a'+n)) + ".fits"
+
+		f.Header.Set("DATE-OBS", start.Add(time.Duration(n)*2*time.Minute).Format("2006-01-02T15:04:05"), "The date of the observation")
+
+		f.Header.Set("EXPOSURE", float32(60), "The exposure time (s)")
+
+		// A world coordinate system of 1"/px, shifted with the frame:
+		f.Header.Set("CTYPE1", "RA---TAN", "")
+		f.Header.Set("CTYPE2", "DEC--TAN", "")
+		f.Header.Set("CRPIX1", 101.0+dx, "")
+		f.Header.Set("CRPIX2", 101.0+dy, "")
+		f.Header.Set("CRVAL1", 120.0, "")
+		f.Header.Set("CRVAL2", 30.0, "")
+		f.Header.Set("CD1_1", -1/3600.0, "")
+		f.Header.Set("CD2_2", 1/3600.0, "")
+
+		frames[n] = *f
+	}
+
+	return frames, stars[:4]
+}
+
+/*****************************************************************************************************************/
+
+func TestGenerateRegistration(t *testing.T) {
+	dims := []float64{0, 0, 0.01, 0.02, 0.02, 0.01, 0}
+
+	frames, stars := getTestSeries(dims)
+
+	params := DefaultParams()
+
+	params.TimeSystem = TimeJD
+
+	lc, err := Generate(frames, stars[0], stars[1:], params)
+
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	if len(lc.Points) != len(dims) {
+		t.Fatalf("expected %d points, but got %d (skipped %v)", len(dims), len(lc.Points), lc.Skipped)
+	}
+
+	baseline := lc.Points[0].Magnitude
+
+	for i, p := range lc.Points {
+		// The dimming of the target, independent of the transparency:
+		expected := -2.5 * math.Log10(1-dims[i])
+
+		if math.Abs(p.Magnitude-baseline-expected) > 0.003 {
+			t.Errorf("expected a differential magnitude of %f in frame %d, but got %f", expected, i, p.Magnitude-baseline)
+		}
+
+		if p.Used != 3 {
+			t.Errorf("expected 3 comparison stars in frame %d, but got %d", i, p.Used)
+		}
+
+		if p.MagnitudeError <= 0 || p.MagnitudeError > 0.02 {
+			t.Errorf("expected a magnitude error in (0, 0.02] in frame %d, but got %f", i, p.MagnitudeError)
+		}
+
+		// The mid-exposure time, 30s after DATE-OBS:
+		mid := start.Add(time.Duration(i)*2*time.Minute + 30*time.Second)
+
+		if math.Abs(p.Time-GetJulianDate(mid)) > 1e-9 {
+			t.Errorf("expected the time %f in frame %d, but got %f", GetJulianDate(mid), i, p.Time)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGenerateWCS(t *testing.T) {
+	dims := []float64{0, 0.01, 0.02, 0}
+
+	frames, stars := getTestSeries(dims)
+
+	// The equatorial coordinates of each star, from the world coordinate system of an unshifted frame:
+	for i := range stars {
+		stars[i].RA = 120 - (stars[i].X+1-101)/3600/math.Cos(30*math.Pi/180)
+		stars[i].Dec = 30 + (stars[i].Y+1-101)/3600
+	}
+
+	params := DefaultParams()
+
+	params.Tracking = TrackWCS
+
+	lc, err := Generate(frames, stars[0], stars[1:], params)
+
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	if len(lc.Points) != len(dims) {
+		t.Fatalf("expected %d points, but got %d (skipped %v)", len(dims), len(lc.Points), lc.Skipped)
+	}
+
+	for i, p := range lc.Points {
+		expected := -2.5 * math.Log10(1-dims[i])
+
+		if math.Abs(p.Magnitude-lc.Points[0].Magnitude-expected) > 0.003 {
+			t.Errorf("expected a differential magnitude of %f in frame %d, but got %f", expected, i, p.Magnitude-lc.Points[0].Magnitude)
+		}
+
+		// The barycentric correction is at most ~8.3 minutes:
+		mid := GetJulianDate(start.Add(time.Duration(i)*2*time.Minute + 30*time.Second))
+
+		if math.Abs(p.Time-mid) > 510.0/86400 || p.Time == mid {
+			t.Errorf("expected a barycentric correction of at most 510s in frame %d, but got %fs", i, (p.Time-mid)*86400)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGenerateSkipsFrames(t *testing.T) {
+	frames, stars := getTestSeries([]float64{0, 0, 0})
+
+	// A frame without a DATE-OBS cannot be timed:
+	delete(frames[1].Header.Strings, "DATE-OBS")
+
+	params := DefaultParams()
+
+	params.TimeSystem = TimeJD
+
+	lc, err := Generate(frames, stars[0], stars[1:], params)
+
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	if len(lc.Points) != 2 || len(lc.Skipped) != 1 || lc.Skipped[0].Index != 1 {
+		t.Errorf("expected 2 points and frame 1 skipped, but got %d points and %v skipped", len(lc.Points), lc.Skipped)
+	}
+
+	// Barycentric times require the equatorial coordinates of the target, here from the world coordinate system:
+	params.TimeSystem = TimeBJD
+
+	for i := range frames {
+		delete(frames[i].Header.Strings, "CTYPE1")
+		delete(frames[i].Header.Floats, "CRVAL1")
+	}
+
+	if _, err := Generate(frames, stars[0], stars[1:], params); err == nil {
+		t.Errorf("expected an error for barycentric times without the equatorial coordinates of the target")
+	}
+
+	if _, err := Generate(frames, stars[0], nil, params); err == nil {
+		t.Errorf("expected an error without comparison stars")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestLightCurveWriteCSV(t *testing.T) {
+	frames, stars := getTestSeries([]float64{0, 0.01})
+
+	params := DefaultParams()
+
+	params.TimeSystem = TimeJD
+
+	lc, err := Generate(frames, stars[0], stars[1:], params)
+
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	var buf bytes.Buffer
+
+	if err := lc.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() failed: %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+
+	if err != nil {
+		t.Fatalf("failed to read the CSV: %v", err)
+	}
+
+	if len(records) != 3 || records[0][0] != "jd_utc" || records[2][13] != "light_b.fits" {
+		t.Errorf("expected a header row and 2 points, but got %v", records)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestLightCurveGetFITSBinaryTable(t *testing.T) {
+	frames, stars := getTestSeries([]float64{0, 0.01, 0.02})
+
+	lc, err := Generate(frames, stars[0], stars[1:], DefaultParams())
+
+	if err != nil {
+		t.Fatalf("Generate() failed: %v", err)
+	}
+
+	table, err := lc.GetFITSBinaryTable()
+
+	if err != nil {
+		t.Fatalf("GetFITSBinaryTable() failed: %v", err)
+	}
+
+	if table.Rows != 3 || table.Header.Strings["TIMESYS"].Value != "TDB" || table.Header.Strings["OBJECT"].Value != "target" {
+		t.Errorf("expected 3 rows of barycentric times of the target, but got %d rows, %v", table.Rows, table.Header.Strings)
+	}
+
+	buf, err := table.WriteToBuffer(nil)
+
+	if err != nil {
+		t.Fatalf("WriteToBuffer() failed: %v", err)
+	}
+
+	if buf.Len()%2880 != 0 || !strings.Contains(buf.String(), "XTENSION= 'BINTABLE'") {
+		t.Errorf("expected a FITS file of 2880 byte blocks with a binary table extension")
+	}
+}
+
+/*****************************************************************************************************************/
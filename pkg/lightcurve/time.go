@@ -0,0 +1,208 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/lightcurve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package lightcurve
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"time"
+)
+
+/*****************************************************************************************************************/
+
+// TimeSystem is the time system of the light curve.
+type TimeSystem int
+
+/*****************************************************************************************************************/
+
+const (
+	// The Julian Date of the mid-exposure time, in UTC:
+	TimeJD TimeSystem = iota
+	// The Barycentric Julian Date of the mid-exposure time, in TDB, i.e., corrected for the light travel time
+	// across the solar system in the direction of the target:
+	TimeBJD
+)
+
+/*****************************************************************************************************************/
+
+// Returns the name of the time system, e.g., "BJD_TDB".
+func (s TimeSystem) String() string {
+	switch s {
+	case TimeJD:
+		return "JD_UTC"
+	case TimeBJD:
+		return "BJD_TDB"
+	default:
+		return "unknown"
+	}
+}
+
+/*****************************************************************************************************************/
+
+// The Julian Date of the Unix epoch, 1970-01-01T00:00:00Z:
+const unixEpochJD = 2440587.5
+
+/*****************************************************************************************************************/
+
+// The Julian Date of the J2000.0 epoch, 2000-01-01T12:00:00 TT:
+const j2000 = 2451545.0
+
+/*****************************************************************************************************************/
+
+// The light travel time across one astronomical unit, in seconds:
+const auLightTime = 499.004784
+
+/*****************************************************************************************************************/
+
+// The dates from which each count of leap seconds (TAI - UTC) applies, since 1972:
+var leapSeconds = []struct {
+	date    time.Time
+	seconds float64
+}{
+	{time.Date(1972, 1, 1, 0, 0, 0, 0, time.UTC), 10},
+	{time.Date(1972, 7, 1, 0, 0, 0, 0, time.UTC), 11},
+	{time.Date(1973, 1, 1, 0, 0, 0, 0, time.UTC), 12},
+	{time.Date(1974, 1, 1, 0, 0, 0, 0, time.UTC), 13},
+	{time.Date(1975, 1, 1, 0, 0, 0, 0, time.UTC), 14},
+	{time.Date(1976, 1, 1, 0, 0, 0, 0, time.UTC), 15},
+	{time.Date(1977, 1, 1, 0, 0, 0, 0, time.UTC), 16},
+	{time.Date(1978, 1, 1, 0, 0, 0, 0, time.UTC), 17},
+	{time.Date(1979, 1, 1, 0, 0, 0, 0, time.UTC), 18},
+	{time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC), 19},
+	{time.Date(1981, 7, 1, 0, 0, 0, 0, time.UTC), 20},
+	{time.Date(1982, 7, 1, 0, 0, 0, 0, time.UTC), 21},
+	{time.Date(1983, 7, 1, 0, 0, 0, 0, time.UTC), 22},
+	{time.Date(1985, 7, 1, 0, 0, 0, 0, time.UTC), 23},
+	{time.Date(1988, 1, 1, 0, 0, 0, 0, time.UTC), 24},
+	{time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC), 25},
+	{time.Date(1991, 1, 1, 0, 0, 0, 0, time.UTC), 26},
+	{time.Date(1992, 7, 1, 0, 0, 0, 0, time.UTC), 27},
+	{time.Date(1993, 7, 1, 0, 0, 0, 0, time.UTC), 28},
+	{time.Date(1994, 7, 1, 0, 0, 0, 0, time.UTC), 29},
+	{time.Date(1996, 1, 1, 0, 0, 0, 0, time.UTC), 30},
+	{time.Date(1997, 7, 1, 0, 0, 0, 0, time.UTC), 31},
+	{time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC), 32},
+	{time.Date(2006, 1, 1, 0, 0, 0, 0, time.UTC), 33},
+	{time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC), 34},
+	{time.Date(2012, 7, 1, 0, 0, 0, 0, time.UTC), 35},
+	{time.Date(2015, 7, 1, 0, 0, 0, 0, time.UTC), 36},
+	{time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC), 37},
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the Julian Date of the (UTC) time.
+func GetJulianDate(t time.Time) float64 {
+	return unixEpochJD + float64(t.UnixNano())/(86400*1e9)
+}
+
+/*****************************************************************************************************************/
+
+/*
+GetBarycentricJulianDate()
+
+Obtains the Barycentric Julian Date (in TDB) of the (UTC) time, for a target at the equatorial
+coordinates { ra, dec } (in degrees), i.e., the time at which the light observed at the Earth
+would have arrived at the barycentre of the solar system.
+
+The position of the Earth is derived from the low-precision solar coordinates of the
+Astronomical Almanac, with the displacement of the Sun from the barycentre by Jupiter and
+Saturn, such that the correction is accurate to about a second, which is ample for transit
+and variable star timing.
+
+@see Eastman, J., Siverd, R. & Gaudi, B. S. (2010). Achieving Better Than 1 Minute Accuracy in the Heliocentric and Barycentric Julian Dates. PASP, 122, 935
+*/
+func GetBarycentricJulianDate(t time.Time, ra float64, dec float64) float64 {
+	jd := GetJulianDate(t)
+
+	// TT = UTC + (TAI - UTC) + 32.184s:
+	tt := jd + (getLeapSeconds(t)+32.184)/86400
+
+	n := tt - j2000
+
+	// The mean anomaly of the Sun (in radians), for TDB - TT and the solar coordinates:
+	g := (357.528 + 0.9856003*n) * math.Pi / 180
+
+	// TDB - TT is a periodic term of at most 1.7ms:
+	tdb := tt + 0.001657*math.Sin(g)/86400
+
+	x, y, z := getBarycentricEarthPosition(n)
+
+	alpha, delta := ra*math.Pi/180, dec*math.Pi/180
+
+	// The Rømer delay, i.e., the projection of the position of the Earth onto the direction of the target:
+	delay := (x*math.Cos(delta)*math.Cos(alpha) + y*math.Cos(delta)*math.Sin(alpha) + z*math.Sin(delta)) * auLightTime
+
+	return tdb + delay/86400
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the number of leap seconds (TAI - UTC) at the time, i.e., 10s before 1972.
+func getLeapSeconds(t time.Time) float64 {
+	seconds := 10.0
+
+	for _, l := range leapSeconds {
+		if t.Before(l.date) {
+			break
+		}
+
+		seconds = l.seconds
+	}
+
+	return seconds
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the equatorial (J2000) position of the Earth relative to the barycentre of the solar system, in
+// astronomical units, for the number of days since J2000.0.
+func getBarycentricEarthPosition(n float64) (float64, float64, float64) {
+	radians := math.Pi / 180
+
+	// The mean longitude and mean anomaly of the Sun:
+	l := (280.460 + 0.9856474*n) * radians
+
+	g := (357.528 + 0.9856003*n) * radians
+
+	// The ecliptic longitude and distance of the Sun, with the longitude precessed from the equinox of date to J2000.0:
+	lambda := l + (1.915*math.Sin(g)+0.020*math.Sin(2*g)-0.0000382*n)*radians
+
+	r := 1.00014 - 0.01671*math.Cos(g) - 0.00014*math.Cos(2*g)
+
+	// The position of the Earth relative to the Sun, in ecliptic coordinates:
+	x, y := -r*math.Cos(lambda), -r*math.Sin(lambda)
+
+	// The displacement of the Sun from the barycentre by Jupiter and Saturn, on circular orbits:
+	planets := []struct {
+		longitude float64 // The mean longitude at J2000.0, in degrees
+		rate      float64 // The rate of the mean longitude, in degrees per day
+		distance  float64 // The semi-major axis, in astronomical units
+		mass      float64 // The ratio of the mass of the planet to that of the Sun
+	}{
+		{34.39644, 0.08308682, 5.20289, 1 / 1047.348},
+		{49.95424, 0.03347005, 9.53668, 1 / 3497.9},
+	}
+
+	for _, p := range planets {
+		longitude := (p.longitude + p.rate*n) * radians
+
+		x -= p.mass * p.distance * math.Cos(longitude)
+
+		y -= p.mass * p.distance * math.Sin(longitude)
+	}
+
+	// Rotate the ecliptic coordinates onto the equator, by the obliquity of the ecliptic at J2000.0:
+	epsilon := 23.439291 * radians
+
+	return x, y * math.Cos(epsilon), y * math.Sin(epsilon)
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,100 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/lightcurve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package lightcurve
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+/*****************************************************************************************************************/
+
+func TestGetJulianDate(t *testing.T) {
+	jd := GetJulianDate(time.Date(2000, 1, 1, 12, 0, 0, 0, time.UTC))
+
+	if jd != 2451545.0 {
+		t.Errorf("expected the Julian Date 2451545.0, but got %f", jd)
+	}
+
+	jd = GetJulianDate(time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	if jd != 2440587.5 {
+		t.Errorf("expected the Julian Date 2440587.5, but got %f", jd)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetLeapSeconds(t *testing.T) {
+	cases := []struct {
+		date    time.Time
+		seconds float64
+	}{
+		{time.Date(1960, 1, 1, 0, 0, 0, 0, time.UTC), 10},
+		{time.Date(2008, 12, 31, 23, 59, 59, 0, time.UTC), 33},
+		{time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC), 34},
+		{time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), 37},
+	}
+
+	for _, c := range cases {
+		if s := getLeapSeconds(c.date); s != c.seconds {
+			t.Errorf("expected %f leap seconds at %s, but got %f", c.seconds, c.date, s)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetBarycentricJulianDate(t *testing.T) {
+	// At the March equinox the Sun is at RA 0°, so the Earth is (almost) 1 AU towards RA 180°:
+	date := time.Date(2024, 3, 20, 3, 6, 0, 0, time.UTC)
+
+	jd := GetJulianDate(date)
+
+	// TT - UTC is 69.184s in 2024:
+	tt := jd + 69.184/86400
+
+	delay := (GetBarycentricJulianDate(date, 180, 0) - tt) * 86400
+
+	if math.Abs(delay-496) > 5 {
+		t.Errorf("expected a light travel time of ~+496s towards RA 180°, but got %fs", delay)
+	}
+
+	delay = (GetBarycentricJulianDate(date, 0, 0) - tt) * 86400
+
+	if math.Abs(delay+496) > 5 {
+		t.Errorf("expected a light travel time of ~-496s towards RA 0°, but got %fs", delay)
+	}
+
+	// The Earth is in the ecliptic, so the delay towards the ecliptic pole is that of the Sun's barycentric motion:
+	delay = (GetBarycentricJulianDate(date, 270, 66.56) - tt) * 86400
+
+	if math.Abs(delay) > 5 {
+		t.Errorf("expected a light travel time of ~0s towards the ecliptic pole, but got %fs", delay)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetBarycentricEarthPosition(t *testing.T) {
+	for n := 0.0; n < 3650; n += 73 {
+		x, y, z := getBarycentricEarthPosition(n)
+
+		r := math.Sqrt(x*x + y*y + z*z)
+
+		if r < 0.97 || r > 1.03 {
+			t.Errorf("expected the Earth within ~1 AU of the barycentre on day %f, but got %f AU", n, r)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
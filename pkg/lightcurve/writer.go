@@ -0,0 +1,146 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/lightcurve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package lightcurve
+
+/*****************************************************************************************************************/
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+// Writes the points of the light curve as CSV, with a header row, and the time column named by its time system.
+func (lc *LightCurve) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{
+		strings.ToLower(lc.TimeSystem.String()), "magnitude", "magnitude_error", "ensemble", "ensemble_error", "comparisons",
+		"flux", "flux_error", "sky", "x", "y", "flags", "index", "filename",
+	})
+
+	if err != nil {
+		return err
+	}
+
+	format := func(v float64, precision int) string {
+		return strconv.FormatFloat(v, 'f', precision, 64)
+	}
+
+	for _, p := range lc.Points {
+		err := writer.Write([]string{
+			format(p.Time, 6),
+			format(p.Magnitude, 5),
+			format(p.MagnitudeError, 5),
+			format(p.Ensemble, 5),
+			format(p.EnsembleError, 5),
+			strconv.Itoa(p.Used),
+			format(p.Target.Flux, 2),
+			format(p.Target.FluxError, 2),
+			format(p.Target.Sky, 2),
+			format(p.Target.X, 3),
+			format(p.Target.Y, 3),
+			strconv.Itoa(int(p.Target.Flags)),
+			strconv.Itoa(p.Index),
+			p.Filename,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the points of the light curve as a FITS binary table, named LIGHTCURVE, with the time system recorded in
+// the TIMESYS keyword, e.g., to be written by FITSBinaryTable.WriteToBuffer().
+func (lc *LightCurve) GetFITSBinaryTable() (*fits.FITSBinaryTable, error) {
+	n := len(lc.Points)
+
+	var (
+		time           = make([]float64, n)
+		magnitude      = make([]float64, n)
+		magnitudeError = make([]float64, n)
+		ensemble       = make([]float64, n)
+		ensembleError  = make([]float64, n)
+		comparisons    = make([]int32, n)
+		flux           = make([]float64, n)
+		fluxError      = make([]float64, n)
+		sky            = make([]float64, n)
+		x              = make([]float64, n)
+		y              = make([]float64, n)
+		flags          = make([]int32, n)
+		index          = make([]int32, n)
+		filename       = make([]string, n)
+	)
+
+	for i, p := range lc.Points {
+		time[i], magnitude[i], magnitudeError[i] = p.Time, p.Magnitude, p.MagnitudeError
+
+		ensemble[i], ensembleError[i], comparisons[i] = p.Ensemble, p.EnsembleError, int32(p.Used)
+
+		flux[i], fluxError[i], sky[i] = p.Target.Flux, p.Target.FluxError, p.Target.Sky
+
+		x[i], y[i], flags[i] = p.Target.X, p.Target.Y, int32(p.Target.Flags)
+
+		index[i], filename[i] = int32(p.Index), p.Filename
+	}
+
+	table, err := fits.NewFITSBinaryTable("LIGHTCURVE", []fits.FITSColumn{
+		{Name: "TIME", Unit: "d", Data: time},
+		{Name: "MAG", Unit: "mag", Data: magnitude},
+		{Name: "MAG_ERR", Unit: "mag", Data: magnitudeError},
+		{Name: "ENSEMBLE", Unit: "mag", Data: ensemble},
+		{Name: "ENS_ERR", Unit: "mag", Data: ensembleError},
+		{Name: "NCOMP", Data: comparisons},
+		{Name: "FLUX", Unit: "adu", Data: flux},
+		{Name: "FLUX_ERR", Unit: "adu", Data: fluxError},
+		{Name: "SKY", Unit: "adu", Data: sky},
+		{Name: "X", Unit: "pix", Data: x},
+		{Name: "Y", Unit: "pix", Data: y},
+		{Name: "FLAGS", Data: flags},
+		{Name: "INDEX", Data: index},
+		{Name: "FILENAME", Data: filename},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch lc.TimeSystem {
+	case TimeBJD:
+		table.Header.Set("TIMESYS", "TDB", "The time scale of the TIME column")
+		table.Header.Set("TIMEREF", "SOLARSYSTEM", "The TIME column is barycentric")
+	default:
+		table.Header.Set("TIMESYS", "UTC", "The time scale of the TIME column")
+		table.Header.Set("TIMEREF", "LOCAL", "The TIME column is topocentric")
+	}
+
+	if lc.Target.Name != "" {
+		table.Header.Set("OBJECT", lc.Target.Name, "The target of the light curve")
+	}
+
+	table.Header.Set("NCOMPS", len(lc.Comparisons), "The number of comparison stars")
+
+	table.Header.Set("NPOINTS", n, "The number of points of the light curve")
+
+	return table, nil
+}
+
+/*****************************************************************************************************************/
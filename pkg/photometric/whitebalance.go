@@ -0,0 +1,212 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/photometric
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package photometric
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// WhiteReference is the reference whose colour is white once the channels are balanced.
+type WhiteReference int
+
+/*****************************************************************************************************************/
+
+const (
+	// Stars of the colour of the Sun, i.e., G2V stars, within the tolerance of the solar colour index:
+	ReferenceG2V WhiteReference = iota
+	// The integrated light of all of the stars of the field, which approximates that of an average spiral galaxy:
+	ReferenceAverageGalaxy
+)
+
+/*****************************************************************************************************************/
+
+// Returns the name of the white reference, e.g., "G2V".
+func (r WhiteReference) String() string {
+	switch r {
+	case ReferenceG2V:
+		return "G2V"
+	case ReferenceAverageGalaxy:
+		return "average galaxy"
+	default:
+		return "unknown"
+	}
+}
+
+/*****************************************************************************************************************/
+
+// The colour indices of the Sun, i.e., of a G2V star, in the Johnson and Gaia systems:
+const (
+	SolarColourBV   = 0.65 // The B−V colour index of the Sun
+	SolarColourBPRP = 0.82 // The Gaia BP−RP colour index of the Sun
+)
+
+/*****************************************************************************************************************/
+
+// WhiteBalanceParams describes how the white balance of the red, green and blue channels is found.
+type WhiteBalanceParams struct {
+	Reference WhiteReference            // The reference whose colour is white
+	Colour    float64                   // The colour index of a G2V star in the colour system of the references
+	Tolerance float64                   // The maximum difference of the colour index of a G2V reference star from that of a G2V star
+	Aperture  photometry.ApertureParams // The aperture photometry of each star in each channel
+	MinSNR    float64                   // The minimum signal-to-noise ratio of the stars in each channel
+	MinStars  int                       // The minimum number of reference stars required
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default white balance parameters, i.e., G2V stars within 0.1 of the solar B−V colour index, of SNR
+// above 20 in each channel.
+func DefaultWhiteBalanceParams() WhiteBalanceParams {
+	return WhiteBalanceParams{
+		Reference: ReferenceG2V,
+		Colour:    SolarColourBV,
+		Tolerance: 0.1,
+		Aperture:  photometry.DefaultApertureParams(),
+		MinSNR:    20,
+		MinStars:  3,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// WhiteBalance is the factors by which each of the red, green and blue channels are multiplied such that the
+// reference is white, relative to the green channel.
+type WhiteBalance struct {
+	Red       float64        // The factor of the red channel
+	Green     float64        // The factor of the green channel, i.e., unity
+	Blue      float64        // The factor of the blue channel
+	Reference WhiteReference // The reference whose colour is white
+	Stars     int            // The number of reference stars
+}
+
+/*****************************************************************************************************************/
+
+/*
+GetWhiteBalance()
+
+Finds the white balance of the red, green and blue channels (e.g., of RGGB64Exposure.GetFITSImages())
+of the (plate solved) frame from the aperture photometry of the reference stars in each channel,
+at their positions projected onto the frame, e.g., by its wcs.WCS.
+
+For the G2V reference, the factors are the median ratios of the green flux to the red and blue
+fluxes of the stars of solar colour, such that a G2V star is white. For the average galaxy
+reference, the factors are the ratios of the total green flux to the total red and blue fluxes
+of all of the stars (whose colour indices are not required), such that their integrated light,
+which approximates that of an average spiral galaxy, is white.
+
+The sky is subtracted from the fluxes of the stars, so the factors are independent of the
+background, which should be neutralised separately, e.g., by background.Estimate().
+*/
+func GetWhiteBalance(r *fits.FITSImage, g *fits.FITSImage, b *fits.FITSImage, projection photometry.Projection, references []Reference, params WhiteBalanceParams) (*WhiteBalance, error) {
+	channels := []*fits.FITSImage{r, g, b}
+
+	xs, ys, ok := g.GetDimensions()
+
+	for _, c := range channels {
+		if !ok || len(c.Data) != xs*ys {
+			return nil, fmt.Errorf("the image data of %d pixels does not match the dimensions %dx%d", len(c.Data), xs, ys)
+		}
+	}
+
+	// The fluxes of each reference star in each of the red, green and blue channels:
+	fluxes := [][3]float64{}
+
+	for _, ref := range references {
+		if params.Reference == ReferenceG2V && !(math.Abs(ref.Colour-params.Colour) <= params.Tolerance) {
+			continue
+		}
+
+		var flux [3]float64
+
+		valid := true
+
+		for k, c := range channels {
+			p, err := photometry.MeasureApertureAtEquatorial(c.Data, xs, ys, projection, ref.RA, ref.Dec, params.Aperture)
+
+			if err != nil || p.Flags&rejectedFlags != 0 || p.Flux <= 0 || p.SNR < params.MinSNR {
+				valid = false
+				break
+			}
+
+			flux[k] = p.Flux
+		}
+
+		if valid {
+			fluxes = append(fluxes, flux)
+		}
+	}
+
+	if len(fluxes) < max(params.MinStars, 1) {
+		return nil, fmt.Errorf("%d %s reference stars were measured, at least %d are required", len(fluxes), params.Reference, max(params.MinStars, 1))
+	}
+
+	w := &WhiteBalance{Green: 1, Reference: params.Reference, Stars: len(fluxes)}
+
+	switch params.Reference {
+	case ReferenceG2V:
+		red, blue := make([]float64, len(fluxes)), make([]float64, len(fluxes))
+
+		for i, f := range fluxes {
+			red[i], blue[i] = f[1]/f[0], f[1]/f[2]
+		}
+
+		w.Red, w.Blue = qsort.MedianFloat64(red), qsort.MedianFloat64(blue)
+	case ReferenceAverageGalaxy:
+		var total [3]float64
+
+		for _, f := range fluxes {
+			total[0], total[1], total[2] = total[0]+f[0], total[1]+f[1], total[2]+f[2]
+		}
+
+		w.Red, w.Blue = total[1]/total[0], total[1]/total[2]
+	default:
+		return nil, errors.New("unknown white reference")
+	}
+
+	return w, nil
+}
+
+/*****************************************************************************************************************/
+
+// Applies the white balance to copies of the red, green and blue channels, recording the factors in their headers.
+func (w *WhiteBalance) Apply(r *fits.FITSImage, g *fits.FITSImage, b *fits.FITSImage) (*fits.FITSImage, *fits.FITSImage, *fits.FITSImage) {
+	balance := func(f *fits.FITSImage, factor float64) *fits.FITSImage {
+		c := f.Copy()
+
+		for i, v := range c.Data {
+			c.Data[i] = v * float32(factor)
+		}
+
+		c.Header.Set("WBRED", w.Red, "White balance factor of the red channel")
+		c.Header.Set("WBGREEN", w.Green, "White balance factor of the green channel")
+		c.Header.Set("WBBLUE", w.Blue, "White balance factor of the blue channel")
+		c.Header.Set("WBREF", w.Reference.String(), "White balance reference")
+
+		c.Header.History = append(
+			c.Header.History,
+			fmt.Sprintf("White balanced to the %s reference from %d stars: factor %.4f", w.Reference, w.Stars, factor),
+		)
+
+		return c
+	}
+
+	return balance(r, w.Red), balance(g, w.Green), balance(b, w.Blue)
+}
+
+/*****************************************************************************************************************/
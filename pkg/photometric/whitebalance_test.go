@@ -0,0 +1,126 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/photometric
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package photometric
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+// Creates the red, green and blue channels of the references, for a detector of which a G2V star is 1.25 times as
+// bright in red and 0.8 times as bright in blue as in green, and redder stars are brighter in red and fainter in
+// blue. Returns the channels, and the total flux of the stars in each channel.
+func getTestChannels(references []Reference) (*fits.FITSImage, *fits.FITSImage, *fits.FITSImage, [3]float64) {
+	fluxes := [3][]float64{make([]float64, len(references)), make([]float64, len(references)), make([]float64, len(references))}
+
+	var total [3]float64
+
+	for i, r := range references {
+		g := 200000 * math.Pow(10, -0.4*(r.Magnitude-10))
+
+		fluxes[0][i] = g * 1.25 * math.Pow(10, 0.2*(r.Colour-SolarColourBV))
+
+		fluxes[1][i] = g
+
+		fluxes[2][i] = g * 0.8 * math.Pow(10, -0.2*(r.Colour-SolarColourBV))
+
+		for k := range total {
+			total[k] += fluxes[k][i]
+		}
+	}
+
+	return getTestImage(references, fluxes[0], 1), getTestImage(references, fluxes[1], 2), getTestImage(references, fluxes[2], 3), total
+}
+
+/*****************************************************************************************************************/
+
+func TestGetWhiteBalanceG2V(t *testing.T) {
+	references := getTestReferences()
+
+	r, g, b, _ := getTestChannels(references)
+
+	params := DefaultWhiteBalanceParams()
+
+	params.Tolerance = 0.2
+
+	w, err := GetWhiteBalance(r, g, b, testWCS, references, params)
+
+	if err != nil {
+		t.Fatalf("GetWhiteBalance() error: %v", err)
+	}
+
+	if math.Abs(w.Red-0.8) > 0.03 || w.Green != 1 || math.Abs(w.Blue-1.25) > 0.05 {
+		t.Errorf("white balance = { %f, %f, %f }, expected { 0.8, 1, 1.25 }", w.Red, w.Green, w.Blue)
+	}
+
+	if w.Stars < 3 || w.Stars >= len(references) {
+		t.Errorf("expected only the stars of solar colour to be used, but %d of %d were used", w.Stars, len(references))
+	}
+
+	// The balanced channels are scaled, and record the white balance:
+	rb, gb, bb := w.Apply(r, g, b)
+
+	if math.Abs(float64(rb.Data[1000]-r.Data[1000]*float32(w.Red))) > 1e-3 || gb.Data[1000] != g.Data[1000] || math.Abs(float64(bb.Data[1000]-b.Data[1000]*float32(w.Blue))) > 1e-3 {
+		t.Errorf("expected the channels to be scaled by the white balance")
+	}
+
+	if bb.Header.Strings["WBREF"].Value != "G2V" || math.Abs(float64(bb.Header.Floats["WBBLUE"].Value)-w.Blue) > 1e-4 {
+		t.Errorf("expected the white balance in the header")
+	}
+
+	if r.Data[1000] == rb.Data[1000] {
+		t.Errorf("expected the original channel to be unchanged")
+	}
+
+	// Without stars of solar colour, there is no G2V reference:
+	params.Colour = 5
+
+	if _, err := GetWhiteBalance(r, g, b, testWCS, references, params); err == nil {
+		t.Errorf("expected an error without stars of solar colour")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetWhiteBalanceAverageGalaxy(t *testing.T) {
+	references := getTestReferences()
+
+	r, g, b, total := getTestChannels(references)
+
+	params := DefaultWhiteBalanceParams()
+
+	params.Reference = ReferenceAverageGalaxy
+
+	// The colour indices are not required:
+	for i := range references {
+		references[i].Colour = math.NaN()
+	}
+
+	w, err := GetWhiteBalance(r, g, b, testWCS, references, params)
+
+	if err != nil {
+		t.Fatalf("GetWhiteBalance() error: %v", err)
+	}
+
+	if w.Stars != len(references) {
+		t.Errorf("expected all %d stars to be used, but %d were used", len(references), w.Stars)
+	}
+
+	if math.Abs(w.Red/(total[1]/total[0])-1) > 0.01 || math.Abs(w.Blue/(total[1]/total[2])-1) > 0.01 {
+		t.Errorf("white balance = { %f, %f, %f }, expected { %f, 1, %f }", w.Red, w.Green, w.Blue, total[1]/total[0], total[1]/total[2])
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,491 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/photometric
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package photometric
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/iris/pkg/platesolve"
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// The flags of the photometry of a star which exclude it from the calibration:
+const rejectedFlags = photometry.FlagSaturated | photometry.FlagEdge | photometry.FlagBadPixel | photometry.FlagNoSky | photometry.FlagNonPositiveFlux
+
+/*****************************************************************************************************************/
+
+// The uncertainty (in magnitudes) added in quadrature to that of each star, for the uncertainty of the catalogue
+// magnitudes, such that the brightest stars do not dominate the fit:
+const magnitudeErrorFloor = 0.01
+
+/*****************************************************************************************************************/
+
+// Reference is a star of the catalogue of the standard system, e.g., of Gaia or APASS.
+type Reference struct {
+	RA        float64 // The Right Ascension of the star, in degrees
+	Dec       float64 // The Declination of the star, in degrees
+	Magnitude float64 // The magnitude of the star in the standard band
+	Colour    float64 // The colour index of the star, e.g., B−V or BP−RP, or NaN if unknown
+}
+
+/*****************************************************************************************************************/
+
+// Creates the references of the stars of the catalogue (e.g., as used to plate solve the frame), which have no colour
+// indices, excluding the stars without a magnitude.
+func NewReferencesFromCatalogue(c *platesolve.Catalogue) []Reference {
+	references := make([]Reference, 0, len(c.Sources))
+
+	for _, s := range c.Sources {
+		if s.Magnitude >= 99 {
+			continue
+		}
+
+		references = append(references, Reference{RA: s.RA, Dec: s.Dec, Magnitude: s.Magnitude, Colour: math.NaN()})
+	}
+
+	return references
+}
+
+/*****************************************************************************************************************/
+
+// Params describes how the zero point of the frame is fitted.
+type Params struct {
+	Aperture   photometry.ApertureParams // The aperture photometry of each star (a zero exposure is that of the frame)
+	ColourTerm bool                      // Whether to fit a colour term, which requires the colour indices of the references
+	Extinction float64                   // The extinction coefficient of the band, in magnitudes per airmass (zero disables the correction)
+	MinSNR     float64                   // The minimum signal-to-noise ratio of the stars used in the fit
+	SigmaClip  float64                   // The number of standard deviations of the residuals at which stars are rejected
+	Iterations int                       // The maximum number of rejection iterations
+	MinStars   int                       // The minimum number of stars (after rejection) required for the fit
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default zero point parameters, i.e., the zero point (without a colour term or extinction correction)
+// of stars of SNR above 20, after iterative 3σ clipping.
+func DefaultParams() Params {
+	return Params{
+		Aperture:   photometry.DefaultApertureParams(),
+		ColourTerm: false,
+		Extinction: 0,
+		MinSNR:     20,
+		SigmaClip:  3,
+		Iterations: 5,
+		MinStars:   5,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Measurement is the instrumental magnitude of a reference star.
+type Measurement struct {
+	Reference         Reference // The reference star
+	X                 float64   // The x position of the star, in pixels
+	Y                 float64   // The y position of the star, in pixels
+	Instrumental      float64   // The instrumental magnitude, i.e., −2.5·log10(flux / exposure)
+	InstrumentalError float64   // The uncertainty of the instrumental magnitude
+	Residual          float64   // The residual of the catalogue magnitude from the fitted magnitude
+	Rejected          bool      // Whether the star was rejected from the fit
+}
+
+/*****************************************************************************************************************/
+
+// ZeroPoint is the photometric calibration of a frame, i.e., the standard magnitude of a star is:
+//
+//	m = m_inst + ZP + C·colour
+//
+// where m_inst is its instrumental magnitude, ZP the zero point at the airmass of the frame, and C the colour term.
+type ZeroPoint struct {
+	ZeroPoint        float64       // The zero point at the airmass of the frame (for a colour index of zero, with a colour term)
+	ZeroPointError   float64       // The uncertainty of the zero point
+	ColourTerm       float64       // The colour term, or zero if not fitted
+	ColourTermError  float64       // The uncertainty of the colour term
+	Airmass          float64       // The airmass of the frame, or zero if unknown
+	Extinction       float64       // The extinction coefficient of the band, in magnitudes per airmass
+	ExtraAtmospheric float64       // The zero point above the atmosphere, i.e., ZP + k·X
+	RMS              float64       // The RMS of the residuals of the stars used in the fit, in magnitudes
+	Stars            int           // The number of stars used in the fit
+	Rejected         int           // The number of stars rejected from the fit
+	Measurements     []Measurement // The measurements of the reference stars
+}
+
+/*****************************************************************************************************************/
+
+/*
+FitZeroPoint()
+
+Fits the zero point of the (plate solved) frame, from the aperture photometry of the reference
+stars at their positions projected onto the frame, e.g., by its wcs.WCS.
+
+The airmass of the frame is that of the observation, if not nil and positive, or otherwise its
+AIRMASS keyword, and corrects the zero point to above the atmosphere by the extinction coefficient.
+
+Stars which are saturated, at the edge of the frame, or below the minimum SNR are excluded.
+*/
+func FitZeroPoint(f *fits.FITSImage, projection photometry.Projection, references []Reference, observation *fits.FITSObservation, params Params) (*ZeroPoint, error) {
+	xs, ys, ok := f.GetDimensions()
+
+	if !ok || len(f.Data) != xs*ys {
+		return nil, fmt.Errorf("the image data of %d pixels does not match the dimensions %dx%d", len(f.Data), xs, ys)
+	}
+
+	aperture := params.Aperture
+
+	// The instrumental magnitudes are of the flux per second, without a zero point:
+	aperture.ZeroPoint = 0
+
+	if aperture.Exposure <= 0 {
+		aperture.Exposure = f.GetExposureTime()
+	}
+
+	measurements := []Measurement{}
+
+	for _, r := range references {
+		if math.IsNaN(r.Magnitude) || (params.ColourTerm && math.IsNaN(r.Colour)) {
+			continue
+		}
+
+		p, err := photometry.MeasureApertureAtEquatorial(f.Data, xs, ys, projection, r.RA, r.Dec, aperture)
+
+		if err != nil || p.Flags&rejectedFlags != 0 || math.IsNaN(p.Magnitude) || p.SNR < params.MinSNR {
+			continue
+		}
+
+		measurements = append(measurements, Measurement{
+			Reference:         r,
+			X:                 p.X,
+			Y:                 p.Y,
+			Instrumental:      p.Magnitude,
+			InstrumentalError: p.MagnitudeError,
+		})
+	}
+
+	return FitZeroPointToMeasurements(measurements, GetAirmass(f, observation), params)
+}
+
+/*****************************************************************************************************************/
+
+/*
+FitZeroPointToMeasurements()
+
+Fits the zero point (and, optionally, the colour term) to the instrumental magnitudes of the
+reference stars by weighted least squares, iteratively rejecting the stars whose residuals are
+more than the number of standard deviations from the fit, e.g., variable stars or misidentified
+stars. The residuals are normalised by the uncertainty of each star, and their standard deviation
+is the robust (MAD) estimate of their scatter, or unity if less, such that stars are not rejected
+for their expected noise.
+
+The uncertainties of the fit are scaled by the reduced χ² of the residuals, if greater than one.
+*/
+func FitZeroPointToMeasurements(measurements []Measurement, airmass float64, params Params) (*ZeroPoint, error) {
+	minimum := max(params.MinStars, 1)
+
+	if params.ColourTerm {
+		minimum = max(minimum, 3)
+	}
+
+	if len(measurements) < minimum {
+		return nil, fmt.Errorf("%d reference stars were measured, at least %d are required", len(measurements), minimum)
+	}
+
+	m := make([]Measurement, len(measurements))
+
+	copy(m, measurements)
+
+	for i := range m {
+		m[i].Rejected = false
+	}
+
+	var zp, zpError, ct, ctError float64
+
+	for iteration := 0; iteration <= max(params.Iterations, 0); iteration++ {
+		var err error
+
+		if zp, zpError, ct, ctError, err = fitWeighted(m, params.ColourTerm); err != nil {
+			return nil, err
+		}
+
+		residuals := make([]float64, 0, len(m))
+
+		for i := range m {
+			m[i].Residual = m[i].Reference.Magnitude - (m[i].Instrumental + zp + ct*getColour(m[i].Reference, params.ColourTerm))
+
+			if !m[i].Rejected {
+				residuals = append(residuals, m[i].Residual/getError(m[i]))
+			}
+		}
+
+		if iteration == params.Iterations || params.SigmaClip <= 0 {
+			break
+		}
+
+		// The robust (MAD) standard deviation of the normalised residuals of the stars used in the fit:
+		sigma := math.Max(1.4826*getMedianAbsolute(residuals), 1)
+
+		changed, kept := false, 0
+
+		for i := range m {
+			rejected := math.Abs(m[i].Residual/getError(m[i])) > params.SigmaClip*sigma
+
+			changed = changed || rejected != m[i].Rejected
+
+			m[i].Rejected = rejected
+
+			if !rejected {
+				kept++
+			}
+		}
+
+		if kept < minimum {
+			return nil, fmt.Errorf("%d reference stars remain after rejection, at least %d are required", kept, minimum)
+		}
+
+		if !changed {
+			break
+		}
+	}
+
+	z := &ZeroPoint{
+		ZeroPoint:       zp,
+		ZeroPointError:  zpError,
+		ColourTerm:      ct,
+		ColourTermError: ctError,
+		Airmass:         airmass,
+		Extinction:      params.Extinction,
+		Measurements:    m,
+	}
+
+	rms := 0.0
+
+	for _, s := range m {
+		if s.Rejected {
+			z.Rejected++
+			continue
+		}
+
+		z.Stars++
+
+		rms += s.Residual * s.Residual
+	}
+
+	z.RMS = math.Sqrt(rms / float64(z.Stars))
+
+	z.ExtraAtmospheric = z.ZeroPoint
+
+	if airmass > 0 {
+		z.ExtraAtmospheric += params.Extinction * airmass
+	}
+
+	return z, nil
+}
+
+/*****************************************************************************************************************/
+
+/*
+FitExtinction()
+
+Fits the extinction coefficient k and the zero point above the atmosphere ZP₀ of the band to the
+zero points of frames at different airmasses, i.e., ZP = ZP₀ − k·X, weighted by their
+uncertainties. The frames must share the band, the instrument and the colour term.
+*/
+func FitExtinction(zeroPoints []ZeroPoint) (float64, float64, error) {
+	var s, sx, sxx, sy, sxy float64
+
+	for _, z := range zeroPoints {
+		if z.Airmass <= 0 {
+			continue
+		}
+
+		w := 1 / math.Max(z.ZeroPointError*z.ZeroPointError, 1e-12)
+
+		s += w
+		sx += w * z.Airmass
+		sxx += w * z.Airmass * z.Airmass
+		sy += w * z.ZeroPoint
+		sxy += w * z.Airmass * z.ZeroPoint
+	}
+
+	det := s*sxx - sx*sx
+
+	if s == 0 || det <= 1e-12*s*s {
+		return 0, 0, errors.New("the extinction requires zero points at two or more different airmasses")
+	}
+
+	zp0, slope := (sxx*sy-sx*sxy)/det, (s*sxy-sx*sy)/det
+
+	return -slope, zp0, nil
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the standard magnitude of a star of the frame from its instrumental magnitude and colour index (ignored
+// without a colour term).
+func (z *ZeroPoint) GetMagnitude(instrumental float64, colour float64) float64 {
+	if z.ColourTerm == 0 {
+		return instrumental + z.ZeroPoint
+	}
+
+	return instrumental + z.ZeroPoint + z.ColourTerm*colour
+}
+
+/*****************************************************************************************************************/
+
+// Writes the photometric calibration to the FITS header, i.e., the MAGZP, MAGZPERR, MAGZP0 and MAGZPN keywords,
+// with the MAGZPCT colour term and EXTINCT coefficient if used.
+func (z *ZeroPoint) WriteToHeader(h *fits.FITSHeader) {
+	h.Set("MAGZP", z.ZeroPoint, "Photometric zero point (mag)")
+	h.Set("MAGZPERR", z.ZeroPointError, "Uncertainty of the photometric zero point (mag)")
+	h.Set("MAGZP0", z.ExtraAtmospheric, "Photometric zero point above the atmosphere (mag)")
+	h.Set("MAGZPN", z.Stars, "The number of stars of the photometric zero point")
+
+	if z.ColourTerm != 0 {
+		h.Set("MAGZPCT", z.ColourTerm, "Colour term of the photometric zero point")
+	}
+
+	if z.Extinction != 0 {
+		h.Set("EXTINCT", z.Extinction, "Extinction coefficient (mag per airmass)")
+	}
+
+	h.History = append(
+		h.History,
+		fmt.Sprintf("Photometric zero point %.3f +/- %.3f mag from %d stars (%d rejected), RMS %.3f mag", z.ZeroPoint, z.ZeroPointError, z.Stars, z.Rejected, z.RMS),
+	)
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the airmass of the frame, from the observation if not nil and positive, or otherwise its AIRMASS keyword,
+// or zero if unknown.
+func GetAirmass(f *fits.FITSImage, observation *fits.FITSObservation) float64 {
+	if observation != nil && observation.Airmass > 0 {
+		return float64(observation.Airmass)
+	}
+
+	if v, ok := f.Header.GetFloat("AIRMASS"); ok && v > 0 {
+		return v
+	}
+
+	return 0
+}
+
+/*****************************************************************************************************************/
+
+// Fits the zero point (and colour term) to the unrejected measurements by weighted least squares, returning the zero
+// point, the colour term, and their uncertainties.
+func fitWeighted(m []Measurement, colourTerm bool) (float64, float64, float64, float64, error) {
+	var s, sx, sxx, sy, sxy float64
+
+	n := 0
+
+	for _, p := range m {
+		if p.Rejected {
+			continue
+		}
+
+		w := 1 / (getError(p) * getError(p))
+
+		c, y := getColour(p.Reference, colourTerm), p.Reference.Magnitude-p.Instrumental
+
+		s += w
+		sx += w * c
+		sxx += w * c * c
+		sy += w * y
+		sxy += w * c * y
+
+		n++
+	}
+
+	if n == 0 {
+		return 0, 0, 0, 0, errors.New("no reference stars remain to fit the zero point")
+	}
+
+	var zp, ct, zpVar, ctVar float64
+
+	if colourTerm {
+		det := s*sxx - sx*sx
+
+		if det <= 1e-12*s*s {
+			return 0, 0, 0, 0, errors.New("the colour term requires reference stars of different colours")
+		}
+
+		zp, ct = (sxx*sy-sx*sxy)/det, (s*sxy-sx*sy)/det
+
+		zpVar, ctVar = sxx/det, s/det
+	} else {
+		zp, zpVar = sy/s, 1/s
+	}
+
+	// Scale the uncertainties by the reduced χ² of the residuals, if greater than one:
+	chi2, dof := 0.0, n-1
+
+	if colourTerm {
+		dof--
+	}
+
+	for _, p := range m {
+		if p.Rejected {
+			continue
+		}
+
+		r := p.Reference.Magnitude - (p.Instrumental + zp + ct*getColour(p.Reference, colourTerm))
+
+		chi2 += r * r / (getError(p) * getError(p))
+	}
+
+	if dof > 0 && chi2/float64(dof) > 1 {
+		zpVar, ctVar = zpVar*chi2/float64(dof), ctVar*chi2/float64(dof)
+	}
+
+	return zp, math.Sqrt(zpVar), ct, math.Sqrt(ctVar), nil
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the uncertainty of the measurement, i.e., of its instrumental magnitude with the floor in quadrature.
+func getError(m Measurement) float64 {
+	return math.Hypot(m.InstrumentalError, magnitudeErrorFloor)
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the colour index of the reference, or zero without a colour term.
+func getColour(r Reference, colourTerm bool) float64 {
+	if !colourTerm {
+		return 0
+	}
+
+	return r.Colour
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the median of the absolute values.
+func getMedianAbsolute(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	absolute := make([]float64, len(values))
+
+	for i, v := range values {
+		absolute[i] = math.Abs(v)
+	}
+
+	return qsort.MedianFloat64(absolute)
+}
+
+/*****************************************************************************************************************/
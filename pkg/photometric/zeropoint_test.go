@@ -0,0 +1,221 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/photometric
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package photometric
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/internal/starfield"
+	"github.com/observerly/iris/pkg/wcs"
+)
+
+/*****************************************************************************************************************/
+
+// The world coordinate system of the 256x256 test frames, with a pixel scale of 2"/px:
+var testWCS = &wcs.WCS{CRPIX1: 128.5, CRPIX2: 128.5, CRVAL1: 100, CRVAL2: 30, CD1_1: -2.0 / 3600, CD2_2: 2.0 / 3600}
+
+/*****************************************************************************************************************/
+
+// Creates the reference stars on a grid of 7x7 positions 32 pixels apart, with magnitudes from 10 to 14 and colour
+// indices from -0.2 to 1.5:
+func getTestReferences() []Reference {
+	random := rand.New(rand.NewSource(42))
+
+	references := []Reference{}
+
+	for j := 0; j < 7; j++ {
+		for i := 0; i < 7; i++ {
+			ra, dec := testWCS.PixelToEquatorial(float64(32+32*i)+0.3, float64(32+32*j)-0.2)
+
+			references = append(references, Reference{RA: ra, Dec: dec, Magnitude: 10 + random.Float64()*4, Colour: -0.2 + random.Float64()*1.7})
+		}
+	}
+
+	return references
+}
+
+/*****************************************************************************************************************/
+
+// Renders the stars of the given fluxes (in ADU) at the positions of the references, as Gaussian stars (σ of 1.5
+// pixels) on a flat background with Gaussian noise:
+func getTestImage(references []Reference, fluxes []float64, seed int64) *fits.FITSImage {
+	random := rand.New(rand.NewSource(seed))
+
+	xs, ys := 256, 256
+
+	data := make([]float32, xs*ys)
+
+	for i := range data {
+		data[i] = float32(100 + random.NormFloat64()*5)
+	}
+
+	for k, r := range references {
+		x, y, _ := testWCS.EquatorialToPixel(r.RA, r.Dec)
+
+		starfield.AddStar(data, xs, ys, x, y, fluxes[k])
+	}
+
+	f := fits.NewFITSImage(2, int32(xs), int32(ys), 65535)
+
+	f.Data = data
+
+	f.Naxisn = []int32{int32(xs), int32(ys)}
+
+	f.Exposure = 10
+
+	return f
+}
+
+/*****************************************************************************************************************/
+
+// Creates a test frame of the references for the zero point and colour term, i.e., the instrumental magnitude of each
+// star is m − ZP − C·colour, with the fourth star brighter by 0.5 magnitudes (e.g., a variable star):
+func getTestFrame(references []Reference, zp float64, ct float64) *fits.FITSImage {
+	fluxes := make([]float64, len(references))
+
+	for i, r := range references {
+		fluxes[i] = 10 * math.Pow(10, -0.4*(r.Magnitude-zp-ct*r.Colour))
+
+		if i == 3 {
+			fluxes[i] *= math.Pow(10, 0.4*0.5)
+		}
+	}
+
+	return getTestImage(references, fluxes, 7)
+}
+
+/*****************************************************************************************************************/
+
+func TestFitZeroPoint(t *testing.T) {
+	references := getTestReferences()
+
+	f := getTestFrame(references, 20, 0)
+
+	params := DefaultParams()
+
+	params.Extinction = 0.2
+
+	z, err := FitZeroPoint(f, testWCS, references, &fits.FITSObservation{Airmass: 1.5}, params)
+
+	if err != nil {
+		t.Fatalf("FitZeroPoint() error: %v", err)
+	}
+
+	if math.Abs(z.ZeroPoint-20) > 0.01 {
+		t.Errorf("zero point = %f, expected 20", z.ZeroPoint)
+	}
+
+	if z.ZeroPointError <= 0 || z.ZeroPointError > 0.01 {
+		t.Errorf("zero point error = %f, expected within (0, 0.01]", z.ZeroPointError)
+	}
+
+	if z.Airmass != 1.5 || math.Abs(z.ExtraAtmospheric-20.3) > 0.01 {
+		t.Errorf("airmass = %f, extra-atmospheric zero point = %f, expected 1.5 and 20.3", z.Airmass, z.ExtraAtmospheric)
+	}
+
+	if z.Rejected != 1 || z.Stars != len(z.Measurements)-1 {
+		t.Errorf("expected only the variable star to be rejected, but %d of %d stars were rejected", z.Rejected, len(z.Measurements))
+	}
+
+	for _, m := range z.Measurements {
+		if m.Rejected && m.Reference != references[3] {
+			t.Errorf("expected the variable star to be rejected, but %+v was rejected", m.Reference)
+		}
+	}
+
+	if z.RMS > 0.02 {
+		t.Errorf("RMS = %f, expected less than 0.02", z.RMS)
+	}
+
+	// The calibrated magnitudes of the stars are their catalogue magnitudes:
+	m := z.Measurements[10]
+
+	if math.Abs(z.GetMagnitude(m.Instrumental, m.Reference.Colour)-m.Reference.Magnitude) > 0.03 {
+		t.Errorf("magnitude = %f, expected %f", z.GetMagnitude(m.Instrumental, m.Reference.Colour), m.Reference.Magnitude)
+	}
+
+	z.WriteToHeader(&f.Header)
+
+	if math.Abs(float64(f.Header.Floats["MAGZP"].Value)-20) > 0.01 || f.Header.Ints["MAGZPN"].Value != int32(z.Stars) {
+		t.Errorf("expected the zero point in the header, but got MAGZP = %f, MAGZPN = %d", f.Header.Floats["MAGZP"].Value, f.Header.Ints["MAGZPN"].Value)
+	}
+
+	if _, ok := f.Header.Floats["MAGZPCT"]; ok {
+		t.Errorf("expected no colour term in the header")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFitZeroPointColourTerm(t *testing.T) {
+	references := getTestReferences()
+
+	f := getTestFrame(references, 21.5, 0.15)
+
+	// The airmass is read from the header:
+	f.Header.Set("AIRMASS", float32(1.2), "")
+
+	params := DefaultParams()
+
+	params.ColourTerm = true
+
+	z, err := FitZeroPoint(f, testWCS, references, nil, params)
+
+	if err != nil {
+		t.Fatalf("FitZeroPoint() error: %v", err)
+	}
+
+	if math.Abs(z.ZeroPoint-21.5) > 0.01 || math.Abs(z.ColourTerm-0.15) > 0.01 {
+		t.Errorf("zero point = %f, colour term = %f, expected 21.5 and 0.15", z.ZeroPoint, z.ColourTerm)
+	}
+
+	if math.Abs(z.Airmass-1.2) > 1e-6 || z.ExtraAtmospheric != z.ZeroPoint {
+		t.Errorf("airmass = %f, extra-atmospheric zero point = %f, expected 1.2 and %f", z.Airmass, z.ExtraAtmospheric, z.ZeroPoint)
+	}
+
+	// Without colour indices, the colour term cannot be fitted:
+	for i := range references {
+		references[i].Colour = math.NaN()
+	}
+
+	if _, err := FitZeroPoint(f, testWCS, references, nil, params); err == nil {
+		t.Errorf("expected an error without colour indices")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFitExtinction(t *testing.T) {
+	zeroPoints := []ZeroPoint{
+		{ZeroPoint: 20 - 0.25*1.1, ZeroPointError: 0.01, Airmass: 1.1},
+		{ZeroPoint: 20 - 0.25*1.6, ZeroPointError: 0.01, Airmass: 1.6},
+		{ZeroPoint: 20 - 0.25*2.3, ZeroPointError: 0.02, Airmass: 2.3},
+	}
+
+	k, zp0, err := FitExtinction(zeroPoints)
+
+	if err != nil {
+		t.Fatalf("FitExtinction() error: %v", err)
+	}
+
+	if math.Abs(k-0.25) > 1e-9 || math.Abs(zp0-20) > 1e-9 {
+		t.Errorf("k = %f, ZP0 = %f, expected 0.25 and 20", k, zp0)
+	}
+
+	if _, _, err := FitExtinction(zeroPoints[:1]); err == nil {
+		t.Errorf("expected an error for a single airmass")
+	}
+}
+
+/*****************************************************************************************************************/
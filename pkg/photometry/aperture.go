@@ -0,0 +1,419 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/photometry
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package photometry
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"math"
+
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// ApertureFlag describes the conditions which may compromise the photometry of a star, as a bitmask.
+type ApertureFlag int
+
+/*****************************************************************************************************************/
+
+const (
+	// A pixel of the aperture is at or above the saturation level:
+	FlagSaturated ApertureFlag = 1 << iota
+	// The aperture extends beyond the edge of the frame:
+	FlagEdge
+	// The sky annulus extends beyond the edge of the frame:
+	FlagSkyEdge
+	// A pixel of the aperture is NaN, i.e., a bad pixel, and is excluded:
+	FlagBadPixel
+	// Too few pixels of the sky annulus remain to estimate the sky:
+	FlagNoSky
+	// The sky-subtracted flux is not positive, so the magnitude is undefined:
+	FlagNonPositiveFlux
+)
+
+/*****************************************************************************************************************/
+
+// The minimum number of pixels of the sky annulus from which the sky is estimated:
+const minimumSkyPixels = 10
+
+/*****************************************************************************************************************/
+
+// Aperture is a circular or elliptical aperture, with a sky annulus of the same shape.
+type Aperture struct {
+	Radius      float64 // The radius (or semi-major axis) of the aperture, in pixels
+	AxisRatio   float64 // The ratio of the semi-minor to semi-major axis, in (0, 1] (zero is circular)
+	Theta       float64 // The angle of the semi-major axis from the x axis, in radians
+	InnerRadius float64 // The inner radius (or semi-major axis) of the sky annulus, in pixels
+	OuterRadius float64 // The outer radius (or semi-major axis) of the sky annulus, in pixels
+}
+
+/*****************************************************************************************************************/
+
+// ApertureParams describes the aperture, and the detector, of the photometry.
+type ApertureParams struct {
+	Aperture   Aperture // The aperture and its sky annulus
+	Gain       float64  // The gain of the detector, in e-/ADU (zero is unity)
+	ReadNoise  float64  // The read noise of the detector, in e-
+	Saturation float64  // The saturation level, in ADU (zero disables the saturation check)
+	ZeroPoint  float64  // The zero point of the magnitudes, i.e., the magnitude of a flux of 1 ADU per second
+	Exposure   float64  // The exposure time, in seconds, by which the flux is normalised for magnitudes (zero is unity)
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default aperture photometry parameters, i.e., a circular aperture of 8 pixels, with a sky annulus from
+// 12 to 20 pixels.
+func DefaultApertureParams() ApertureParams {
+	return ApertureParams{
+		Aperture: Aperture{
+			Radius:      8,
+			InnerRadius: 12,
+			OuterRadius: 20,
+		},
+		Gain: 1,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// AperturePhotometry is the photometry of a star measured within an aperture.
+type AperturePhotometry struct {
+	X              float64      // The x position of the centre of the aperture, in pixels
+	Y              float64      // The y position of the centre of the aperture, in pixels
+	RA             float64      // The Right Ascension of the centre of the aperture, in degrees (if measured by WCS)
+	Dec            float64      // The Declination of the centre of the aperture, in degrees (if measured by WCS)
+	Flux           float64      // The sky-subtracted flux within the aperture, in ADU
+	FluxError      float64      // The uncertainty of the flux, in ADU
+	SNR            float64      // The signal-to-noise ratio of the flux
+	Sky            float64      // The sky level per pixel, in ADU
+	SkySigma       float64      // The standard deviation of the sky pixels, in ADU
+	SkyPixels      int          // The number of pixels of the sky annulus used to estimate the sky
+	Area           float64      // The area of the aperture within the frame, in pixels
+	Peak           float64      // The maximum pixel value within the aperture, in ADU
+	Magnitude      float64      // The instrumental magnitude, i.e., ZP - 2.5·log10(flux / exposure), or NaN
+	MagnitudeError float64      // The uncertainty of the instrumental magnitude, or NaN
+	Flags          ApertureFlag // The conditions which may compromise the photometry
+}
+
+/*****************************************************************************************************************/
+
+// Returns whether the photometry has the given flag(s) set.
+func (p *AperturePhotometry) Has(flag ApertureFlag) bool {
+	return p.Flags&flag != 0
+}
+
+/*****************************************************************************************************************/
+
+// Projection is the projection of the equatorial coordinates of the sky onto the pixels of a frame, e.g., a
+// wcs.WCS.
+type Projection interface {
+	EquatorialToPixel(ra float64, dec float64) (float64, float64, error)
+}
+
+/*****************************************************************************************************************/
+
+/*
+MeasureAperture()
+
+Measures the photometry of the star centred at the pixel { x, y } within the aperture.
+
+The sky level is the sigma-clipped median of the pixels whose centres lie within the sky
+annulus, and is subtracted from every pixel of the aperture. Pixels straddling the edge of the
+aperture are weighted by the fraction of their area within it, by sub-sampling, and NaN pixels
+are excluded.
+
+The uncertainty of the flux follows the CCD equation, i.e., the variance (in ADU²) is:
+
+	σ² = F/g + A·(1 + A/n)·σ²_sky
+
+where F is the flux, g the gain, A the area of the aperture, n the number of sky pixels, and
+σ²_sky the variance of a sky pixel, i.e., the larger of the measured variance of the annulus
+and the sky photon noise with the read noise (S/g + RN²/g²).
+
+@see Merline, W. J. & Howell, S. B. (1995). A Realistic Model for Point-sources Imaged on Array Detectors. ExA, 6, 163
+*/
+func MeasureAperture(data []float32, xs int, ys int, x float64, y float64, params ApertureParams) (*AperturePhotometry, error) {
+	aperture := params.Aperture
+
+	if aperture.Radius <= 0 {
+		return nil, errors.New("the radius of the aperture must be positive")
+	}
+
+	if aperture.InnerRadius < aperture.Radius || aperture.OuterRadius <= aperture.InnerRadius {
+		return nil, errors.New("the sky annulus must lie outside the aperture, with its outer radius beyond its inner radius")
+	}
+
+	if len(data) != xs*ys {
+		return nil, errors.New("the data does not match the dimensions of the frame")
+	}
+
+	if math.IsNaN(x) || math.IsNaN(y) {
+		return nil, errors.New("the position of the aperture is undefined")
+	}
+
+	ratio := aperture.AxisRatio
+
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+
+	gain := params.Gain
+
+	if gain <= 0 {
+		gain = 1
+	}
+
+	cos, sin := math.Cos(aperture.Theta), math.Sin(aperture.Theta)
+
+	// The elliptical radius of the offset { dx, dy }, i.e., the semi-major axis of the ellipse through it:
+	radius := func(dx, dy float64) float64 {
+		u, v := dx*cos+dy*sin, (-dx*sin+dy*cos)/ratio
+
+		return math.Hypot(u, v)
+	}
+
+	photometry := &AperturePhotometry{
+		X:   x,
+		Y:   y,
+		RA:  math.NaN(),
+		Dec: math.NaN(),
+	}
+
+	// Estimate the sky from the pixels of the annulus:
+	sky := make([]float64, 0)
+
+	outer := int(math.Ceil(aperture.OuterRadius)) + 1
+
+	for j := int(math.Floor(y)) - outer; j <= int(math.Ceil(y))+outer; j++ {
+		for i := int(math.Floor(x)) - outer; i <= int(math.Ceil(x))+outer; i++ {
+			r := radius(float64(i)-x, float64(j)-y)
+
+			if r < aperture.InnerRadius || r > aperture.OuterRadius {
+				continue
+			}
+
+			if i < 0 || j < 0 || i >= xs || j >= ys {
+				photometry.Flags |= FlagSkyEdge
+				continue
+			}
+
+			if v := data[j*xs+i]; !math.IsNaN(float64(v)) {
+				sky = append(sky, float64(v))
+			}
+		}
+	}
+
+	photometry.Sky, photometry.SkySigma, photometry.SkyPixels = getSkyLevel(sky)
+
+	if photometry.SkyPixels < minimumSkyPixels {
+		photometry.Flags |= FlagNoSky
+	}
+
+	// The half-diagonal of a pixel, as a fraction of the semi-minor axis, to find pixels straddling the edge:
+	margin := math.Sqrt2 / 2 / (aperture.Radius * ratio)
+
+	const subsamples = 8
+
+	sum, area, peak := 0.0, 0.0, math.Inf(-1)
+
+	extent := int(math.Ceil(aperture.Radius)) + 1
+
+	for j := int(math.Floor(y)) - extent; j <= int(math.Ceil(y))+extent; j++ {
+		for i := int(math.Floor(x)) - extent; i <= int(math.Ceil(x))+extent; i++ {
+			dx, dy := float64(i)-x, float64(j)-y
+
+			r := radius(dx, dy) / aperture.Radius
+
+			weight := 0.0
+
+			switch {
+			case r <= 1-margin:
+				weight = 1
+			case r >= 1+margin:
+				continue
+			default:
+				// Sub-sample the pixel to find the fraction of its area within the aperture:
+				inside := 0
+
+				for sj := 0; sj < subsamples; sj++ {
+					for si := 0; si < subsamples; si++ {
+						sx := dx + (float64(si)+0.5)/subsamples - 0.5
+
+						sy := dy + (float64(sj)+0.5)/subsamples - 0.5
+
+						if radius(sx, sy) <= aperture.Radius {
+							inside++
+						}
+					}
+				}
+
+				weight = float64(inside) / (subsamples * subsamples)
+			}
+
+			if weight == 0 {
+				continue
+			}
+
+			if i < 0 || j < 0 || i >= xs || j >= ys {
+				photometry.Flags |= FlagEdge
+				continue
+			}
+
+			v := float64(data[j*xs+i])
+
+			if math.IsNaN(v) {
+				photometry.Flags |= FlagBadPixel
+				continue
+			}
+
+			if params.Saturation > 0 && v >= params.Saturation {
+				photometry.Flags |= FlagSaturated
+			}
+
+			peak = math.Max(peak, v)
+
+			sum += weight * v
+
+			area += weight
+		}
+	}
+
+	if area == 0 {
+		return nil, errors.New("the aperture does not contain any valid pixels of the frame")
+	}
+
+	photometry.Area, photometry.Peak = area, peak
+
+	photometry.Flux = sum - area*photometry.Sky
+
+	// The variance of a sky pixel, at least that expected from the sky photon noise and read noise:
+	variance := math.Max(photometry.SkySigma*photometry.SkySigma, math.Max(photometry.Sky, 0)/gain+params.ReadNoise*params.ReadNoise/(gain*gain))
+
+	errorSquared := math.Max(photometry.Flux, 0) / gain
+
+	if photometry.SkyPixels > 0 {
+		errorSquared += area * (1 + area/float64(photometry.SkyPixels)) * variance
+	} else {
+		errorSquared += area * variance
+	}
+
+	photometry.FluxError = math.Sqrt(errorSquared)
+
+	photometry.SNR = photometry.Flux / photometry.FluxError
+
+	photometry.Magnitude, photometry.MagnitudeError = math.NaN(), math.NaN()
+
+	if photometry.Flux <= 0 {
+		photometry.Flags |= FlagNonPositiveFlux
+
+		return photometry, nil
+	}
+
+	exposure := params.Exposure
+
+	if exposure <= 0 {
+		exposure = 1
+	}
+
+	photometry.Magnitude = params.ZeroPoint - 2.5*math.Log10(photometry.Flux/exposure)
+
+	// The magnitude error is 2.5·log10(e)·σ/F, to first order:
+	photometry.MagnitudeError = 2.5 / math.Ln10 * photometry.FluxError / photometry.Flux
+
+	return photometry, nil
+}
+
+/*****************************************************************************************************************/
+
+// Measures the photometry of each of the stars (e.g., as found by StarsExtractor.FindStars()) within the aperture,
+// skipping any star whose photometry cannot be measured.
+func MeasureApertures(data []float32, xs int, ys int, stars []Star, params ApertureParams) []AperturePhotometry {
+	photometry := make([]AperturePhotometry, 0, len(stars))
+
+	for _, s := range stars {
+		p, err := MeasureAperture(data, xs, ys, float64(s.X), float64(s.Y), params)
+
+		if err != nil {
+			continue
+		}
+
+		photometry = append(photometry, *p)
+	}
+
+	return photometry
+}
+
+/*****************************************************************************************************************/
+
+// Measures the photometry of the star at the equatorial coordinates { ra, dec } (in degrees) within the aperture,
+// projected onto the pixels of the frame by its world coordinate system.
+func MeasureApertureAtEquatorial(data []float32, xs int, ys int, projection Projection, ra float64, dec float64, params ApertureParams) (*AperturePhotometry, error) {
+	x, y, err := projection.EquatorialToPixel(ra, dec)
+
+	if err != nil {
+		return nil, err
+	}
+
+	photometry, err := MeasureAperture(data, xs, ys, x, y, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	photometry.RA, photometry.Dec = ra, dec
+
+	return photometry, nil
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the sky level and its standard deviation from the pixels of the sky annulus, by iterative 3σ clipping
+// about the median, such that stars within the annulus are rejected, with the number of pixels retained.
+func getSkyLevel(values []float64) (float64, float64, int) {
+	if len(values) == 0 {
+		return 0, 0, 0
+	}
+
+	// The median and standard deviation (about the median) of the values:
+	getLevel := func(values []float64) (float64, float64) {
+		median, sum := qsort.MedianFloat64(values), 0.0
+
+		for _, v := range values {
+			sum += (v - median) * (v - median)
+		}
+
+		return median, math.Sqrt(sum / float64(len(values)))
+	}
+
+	median, sigma := getLevel(values)
+
+	for iteration := 0; iteration < 5; iteration++ {
+		retained := make([]float64, 0, len(values))
+
+		for _, v := range values {
+			if math.Abs(v-median) <= 3*sigma {
+				retained = append(retained, v)
+			}
+		}
+
+		if len(retained) == len(values) || len(retained) == 0 {
+			break
+		}
+
+		values = retained
+
+		median, sigma = getLevel(values)
+	}
+
+	return median, sigma, len(values)
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,256 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/photometry
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package photometry
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+// Renders a circular Gaussian star of the given total flux onto a noisy sky background:
+func getTestApertureData(xs int, ys int, x float64, y float64, flux float64, sigma float64, sky float64, noise float64) []float32 {
+	random := rand.New(rand.NewSource(7))
+
+	data := make([]float32, xs*ys)
+
+	for j := 0; j < ys; j++ {
+		for i := 0; i < xs; i++ {
+			dx, dy := float64(i)-x, float64(j)-y
+
+			star := flux / (2 * math.Pi * sigma * sigma) * math.Exp(-(dx*dx+dy*dy)/(2*sigma*sigma))
+
+			data[j*xs+i] = float32(sky + star + random.NormFloat64()*noise)
+		}
+	}
+
+	return data
+}
+
+/*****************************************************************************************************************/
+
+// A projection of equatorial coordinates onto pixels by a fixed offset, standing in for a world coordinate system:
+type testProjection struct{}
+
+func (testProjection) EquatorialToPixel(ra float64, dec float64) (float64, float64, error) {
+	return (ra - 10) * 100, (dec - 20) * 100, nil
+}
+
+/*****************************************************************************************************************/
+
+func TestMeasureApertureFlux(t *testing.T) {
+	data := getTestApertureData(100, 100, 50.3, 49.6, 50000, 2, 1000, 10)
+
+	params := DefaultApertureParams()
+
+	params.Aperture.Radius = 10
+
+	params.Aperture.InnerRadius, params.Aperture.OuterRadius = 15, 25
+
+	// A gain for which the sky photon noise is below the measured sky noise:
+	params.Gain = 20
+
+	p, err := MeasureAperture(data, 100, 100, 50.3, 49.6, params)
+
+	if err != nil {
+		t.Fatalf("MeasureAperture() failed: %v", err)
+	}
+
+	if math.Abs(p.Sky-1000) > 1 {
+		t.Errorf("expected a sky level of ~1000, but got %f", p.Sky)
+	}
+
+	if math.Abs(p.SkySigma-10) > 1 {
+		t.Errorf("expected a sky sigma of ~10, but got %f", p.SkySigma)
+	}
+
+	if math.Abs(p.Area-math.Pi*100) > 1 {
+		t.Errorf("expected an area of ~%f, but got %f", math.Pi*100, p.Area)
+	}
+
+	if math.Abs(p.Flux-50000) > 3*p.FluxError {
+		t.Errorf("expected a flux of ~50000 ± %f, but got %f", 3*p.FluxError, p.Flux)
+	}
+
+	// The CCD equation, with the measured sky variance:
+	expected := math.Sqrt(p.Flux/20 + p.Area*(1+p.Area/float64(p.SkyPixels))*p.SkySigma*p.SkySigma)
+
+	if math.Abs(p.FluxError-expected) > 1e-6 {
+		t.Errorf("expected a flux error of %f, but got %f", expected, p.FluxError)
+	}
+
+	if math.Abs(p.Magnitude-(-2.5*math.Log10(p.Flux))) > 1e-9 {
+		t.Errorf("expected an instrumental magnitude of %f, but got %f", -2.5*math.Log10(p.Flux), p.Magnitude)
+	}
+
+	if math.Abs(p.MagnitudeError-1.0857*p.FluxError/p.Flux) > 1e-4 {
+		t.Errorf("expected a magnitude error of %f, but got %f", 1.0857*p.FluxError/p.Flux, p.MagnitudeError)
+	}
+
+	if p.Flags != 0 {
+		t.Errorf("expected no flags, but got %b", p.Flags)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestMeasureApertureReadNoiseFloor(t *testing.T) {
+	// A noiseless frame, such that the sky variance is that expected from the sky and the read noise:
+	data := getTestApertureData(100, 100, 50, 50, 50000, 2, 400, 0)
+
+	params := DefaultApertureParams()
+
+	params.Gain, params.ReadNoise = 2, 6
+
+	p, err := MeasureAperture(data, 100, 100, 50, 50, params)
+
+	if err != nil {
+		t.Fatalf("MeasureAperture() failed: %v", err)
+	}
+
+	variance := 400.0/2 + 36.0/4
+
+	expected := math.Sqrt(p.Flux/2 + p.Area*(1+p.Area/float64(p.SkyPixels))*variance)
+
+	if math.Abs(p.FluxError-expected) > 1e-6 {
+		t.Errorf("expected a flux error of %f, but got %f", expected, p.FluxError)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestMeasureApertureElliptical(t *testing.T) {
+	data := make([]float32, 100*100)
+
+	for i := range data {
+		data[i] = 1
+	}
+
+	params := DefaultApertureParams()
+
+	params.Aperture = Aperture{Radius: 8, AxisRatio: 0.5, Theta: math.Pi / 6, InnerRadius: 12, OuterRadius: 20}
+
+	p, err := MeasureAperture(data, 100, 100, 50.5, 50.5, params)
+
+	if err != nil {
+		t.Fatalf("MeasureAperture() failed: %v", err)
+	}
+
+	if math.Abs(p.Area-math.Pi*8*4)/(math.Pi*8*4) > 0.01 {
+		t.Errorf("expected an area of ~%f, but got %f", math.Pi*8*4, p.Area)
+	}
+
+	// The sky-subtracted flux of a flat frame is zero:
+	if math.Abs(p.Flux) > 1e-6 {
+		t.Errorf("expected a flux of 0, but got %f", p.Flux)
+	}
+
+	if !p.Has(FlagNonPositiveFlux) || !math.IsNaN(p.Magnitude) {
+		t.Errorf("expected a non-positive flux flag and undefined magnitude, but got flags %b and magnitude %f", p.Flags, p.Magnitude)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestMeasureApertureFlags(t *testing.T) {
+	data := getTestApertureData(100, 100, 4, 50, 500000, 1.5, 1000, 10)
+
+	data[50*100+60] = float32(math.NaN())
+
+	params := DefaultApertureParams()
+
+	params.Saturation = 20000
+
+	p, err := MeasureAperture(data, 100, 100, 4, 50, params)
+
+	if err != nil {
+		t.Fatalf("MeasureAperture() failed: %v", err)
+	}
+
+	if !p.Has(FlagEdge) || !p.Has(FlagSkyEdge) || !p.Has(FlagSaturated) {
+		t.Errorf("expected the edge, sky edge and saturated flags, but got %b", p.Flags)
+	}
+
+	// The NaN pixel is within the aperture of a star centred on it:
+	p, err = MeasureAperture(data, 100, 100, 60, 50, params)
+
+	if err != nil {
+		t.Fatalf("MeasureAperture() failed: %v", err)
+	}
+
+	if !p.Has(FlagBadPixel) || p.Has(FlagEdge) || p.Has(FlagSaturated) {
+		t.Errorf("expected only the bad pixel flag, but got %b", p.Flags)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestMeasureApertureInvalidParams(t *testing.T) {
+	data := make([]float32, 100*100)
+
+	params := DefaultApertureParams()
+
+	params.Aperture.InnerRadius = 4
+
+	if _, err := MeasureAperture(data, 100, 100, 50, 50, params); err == nil {
+		t.Errorf("expected an error for a sky annulus within the aperture")
+	}
+
+	if _, err := MeasureAperture(data, 100, 100, -50, -50, DefaultApertureParams()); err == nil {
+		t.Errorf("expected an error for an aperture outside the frame")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestMeasureApertures(t *testing.T) {
+	data := getTestApertureData(100, 100, 30, 30, 20000, 2, 1000, 5)
+
+	stars := []Star{{X: 30, Y: 30}, {X: -100, Y: -100}}
+
+	photometry := MeasureApertures(data, 100, 100, stars, DefaultApertureParams())
+
+	if len(photometry) != 1 {
+		t.Fatalf("expected the photometry of 1 star, but got %d", len(photometry))
+	}
+
+	if math.Abs(photometry[0].Flux-20000) > 3*photometry[0].FluxError {
+		t.Errorf("expected a flux of ~20000, but got %f", photometry[0].Flux)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestMeasureApertureAtEquatorial(t *testing.T) {
+	data := getTestApertureData(100, 100, 40, 60, 20000, 2, 1000, 5)
+
+	p, err := MeasureApertureAtEquatorial(data, 100, 100, testProjection{}, 10.4, 20.6, DefaultApertureParams())
+
+	if err != nil {
+		t.Fatalf("MeasureApertureAtEquatorial() failed: %v", err)
+	}
+
+	if math.Abs(p.X-40) > 1e-9 || math.Abs(p.Y-60) > 1e-9 {
+		t.Errorf("expected the aperture at { 40, 60 }, but got { %f, %f }", p.X, p.Y)
+	}
+
+	if p.RA != 10.4 || p.Dec != 20.6 {
+		t.Errorf("expected the equatorial coordinates { 10.4, 20.6 }, but got { %f, %f }", p.RA, p.Dec)
+	}
+
+	if math.Abs(p.Flux-20000) > 3*p.FluxError {
+		t.Errorf("expected a flux of ~20000, but got %f", p.Flux)
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,365 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/photometry
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package photometry
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/optimize"
+
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// PSFModel is the analytic model of the Point Spread Function fitted to each star.
+type PSFModel int
+
+/*****************************************************************************************************************/
+
+const (
+	// An elliptical 2D Gaussian, i.e., A·exp(-(u²/2σx² + v²/2σy²)) + B:
+	GaussianPSF PSFModel = iota
+	// An elliptical 2D Moffat, i.e., A·(1 + u²/αx² + v²/αy²)^-β + B, whose wings better describe real seeing:
+	MoffatPSF
+)
+
+/*****************************************************************************************************************/
+
+// Returns the name of the PSF model, e.g., "gaussian".
+func (m PSFModel) String() string {
+	switch m {
+	case GaussianPSF:
+		return "gaussian"
+	case MoffatPSF:
+		return "moffat"
+	default:
+		return "unknown"
+	}
+}
+
+/*****************************************************************************************************************/
+
+// PSFParams describes how the PSF is fitted to each star.
+type PSFParams struct {
+	Model  PSFModel // The PSF model
+	Radius int      // The half-width of the square fitting window, in pixels (zero sizes the window from the HFR)
+	Beta   float64  // The initial Moffat β (zero is 2.5)
+}
+
+/*****************************************************************************************************************/
+
+// PSF is the Point Spread Function fitted to a star.
+type PSF struct {
+	Model        PSFModel // The PSF model
+	X            float64  // The x position of the centre of the star, in pixels
+	Y            float64  // The y position of the centre of the star, in pixels
+	Amplitude    float64  // The peak of the star above the local background, in ADU
+	Background   float64  // The local background level, in ADU
+	Major        float64  // The width parameter (σ or α) along the major axis, in pixels
+	Minor        float64  // The width parameter (σ or α) along the minor axis, in pixels
+	Theta        float64  // The angle of the major axis from the x axis, in radians in (-π/2, π/2]
+	Beta         float64  // The Moffat β, zero for a Gaussian
+	FWHMMajor    float64  // The FWHM along the major axis, in pixels
+	FWHMMinor    float64  // The FWHM along the minor axis, in pixels
+	FWHM         float64  // The FWHM, i.e., the geometric mean of the major and minor FWHM, in pixels
+	Eccentricity float64  // The eccentricity, from 0 (round) to 1 (elongated)
+	RMS          float64  // The root-mean-square residual of the fit, in ADU
+	RSquared     float64  // The coefficient of determination of the fit, i.e., 1 is a perfect fit
+	Pixels       int      // The number of pixels fitted
+}
+
+/*****************************************************************************************************************/
+
+// PSFSummary is the aggregate PSF of the fitted stars of a frame.
+type PSFSummary struct {
+	Fitted       int     // The number of stars fitted
+	Failed       int     // The number of stars which could not be fitted
+	FWHM         float64 // The median FWHM, in pixels
+	Eccentricity float64 // The median eccentricity
+	Theta        float64 // The mean angle of the major axis (modulo π), in radians
+	RSquared     float64 // The median coefficient of determination of the fits
+}
+
+/*****************************************************************************************************************/
+
+/*
+FitPSF()
+
+Fits the PSF model to the star, within a square window about its position, seeded from the
+position and HFR of the star (e.g., as found by StarsExtractor.FindStars()). The amplitude,
+local background, centre, widths along the major and minor axes, rotation and (for the Moffat
+model) β are all free parameters, minimising the sum of the squared residuals. NaN pixels are
+excluded from the fit.
+
+Returns an error if the window has too few pixels, or if the fit does not converge to a star
+within the window.
+*/
+func FitPSF(data []float32, xs int, ys int, star Star, params PSFParams) (*PSF, error) {
+	// For a Gaussian, the HFR is σ·√(2ln2):
+	sigma := math.Max(float64(star.HFR)/math.Sqrt(2*math.Ln2), 0.5)
+
+	radius := params.Radius
+
+	if radius <= 0 {
+		radius = max(int(math.Ceil(4*sigma)), 4)
+	}
+
+	cx, cy := int(math.Round(float64(star.X))), int(math.Round(float64(star.Y)))
+
+	// Gather the pixels of the fitting window:
+	px, py, pv := make([]float64, 0), make([]float64, 0), make([]float64, 0)
+
+	border := make([]float64, 0)
+
+	peak := math.Inf(-1)
+
+	for y := cy - radius; y <= cy+radius; y++ {
+		for x := cx - radius; x <= cx+radius; x++ {
+			if x < 0 || y < 0 || x >= xs || y >= ys {
+				continue
+			}
+
+			v := float64(data[y*xs+x])
+
+			if math.IsNaN(v) {
+				continue
+			}
+
+			px, py, pv = append(px, float64(x)), append(py, float64(y)), append(pv, v)
+
+			if x == cx-radius || x == cx+radius || y == cy-radius || y == cy+radius {
+				border = append(border, v)
+			}
+
+			peak = math.Max(peak, v)
+		}
+	}
+
+	beta := params.Beta
+
+	if beta <= 0 {
+		beta = 2.5
+	}
+
+	n := 7
+
+	if params.Model == MoffatPSF {
+		n = 8
+	}
+
+	if len(pv) <= n {
+		return nil, fmt.Errorf("too few pixels (%d) to fit the PSF of the star at (%g, %g)", len(pv), star.X, star.Y)
+	}
+
+	background := qsort.MedianFloat64(border)
+
+	width := sigma
+
+	if params.Model == MoffatPSF {
+		// The α of a Moffat with the same FWHM as the Gaussian:
+		width = 2 * math.Sqrt(2*math.Ln2) * sigma / (2 * math.Sqrt(math.Pow(2, 1/beta)-1))
+	}
+
+	// The parameters are A, B, x, y, ln(major), ln(minor), θ and (for the Moffat) ln(β - 1), such that the widths
+	// remain positive and β remains above 1:
+	initial := []float64{peak - background, background, float64(star.X), float64(star.Y), math.Log(width), math.Log(width), 0}
+
+	if params.Model == MoffatPSF {
+		initial = append(initial, math.Log(beta-1))
+	}
+
+	problem := optimize.Problem{
+		Func: func(p []float64) float64 {
+			sum := 0.0
+
+			for i := range pv {
+				r := pv[i] - evaluatePSF(params.Model, p, px[i], py[i])
+
+				sum += r * r
+			}
+
+			return sum
+		},
+	}
+
+	settings := &optimize.Settings{
+		FuncEvaluations: 20000,
+	}
+
+	result, err := optimize.Minimize(problem, initial, settings, &optimize.NelderMead{})
+
+	if err != nil {
+		return nil, fmt.Errorf("the PSF fit of the star at (%g, %g) failed: %w", star.X, star.Y, err)
+	}
+
+	if result.Status.Early() {
+		return nil, fmt.Errorf("the PSF fit of the star at (%g, %g) did not converge: %s", star.X, star.Y, result.Status)
+	}
+
+	p := result.X
+
+	for _, v := range p {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, errors.New("the PSF fit did not converge")
+		}
+	}
+
+	if p[0] <= 0 || math.Abs(p[2]-float64(cx)) > float64(radius) || math.Abs(p[3]-float64(cy)) > float64(radius) {
+		return nil, fmt.Errorf("the PSF fit of the star at (%g, %g) did not converge to a star within the window", star.X, star.Y)
+	}
+
+	psf := &PSF{
+		Model:      params.Model,
+		X:          p[2],
+		Y:          p[3],
+		Amplitude:  p[0],
+		Background: p[1],
+		Major:      math.Exp(p[4]),
+		Minor:      math.Exp(p[5]),
+		Theta:      p[6],
+		Pixels:     len(pv),
+	}
+
+	// Order the axes such that the major axis is the wider, rotating the angle with them:
+	if psf.Minor > psf.Major {
+		psf.Major, psf.Minor = psf.Minor, psf.Major
+
+		psf.Theta += math.Pi / 2
+	}
+
+	psf.Theta = math.Mod(psf.Theta, math.Pi)
+
+	if psf.Theta > math.Pi/2 {
+		psf.Theta -= math.Pi
+	} else if psf.Theta <= -math.Pi/2 {
+		psf.Theta += math.Pi
+	}
+
+	// The FWHM of a Gaussian is 2√(2ln2)·σ, and of a Moffat is 2α·√(2^(1/β) - 1):
+	scale := 2 * math.Sqrt(2*math.Ln2)
+
+	if params.Model == MoffatPSF {
+		psf.Beta = 1 + math.Exp(p[7])
+
+		scale = 2 * math.Sqrt(math.Pow(2, 1/psf.Beta)-1)
+	}
+
+	psf.FWHMMajor, psf.FWHMMinor = scale*psf.Major, scale*psf.Minor
+
+	psf.FWHM = math.Sqrt(psf.FWHMMajor * psf.FWHMMinor)
+
+	psf.Eccentricity = math.Sqrt(1 - (psf.Minor*psf.Minor)/(psf.Major*psf.Major))
+
+	// The quality of the fit:
+	mean := 0.0
+
+	for _, v := range pv {
+		mean += v
+	}
+
+	mean /= float64(len(pv))
+
+	residuals, total := result.F, 0.0
+
+	for _, v := range pv {
+		total += (v - mean) * (v - mean)
+	}
+
+	psf.RMS = math.Sqrt(residuals / float64(len(pv)))
+
+	if total > 0 {
+		psf.RSquared = 1 - residuals/total
+	}
+
+	return psf, nil
+}
+
+/*****************************************************************************************************************/
+
+// Fits the PSF model to each of the stars, returning the PSF of each star which could be fitted, with the median
+// FWHM and eccentricity, and the mean rotation, of the frame.
+func FitPSFs(data []float32, xs int, ys int, stars []Star, params PSFParams) ([]PSF, PSFSummary) {
+	psfs := make([]PSF, 0, len(stars))
+
+	summary := PSFSummary{}
+
+	for _, s := range stars {
+		psf, err := FitPSF(data, xs, ys, s, params)
+
+		if err != nil {
+			summary.Failed++
+			continue
+		}
+
+		psfs = append(psfs, *psf)
+	}
+
+	summary.Fitted = len(psfs)
+
+	fwhm := make([]float64, len(psfs))
+
+	eccentricity := make([]float64, len(psfs))
+
+	theta := make([]float64, len(psfs))
+
+	r2 := make([]float64, len(psfs))
+
+	for i, psf := range psfs {
+		fwhm[i], eccentricity[i], theta[i], r2[i] = psf.FWHM, psf.Eccentricity, psf.Theta, psf.RSquared
+	}
+
+	summary.FWHM = qsort.MedianFloat64(fwhm)
+
+	summary.Eccentricity = qsort.MedianFloat64(eccentricity)
+
+	// The angles are axial (i.e., modulo π), so their mean is that of the doubled angles, halved:
+	sin, cos := 0.0, 0.0
+
+	for _, t := range theta {
+		sin, cos = sin+math.Sin(2*t), cos+math.Cos(2*t)
+	}
+
+	if len(theta) > 0 {
+		summary.Theta = math.Atan2(sin, cos) / 2
+	}
+
+	summary.RSquared = qsort.MedianFloat64(r2)
+
+	return psfs, summary
+}
+
+/*****************************************************************************************************************/
+
+// Evaluates the PSF model with the (transformed) parameters at the pixel { x, y }.
+func evaluatePSF(model PSFModel, p []float64, x float64, y float64) float64 {
+	dx, dy := x-p[2], y-p[3]
+
+	cos, sin := math.Cos(p[6]), math.Sin(p[6])
+
+	// The position along the major (u) and minor (v) axes:
+	u, v := dx*cos+dy*sin, -dx*sin+dy*cos
+
+	a, b := math.Exp(p[4]), math.Exp(p[5])
+
+	switch model {
+	case MoffatPSF:
+		beta := 1 + math.Exp(p[7])
+
+		return p[0]*math.Pow(1+u*u/(a*a)+v*v/(b*b), -beta) + p[1]
+	default:
+		return p[0]*math.Exp(-(u*u/(2*a*a)+v*v/(2*b*b))) + p[1]
+	}
+}
+
+/*****************************************************************************************************************/
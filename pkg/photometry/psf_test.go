@@ -0,0 +1,170 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/photometry
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package photometry
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+// Renders a star of the PSF model with the given (untransformed) parameters onto a noisy background:
+func getTestPSFData(model PSFModel, xs int, ys int, x float64, y float64, major float64, minor float64, theta float64, beta float64) []float32 {
+	p := []float64{10000, 500, x, y, math.Log(major), math.Log(minor), theta, math.Log(beta - 1)}
+
+	noise := rand.New(rand.NewSource(1))
+
+	data := make([]float32, xs*ys)
+
+	for j := 0; j < ys; j++ {
+		for i := 0; i < xs; i++ {
+			data[j*xs+i] = float32(evaluatePSF(model, p, float64(i), float64(j)) + noise.NormFloat64()*5)
+		}
+	}
+
+	return data
+}
+
+/*****************************************************************************************************************/
+
+func TestFitPSFEllipticalGaussian(t *testing.T) {
+	data := getTestPSFData(GaussianPSF, 32, 32, 15.3, 16.6, 2.4, 1.6, 0.5, 2)
+
+	psf, err := FitPSF(data, 32, 32, Star{X: 15, Y: 17, HFR: 2.3}, PSFParams{Model: GaussianPSF})
+
+	if err != nil {
+		t.Fatalf("FitPSF() error: %v", err)
+	}
+
+	if math.Abs(psf.X-15.3) > 0.05 || math.Abs(psf.Y-16.6) > 0.05 {
+		t.Errorf("centre = (%f, %f), expected (15.3, 16.6)", psf.X, psf.Y)
+	}
+
+	if math.Abs(psf.Major-2.4) > 0.05 || math.Abs(psf.Minor-1.6) > 0.05 {
+		t.Errorf("axes = %f and %f, expected 2.4 and 1.6", psf.Major, psf.Minor)
+	}
+
+	if math.Abs(psf.Theta-0.5) > 0.05 {
+		t.Errorf("Theta = %f, expected 0.5", psf.Theta)
+	}
+
+	if math.Abs(psf.Amplitude-10000) > 100 || math.Abs(psf.Background-500) > 5 {
+		t.Errorf("Amplitude = %f and Background = %f, expected 10000 and 500", psf.Amplitude, psf.Background)
+	}
+
+	// The FWHM is 2√(2ln2)·√(2.4·1.6):
+	if math.Abs(psf.FWHM-4.615) > 0.1 {
+		t.Errorf("FWHM = %f, expected 4.615", psf.FWHM)
+	}
+
+	if math.Abs(psf.Eccentricity-0.745) > 0.02 {
+		t.Errorf("Eccentricity = %f, expected 0.745", psf.Eccentricity)
+	}
+
+	if psf.RSquared < 0.99 || psf.RMS > 10 {
+		t.Errorf("RSquared = %f and RMS = %f, expected a good fit", psf.RSquared, psf.RMS)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFitPSFMoffat(t *testing.T) {
+	// A round Moffat, with the major axis rotated by π/2 from the fitted axes:
+	data := getTestPSFData(MoffatPSF, 40, 40, 20.2, 19.7, 2.5, 3.5, 0, 3)
+
+	psf, err := FitPSF(data, 40, 40, Star{X: 20, Y: 20, HFR: 2.5}, PSFParams{Model: MoffatPSF, Radius: 12})
+
+	if err != nil {
+		t.Fatalf("FitPSF() error: %v", err)
+	}
+
+	if math.Abs(psf.Beta-3) > 0.2 {
+		t.Errorf("Beta = %f, expected 3", psf.Beta)
+	}
+
+	if math.Abs(psf.Major-3.5) > 0.15 || math.Abs(psf.Minor-2.5) > 0.15 {
+		t.Errorf("axes = %f and %f, expected 3.5 and 2.5", psf.Major, psf.Minor)
+	}
+
+	if math.Abs(math.Abs(psf.Theta)-math.Pi/2) > 0.05 {
+		t.Errorf("Theta = %f, expected ±π/2", psf.Theta)
+	}
+
+	// The FWHM of the major axis is 2·3.5·√(2^(1/3) - 1):
+	if math.Abs(psf.FWHMMajor-3.57) > 0.1 {
+		t.Errorf("FWHMMajor = %f, expected 3.57", psf.FWHMMajor)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFitPSFsSummary(t *testing.T) {
+	data := getTestPSFData(GaussianPSF, 32, 32, 15.3, 16.6, 2, 2, 0, 2)
+
+	stars := []Star{{X: 15, Y: 17, HFR: 2.3}, {X: 0, Y: 0, HFR: 2}}
+
+	// The second star is in the corner of the frame, where there is no star to fit:
+	psfs, summary := FitPSFs(data, 32, 32, stars, PSFParams{Model: GaussianPSF})
+
+	if len(psfs) != summary.Fitted || summary.Fitted+summary.Failed != 2 || summary.Fitted < 1 {
+		t.Fatalf("Fitted = %d and Failed = %d, expected at least one fit of two stars", summary.Fitted, summary.Failed)
+	}
+
+	if math.Abs(psfs[0].FWHM-4.71) > 0.1 {
+		t.Errorf("FWHM = %f, expected 4.71", psfs[0].FWHM)
+	}
+
+	if psfs[0].Eccentricity > 0.2 {
+		t.Errorf("Eccentricity = %f, expected a round star", psfs[0].Eccentricity)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFitPSFsSummaryNearVertical(t *testing.T) {
+	xs, ys := 96, 32
+
+	data := make([]float32, xs*ys)
+
+	stars := []Star{}
+
+	// Near-vertical stars, whose angles lie either side of ±π/2:
+	for i, theta := range []float64{1.50, 1.54, -1.52, -1.55} {
+		x := float64(12 + 24*i)
+
+		star := getTestPSFData(GaussianPSF, xs, ys, x, 16, 3, 1.5, theta, 2)
+
+		for j := range data {
+			data[j] += star[j] - 500
+		}
+
+		stars = append(stars, Star{X: float32(x), Y: 16, HFR: 2.3})
+	}
+
+	for j := range data {
+		data[j] += 500
+	}
+
+	_, summary := FitPSFs(data, xs, ys, stars, PSFParams{Model: GaussianPSF})
+
+	if summary.Fitted != 4 {
+		t.Fatalf("Fitted = %d, expected all 4 stars to be fitted", summary.Fitted)
+	}
+
+	// The mean of the axial angles is vertical, i.e., ±π/2, not the perpendicular horizontal axis:
+	if math.Abs(math.Abs(summary.Theta)-math.Pi/2) > 0.05 {
+		t.Errorf("Theta = %f, expected ±%f", summary.Theta, math.Pi/2)
+	}
+}
+
+/*****************************************************************************************************************/
@@ -148,6 +148,50 @@ func (s *StarsExtractor) FindStars(stats *stats.Stats, sigma float32, starInOut
 
 /*****************************************************************************************************************/
 
+// Finds the stars of the image data, for the ADU of the image (or 65535 if it is not known), returning them with
+// their median Half-Flux Radius.
+func FindStars(data []float32, xs int, ys int, adu int32, radius float32, sigma float32, starInOut float32) ([]Star, float32) {
+	if adu <= 0 {
+		adu = 65535
+	}
+
+	extractor := NewStarsExtractor(data, xs, ys, radius, adu)
+
+	stars := extractor.FindStars(stats.NewStats(data, adu, xs), sigma, starInOut)
+
+	return stars, extractor.HFR
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the mask of the pixels within the mask radius of each of the stars, i.e., its HFR times the scale, limited
+// to the minimum radius and (if positive) the maximum radius.
+func GetStarMask(xs int, ys int, stars []Star, scale float64, minRadius float64, maxRadius float64) []bool {
+	mask := make([]bool, xs*ys)
+
+	for _, s := range stars {
+		radius := math.Max(scale*float64(s.HFR), minRadius)
+
+		if maxRadius > 0 {
+			radius = math.Min(radius, maxRadius)
+		}
+
+		x, y := float64(s.X), float64(s.Y)
+
+		for j := max(int(y-radius), 0); j <= min(int(y+radius)+1, ys-1); j++ {
+			for i := max(int(x-radius), 0); i <= min(int(x+radius)+1, xs-1); i++ {
+				if math.Hypot(float64(i)-x, float64(j)-y) <= radius {
+					mask[j*xs+i] = true
+				}
+			}
+		}
+	}
+
+	return mask
+}
+
+/*****************************************************************************************************************/
+
 // Applies an element-wise Median filter to the sparse data points provided by the indices, with the local
 // neighborhood defined by the mask.
 func gatherNeighbourhoodAndCalcMedian(data []float32, index int32, mask []int32, buffer []float32, adu int32) float32 {
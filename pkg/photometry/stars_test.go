@@ -433,3 +433,52 @@ func TestNewFindStarsFrom2DData(t *testing.T) {
 }
 
 /*****************************************************************************************************************/
+
+func TestFindStarsFrom2DData(t *testing.T) {
+	data, bounds := GetTestDataFromImage()
+
+	xs, ys := bounds.Dx(), bounds.Dy()
+
+	d := utils.Flatten2DUInt32Array(data)
+
+	s := NewStarsExtractor(d, xs, ys, 16, 65535)
+
+	expected := s.FindStars(stats.NewStats(d, 65535, xs), 8, 2)
+
+	// An unknown ADU defaults to 65535:
+	stars, hfr := FindStars(d, xs, ys, 0, 16, 8, 2)
+
+	if len(stars) != len(expected) {
+		t.Errorf("Expected %d stars, but got %d", len(expected), len(stars))
+	}
+
+	if hfr != s.HFR {
+		t.Errorf("Expected a HFR of %f, but got %f", s.HFR, hfr)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetStarMask(t *testing.T) {
+	stars := []Star{{X: 10, Y: 10, HFR: 2}, {X: 1, Y: 1, HFR: 10}}
+
+	// The radius of the first star is 3 x 2 = 6, and of the second is limited to 8:
+	mask := GetStarMask(32, 32, stars, 3, 2, 8)
+
+	if !mask[10*32+16] || mask[10*32+17] {
+		t.Errorf("Expected the mask of the first star to extend to a radius of 6 pixels")
+	}
+
+	if !mask[1*32+9] || mask[1*32+10] {
+		t.Errorf("Expected the mask of the second star to be limited to a radius of 8 pixels")
+	}
+
+	// The radius is at least the minimum radius:
+	mask = GetStarMask(32, 32, []Star{{X: 10, Y: 10, HFR: 0}}, 3, 2, 0)
+
+	if !mask[10*32+12] || mask[10*32+13] {
+		t.Errorf("Expected the mask to extend to the minimum radius of 2 pixels")
+	}
+}
+
+/*****************************************************************************************************************/
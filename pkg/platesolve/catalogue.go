@@ -0,0 +1,300 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/platesolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package platesolve
+
+/*****************************************************************************************************************/
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+// The (case-insensitive) column names of the Right Ascension, Declination and magnitude of the catalogues, e.g., of
+// Gaia (ra, dec, phot_g_mean_mag) or Tycho-2 (RAmdeg, DEmdeg, VTmag), in order of preference:
+var (
+	raColumns        = []string{"ra", "ra_icrs", "raj2000", "_raj2000", "ramdeg", "radeg", "ra_deg"}
+	decColumns       = []string{"dec", "de", "dec_icrs", "dej2000", "_dej2000", "demdeg", "dedeg", "dec_deg"}
+	magnitudeColumns = []string{"mag", "magnitude", "phot_g_mean_mag", "gmag", "vtmag", "vmag", "btmag", "hpmag", "rmag"}
+)
+
+/*****************************************************************************************************************/
+
+// Source is a star of the catalogue.
+type Source struct {
+	RA        float64 // The Right Ascension of the star (J2000), in degrees
+	Dec       float64 // The Declination of the star (J2000), in degrees
+	Magnitude float64 // The magnitude of the star, or zero if the catalogue has no magnitudes
+}
+
+/*****************************************************************************************************************/
+
+// Catalogue is a list of stars, e.g., a subset of Gaia or Tycho-2 covering the fields to be solved.
+type Catalogue struct {
+	Sources []Source
+}
+
+/*****************************************************************************************************************/
+
+/*
+NewCatalogueFromCSV()
+
+Reads the catalogue from CSV, with a header row naming the columns of the Right Ascension and
+Declination (in degrees) and, optionally, the magnitude of each star, e.g., "ra,dec,phot_g_mean_mag"
+for Gaia or "RAmdeg,DEmdeg,VTmag" for Tycho-2.
+
+Lines beginning with '#' are ignored, as are rows without a valid position.
+*/
+func NewCatalogueFromCSV(r io.Reader) (*Catalogue, error) {
+	reader := csv.NewReader(r)
+
+	reader.Comment = '#'
+
+	reader.FieldsPerRecord = -1
+
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the header of the catalogue: %w", err)
+	}
+
+	ra, dec, magnitude := getColumnIndex(header, raColumns), getColumnIndex(header, decColumns), getColumnIndex(header, magnitudeColumns)
+
+	if ra < 0 || dec < 0 {
+		return nil, fmt.Errorf("the catalogue must have Right Ascension and Declination columns, but has %q", header)
+	}
+
+	c := &Catalogue{Sources: []Source{}}
+
+	for {
+		record, err := reader.Read()
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the catalogue: %w", err)
+		}
+
+		s, ok := Source{}, true
+
+		if s.RA, ok = parseField(record, ra); !ok {
+			continue
+		}
+
+		if s.Dec, ok = parseField(record, dec); !ok {
+			continue
+		}
+
+		// Stars without a magnitude are assumed to be faint:
+		if magnitude >= 0 {
+			if s.Magnitude, ok = parseField(record, magnitude); !ok {
+				s.Magnitude = 99
+			}
+		}
+
+		c.Sources = append(c.Sources, s)
+	}
+
+	return c, c.validate()
+}
+
+/*****************************************************************************************************************/
+
+/*
+NewCatalogueFromFITS()
+
+Reads the catalogue from the first binary table extension of a FITS file, with columns of the
+Right Ascension and Declination (in degrees) and, optionally, the magnitude of each star, named
+as for NewCatalogueFromCSV().
+*/
+func NewCatalogueFromFITS(r io.Reader) (*Catalogue, error) {
+	table, err := fits.NewFITSBinaryTableFromReader(r, "")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the catalogue: %w", err)
+	}
+
+	names := make([]string, len(table.Columns))
+
+	for i, column := range table.Columns {
+		names[i] = column.Name
+	}
+
+	ra, dec, magnitude := getColumnIndex(names, raColumns), getColumnIndex(names, decColumns), getColumnIndex(names, magnitudeColumns)
+
+	if ra < 0 || dec < 0 {
+		return nil, fmt.Errorf("the catalogue must have Right Ascension and Declination columns, but has %q", names)
+	}
+
+	ras, ok := getFloat64Column(table.Columns[ra])
+
+	if !ok {
+		return nil, fmt.Errorf("the Right Ascension column %s is not numeric", table.Columns[ra].Name)
+	}
+
+	decs, ok := getFloat64Column(table.Columns[dec])
+
+	if !ok {
+		return nil, fmt.Errorf("the Declination column %s is not numeric", table.Columns[dec].Name)
+	}
+
+	magnitudes := make([]float64, table.Rows)
+
+	if magnitude >= 0 {
+		if m, ok := getFloat64Column(table.Columns[magnitude]); ok {
+			magnitudes = m
+		}
+	}
+
+	c := &Catalogue{Sources: make([]Source, 0, table.Rows)}
+
+	for i := 0; i < table.Rows; i++ {
+		if math.IsNaN(ras[i]) || math.IsNaN(decs[i]) {
+			continue
+		}
+
+		m := magnitudes[i]
+
+		// Stars without a magnitude (NaN) are assumed to be faint:
+		if math.IsNaN(m) {
+			m = 99
+		}
+
+		c.Sources = append(c.Sources, Source{RA: ras[i], Dec: decs[i], Magnitude: m})
+	}
+
+	return c, c.validate()
+}
+
+/*****************************************************************************************************************/
+
+// Reads the catalogue from the CSV (.csv) or FITS (.fits, .fit or .fts) file.
+func NewCatalogueFromFile(fp string) (*Catalogue, error) {
+	file, err := os.Open(fp)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+
+	switch strings.ToLower(filepath.Ext(fp)) {
+	case ".csv":
+		return NewCatalogueFromCSV(file)
+	case ".fits", ".fit", ".fts":
+		return NewCatalogueFromFITS(file)
+	default:
+		return nil, fmt.Errorf("unsupported catalogue file %s, expected a CSV or FITS file", fp)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Checks that the catalogue has stars, and that their positions are valid.
+func (c *Catalogue) validate() error {
+	if len(c.Sources) == 0 {
+		return errors.New("the catalogue has no stars")
+	}
+
+	for i, s := range c.Sources {
+		if s.Dec < -90 || s.Dec > 90 {
+			return fmt.Errorf("star %d of the catalogue has an invalid Declination of %f", i, s.Dec)
+		}
+	}
+
+	return nil
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the index of the first of the (case-insensitive) column names in the header, or -1 if there are none.
+func getColumnIndex(header []string, names []string) int {
+	for _, name := range names {
+		for i, h := range header {
+			if strings.EqualFold(strings.TrimSpace(h), name) {
+				return i
+			}
+		}
+	}
+
+	return -1
+}
+
+/*****************************************************************************************************************/
+
+// Parses the numeric field of the record, returning whether it is present and valid.
+func parseField(record []string, i int) (float64, bool) {
+	if i >= len(record) {
+		return 0, false
+	}
+
+	v, err := strconv.ParseFloat(strings.TrimSpace(record[i]), 64)
+
+	return v, err == nil
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the values of the numeric column as float64, returning whether the column is numeric.
+func getFloat64Column(c fits.FITSColumn) ([]float64, bool) {
+	switch data := c.Data.(type) {
+	case []float64:
+		return data, true
+	case []float32:
+		values := make([]float64, len(data))
+
+		for i, v := range data {
+			values[i] = float64(v)
+		}
+
+		return values, true
+	case []int32:
+		values := make([]float64, len(data))
+
+		for i, v := range data {
+			values[i] = float64(v)
+		}
+
+		return values, true
+	case []int64:
+		values := make([]float64, len(data))
+
+		for i, v := range data {
+			values[i] = float64(v)
+		}
+
+		return values, true
+	case []int16:
+		values := make([]float64, len(data))
+
+		for i, v := range data {
+			values[i] = float64(v)
+		}
+
+		return values, true
+	default:
+		return nil, false
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,108 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/platesolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package platesolve
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+func TestNewCatalogueFromCSV(t *testing.T) {
+	csv := `# A subset of Gaia DR3
+source_id,ra,dec,phot_g_mean_mag
+1,150.1,20.2,9.5
+2,150.3,19.8,
+3,invalid,19.9,10.1
+4,149.9,20.0,11.2
+`
+
+	c, err := NewCatalogueFromCSV(strings.NewReader(csv))
+
+	if err != nil {
+		t.Fatalf("NewCatalogueFromCSV() error: %v", err)
+	}
+
+	expected := []Source{{RA: 150.1, Dec: 20.2, Magnitude: 9.5}, {RA: 150.3, Dec: 19.8, Magnitude: 99}, {RA: 149.9, Dec: 20, Magnitude: 11.2}}
+
+	if len(c.Sources) != len(expected) {
+		t.Fatalf("expected %d stars, but got %d", len(expected), len(c.Sources))
+	}
+
+	for i, s := range expected {
+		if c.Sources[i] != s {
+			t.Errorf("star %d = %+v, expected %+v", i, c.Sources[i], s)
+		}
+	}
+
+	// The Tycho-2 column names are also recognised:
+	c, err = NewCatalogueFromCSV(strings.NewReader("RAmdeg, DEmdeg, VTmag\n10.5, -30.25, 8.1\n"))
+
+	if err != nil {
+		t.Fatalf("NewCatalogueFromCSV() error: %v", err)
+	}
+
+	if len(c.Sources) != 1 || c.Sources[0] != (Source{RA: 10.5, Dec: -30.25, Magnitude: 8.1}) {
+		t.Errorf("expected the Tycho-2 star, but got %+v", c.Sources)
+	}
+
+	if _, err := NewCatalogueFromCSV(strings.NewReader("x,y\n1,2\n")); err == nil {
+		t.Errorf("expected an error for a catalogue without positions")
+	}
+
+	if _, err := NewCatalogueFromCSV(strings.NewReader("ra,dec\n")); err == nil {
+		t.Errorf("expected an error for a catalogue without stars")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewCatalogueFromFITS(t *testing.T) {
+	table, err := fits.NewFITSBinaryTable("TYCHO2", []fits.FITSColumn{
+		{Name: "RAmdeg", Unit: "deg", Data: []float64{150.1, 150.3}},
+		{Name: "DEmdeg", Unit: "deg", Data: []float64{20.2, 19.8}},
+		{Name: "VTmag", Unit: "mag", Data: []float32{9.5, float32(math.NaN())}},
+	})
+
+	if err != nil {
+		t.Fatalf("NewFITSBinaryTable() error: %v", err)
+	}
+
+	buf, err := table.WriteToBuffer(nil)
+
+	if err != nil {
+		t.Fatalf("WriteToBuffer() error: %v", err)
+	}
+
+	c, err := NewCatalogueFromFITS(buf)
+
+	if err != nil {
+		t.Fatalf("NewCatalogueFromFITS() error: %v", err)
+	}
+
+	expected := []Source{{RA: 150.1, Dec: 20.2, Magnitude: 9.5}, {RA: 150.3, Dec: 19.8, Magnitude: 99}}
+
+	if len(c.Sources) != len(expected) {
+		t.Fatalf("expected %d stars, but got %d", len(expected), len(c.Sources))
+	}
+
+	for i, s := range expected {
+		if c.Sources[i] != s {
+			t.Errorf("star %d = %+v, expected %+v", i, c.Sources[i], s)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
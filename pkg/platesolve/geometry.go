@@ -0,0 +1,83 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/platesolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package platesolve
+
+/*****************************************************************************************************************/
+
+import "math"
+
+/*****************************************************************************************************************/
+
+// Obtains the angular distance between the equatorial coordinates { ra1, dec1 } and { ra2, dec2 }, in degrees.
+func getAngularDistance(ra1 float64, dec1 float64, ra2 float64, dec2 float64) float64 {
+	alpha1, delta1, alpha2, delta2 := ra1*math.Pi/180, dec1*math.Pi/180, ra2*math.Pi/180, dec2*math.Pi/180
+
+	// The haversine formula, which is well-conditioned for small distances:
+	h := math.Pow(math.Sin((delta2-delta1)/2), 2) + math.Cos(delta1)*math.Cos(delta2)*math.Pow(math.Sin((alpha2-alpha1)/2), 2)
+
+	return 2 * math.Asin(math.Min(math.Sqrt(h), 1)) * 180 / math.Pi
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the midpoint of the great circle between the equatorial coordinates { ra1, dec1 } and { ra2, dec2 }, in
+// degrees.
+func getMidpoint(ra1 float64, dec1 float64, ra2 float64, dec2 float64) (float64, float64) {
+	alpha1, delta1, alpha2, delta2 := ra1*math.Pi/180, dec1*math.Pi/180, ra2*math.Pi/180, dec2*math.Pi/180
+
+	x := math.Cos(delta1)*math.Cos(alpha1) + math.Cos(delta2)*math.Cos(alpha2)
+
+	y := math.Cos(delta1)*math.Sin(alpha1) + math.Cos(delta2)*math.Sin(alpha2)
+
+	z := math.Sin(delta1) + math.Sin(delta2)
+
+	ra := math.Atan2(y, x) * 180 / math.Pi
+
+	if ra < 0 {
+		ra += 360
+	}
+
+	return ra, math.Atan2(z, math.Hypot(x, y)) * 180 / math.Pi
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the gnomonic projection { xi, eta } (in degrees) of the equatorial coordinates { ra, dec } onto the plane
+// tangent to the sky at { ra0, dec0 }, with xi towards the east and eta towards the north.
+func project(ra0 float64, dec0 float64, ra float64, dec float64) (float64, float64) {
+	alpha, delta, alpha0, delta0 := ra*math.Pi/180, dec*math.Pi/180, ra0*math.Pi/180, dec0*math.Pi/180
+
+	cosc := math.Sin(delta0)*math.Sin(delta) + math.Cos(delta0)*math.Cos(delta)*math.Cos(alpha-alpha0)
+
+	xi := math.Cos(delta) * math.Sin(alpha-alpha0) / cosc
+
+	eta := (math.Cos(delta0)*math.Sin(delta) - math.Sin(delta0)*math.Cos(delta)*math.Cos(alpha-alpha0)) / cosc
+
+	return xi * 180 / math.Pi, eta * 180 / math.Pi
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the equatorial coordinates { ra, dec } (in degrees) of the gnomonic projection { xi, eta } (in degrees)
+// onto the plane tangent to the sky at { ra0, dec0 }, i.e., the inverse of project().
+func deproject(ra0 float64, dec0 float64, xi float64, eta float64) (float64, float64) {
+	x, y, delta0 := xi*math.Pi/180, eta*math.Pi/180, dec0*math.Pi/180
+
+	d := math.Cos(delta0) - y*math.Sin(delta0)
+
+	ra := math.Mod(ra0+math.Atan2(x, d)*180/math.Pi, 360)
+
+	if ra < 0 {
+		ra += 360
+	}
+
+	return ra, math.Atan2(math.Sin(delta0)+y*math.Cos(delta0), math.Hypot(x, d)) * 180 / math.Pi
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,551 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/platesolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package platesolve
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+// The width of the bins of the geometric hash codes of the quads, in each of their four dimensions:
+const codeBin = 0.02
+
+/*****************************************************************************************************************/
+
+// The maximum number of stars within the circle of each pair of stars used to form its quads, brightest first:
+const maxIndexInner = 3
+
+/*****************************************************************************************************************/
+
+// IndexParams describes how the quads of the index are built from the catalogue.
+type IndexParams struct {
+	MinQuadSize  float64 // The minimum diameter of the quads, in degrees
+	MaxQuadSize  float64 // The maximum diameter of the quads, in degrees
+	StarsPerCell int     // The number of the brightest stars of each cell of the sky (of half the maximum diameter) used to build quads
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default index parameters, i.e., quads of 0.2° to 1° for fields of about 0.5° to 3°.
+func DefaultIndexParams() IndexParams {
+	return IndexParams{
+		MinQuadSize:  0.2,
+		MaxQuadSize:  1,
+		StarsPerCell: 8,
+	}
+}
+
+/*****************************************************************************************************************/
+
+/*
+Index is the quad-hash index of a catalogue.
+
+A quad is four stars A, B, C and D, where C and D lie within the circle of which A and B are
+the diameter. Its geometric hash code is the position of C and D in the frame in which A is at
+{ 0, 0 } and B is at { 1, 1 }, which is invariant to the translation, rotation and scale of
+the quad, such that it may be matched to a quad of stars of an image.
+
+@see Lang, D. et al. (2010). Astrometry.net: Blind astrometric calibration of arbitrary astronomical images. AJ, 139, 1782
+*/
+type Index struct {
+	Params  IndexParams // The parameters of the index
+	Sources []Source    // The stars of the catalogue, brightest first
+	Quads   [][4]int32  // The stars of each quad, in the canonical order of its code
+
+	cellSize float64              // The size of the cells of the sky, in degrees
+	cells    map[[2]int][]int32   // The stars of each cell of the sky, brightest first
+	codes    [][4]float64         // The geometric hash code of each quad
+	sizes    []float64            // The diameter of each quad, in degrees
+	buckets  map[[4]int16][]int32 // The quads of each bin of the codes
+}
+
+/*****************************************************************************************************************/
+
+/*
+NewIndex()
+
+Builds the quad-hash index of the catalogue.
+
+The sky is divided into cells of half of the maximum diameter of the quads, and the brightest
+stars of each cell are used to build quads, such that the quads cover the sky uniformly. Each
+pair of these stars separated by a quad diameter forms quads with the brightest of these stars
+within its circle.
+*/
+func NewIndex(c *Catalogue, params IndexParams) (*Index, error) {
+	if params.MinQuadSize <= 0 || params.MaxQuadSize <= params.MinQuadSize {
+		return nil, errors.New("the quad diameters must be positive, and the maximum greater than the minimum")
+	}
+
+	if params.StarsPerCell < 4 {
+		return nil, errors.New("at least 4 stars of each cell are required to build quads")
+	}
+
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	sources := make([]Source, len(c.Sources))
+
+	copy(sources, c.Sources)
+
+	sort.SliceStable(sources, func(i, j int) bool {
+		return sources[i].Magnitude < sources[j].Magnitude
+	})
+
+	index := newIndex(sources, params)
+
+	index.buildQuads()
+
+	index.buildCodes()
+
+	return index, nil
+}
+
+/*****************************************************************************************************************/
+
+/*
+NewIndexFromReader()
+
+Reads the index from a FITS file written by Index.WriteToBuffer(), i.e., the SOURCES binary
+table of the stars of the catalogue followed by the QUADS binary table of its quads, from which
+the codes of the quads are recomputed.
+*/
+func NewIndexFromReader(r io.Reader) (*Index, error) {
+	table, err := fits.NewFITSBinaryTableFromReader(r, "SOURCES")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the stars of the index: %w", err)
+	}
+
+	ra, dec, magnitude := table.GetColumn("RA"), table.GetColumn("DEC"), table.GetColumn("MAG")
+
+	if ra == nil || dec == nil || magnitude == nil {
+		return nil, errors.New("the stars of the index require the RA, DEC and MAG columns")
+	}
+
+	ras, _ := ra.Data.([]float64)
+
+	decs, _ := dec.Data.([]float64)
+
+	magnitudes, _ := magnitude.Data.([]float64)
+
+	if len(ras) != table.Rows || len(decs) != table.Rows || len(magnitudes) != table.Rows {
+		return nil, errors.New("the RA, DEC and MAG columns of the index must be double precision")
+	}
+
+	sources := make([]Source, table.Rows)
+
+	for i := range sources {
+		sources[i] = Source{RA: ras[i], Dec: decs[i], Magnitude: magnitudes[i]}
+	}
+
+	params := IndexParams{
+		MinQuadSize:  float64(table.Header.Floats["MINQUAD"].Value),
+		MaxQuadSize:  float64(table.Header.Floats["MAXQUAD"].Value),
+		StarsPerCell: int(table.Header.Ints["SPERCELL"].Value),
+	}
+
+	if params.MinQuadSize <= 0 || params.MaxQuadSize <= params.MinQuadSize {
+		return nil, errors.New("the index requires the MINQUAD and MAXQUAD keywords")
+	}
+
+	quads, err := fits.NewFITSBinaryTableFromReader(r, "QUADS")
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the quads of the index: %w", err)
+	}
+
+	index := newIndex(sources, params)
+
+	index.Quads = make([][4]int32, quads.Rows)
+
+	for k, name := range []string{"A", "B", "C", "D"} {
+		column := quads.GetColumn(name)
+
+		if column == nil {
+			return nil, fmt.Errorf("the quads of the index require the %s column", name)
+		}
+
+		stars, ok := column.Data.([]int32)
+
+		if !ok {
+			return nil, fmt.Errorf("the %s column of the quads of the index must be 32-bit integers", name)
+		}
+
+		for i, s := range stars {
+			if s < 0 || int(s) >= len(sources) {
+				return nil, fmt.Errorf("quad %d of the index refers to star %d, but the index has %d stars", i, s, len(sources))
+			}
+
+			index.Quads[i][k] = s
+		}
+	}
+
+	index.buildCodes()
+
+	return index, nil
+}
+
+/*****************************************************************************************************************/
+
+// Writes the index to a FITS file of the SOURCES and QUADS binary tables, e.g., to be read by NewIndexFromReader()
+// rather than built again from the catalogue.
+func (i *Index) WriteToBuffer() (*bytes.Buffer, error) {
+	ras, decs, magnitudes := make([]float64, len(i.Sources)), make([]float64, len(i.Sources)), make([]float64, len(i.Sources))
+
+	for k, s := range i.Sources {
+		ras[k], decs[k], magnitudes[k] = s.RA, s.Dec, s.Magnitude
+	}
+
+	sources, err := fits.NewFITSBinaryTable("SOURCES", []fits.FITSColumn{
+		{Name: "RA", Unit: "deg", Data: ras},
+		{Name: "DEC", Unit: "deg", Data: decs},
+		{Name: "MAG", Unit: "mag", Data: magnitudes},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sources.Header.Set("MINQUAD", i.Params.MinQuadSize, "The minimum diameter of the quads (deg)")
+
+	sources.Header.Set("MAXQUAD", i.Params.MaxQuadSize, "The maximum diameter of the quads (deg)")
+
+	sources.Header.Set("SPERCELL", i.Params.StarsPerCell, "The number of stars of each cell used for quads")
+
+	columns := make([][]int32, 4)
+
+	for k := range columns {
+		columns[k] = make([]int32, len(i.Quads))
+
+		for q, quad := range i.Quads {
+			columns[k][q] = quad[k]
+		}
+	}
+
+	quads, err := fits.NewFITSBinaryTable("QUADS", []fits.FITSColumn{
+		{Name: "A", Data: columns[0]},
+		{Name: "B", Data: columns[1]},
+		{Name: "C", Data: columns[2]},
+		{Name: "D", Data: columns[3]},
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := sources.WriteToBuffer(nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return quads.WriteToBuffer(buf)
+}
+
+/*****************************************************************************************************************/
+
+// Creates the index of the stars (brightest first), assigning each star to its cell of the sky.
+func newIndex(sources []Source, params IndexParams) *Index {
+	index := &Index{
+		Params:   params,
+		Sources:  sources,
+		Quads:    [][4]int32{},
+		cellSize: params.MaxQuadSize / 2,
+		cells:    make(map[[2]int][]int32),
+	}
+
+	for k, s := range sources {
+		cell := index.getCell(s.RA, s.Dec)
+
+		index.cells[cell] = append(index.cells[cell], int32(k))
+	}
+
+	return index
+}
+
+/*****************************************************************************************************************/
+
+// Builds the quads from the brightest stars of each cell of the sky.
+func (i *Index) buildQuads() {
+	for _, stars := range i.cells {
+		for _, a := range stars[:min(len(stars), i.Params.StarsPerCell)] {
+			sa := i.Sources[a]
+
+			for _, b := range i.getSourcesWithin(sa.RA, sa.Dec, i.Params.MaxQuadSize, i.Params.StarsPerCell) {
+				// Each pair of stars is considered once:
+				if b <= a {
+					continue
+				}
+
+				sb := i.Sources[b]
+
+				size := getAngularDistance(sa.RA, sa.Dec, sb.RA, sb.Dec)
+
+				if size < i.Params.MinQuadSize || size > i.Params.MaxQuadSize {
+					continue
+				}
+
+				// The stars within the circle of which the pair is the diameter, brightest first:
+				ra, dec := getMidpoint(sa.RA, sa.Dec, sb.RA, sb.Dec)
+
+				inner := []int32{}
+
+				for _, c := range i.getSourcesWithin(ra, dec, size/2, i.Params.StarsPerCell) {
+					if c != a && c != b {
+						inner = append(inner, c)
+					}
+				}
+
+				sort.Slice(inner, func(j, k int) bool { return inner[j] < inner[k] })
+
+				inner = inner[:min(len(inner), maxIndexInner)]
+
+				for j := 0; j < len(inner); j++ {
+					for k := j + 1; k < len(inner); k++ {
+						i.Quads = append(i.Quads, [4]int32{a, b, inner[j], inner[k]})
+					}
+				}
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Computes the geometric hash code of each quad, reordering its stars into the canonical order of its code, and
+// bins the codes for lookup.
+func (i *Index) buildCodes() {
+	i.codes = make([][4]float64, len(i.Quads))
+
+	i.sizes = make([]float64, len(i.Quads))
+
+	i.buckets = make(map[[4]int16][]int32)
+
+	for q, quad := range i.Quads {
+		a, b := i.Sources[quad[0]], i.Sources[quad[1]]
+
+		// The stars of the quad are projected onto the plane tangent to the sky at the midpoint of A and B:
+		ra, dec := getMidpoint(a.RA, a.Dec, b.RA, b.Dec)
+
+		var points [4][2]float64
+
+		for k, s := range quad {
+			points[k][0], points[k][1] = project(ra, dec, i.Sources[s].RA, i.Sources[s].Dec)
+		}
+
+		code, order := getCode(points)
+
+		i.Quads[q] = [4]int32{quad[order[0]], quad[order[1]], quad[order[2]], quad[order[3]]}
+
+		i.codes[q] = code
+
+		i.sizes[q] = getAngularDistance(a.RA, a.Dec, b.RA, b.Dec)
+
+		key := getCodeBin(code)
+
+		i.buckets[key] = append(i.buckets[key], int32(q))
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Finds the quads with codes within the tolerance of the code.
+func (i *Index) findQuads(code [4]float64, tolerance float64) []int32 {
+	lo, hi := getCodeBin([4]float64{code[0] - tolerance, code[1] - tolerance, code[2] - tolerance, code[3] - tolerance}),
+		getCodeBin([4]float64{code[0] + tolerance, code[1] + tolerance, code[2] + tolerance, code[3] + tolerance})
+
+	quads := []int32{}
+
+	var key [4]int16
+
+	for key[0] = lo[0]; key[0] <= hi[0]; key[0]++ {
+		for key[1] = lo[1]; key[1] <= hi[1]; key[1]++ {
+			for key[2] = lo[2]; key[2] <= hi[2]; key[2]++ {
+				for key[3] = lo[3]; key[3] <= hi[3]; key[3]++ {
+					for _, q := range i.buckets[key] {
+						d := 0.0
+
+						for k := range code {
+							d += (code[k] - i.codes[q][k]) * (code[k] - i.codes[q][k])
+						}
+
+						if d <= tolerance*tolerance {
+							quads = append(quads, q)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return quads
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the cell of the sky of the position, i.e., its band of Declination and its cell of Right Ascension within
+// the band, of which there are fewer towards the poles such that the cells are of about equal area.
+func (i *Index) getCell(ra float64, dec float64) [2]int {
+	bands := int(math.Ceil(180 / i.cellSize))
+
+	band := min(int((dec+90)/i.cellSize), bands-1)
+
+	n := i.getBandCells(band)
+
+	ra = math.Mod(ra, 360)
+
+	if ra < 0 {
+		ra += 360
+	}
+
+	return [2]int{band, min(int(ra/360*float64(n)), n-1)}
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the number of cells of Right Ascension of the band of Declination.
+func (i *Index) getBandCells(band int) int {
+	// The Declination of the edge of the band nearest the equator:
+	dec := math.Min(math.Abs(-90+float64(band)*i.cellSize), math.Abs(-90+float64(band+1)*i.cellSize))
+
+	if (-90+float64(band)*i.cellSize) < 0 && (-90+float64(band+1)*i.cellSize) > 0 {
+		dec = 0
+	}
+
+	return max(int(360*math.Cos(dec*math.Pi/180)/i.cellSize), 1)
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the stars within the radius (in degrees) of the position, considering only the given number of the
+// brightest stars of each cell (or all stars, if zero).
+func (i *Index) getSourcesWithin(ra float64, dec float64, radius float64, brightest int) []int32 {
+	bands := int(math.Ceil(180 / i.cellSize))
+
+	b0, b1 := max(int((dec-radius+90)/i.cellSize), 0), min(int((dec+radius+90)/i.cellSize), bands-1)
+
+	sources := []int32{}
+
+	for band := b0; band <= b1; band++ {
+		n := i.getBandCells(band)
+
+		cells := []int{}
+
+		// The half-width in Right Ascension of the circle, at the Declination of the band furthest from the equator:
+		extreme := math.Max(math.Abs(-90+float64(band)*i.cellSize), math.Abs(-90+float64(band+1)*i.cellSize))
+
+		extreme = math.Max(extreme, math.Abs(dec)+radius)
+
+		if extreme >= 89.999 || n < 3 {
+			for c := 0; c < n; c++ {
+				cells = append(cells, c)
+			}
+		} else {
+			width := radius / math.Cos(extreme*math.Pi/180)
+
+			if width >= 180 {
+				for c := 0; c < n; c++ {
+					cells = append(cells, c)
+				}
+			} else {
+				c0 := int(math.Floor((ra - width) / 360 * float64(n)))
+
+				c1 := int(math.Floor((ra + width) / 360 * float64(n)))
+
+				for c := c0; c <= min(c1, c0+n-1); c++ {
+					cells = append(cells, ((c%n)+n)%n)
+				}
+			}
+		}
+
+		for _, c := range cells {
+			stars := i.cells[[2]int{band, c}]
+
+			if brightest > 0 {
+				stars = stars[:min(len(stars), brightest)]
+			}
+
+			for _, s := range stars {
+				if getAngularDistance(ra, dec, i.Sources[s].RA, i.Sources[s].Dec) <= radius {
+					sources = append(sources, s)
+				}
+			}
+		}
+	}
+
+	return sources
+}
+
+/*****************************************************************************************************************/
+
+/*
+Obtains the geometric hash code of the quad of points { A, B, C, D }, with the order of the points
+in the canonical order of the code, i.e., the positions { cx, cy, dx, dy } of C and D in the frame
+in which A is at { 0, 0 } and B is at { 1, 1 }, with A and B swapped such that cx + dx <= 1, and
+C and D swapped such that cx <= dx, which removes the symmetries of the code.
+*/
+func getCode(points [4][2]float64) ([4]float64, [4]int) {
+	order := [4]int{0, 1, 2, 3}
+
+	transform := func(a [2]float64, b [2]float64, p [2]float64) (float64, float64) {
+		bx, by := b[0]-a[0], b[1]-a[1]
+
+		scale := bx*bx + by*by
+
+		cos, sin := (bx+by)/scale, (by-bx)/scale
+
+		px, py := p[0]-a[0], p[1]-a[1]
+
+		return px*cos + py*sin, -px*sin + py*cos
+	}
+
+	cx, cy := transform(points[0], points[1], points[2])
+
+	dx, dy := transform(points[0], points[1], points[3])
+
+	if cx+dx > 1 {
+		order[0], order[1] = order[1], order[0]
+
+		cx, cy, dx, dy = 1-cx, 1-cy, 1-dx, 1-dy
+	}
+
+	if cx > dx {
+		order[2], order[3] = order[3], order[2]
+
+		cx, cy, dx, dy = dx, dy, cx, cy
+	}
+
+	return [4]float64{cx, cy, dx, dy}, order
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the bin of the code.
+func getCodeBin(code [4]float64) [4]int16 {
+	var key [4]int16
+
+	for k, c := range code {
+		key[k] = int16(math.Floor(c / codeBin))
+	}
+
+	return key
+}
+
+/*****************************************************************************************************************/
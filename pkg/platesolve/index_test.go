@@ -0,0 +1,158 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/platesolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package platesolve
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+func TestGetCodeInvariance(t *testing.T) {
+	points := [4][2]float64{{0, 0}, {10, 2}, {4, 3}, {6, -1}}
+
+	code, _ := getCode(points)
+
+	// The code is invariant to the translation, rotation and scale of the quad, and to the order of its points:
+	cos, sin, scale := math.Cos(1.1), math.Sin(1.1), 3.7
+
+	var transformed [4][2]float64
+
+	for i, p := range [][2]float64{points[1], points[0], points[3], points[2]} {
+		transformed[i] = [2]float64{scale*(p[0]*cos-p[1]*sin) + 100, scale*(p[0]*sin+p[1]*cos) - 50}
+	}
+
+	other, order := getCode(transformed)
+
+	for k := range code {
+		if math.Abs(code[k]-other[k]) > 1e-9 {
+			t.Errorf("code = %v, expected %v", other, code)
+			break
+		}
+	}
+
+	if order != [4]int{1, 0, 3, 2} {
+		t.Errorf("order = %v, expected [1 0 3 2]", order)
+	}
+
+	// The canonical code has C and D nearer to A than to B, with C before D:
+	if code[0]+code[2] > 1 || code[0] > code[2] {
+		t.Errorf("code = %v is not canonical", code)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewIndex(t *testing.T) {
+	if _, err := NewIndex(getTestCatalogue(), IndexParams{MinQuadSize: 1, MaxQuadSize: 0.5, StarsPerCell: 8}); err == nil {
+		t.Errorf("expected an error for invalid quad diameters")
+	}
+
+	index, err := NewIndex(getTestCatalogue(), DefaultIndexParams())
+
+	if err != nil {
+		t.Fatalf("NewIndex() error: %v", err)
+	}
+
+	if len(index.Quads) == 0 {
+		t.Fatalf("expected the index to have quads")
+	}
+
+	for i := 1; i < len(index.Sources); i++ {
+		if index.Sources[i].Magnitude < index.Sources[i-1].Magnitude {
+			t.Fatalf("expected the stars of the index to be ordered brightest first")
+		}
+	}
+
+	for q, quad := range index.Quads {
+		a, b := index.Sources[quad[0]], index.Sources[quad[1]]
+
+		size := getAngularDistance(a.RA, a.Dec, b.RA, b.Dec)
+
+		if size < index.Params.MinQuadSize || size > index.Params.MaxQuadSize {
+			t.Fatalf("quad %d has a diameter of %f°, expected within [%f°, %f°]", q, size, index.Params.MinQuadSize, index.Params.MaxQuadSize)
+		}
+	}
+
+	// Each quad is found by its own code:
+	for _, q := range []int32{0, int32(len(index.Quads) / 2), int32(len(index.Quads) - 1)} {
+		found := false
+
+		for _, f := range index.findQuads(index.codes[q], 0.001) {
+			found = found || f == q
+		}
+
+		if !found {
+			t.Errorf("quad %d was not found by its code", q)
+		}
+	}
+
+	// The stars within a radius are those found by brute force:
+	within := index.getSourcesWithin(150.5, 20.5, 0.75, 0)
+
+	expected := 0
+
+	for _, s := range index.Sources {
+		if getAngularDistance(150.5, 20.5, s.RA, s.Dec) <= 0.75 {
+			expected++
+		}
+	}
+
+	if len(within) != expected {
+		t.Errorf("found %d stars within 0.75°, expected %d", len(within), expected)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestIndexWriteToBuffer(t *testing.T) {
+	index, err := NewIndex(getTestCatalogue(), DefaultIndexParams())
+
+	if err != nil {
+		t.Fatalf("NewIndex() error: %v", err)
+	}
+
+	buf, err := index.WriteToBuffer()
+
+	if err != nil {
+		t.Fatalf("WriteToBuffer() error: %v", err)
+	}
+
+	read, err := NewIndexFromReader(buf)
+
+	if err != nil {
+		t.Fatalf("NewIndexFromReader() error: %v", err)
+	}
+
+	// The parameters are read to the (single) precision of the header:
+	if math.Abs(read.Params.MinQuadSize-index.Params.MinQuadSize) > 1e-6 || math.Abs(read.Params.MaxQuadSize-index.Params.MaxQuadSize) > 1e-6 || read.Params.StarsPerCell != index.Params.StarsPerCell {
+		t.Errorf("params = %+v, expected %+v", read.Params, index.Params)
+	}
+
+	if len(read.Sources) != len(index.Sources) || len(read.Quads) != len(index.Quads) {
+		t.Fatalf("read %d stars and %d quads, expected %d and %d", len(read.Sources), len(read.Quads), len(index.Sources), len(index.Quads))
+	}
+
+	for i := range index.Sources {
+		if read.Sources[i] != index.Sources[i] {
+			t.Fatalf("star %d = %+v, expected %+v", i, read.Sources[i], index.Sources[i])
+		}
+	}
+
+	for q := range index.Quads {
+		if read.Quads[q] != index.Quads[q] {
+			t.Fatalf("quad %d = %v, expected %v", q, read.Quads[q], index.Quads[q])
+		}
+	}
+}
+
+/*****************************************************************************************************************/
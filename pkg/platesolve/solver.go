@@ -0,0 +1,688 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/platesolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package platesolve
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"gonum.org/v1/gonum/mat"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/iris/pkg/wcs"
+)
+
+/*****************************************************************************************************************/
+
+// The maximum number of stars within the circle of each pair of stars of the image used to form its quads, which
+// is greater than for the index such that the quads of the index are found despite missing or spurious stars:
+const maxImageInner = 5
+
+/*****************************************************************************************************************/
+
+// The number of iterations of matching and fitting which refine the solution:
+const refinements = 4
+
+/*****************************************************************************************************************/
+
+// Hints constrain the search for the solution, e.g., from the pointing and optics of the observation.
+type Hints struct {
+	RA        float64 // The approximate Right Ascension of the centre of the frame, in degrees
+	Dec       float64 // The approximate Declination of the centre of the frame, in degrees
+	Radius    float64 // The radius about { RA, Dec } within which the centre of the frame is searched, in degrees, or zero for a blind search
+	ScaleLow  float64 // The minimum pixel scale, in arcseconds per pixel, or zero if unknown
+	ScaleHigh float64 // The maximum pixel scale, in arcseconds per pixel, or zero if unknown
+}
+
+/*****************************************************************************************************************/
+
+/*
+NewHintsFromObservation()
+
+Creates the hints of the observation, i.e., its RA and Dec (if either is non-zero) within the
+radius (in degrees), and its pixel scale (if known) within the fractional tolerance, e.g., 0.1
+for ±10%.
+*/
+func NewHintsFromObservation(o *fits.FITSObservation, radius float64, tolerance float64) Hints {
+	hints := Hints{}
+
+	if o.RA != 0 || o.Dec != 0 {
+		hints.RA, hints.Dec, hints.Radius = float64(o.RA), float64(o.Dec), radius
+	}
+
+	if o.PixelScale > 0 {
+		hints.ScaleLow, hints.ScaleHigh = float64(o.PixelScale)*(1-tolerance), float64(o.PixelScale)*(1+tolerance)
+	}
+
+	return hints
+}
+
+/*****************************************************************************************************************/
+
+/*
+NewHintsFromHeader()
+
+Creates the hints of the FITS header, as NewHintsFromObservation(), from the RA and DEC keywords
+(in degrees, as written by AddObservationEntry()), and from the PIXSCALE keyword or otherwise
+the pixel size (XPIXSZ, in μm, including any binning) and focal length (FOCALLEN, in mm).
+*/
+func NewHintsFromHeader(h fits.FITSHeader, radius float64, tolerance float64) Hints {
+	hints := Hints{}
+
+	ra, okRA := h.GetFloat("RA")
+
+	dec, okDec := h.GetFloat("DEC")
+
+	if okRA && okDec && (ra != 0 || dec != 0) {
+		hints.RA, hints.Dec, hints.Radius = ra, dec, radius
+	}
+
+	scale, ok := h.GetFloat("PIXSCALE")
+
+	if !ok || scale <= 0 {
+		size, okSize := h.GetFloat("XPIXSZ")
+
+		length, okLength := h.GetFloat("FOCALLEN")
+
+		if okSize && okLength && length > 0 {
+			// The pixel scale, in arcseconds per pixel, is 206.265 times the pixel size (μm) over the focal length (mm):
+			scale = 206.265 * size / length
+		}
+	}
+
+	if scale > 0 {
+		hints.ScaleLow, hints.ScaleHigh = scale*(1-tolerance), scale*(1+tolerance)
+	}
+
+	return hints
+}
+
+/*****************************************************************************************************************/
+
+// Params describes how the frame is solved against the index.
+type Params struct {
+	StarRadius       float32 // The radius of the star extraction
+	StarSigma        float32 // The detection threshold of the star extraction
+	StarInOut        float32 // The inner/outer ratio of the HFR filter of the star extraction
+	MaxStars         int     // The number of the brightest stars of the frame used to verify the solution
+	QuadStars        int     // The number of the brightest stars of the frame used to form quads
+	MinQuadPixels    float64 // The minimum diameter of the quads of the frame, in pixels
+	CodeTolerance    float64 // The maximum distance between the codes of matching quads
+	MatchRadius      float64 // The maximum distance between a star and its catalogue position, in pixels
+	MinMatches       int     // The minimum number of stars matched to the catalogue to accept a solution
+	MinMatchFraction float64 // The minimum fraction of the stars (or catalogue stars within the frame) matched to accept a solution
+	SIPOrder         int     // The order of the SIP distortion polynomials of the solution, or zero for a TAN projection
+	Hints            Hints   // The hints which constrain the search for the solution
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default plate solving parameters, i.e., a blind search matching quads of the 20 brightest stars, for
+// a second-order TAN-SIP solution.
+func DefaultParams() Params {
+	return Params{
+		StarRadius:       16,
+		StarSigma:        5,
+		StarInOut:        2,
+		MaxStars:         100,
+		QuadStars:        20,
+		MinQuadPixels:    30,
+		CodeTolerance:    0.01,
+		MatchRadius:      3,
+		MinMatches:       8,
+		MinMatchFraction: 0.25,
+		SIPOrder:         2,
+		Hints:            Hints{},
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Solution is the astrometric solution of a frame.
+type Solution struct {
+	WCS         *wcs.WCS // The world coordinate system of the frame
+	RA          float64  // The Right Ascension of the centre of the frame, in degrees
+	Dec         float64  // The Declination of the centre of the frame, in degrees
+	PixelScale  float64  // The pixel scale, in arcseconds per pixel
+	Rotation    float64  // The position angle of the +y axis of the frame, east of north, in degrees
+	Flipped     bool     // Whether the frame is mirrored, i.e., east is clockwise of north
+	FieldWidth  float64  // The width of the field, in degrees
+	FieldHeight float64  // The height of the field, in degrees
+	Stars       int      // The number of stars of the frame used to solve it
+	Matches     int      // The number of stars matched to the catalogue
+	RMS         float64  // The RMS of the distances between the matched stars and their catalogue positions, in arcseconds
+}
+
+/*****************************************************************************************************************/
+
+// A star of the frame matched to a star of the catalogue:
+type match struct {
+	star     int     // The index of the star of the frame
+	source   int32   // The index of the star of the catalogue
+	distance float64 // The distance between the star and the projected catalogue star, in pixels
+}
+
+/*****************************************************************************************************************/
+
+type solver struct {
+	index  *Index
+	params Params
+	xs     int
+	ys     int
+	stars  [][2]float64
+}
+
+/*****************************************************************************************************************/
+
+/*
+Solve()
+
+Finds the astrometric solution of the frame against the index, from the stars found by
+StarsExtractor.FindStars(), as SolveStars().
+
+@see Lang, D. et al. (2010). Astrometry.net: Blind astrometric calibration of arbitrary astronomical images. AJ, 139, 1782
+*/
+func Solve(f *fits.FITSImage, index *Index, params Params) (*Solution, error) {
+	xs, ys, ok := f.GetDimensions()
+
+	if !ok || len(f.Data) != xs*ys {
+		return nil, fmt.Errorf("the image data of %d pixels does not match the dimensions %dx%d", len(f.Data), xs, ys)
+	}
+
+	stars, _ := photometry.FindStars(f.Data, xs, ys, f.ADU, params.StarRadius, params.StarSigma, params.StarInOut)
+
+	return SolveStars(stars, xs, ys, index, params)
+}
+
+/*****************************************************************************************************************/
+
+/*
+SolveStars()
+
+Finds the astrometric solution of the frame of the dimensions against the index, from its stars.
+
+Quads are formed from the brightest stars of the frame (in both parities, as the frame may be
+mirrored), and their codes are looked up in the index. Each matching quad of the index gives a
+candidate solution, within the hints, which is verified by the number of the brightest stars of
+the frame which lie on the projected positions of the stars of the catalogue. The first verified
+solution is refined by iteratively matching all of the stars and fitting the TAN-SIP projection
+by least squares.
+*/
+func SolveStars(stars []photometry.Star, xs int, ys int, index *Index, params Params) (*Solution, error) {
+	if index == nil || len(index.Quads) == 0 {
+		return nil, errors.New("the index has no quads")
+	}
+
+	if params.QuadStars < 4 || params.MaxStars < params.QuadStars {
+		return nil, errors.New("at least 4 stars are required to form quads, and no more than are used to verify the solution")
+	}
+
+	sorted := make([]photometry.Star, len(stars))
+
+	copy(sorted, stars)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Intensity > sorted[j].Intensity
+	})
+
+	s := &solver{
+		index:  index,
+		params: params,
+		xs:     xs,
+		ys:     ys,
+		stars:  make([][2]float64, 0, params.MaxStars),
+	}
+
+	for _, star := range sorted[:min(len(sorted), params.MaxStars)] {
+		s.stars = append(s.stars, [2]float64{float64(star.X), float64(star.Y)})
+	}
+
+	if len(s.stars) < 4 {
+		return nil, fmt.Errorf("at least 4 stars are required to solve the frame, but %d were found", len(s.stars))
+	}
+
+	w, err := s.search()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.refine(w)
+}
+
+/*****************************************************************************************************************/
+
+/*
+WriteToHeader()
+
+Writes the solution to the FITS header, i.e., the TAN or TAN-SIP world coordinate system with
+the PLTSOLVD keyword, the pixel scale and the quality of the solution.
+*/
+func (s *Solution) WriteToHeader(h *fits.FITSHeader) {
+	s.WCS.WriteToHeader(h)
+
+	h.Set("PLTSOLVD", true, "The frame has been plate solved")
+	h.Set("PIXSCALE", s.PixelScale, "Pixel scale (in arcseconds per pixel)")
+	h.Set("ASTMATCH", s.Matches, "The number of stars matched to the catalogue")
+	h.Set("ASTRRMS", s.RMS, "The RMS of the astrometric residuals (arcsec)")
+
+	h.History = append(
+		h.History,
+		fmt.Sprintf("Plate solved against a local catalogue index: %d of %d stars matched, RMS %.2f arcsec", s.Matches, s.Stars, s.RMS),
+	)
+}
+
+/*****************************************************************************************************************/
+
+// Searches for a verified solution from the quads of the brightest stars, brightest pairs first.
+func (s *solver) search() (*wcs.WCS, error) {
+	n := min(len(s.stars), s.params.QuadStars)
+
+	for b := 1; b < n; b++ {
+		for a := 0; a < b; a++ {
+			pa, pb := s.stars[a], s.stars[b]
+
+			size := math.Hypot(pb[0]-pa[0], pb[1]-pa[1])
+
+			if size < s.params.MinQuadPixels {
+				continue
+			}
+
+			// The stars within the circle of which the pair is the diameter, brightest first:
+			mx, my := (pa[0]+pb[0])/2, (pa[1]+pb[1])/2
+
+			inner := []int{}
+
+			for k := 0; k < n && len(inner) < maxImageInner; k++ {
+				if k != a && k != b && math.Hypot(s.stars[k][0]-mx, s.stars[k][1]-my) <= size/2 {
+					inner = append(inner, k)
+				}
+			}
+
+			for j := 0; j < len(inner); j++ {
+				for k := j + 1; k < len(inner); k++ {
+					stars := [4]int{a, b, inner[j], inner[k]}
+
+					// The frame may be mirrored relative to the sky, so the quad is coded in both parities:
+					for _, parity := range []float64{1, -1} {
+						var points [4][2]float64
+
+						for i, star := range stars {
+							points[i] = [2]float64{parity * s.stars[star][0], s.stars[star][1]}
+						}
+
+						code, order := getCode(points)
+
+						ordered := [4]int{stars[order[0]], stars[order[1]], stars[order[2]], stars[order[3]]}
+
+						for _, q := range s.index.findQuads(code, s.params.CodeTolerance) {
+							if w := s.verify(ordered, q); w != nil {
+								return w, nil
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return nil, errors.New("no solution was found, the frame may not be covered by the index or may not satisfy the hints")
+}
+
+/*****************************************************************************************************************/
+
+// Verifies the candidate solution of the quad of the frame matched to the quad of the index, returning its world
+// coordinate system if it satisfies the hints and enough stars of the frame are matched to the catalogue.
+func (s *solver) verify(stars [4]int, q int32) *wcs.WCS {
+	quad := s.index.Quads[q]
+
+	a, b := s.index.Sources[quad[0]], s.index.Sources[quad[1]]
+
+	pixels := math.Hypot(s.stars[stars[1]][0]-s.stars[stars[0]][0], s.stars[stars[1]][1]-s.stars[stars[0]][1])
+
+	scale := s.index.sizes[q] / pixels * 3600
+
+	hints := s.params.Hints
+
+	if (hints.ScaleLow > 0 && scale < hints.ScaleLow) || (hints.ScaleHigh > 0 && scale > hints.ScaleHigh) {
+		return nil
+	}
+
+	ra, dec := getMidpoint(a.RA, a.Dec, b.RA, b.Dec)
+
+	// The quad is within the field, so its centre is within half of the diagonal of the field of the centre of the frame:
+	if hints.Radius > 0 && getAngularDistance(ra, dec, hints.RA, hints.Dec) > hints.Radius+math.Hypot(float64(s.xs), float64(s.ys))/2*scale/3600 {
+		return nil
+	}
+
+	xs, ys, ras, decs := make([]float64, 4), make([]float64, 4), make([]float64, 4), make([]float64, 4)
+
+	for i := range stars {
+		xs[i], ys[i] = s.stars[stars[i]][0], s.stars[stars[i]][1]
+
+		ras[i], decs[i] = s.index.Sources[quad[i]].RA, s.index.Sources[quad[i]].Dec
+	}
+
+	w, err := s.fit(xs, ys, ras, decs, ra, dec, 1)
+
+	if err != nil {
+		return nil
+	}
+
+	if hints.Radius > 0 {
+		cra, cdec := w.PixelToEquatorial(float64(s.xs-1)/2, float64(s.ys-1)/2)
+
+		if getAngularDistance(cra, cdec, hints.RA, hints.Dec) > hints.Radius {
+			return nil
+		}
+	}
+
+	matches, sources := s.match(w)
+
+	if len(matches) < s.params.MinMatches {
+		return nil
+	}
+
+	if float64(len(matches)) < s.params.MinMatchFraction*float64(min(len(s.stars), sources)) {
+		return nil
+	}
+
+	return w
+}
+
+/*****************************************************************************************************************/
+
+// Refines the solution by iteratively matching the stars of the frame to the catalogue and fitting the projection,
+// falling back to a linear (TAN) fit if there are too few matches to constrain the distortion.
+func (s *solver) refine(w *wcs.WCS) (*Solution, error) {
+	var matches []match
+
+	for iteration := 0; iteration < refinements; iteration++ {
+		matches, _ = s.match(w)
+
+		// After the first iteration, the matches of blended or misidentified stars are rejected from the fit:
+		if iteration > 0 {
+			matches = getInliers(matches)
+		}
+
+		order := s.params.SIPOrder
+
+		// At least three matches for each coefficient of each polynomial are required to fit the distortion:
+		if len(matches) < 3*(order+1)*(order+2)/2 {
+			order = 1
+		}
+
+		xs, ys, ras, decs := make([]float64, len(matches)), make([]float64, len(matches)), make([]float64, len(matches)), make([]float64, len(matches))
+
+		for i, m := range matches {
+			xs[i], ys[i] = s.stars[m.star][0], s.stars[m.star][1]
+
+			ras[i], decs[i] = s.index.Sources[m.source].RA, s.index.Sources[m.source].Dec
+		}
+
+		refined, err := s.fit(xs, ys, ras, decs, w.CRVAL1, w.CRVAL2, max(order, 1))
+
+		if err != nil {
+			return nil, err
+		}
+
+		w = refined
+	}
+
+	matches, _ = s.match(w)
+
+	matches = getInliers(matches)
+
+	rms := 0.0
+
+	for _, m := range matches {
+		ra, dec := w.PixelToEquatorial(s.stars[m.star][0], s.stars[m.star][1])
+
+		d := getAngularDistance(ra, dec, s.index.Sources[m.source].RA, s.index.Sources[m.source].Dec) * 3600
+
+		rms += d * d
+	}
+
+	if len(matches) > 0 {
+		rms = math.Sqrt(rms / float64(len(matches)))
+	}
+
+	ra, dec := w.PixelToEquatorial(float64(s.xs-1)/2, float64(s.ys-1)/2)
+
+	scale := w.GetPixelScale()
+
+	return &Solution{
+		WCS:         w,
+		RA:          ra,
+		Dec:         dec,
+		PixelScale:  scale,
+		Rotation:    math.Atan2(w.CD1_2, w.CD2_2) * 180 / math.Pi,
+		Flipped:     w.CD1_1*w.CD2_2-w.CD1_2*w.CD2_1 > 0,
+		FieldWidth:  float64(s.xs) * scale / 3600,
+		FieldHeight: float64(s.ys) * scale / 3600,
+		Stars:       len(s.stars),
+		Matches:     len(matches),
+		RMS:         rms,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+/*
+Matches the stars of the frame to the stars of the catalogue projected onto the frame by the
+world coordinate system, considering the brightest of the catalogue stars within the frame (twice
+as many as the stars of the frame), such that each star of the catalogue is matched at most once,
+to its nearest star within the match radius.
+
+Returns the matches, and the number of catalogue stars within the frame which were considered.
+*/
+func (s *solver) match(w *wcs.WCS) ([]match, int) {
+	ra, dec := w.PixelToEquatorial(float64(s.xs-1)/2, float64(s.ys-1)/2)
+
+	radius := math.Hypot(float64(s.xs), float64(s.ys)) / 2 * w.GetPixelScale() / 3600
+
+	candidates := s.index.getSourcesWithin(ra, dec, radius*1.05, 0)
+
+	// The stars of the index are ordered brightest first:
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+
+	sources, projected := make([]int32, 0, 2*len(s.stars)), make([][2]float64, 0, 2*len(s.stars))
+
+	for _, c := range candidates {
+		x, y, err := w.EquatorialToPixel(s.index.Sources[c].RA, s.index.Sources[c].Dec)
+
+		if err != nil || x < 0 || y < 0 || x > float64(s.xs-1) || y > float64(s.ys-1) {
+			continue
+		}
+
+		sources, projected = append(sources, c), append(projected, [2]float64{x, y})
+
+		if len(sources) >= 2*len(s.stars) {
+			break
+		}
+	}
+
+	// The nearest catalogue star of each star of the frame, within the match radius:
+	nearest := []match{}
+
+	for i, p := range s.stars {
+		best := match{star: i, source: -1, distance: s.params.MatchRadius}
+
+		for k, c := range projected {
+			if d := math.Hypot(c[0]-p[0], c[1]-p[1]); d <= best.distance {
+				best.source, best.distance = sources[k], d
+			}
+		}
+
+		if best.source >= 0 {
+			nearest = append(nearest, best)
+		}
+	}
+
+	sort.SliceStable(nearest, func(i, j int) bool { return nearest[i].distance < nearest[j].distance })
+
+	matched := make(map[int32]bool)
+
+	matches := make([]match, 0, len(nearest))
+
+	for _, m := range nearest {
+		if !matched[m.source] {
+			matched[m.source] = true
+
+			matches = append(matches, m)
+		}
+	}
+
+	return matches, len(sources)
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the matches within 3 times the median distance of the matches, rejecting those of blended or misidentified
+// stars.
+func getInliers(matches []match) []match {
+	if len(matches) == 0 {
+		return matches
+	}
+
+	distances := make([]float64, len(matches))
+
+	for i, m := range matches {
+		distances[i] = m.distance
+	}
+
+	sort.Float64s(distances)
+
+	threshold := 3 * distances[len(distances)/2]
+
+	inliers := make([]match, 0, len(matches))
+
+	for _, m := range matches {
+		if m.distance <= threshold {
+			inliers = append(inliers, m)
+		}
+	}
+
+	return inliers
+}
+
+/*****************************************************************************************************************/
+
+/*
+Fits the TAN (order 1) or TAN-SIP projection of the stars of the frame at pixels { xs, ys } onto
+their equatorial coordinates { ras, decs }, with the reference pixel at the centre of the frame.
+
+The gnomonic projection { xi, eta } of the stars about the reference point is fitted by a
+polynomial of the pixel offsets from the reference pixel by least squares, and the reference
+point is moved to the constant term of the fit until it vanishes. The linear terms are the CD
+matrix, and the higher terms are the SIP distortion, i.e., CD·(A_p_q, B_p_q).
+*/
+func (s *solver) fit(xs []float64, ys []float64, ras []float64, decs []float64, ra0 float64, dec0 float64, order int) (*wcs.WCS, error) {
+	terms := [][2]int{}
+
+	for n := 0; n <= order; n++ {
+		for q := 0; q <= n; q++ {
+			terms = append(terms, [2]int{n - q, q})
+		}
+	}
+
+	if len(xs) < len(terms) {
+		return nil, fmt.Errorf("%d stars were matched, %d are required for an order %d fit", len(xs), len(terms), order)
+	}
+
+	crpix1, crpix2 := float64(s.xs+1)/2, float64(s.ys+1)/2
+
+	// The pixel offsets are normalised by the half-size of the frame, to condition the fit:
+	norm := math.Max(float64(max(s.xs, s.ys))/2, 1)
+
+	a := mat.NewDense(len(xs), len(terms), nil)
+
+	for i := range xs {
+		u, v := (xs[i]+1-crpix1)/norm, (ys[i]+1-crpix2)/norm
+
+		for k, t := range terms {
+			a.Set(i, k, math.Pow(u, float64(t[0]))*math.Pow(v, float64(t[1])))
+		}
+	}
+
+	var cx, cy []float64
+
+	for iteration := 0; iteration < 10; iteration++ {
+		xi, eta := mat.NewVecDense(len(xs), nil), mat.NewVecDense(len(xs), nil)
+
+		for i := range xs {
+			x, y := project(ra0, dec0, ras[i], decs[i])
+
+			xi.SetVec(i, x)
+
+			eta.SetVec(i, y)
+		}
+
+		var x, y mat.VecDense
+
+		if err := x.SolveVec(a, xi); err != nil {
+			return nil, fmt.Errorf("the projection could not be fitted: %w", err)
+		}
+
+		if err := y.SolveVec(a, eta); err != nil {
+			return nil, fmt.Errorf("the projection could not be fitted: %w", err)
+		}
+
+		cx, cy = x.RawVector().Data, y.RawVector().Data
+
+		// The reference point has converged when the reference pixel is projected to within 1e-9° of it:
+		if math.Hypot(cx[0], cy[0]) < 1e-9 {
+			break
+		}
+
+		ra0, dec0 = deproject(ra0, dec0, cx[0], cy[0])
+	}
+
+	w := &wcs.WCS{
+		CRPIX1: crpix1,
+		CRPIX2: crpix2,
+		CRVAL1: ra0,
+		CRVAL2: dec0,
+		CD1_1:  cx[1] / norm,
+		CD1_2:  cx[2] / norm,
+		CD2_1:  cy[1] / norm,
+		CD2_2:  cy[2] / norm,
+	}
+
+	det := w.CD1_1*w.CD2_2 - w.CD1_2*w.CD2_1
+
+	if det == 0 || math.IsNaN(det) {
+		return nil, errors.New("the fitted projection is singular")
+	}
+
+	if order < 2 {
+		return w, nil
+	}
+
+	w.SetSIPOrder(order)
+
+	for k, t := range terms[3:] {
+		scale := math.Pow(norm, float64(t[0]+t[1]))
+
+		alpha, beta := cx[k+3]/scale, cy[k+3]/scale
+
+		w.A[t[0]][t[1]] = (w.CD2_2*alpha - w.CD1_2*beta) / det
+
+		w.B[t[0]][t[1]] = (-w.CD2_1*alpha + w.CD1_1*beta) / det
+	}
+
+	return w, nil
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,303 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/platesolve
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package platesolve
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/internal/starfield"
+	"github.com/observerly/iris/pkg/wcs"
+)
+
+/*****************************************************************************************************************/
+
+// Creates a test catalogue of stars uniformly distributed within 3° of { 150, 20 }, with magnitudes distributed as
+// for a field of stars, i.e., with about 2.5 times as many stars of each fainter magnitude:
+func getTestCatalogue() *Catalogue {
+	random := rand.New(rand.NewSource(42))
+
+	c := &Catalogue{Sources: []Source{}}
+
+	for len(c.Sources) < 3000 {
+		xi, eta := (random.Float64()*2-1)*3, (random.Float64()*2-1)*3
+
+		if math.Hypot(xi, eta) > 3 {
+			continue
+		}
+
+		ra, dec := deproject(150, 20, xi, eta)
+
+		magnitude := 6 + math.Log(1+random.Float64()*(math.Pow(2.5, 8)-1))/math.Log(2.5)
+
+		c.Sources = append(c.Sources, Source{RA: ra, Dec: dec, Magnitude: magnitude})
+	}
+
+	return c
+}
+
+/*****************************************************************************************************************/
+
+// Creates the world coordinate system of a 512x512 test frame centred near { 150.3, 19.8 }, with a pixel scale of
+// 10"/px rotated by 30° and a small second-order distortion:
+func getTestWCS() *wcs.WCS {
+	scale, rotation := 10.0/3600, 30*math.Pi/180
+
+	w := &wcs.WCS{
+		CRPIX1: 256.5,
+		CRPIX2: 256.5,
+		CRVAL1: 150.3,
+		CRVAL2: 19.8,
+		CD1_1:  -scale * math.Cos(rotation),
+		CD1_2:  -scale * math.Sin(rotation),
+		CD2_1:  -scale * math.Sin(rotation),
+		CD2_2:  scale * math.Cos(rotation),
+	}
+
+	w.SetSIPOrder(2)
+
+	w.A[2][0], w.A[0][2], w.B[1][1] = 2e-5, -1e-5, 1.5e-5
+
+	return w
+}
+
+/*****************************************************************************************************************/
+
+// Renders the stars of the catalogue within the frame of the world coordinate system, as Gaussian stars (σ of 1.5
+// pixels) on a flat background with Gaussian noise:
+func getTestImage(c *Catalogue, w *wcs.WCS, xs int, ys int) *fits.FITSImage {
+	random := rand.New(rand.NewSource(7))
+
+	data := make([]float32, xs*ys)
+
+	for i := range data {
+		data[i] = float32(1000 + random.NormFloat64()*10)
+	}
+
+	for _, s := range c.Sources {
+		x, y, err := w.EquatorialToPixel(s.RA, s.Dec)
+
+		if err != nil || x < -10 || y < -10 || x > float64(xs+10) || y > float64(ys+10) {
+			continue
+		}
+
+		flux := 300000 * math.Pow(10, -0.4*(s.Magnitude-6))
+
+		starfield.AddStar(data, xs, ys, x, y, flux)
+	}
+
+	for i := range data {
+		data[i] = float32(math.Min(float64(data[i]), 65535))
+	}
+
+	f := fits.NewFITSImage(2, int32(xs), int32(ys), 65535)
+
+	f.Data = data
+
+	f.Naxisn = []int32{int32(xs), int32(ys)}
+
+	return f
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the maximum distance (in arcseconds) between the positions of the pixels of the frame by the solution
+// and by the world coordinate system of the frame:
+func getMaximumError(s *Solution, w *wcs.WCS, xs int, ys int) float64 {
+	maximum := 0.0
+
+	for y := 0; y < ys; y += 32 {
+		for x := 0; x < xs; x += 32 {
+			ra1, dec1 := s.WCS.PixelToEquatorial(float64(x), float64(y))
+
+			ra2, dec2 := w.PixelToEquatorial(float64(x), float64(y))
+
+			maximum = math.Max(maximum, getAngularDistance(ra1, dec1, ra2, dec2)*3600)
+		}
+	}
+
+	return maximum
+}
+
+/*****************************************************************************************************************/
+
+func TestSolveBlind(t *testing.T) {
+	index, err := NewIndex(getTestCatalogue(), DefaultIndexParams())
+
+	if err != nil {
+		t.Fatalf("NewIndex() error: %v", err)
+	}
+
+	w := getTestWCS()
+
+	f := getTestImage(&Catalogue{Sources: index.Sources}, w, 512, 512)
+
+	s, err := Solve(f, index, DefaultParams())
+
+	if err != nil {
+		t.Fatalf("Solve() error: %v", err)
+	}
+
+	if getAngularDistance(s.RA, s.Dec, 150.3, 19.8) > 10.0/3600 {
+		t.Errorf("centre = (%f, %f), expected (150.3, 19.8)", s.RA, s.Dec)
+	}
+
+	if math.Abs(s.PixelScale-10) > 0.05 {
+		t.Errorf("pixel scale = %f, expected 10", s.PixelScale)
+	}
+
+	if math.Abs(s.Rotation-(-30)) > 0.1 {
+		t.Errorf("rotation = %f, expected -30", s.Rotation)
+	}
+
+	if s.Flipped {
+		t.Errorf("expected the solution not to be flipped")
+	}
+
+	if s.WCS.SIPOrder != 2 {
+		t.Errorf("SIP order = %d, expected 2", s.WCS.SIPOrder)
+	}
+
+	if s.Matches < 20 {
+		t.Errorf("matches = %d, expected at least 20", s.Matches)
+	}
+
+	if s.RMS > 2 {
+		t.Errorf("RMS = %f\", expected less than 2\"", s.RMS)
+	}
+
+	if e := getMaximumError(s, w, 512, 512); e > 3 {
+		t.Errorf("maximum error = %f\", expected less than 3\"", e)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestSolveFlippedWithHints(t *testing.T) {
+	index, err := NewIndex(getTestCatalogue(), DefaultIndexParams())
+
+	if err != nil {
+		t.Fatalf("NewIndex() error: %v", err)
+	}
+
+	w := getTestWCS()
+
+	// Mirror the frame, e.g., as by a diagonal mirror:
+	w.CD1_1, w.CD2_1 = -w.CD1_1, -w.CD2_1
+
+	w.SetSIPOrder(0)
+
+	f := getTestImage(&Catalogue{Sources: index.Sources}, w, 512, 512)
+
+	params := DefaultParams()
+
+	params.Hints = NewHintsFromObservation(&fits.FITSObservation{RA: 150.2, Dec: 19.9, PixelScale: 10}, 1, 0.1)
+
+	s, err := Solve(f, index, params)
+
+	if err != nil {
+		t.Fatalf("Solve() error: %v", err)
+	}
+
+	if !s.Flipped {
+		t.Errorf("expected the solution to be flipped")
+	}
+
+	if e := getMaximumError(s, w, 512, 512); e > 3 {
+		t.Errorf("maximum error = %f\", expected less than 3\"", e)
+	}
+
+	// The hints exclude the position of the frame:
+	params.Hints = Hints{RA: 160, Dec: 20, Radius: 1}
+
+	if _, err := Solve(f, index, params); err == nil {
+		t.Errorf("expected an error when the hints exclude the frame")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestSolutionWriteToHeader(t *testing.T) {
+	index, err := NewIndex(getTestCatalogue(), DefaultIndexParams())
+
+	if err != nil {
+		t.Fatalf("NewIndex() error: %v", err)
+	}
+
+	w := getTestWCS()
+
+	f := getTestImage(&Catalogue{Sources: index.Sources}, w, 512, 512)
+
+	s, err := Solve(f, index, DefaultParams())
+
+	if err != nil {
+		t.Fatalf("Solve() error: %v", err)
+	}
+
+	s.WriteToHeader(&f.Header)
+
+	if f.Header.Strings["CTYPE1"].Value != "RA---TAN-SIP" {
+		t.Errorf("CTYPE1 = %q, expected RA---TAN-SIP", f.Header.Strings["CTYPE1"].Value)
+	}
+
+	if !f.Header.Bools["PLTSOLVD"].Value {
+		t.Errorf("expected PLTSOLVD to be true")
+	}
+
+	// The world coordinate system read from the header is that of the solution, to the precision of the header:
+	header, err := wcs.NewWCSFromHeader(f.Header)
+
+	if err != nil {
+		t.Fatalf("NewWCSFromHeader() error: %v", err)
+	}
+
+	for _, p := range [][2]float64{{0, 0}, {511, 0}, {0, 511}, {511, 511}, {256, 256}} {
+		ra1, dec1 := header.PixelToEquatorial(p[0], p[1])
+
+		ra2, dec2 := s.WCS.PixelToEquatorial(p[0], p[1])
+
+		if d := getAngularDistance(ra1, dec1, ra2, dec2) * 3600; d > 0.5 {
+			t.Errorf("pixel (%f, %f) differs by %f\" from the solution", p[0], p[1], d)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewHintsFromHeader(t *testing.T) {
+	h := fits.NewFITSHeader(2, 512, 512)
+
+	h.Set("RA", float32(150.3), "")
+	h.Set("DEC", float32(19.8), "")
+	h.Set("XPIXSZ", float32(3.76), "")
+	h.Set("FOCALLEN", float32(400), "")
+
+	hints := NewHintsFromHeader(h, 2, 0.1)
+
+	if math.Abs(hints.RA-150.3) > 1e-4 || math.Abs(hints.Dec-19.8) > 1e-4 || hints.Radius != 2 {
+		t.Errorf("position = (%f, %f) within %f, expected (150.3, 19.8) within 2", hints.RA, hints.Dec, hints.Radius)
+	}
+
+	scale := 206.265 * 3.76 / 400
+
+	if math.Abs(hints.ScaleLow-0.9*scale) > 1e-4 || math.Abs(hints.ScaleHigh-1.1*scale) > 1e-4 {
+		t.Errorf("scale = [%f, %f], expected [%f, %f]", hints.ScaleLow, hints.ScaleHigh, 0.9*scale, 1.1*scale)
+	}
+
+	// Without a position or scale, the search is blind:
+	if hints := NewHintsFromHeader(fits.NewFITSHeader(2, 512, 512), 2, 0.1); hints != (Hints{}) {
+		t.Errorf("expected blind hints, but got %+v", hints)
+	}
+}
+
+/*****************************************************************************************************************/
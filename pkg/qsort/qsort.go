@@ -10,6 +10,12 @@ package qsort
 
 /*****************************************************************************************************************/
 
+import (
+	"math"
+)
+
+/*****************************************************************************************************************/
+
 // Partition an array of float32 with the middle pivot element, and return the pivot index.
 //
 // Values less than the pivot are moved left of the pivot, those greater are moved right.
@@ -137,3 +143,118 @@ func QSelectMedianFloat32(a []float32) float32 {
 }
 
 /*****************************************************************************************************************/
+
+// Select kth lowest element from an array of float64. Partially reorders the array.
+func QSelectFloat64(a []float64, k int) float64 {
+	left, right := 0, len(a)-1
+
+	for left < right {
+		mid := (left + right) >> 1
+
+		pivot := a[mid]
+
+		l, r := left-1, right+1
+
+		for {
+			for {
+				l++
+				if a[l] >= pivot {
+					break
+				}
+			}
+			for {
+				r--
+				if a[r] <= pivot {
+					break
+				}
+			}
+			if l >= r {
+				break
+			}
+			a[l], a[r] = a[r], a[l]
+		}
+
+		index := r
+
+		offset := index - left + 1
+
+		if k <= offset {
+			right = index
+		} else {
+			left = index + 1
+			k -= offset
+		}
+	}
+
+	return a[left]
+}
+
+/*****************************************************************************************************************/
+
+// Selects the median of an array of float64 and partially reorders the array.
+func QSelectMedianFloat64(a []float64) float64 {
+	// Quickly  select the midpoint element:
+	k := (len(a) >> 1) + 1
+
+	// Get the upper kth element:
+	upper := QSelectFloat64(a, k)
+
+	// For odd lengths, the found element is the median:
+	if (len(a) & 1) != 0 {
+		return upper
+	}
+
+	// For even lengths, calculate the maximum of all elements below the pivot:
+	lower := a[0]
+
+	for i := 1; i < k-1; i++ {
+		if a[i] > lower {
+			lower = a[i]
+		}
+	}
+
+	// Return average of the upper and lower elements:
+	return 0.5 * (lower + upper)
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the median of the (non-NaN) values of an array of float32, without reordering the array, or zero if
+// there are none.
+func MedianFloat32(a []float32) float32 {
+	values := make([]float32, 0, len(a))
+
+	for _, v := range a {
+		if !math.IsNaN(float64(v)) {
+			values = append(values, v)
+		}
+	}
+
+	if len(values) == 0 {
+		return 0
+	}
+
+	return QSelectMedianFloat32(values)
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the median of the (non-NaN) values of an array of float64, without reordering the array, or zero if
+// there are none.
+func MedianFloat64(a []float64) float64 {
+	values := make([]float64, 0, len(a))
+
+	for _, v := range a {
+		if !math.IsNaN(v) {
+			values = append(values, v)
+		}
+	}
+
+	if len(values) == 0 {
+		return 0
+	}
+
+	return QSelectMedianFloat64(values)
+}
+
+/*****************************************************************************************************************/
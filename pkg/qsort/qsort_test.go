@@ -11,6 +11,7 @@ package qsort
 /*****************************************************************************************************************/
 
 import (
+	"math"
 	"testing"
 )
 
@@ -191,3 +192,57 @@ func TestQSelectMedianFloat32DispersedRandom(t *testing.T) {
 }
 
 /*****************************************************************************************************************/
+
+func TestQSelectMedianFloat64DispersedRandom(t *testing.T) {
+	a := []float64{10, 12, 23, 23, 16, 23, 21, 16}
+
+	median := 18.5
+
+	got := QSelectMedianFloat64(a)
+
+	if median != got {
+		t.Errorf("median should be 18.5, but got %v", got)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestMedianFloat32(t *testing.T) {
+	a := []float32{5, float32(math.NaN()), 1, 4, 2}
+
+	got := MedianFloat32(a)
+
+	if got != 3 {
+		t.Errorf("median of the non-NaN values should be 3, but got %v", got)
+	}
+
+	if a[0] != 5 || a[2] != 1 {
+		t.Errorf("expected the array not to be reordered, but got %v", a)
+	}
+
+	if got := MedianFloat32([]float32{}); got != 0 {
+		t.Errorf("median of no values should be 0, but got %v", got)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestMedianFloat64(t *testing.T) {
+	a := []float64{7, 3, math.NaN(), 9, 1, 5}
+
+	got := MedianFloat64(a)
+
+	if got != 5 {
+		t.Errorf("median of the non-NaN values should be 5, but got %v", got)
+	}
+
+	if a[0] != 7 || a[1] != 3 {
+		t.Errorf("expected the array not to be reordered, but got %v", a)
+	}
+
+	if got := MedianFloat64([]float64{math.NaN()}); got != 0 {
+		t.Errorf("median of no values should be 0, but got %v", got)
+	}
+}
+
+/*****************************************************************************************************************/
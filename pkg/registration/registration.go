@@ -0,0 +1,403 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/registration
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package registration
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// Params describes how the star lists of the reference and target frames are matched.
+type Params struct {
+	Model           TransformModel // The transform model fitted between the target and reference frames
+	MaxStars        int            // The number of brightest stars of each frame used to build triangles
+	Tolerance       float64        // The maximum difference in triangle invariants for triangles to match
+	MinimumSide     float64        // The minimum side length (in pixels) of the triangles
+	InlierThreshold float64        // The maximum residual (in pixels) of a matched pair of stars
+	Iterations      int            // The number of RANSAC iterations
+	MinimumMatches  int            // The minimum number of matched pairs of stars for a successful registration
+	Seed            int64          // The seed of the RANSAC random sampling, such that registration is repeatable
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default registration parameters, i.e., a similarity transform fitted to the 40 brightest stars.
+func DefaultParams() Params {
+	return Params{
+		Model:           Similarity,
+		MaxStars:        40,
+		Tolerance:       0.005,
+		MinimumSide:     10,
+		InlierThreshold: 2,
+		Iterations:      500,
+		MinimumMatches:  6,
+		Seed:            1,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Match is a pair of stars in the reference and target frames which are the same star.
+type Match struct {
+	Reference int     // The index of the star in the reference star list
+	Target    int     // The index of the star in the target star list
+	Residual  float64 // The distance (in pixels) between the reference star and the transformed target star
+}
+
+/*****************************************************************************************************************/
+
+// Result is the registration of a target frame onto a reference frame.
+type Result struct {
+	Transform      Transform // The transform of target frame pixel coordinates to reference frame pixel coordinates
+	Matches        []Match   // The matched pairs of stars, i.e., the inliers of the fitted transform
+	Candidates     int       // The number of candidate pairs of stars proposed by matching triangles
+	RMS            float64   // The root-mean-square residual of the matched pairs, in pixels
+	MedianResidual float64   // The median residual of the matched pairs, in pixels
+	MaxResidual    float64   // The maximum residual of the matched pairs, in pixels
+}
+
+/*****************************************************************************************************************/
+
+// A candidate pair of reference and target stars, with the number of matching triangles voting for it:
+type candidate struct {
+	reference int
+	target    int
+	votes     int
+}
+
+/*****************************************************************************************************************/
+
+/*
+Register()
+
+Registers the target frame onto the reference frame from their star lists, e.g., as found by
+photometry.StarsExtractor.FindStars().
+
+Triangles are built from the brightest stars of each frame, and described by the ratios of
+their sides, which are invariant under translation, rotation and scaling. Triangles of the
+two frames with matching invariants vote for the correspondence of their vertices, and the
+most voted pairs of stars are the candidate matches. A transform of the given model is then
+fitted to the candidates by RANSAC, rejecting the false matches, before being refined by
+least squares over every pair of stars consistent with it.
+
+Returns the transform of target pixel coordinates to reference pixel coordinates, the matched
+pairs of stars, and the residual statistics of the fit.
+
+@see Valdes, F. et al. (1995). FOCAS Automatic Catalog Matching Algorithms. PASP, 107, 1119
+@see Fischler, M. & Bolles, R. (1981). Random Sample Consensus. Comm. ACM, 24(6), 381
+*/
+func Register(reference []photometry.Star, target []photometry.Star, params Params) (*Result, error) {
+	minimum := params.Model.MinimumPoints()
+
+	if len(reference) < max(3, minimum) || len(target) < max(3, minimum) {
+		return nil, fmt.Errorf("at least %d stars are required in each frame to register a %s transform", max(3, minimum), params.Model)
+	}
+
+	// Select the brightest stars of each frame, retaining their indices in the original star lists:
+	ri := getBrightestStars(reference, params.MaxStars)
+
+	ti := getBrightestStars(target, params.MaxStars)
+
+	rp := getPoints(reference, ri)
+
+	tp := getPoints(target, ti)
+
+	votes := voteTriangleCorrespondences(NewTriangles(rp, params.MinimumSide), NewTriangles(tp, params.MinimumSide), len(rp), len(tp), params.Tolerance)
+
+	candidates := getCandidates(votes)
+
+	if len(candidates) < minimum {
+		return nil, fmt.Errorf("only %d candidate pairs of stars were found by triangle matching, %d are required", len(candidates), minimum)
+	}
+
+	src := make([]Point, len(candidates))
+
+	dst := make([]Point, len(candidates))
+
+	for i, c := range candidates {
+		src[i], dst[i] = tp[c.target], rp[c.reference]
+	}
+
+	// Fit the transform to the candidate pairs by RANSAC, rejecting the false matches:
+	transform, err := ransac(src, dst, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Refine the transform by least squares over every pair of stars consistent with it:
+	allReference := getPoints(reference, nil)
+
+	allTarget := getPoints(target, nil)
+
+	var matches []Match
+
+	for i := 0; i < 3; i++ {
+		matches = matchStars(allReference, allTarget, transform, params.InlierThreshold)
+
+		if len(matches) < minimum {
+			break
+		}
+
+		src := make([]Point, len(matches))
+
+		dst := make([]Point, len(matches))
+
+		for j, m := range matches {
+			src[j], dst[j] = allTarget[m.Target], allReference[m.Reference]
+		}
+
+		refined, err := FitTransform(params.Model, src, dst)
+
+		if err != nil {
+			break
+		}
+
+		transform = refined
+	}
+
+	matches = matchStars(allReference, allTarget, transform, params.InlierThreshold)
+
+	if len(matches) < max(minimum, params.MinimumMatches) {
+		return nil, fmt.Errorf("only %d pairs of stars were matched, %d are required", len(matches), max(minimum, params.MinimumMatches))
+	}
+
+	result := &Result{
+		Transform:  transform,
+		Matches:    matches,
+		Candidates: len(candidates),
+	}
+
+	residuals := make([]float64, len(matches))
+
+	for i, m := range matches {
+		residuals[i] = m.Residual
+
+		result.RMS += m.Residual * m.Residual
+
+		result.MaxResidual = math.Max(result.MaxResidual, m.Residual)
+	}
+
+	result.RMS = math.Sqrt(result.RMS / float64(len(matches)))
+
+	result.MedianResidual = qsort.MedianFloat64(residuals)
+
+	return result, nil
+}
+
+/*****************************************************************************************************************/
+
+// Fits the transform model to the candidate pairs by RANSAC, i.e., fitting the transform to many random minimal
+// samples of the candidates, and keeping the transform consistent with the most candidates.
+func ransac(src []Point, dst []Point, params Params) (Transform, error) {
+	minimum := params.Model.MinimumPoints()
+
+	random := rand.New(rand.NewSource(params.Seed))
+
+	best, bestInliers, bestError := Transform{}, 0, math.Inf(1)
+
+	sample := make([]int, minimum)
+
+	ss := make([]Point, minimum)
+
+	ds := make([]Point, minimum)
+
+	iterations := max(params.Iterations, 1)
+
+	for i := 0; i < iterations; i++ {
+		// Draw a minimal sample of distinct candidate pairs:
+		for j := range sample {
+		draw:
+			for {
+				sample[j] = random.Intn(len(src))
+
+				for k := 0; k < j; k++ {
+					if sample[k] == sample[j] {
+						continue draw
+					}
+				}
+
+				break
+			}
+
+			ss[j], ds[j] = src[sample[j]], dst[sample[j]]
+		}
+
+		t, err := FitTransform(params.Model, ss, ds)
+
+		if err != nil {
+			continue
+		}
+
+		inliers, sum := 0, 0.0
+
+		for j := range src {
+			p := t.ApplyPoint(src[j])
+
+			d := math.Hypot(p.X-dst[j].X, p.Y-dst[j].Y)
+
+			if d <= params.InlierThreshold {
+				inliers++
+				sum += d
+			}
+		}
+
+		if inliers > bestInliers || (inliers == bestInliers && sum < bestError) {
+			best, bestInliers, bestError = t, inliers, sum
+		}
+
+		// Every candidate is consistent with the transform, so no better transform can be found:
+		if inliers == len(src) {
+			break
+		}
+	}
+
+	if bestInliers < minimum {
+		return Transform{}, errors.New("no transform is consistent with the candidate pairs of stars")
+	}
+
+	return best, nil
+}
+
+/*****************************************************************************************************************/
+
+// Matches each target star to the nearest reference star to its transformed position, within the threshold,
+// keeping only mutually nearest pairs such that each star is matched at most once.
+func matchStars(reference []Point, target []Point, transform Transform, threshold float64) []Match {
+	nearest := func(p Point, points []Point) (int, float64) {
+		best, distance := -1, math.Inf(1)
+
+		for i, q := range points {
+			if d := math.Hypot(p.X-q.X, p.Y-q.Y); d < distance {
+				best, distance = i, d
+			}
+		}
+
+		return best, distance
+	}
+
+	transformed := make([]Point, len(target))
+
+	for i, p := range target {
+		transformed[i] = transform.ApplyPoint(p)
+	}
+
+	matches := make([]Match, 0)
+
+	for t, p := range transformed {
+		r, d := nearest(p, reference)
+
+		if r < 0 || d > threshold {
+			continue
+		}
+
+		// Ensure the target star is also the nearest to the reference star:
+		if back, _ := nearest(reference[r], transformed); back != t {
+			continue
+		}
+
+		matches = append(matches, Match{Reference: r, Target: t, Residual: d})
+	}
+
+	return matches
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the candidate pairs of stars from the triangle votes, i.e., for each reference star the target star
+// with the most votes, keeping each target star only for its most voted reference star, most voted first.
+func getCandidates(votes [][]int) []candidate {
+	all := make([]candidate, 0)
+
+	for r := range votes {
+		for t, v := range votes[r] {
+			if v > 0 {
+				all = append(all, candidate{reference: r, target: t, votes: v})
+			}
+		}
+	}
+
+	sort.SliceStable(all, func(a, b int) bool {
+		return all[a].votes > all[b].votes
+	})
+
+	usedReference := make(map[int]bool)
+
+	usedTarget := make(map[int]bool)
+
+	candidates := make([]candidate, 0)
+
+	for _, c := range all {
+		if usedReference[c.reference] || usedTarget[c.target] {
+			continue
+		}
+
+		usedReference[c.reference], usedTarget[c.target] = true, true
+
+		candidates = append(candidates, c)
+	}
+
+	return candidates
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the indices of the brightest stars (by intensity), up to the given number (zero is unlimited).
+func getBrightestStars(stars []photometry.Star, n int) []int {
+	indices := make([]int, len(stars))
+
+	for i := range indices {
+		indices[i] = i
+	}
+
+	sort.SliceStable(indices, func(a, b int) bool {
+		return stars[indices[a]].Intensity > stars[indices[b]].Intensity
+	})
+
+	if n > 0 && len(indices) > n {
+		indices = indices[:n]
+	}
+
+	return indices
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the positions of the stars with the given indices, or of every star if the indices are nil.
+func getPoints(stars []photometry.Star, indices []int) []Point {
+	if indices == nil {
+		points := make([]Point, len(stars))
+
+		for i, s := range stars {
+			points[i] = Point{X: float64(s.X), Y: float64(s.Y)}
+		}
+
+		return points
+	}
+
+	points := make([]Point, len(indices))
+
+	for i, j := range indices {
+		points[i] = Point{X: float64(stars[j].X), Y: float64(stars[j].Y)}
+	}
+
+	return points
+}
+
+/*****************************************************************************************************************/
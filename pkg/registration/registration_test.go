@@ -0,0 +1,150 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/registration
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package registration
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/observerly/iris/pkg/photometry"
+)
+
+/*****************************************************************************************************************/
+
+func getTestStarField(n int, seed int64) []photometry.Star {
+	random := rand.New(rand.NewSource(seed))
+
+	stars := make([]photometry.Star, n)
+
+	for i := range stars {
+		stars[i] = photometry.Star{
+			X:         float32(20 + random.Float64()*460),
+			Y:         float32(20 + random.Float64()*460),
+			Intensity: float32(100 + random.Float64()*1000),
+		}
+	}
+
+	return stars
+}
+
+/*****************************************************************************************************************/
+
+// Transforms the reference stars into the target frame, i.e., by the inverse of the target to reference transform:
+func getTestTargetStars(t *testing.T, reference []photometry.Star, transform Transform) []photometry.Star {
+	inv, err := transform.Inverse()
+
+	if err != nil {
+		t.Fatalf("Inverse() error: %v", err)
+	}
+
+	target := make([]photometry.Star, len(reference))
+
+	for i, s := range reference {
+		x, y := inv.Apply(float64(s.X), float64(s.Y))
+
+		target[i] = photometry.Star{X: float32(x), Y: float32(y), Intensity: s.Intensity}
+	}
+
+	return target
+}
+
+/*****************************************************************************************************************/
+
+func TestRegisterSimilarity(t *testing.T) {
+	reference := getTestStarField(30, 7)
+
+	expected := NewSimilarityTransform(1.01, 0.15, 12.5, -8.25)
+
+	target := getTestTargetStars(t, reference, expected)
+
+	result, err := Register(reference, target, DefaultParams())
+
+	if err != nil {
+		t.Fatalf("Register() error: %v", err)
+	}
+
+	if len(result.Matches) != len(reference) {
+		t.Errorf("len(Matches) = %d, expected %d", len(result.Matches), len(reference))
+	}
+
+	for _, m := range result.Matches {
+		if m.Reference != m.Target {
+			t.Errorf("reference star %d matched target star %d", m.Reference, m.Target)
+		}
+	}
+
+	if math.Abs(result.Transform.Scale()-1.01) > 1e-4 {
+		t.Errorf("Scale() = %f, expected 1.01", result.Transform.Scale())
+	}
+
+	if math.Abs(result.Transform.Rotation()-0.15) > 1e-4 {
+		t.Errorf("Rotation() = %f, expected 0.15", result.Transform.Rotation())
+	}
+
+	if result.RMS > 1e-3 {
+		t.Errorf("RMS = %f, expected < 0.001", result.RMS)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRegisterHomographyWithOutliers(t *testing.T) {
+	reference := getTestStarField(30, 11)
+
+	expected := Transform{
+		Model:  Homography,
+		Matrix: [3][3]float64{{0.999, 0.01, 6}, {-0.01, 1.001, -4}, {2e-6, -1e-6, 1}},
+	}
+
+	target := getTestTargetStars(t, reference, expected)
+
+	// Add stars which are only present in each frame, e.g., noise or stars which have left the field:
+	reference = append(reference, getTestStarField(5, 13)...)
+
+	target = append(getTestStarField(5, 17), target...)
+
+	params := DefaultParams()
+
+	params.Model = Homography
+
+	result, err := Register(reference, target, params)
+
+	if err != nil {
+		t.Fatalf("Register() error: %v", err)
+	}
+
+	if len(result.Matches) < 30 {
+		t.Errorf("len(Matches) = %d, expected at least 30", len(result.Matches))
+	}
+
+	for _, m := range result.Matches {
+		if m.Reference < 30 && m.Target != m.Reference+5 {
+			t.Errorf("reference star %d matched target star %d, expected %d", m.Reference, m.Target, m.Reference+5)
+		}
+	}
+
+	if result.MaxResidual > params.InlierThreshold {
+		t.Errorf("MaxResidual = %f, expected <= %f", result.MaxResidual, params.InlierThreshold)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestRegisterTooFewStars(t *testing.T) {
+	stars := getTestStarField(2, 1)
+
+	if _, err := Register(stars, stars, DefaultParams()); err == nil {
+		t.Errorf("expected an error registering two stars")
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,476 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/registration
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package registration
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"gonum.org/v1/gonum/mat"
+)
+
+/*****************************************************************************************************************/
+
+// Point is a position on the image plane, in pixels.
+type Point struct {
+	X float64
+	Y float64
+}
+
+/*****************************************************************************************************************/
+
+// TransformModel determines the degrees of freedom of the transform fitted between two frames.
+type TransformModel int
+
+/*****************************************************************************************************************/
+
+const (
+	// A shift in x and y, e.g., for an equatorially mounted telescope without field rotation (2 parameters):
+	Translation TransformModel = iota
+	// A shift, rotation and uniform scale, e.g., for field rotation or a change in focal length (4 parameters):
+	Similarity
+	// A shift, rotation, non-uniform scale and shear, e.g., for differential refraction (6 parameters):
+	Affine
+	// A projective transform, e.g., for frames from different optical systems (8 parameters):
+	Homography
+)
+
+/*****************************************************************************************************************/
+
+// Returns the name of the transform model, e.g., "similarity".
+func (m TransformModel) String() string {
+	switch m {
+	case Translation:
+		return "translation"
+	case Similarity:
+		return "similarity"
+	case Affine:
+		return "affine"
+	case Homography:
+		return "homography"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(m))
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Returns the minimum number of point correspondences required to fit the transform model.
+func (m TransformModel) MinimumPoints() int {
+	switch m {
+	case Translation:
+		return 1
+	case Similarity:
+		return 2
+	case Affine:
+		return 3
+	default:
+		return 4
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Transform is a projective transform of the image plane, in homogeneous coordinates, i.e., a point { x, y }
+// is transformed to { x', y' } where [x'w, y'w, w] = Matrix · [x, y, 1].
+type Transform struct {
+	Model  TransformModel // The model of the transform
+	Matrix [3][3]float64  // The homogeneous transform matrix
+}
+
+/*****************************************************************************************************************/
+
+// Returns the identity transform.
+func NewIdentityTransform() Transform {
+	return Transform{
+		Model:  Translation,
+		Matrix: [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}},
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Returns the transform shifting every point by { dx, dy }.
+func NewTranslationTransform(dx float64, dy float64) Transform {
+	return Transform{
+		Model:  Translation,
+		Matrix: [3][3]float64{{1, 0, dx}, {0, 1, dy}, {0, 0, 1}},
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Returns the transform scaling and rotating (anti-clockwise, in radians) every point about the origin, then
+// shifting it by { dx, dy }.
+func NewSimilarityTransform(scale float64, rotation float64, dx float64, dy float64) Transform {
+	a, b := scale*math.Cos(rotation), scale*math.Sin(rotation)
+
+	return Transform{
+		Model:  Similarity,
+		Matrix: [3][3]float64{{a, -b, dx}, {b, a, dy}, {0, 0, 1}},
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Transforms the point { x, y }.
+func (t Transform) Apply(x float64, y float64) (float64, float64) {
+	m := t.Matrix
+
+	w := m[2][0]*x + m[2][1]*y + m[2][2]
+
+	return (m[0][0]*x + m[0][1]*y + m[0][2]) / w, (m[1][0]*x + m[1][1]*y + m[1][2]) / w
+}
+
+/*****************************************************************************************************************/
+
+// Transforms the point.
+func (t Transform) ApplyPoint(p Point) Point {
+	x, y := t.Apply(p.X, p.Y)
+
+	return Point{X: x, Y: y}
+}
+
+/*****************************************************************************************************************/
+
+// Returns the inverse transform, or an error if the transform is singular.
+func (t Transform) Inverse() (Transform, error) {
+	m := t.Matrix
+
+	det := m[0][0]*(m[1][1]*m[2][2]-m[1][2]*m[2][1]) -
+		m[0][1]*(m[1][0]*m[2][2]-m[1][2]*m[2][0]) +
+		m[0][2]*(m[1][0]*m[2][1]-m[1][1]*m[2][0])
+
+	if math.Abs(det) < 1e-15 {
+		return Transform{}, errors.New("the transform is singular and cannot be inverted")
+	}
+
+	inv := [3][3]float64{
+		{
+			(m[1][1]*m[2][2] - m[1][2]*m[2][1]) / det,
+			(m[0][2]*m[2][1] - m[0][1]*m[2][2]) / det,
+			(m[0][1]*m[1][2] - m[0][2]*m[1][1]) / det,
+		},
+		{
+			(m[1][2]*m[2][0] - m[1][0]*m[2][2]) / det,
+			(m[0][0]*m[2][2] - m[0][2]*m[2][0]) / det,
+			(m[0][2]*m[1][0] - m[0][0]*m[1][2]) / det,
+		},
+		{
+			(m[1][0]*m[2][1] - m[1][1]*m[2][0]) / det,
+			(m[0][1]*m[2][0] - m[0][0]*m[2][1]) / det,
+			(m[0][0]*m[1][1] - m[0][1]*m[1][0]) / det,
+		},
+	}
+
+	// Normalise the homogeneous scale, such that affine transforms remain affine:
+	if inv[2][2] != 0 {
+		s := inv[2][2]
+
+		for i := range inv {
+			for j := range inv[i] {
+				inv[i][j] /= s
+			}
+		}
+	}
+
+	return Transform{Model: t.Model, Matrix: inv}, nil
+}
+
+/*****************************************************************************************************************/
+
+// Returns the transform which applies t, followed by u.
+func (t Transform) Then(u Transform) Transform {
+	var m [3][3]float64
+
+	for i := 0; i < 3; i++ {
+		for j := 0; j < 3; j++ {
+			for k := 0; k < 3; k++ {
+				m[i][j] += u.Matrix[i][k] * t.Matrix[k][j]
+			}
+		}
+	}
+
+	return Transform{Model: max(t.Model, u.Model), Matrix: m}
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the mean linear scale of the transform, i.e., the square root of the determinant of its linear part.
+func (t Transform) Scale() float64 {
+	m := t.Matrix
+
+	return math.Sqrt(math.Abs(m[0][0]*m[1][1] - m[0][1]*m[1][0]))
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the rotation (anti-clockwise, in radians) of the linear part of the transform.
+func (t Transform) Rotation() float64 {
+	m := t.Matrix
+
+	return math.Atan2(m[1][0]-m[0][1], m[0][0]+m[1][1])
+}
+
+/*****************************************************************************************************************/
+
+// Fits the transform model to the point correspondences by (linear) least squares, such that each source point
+// is transformed to its corresponding destination point.
+func FitTransform(model TransformModel, src []Point, dst []Point) (Transform, error) {
+	if len(src) != len(dst) {
+		return Transform{}, errors.New("to fit a transform the source and destination points must be of same length")
+	}
+
+	if len(src) < model.MinimumPoints() {
+		return Transform{}, fmt.Errorf("a %s transform requires at least %d points, got %d", model, model.MinimumPoints(), len(src))
+	}
+
+	switch model {
+	case Translation:
+		return fitTranslation(src, dst), nil
+	case Similarity:
+		return fitSimilarity(src, dst)
+	case Affine:
+		return fitAffine(src, dst)
+	case Homography:
+		return fitHomography(src, dst)
+	default:
+		return Transform{}, fmt.Errorf("unknown transform model: %d", model)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Fits a translation, i.e., the mean shift between the corresponding points.
+func fitTranslation(src []Point, dst []Point) Transform {
+	dx, dy := 0.0, 0.0
+
+	for i := range src {
+		dx += dst[i].X - src[i].X
+		dy += dst[i].Y - src[i].Y
+	}
+
+	return NewTranslationTransform(dx/float64(len(src)), dy/float64(len(src)))
+}
+
+/*****************************************************************************************************************/
+
+// Fits a similarity transform, i.e., x' = a·x - b·y + tx and y' = b·x + a·y + ty, in normalised coordinates.
+func fitSimilarity(src []Point, dst []Point) (Transform, error) {
+	ns, ps := normalisePoints(src)
+
+	nd, pd := normalisePoints(dst)
+
+	a := mat.NewDense(2*len(src), 4, nil)
+
+	b := mat.NewVecDense(2*len(src), nil)
+
+	for i := range ps {
+		a.SetRow(2*i, []float64{ps[i].X, -ps[i].Y, 1, 0})
+		a.SetRow(2*i+1, []float64{ps[i].Y, ps[i].X, 0, 1})
+		b.SetVec(2*i, pd[i].X)
+		b.SetVec(2*i+1, pd[i].Y)
+	}
+
+	x, err := solveLeastSquares(a, b)
+
+	if err != nil {
+		return Transform{}, err
+	}
+
+	t := Transform{
+		Model:  Similarity,
+		Matrix: [3][3]float64{{x[0], -x[1], x[2]}, {x[1], x[0], x[3]}, {0, 0, 1}},
+	}
+
+	return denormalise(t, ns, nd)
+}
+
+/*****************************************************************************************************************/
+
+// Fits an affine transform, i.e., x' = a·x + b·y + c and y' = d·x + e·y + f, in normalised coordinates.
+func fitAffine(src []Point, dst []Point) (Transform, error) {
+	ns, ps := normalisePoints(src)
+
+	nd, pd := normalisePoints(dst)
+
+	a := mat.NewDense(len(src), 3, nil)
+
+	bx := mat.NewVecDense(len(src), nil)
+
+	by := mat.NewVecDense(len(src), nil)
+
+	for i := range ps {
+		a.SetRow(i, []float64{ps[i].X, ps[i].Y, 1})
+		bx.SetVec(i, pd[i].X)
+		by.SetVec(i, pd[i].Y)
+	}
+
+	x, err := solveLeastSquares(a, bx)
+
+	if err != nil {
+		return Transform{}, err
+	}
+
+	y, err := solveLeastSquares(a, by)
+
+	if err != nil {
+		return Transform{}, err
+	}
+
+	t := Transform{
+		Model:  Affine,
+		Matrix: [3][3]float64{{x[0], x[1], x[2]}, {y[0], y[1], y[2]}, {0, 0, 1}},
+	}
+
+	return denormalise(t, ns, nd)
+}
+
+/*****************************************************************************************************************/
+
+// Fits a homography by the normalised Direct Linear Transform, i.e., the right singular vector of the smallest
+// singular value of the stacked constraints.
+//
+// @see Hartley, R. & Zisserman, A. (2004). Multiple View Geometry in Computer Vision. p.109
+func fitHomography(src []Point, dst []Point) (Transform, error) {
+	ns, ps := normalisePoints(src)
+
+	nd, pd := normalisePoints(dst)
+
+	rows := 2 * len(src)
+
+	// The SVD requires at least as many rows as columns:
+	if rows < 9 {
+		rows = 9
+	}
+
+	a := mat.NewDense(rows, 9, nil)
+
+	for i := range ps {
+		x, y, u, v := ps[i].X, ps[i].Y, pd[i].X, pd[i].Y
+
+		a.SetRow(2*i, []float64{-x, -y, -1, 0, 0, 0, u * x, u * y, u})
+		a.SetRow(2*i+1, []float64{0, 0, 0, -x, -y, -1, v * x, v * y, v})
+	}
+
+	var svd mat.SVD
+
+	if ok := svd.Factorize(a, mat.SVDFullV); !ok {
+		return Transform{}, errors.New("the homography could not be fitted")
+	}
+
+	var vt mat.Dense
+
+	svd.VTo(&vt)
+
+	h := make([]float64, 9)
+
+	for i := range h {
+		h[i] = vt.At(i, 8)
+	}
+
+	if math.Abs(h[8]) < 1e-12 {
+		return Transform{}, errors.New("the homography is degenerate")
+	}
+
+	t := Transform{
+		Model:  Homography,
+		Matrix: [3][3]float64{{h[0], h[1], h[2]}, {h[3], h[4], h[5]}, {h[6], h[7], h[8]}},
+	}
+
+	return denormalise(t, ns, nd)
+}
+
+/*****************************************************************************************************************/
+
+// Solves the (possibly overdetermined) linear system a·x = b by least squares.
+func solveLeastSquares(a *mat.Dense, b *mat.VecDense) ([]float64, error) {
+	var x mat.VecDense
+
+	if err := x.SolveVec(a, b); err != nil {
+		return nil, fmt.Errorf("the transform could not be fitted: %w", err)
+	}
+
+	return x.RawVector().Data, nil
+}
+
+/*****************************************************************************************************************/
+
+// Normalises the points such that their centroid is at the origin and their mean distance from it is √2,
+// returning the normalising transform and the normalised points.
+func normalisePoints(points []Point) (Transform, []Point) {
+	cx, cy := 0.0, 0.0
+
+	for _, p := range points {
+		cx += p.X
+		cy += p.Y
+	}
+
+	cx /= float64(len(points))
+
+	cy /= float64(len(points))
+
+	d := 0.0
+
+	for _, p := range points {
+		d += math.Hypot(p.X-cx, p.Y-cy)
+	}
+
+	d /= float64(len(points))
+
+	s := 1.0
+
+	if d > 0 {
+		s = math.Sqrt2 / d
+	}
+
+	t := Transform{
+		Model:  Similarity,
+		Matrix: [3][3]float64{{s, 0, -s * cx}, {0, s, -s * cy}, {0, 0, 1}},
+	}
+
+	normalised := make([]Point, len(points))
+
+	for i, p := range points {
+		normalised[i] = t.ApplyPoint(p)
+	}
+
+	return t, normalised
+}
+
+/*****************************************************************************************************************/
+
+// Converts a transform fitted between normalised points back to the original coordinates, i.e., T = nd⁻¹ · t · ns.
+func denormalise(t Transform, ns Transform, nd Transform) (Transform, error) {
+	inv, err := nd.Inverse()
+
+	if err != nil {
+		return Transform{}, err
+	}
+
+	r := ns.Then(t).Then(inv)
+
+	// Normalise the homogeneous scale:
+	if s := r.Matrix[2][2]; s != 0 {
+		for i := range r.Matrix {
+			for j := range r.Matrix[i] {
+				r.Matrix[i][j] /= s
+			}
+		}
+	}
+
+	r.Model = t.Model
+
+	return r, nil
+}
+
+/*****************************************************************************************************************/
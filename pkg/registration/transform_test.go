@@ -0,0 +1,123 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/registration
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package registration
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+func getTestPoints() []Point {
+	return []Point{
+		{12, 15}, {87, 22}, {140, 61}, {33, 118}, {95, 97}, {170, 150}, {58, 175}, {121, 190},
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestTransformModelMinimumPoints(t *testing.T) {
+	expected := map[TransformModel]int{Translation: 1, Similarity: 2, Affine: 3, Homography: 4}
+
+	for model, n := range expected {
+		if model.MinimumPoints() != n {
+			t.Errorf("%s MinimumPoints() = %d, expected %d", model, model.MinimumPoints(), n)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewSimilarityTransformApplyAndInverse(t *testing.T) {
+	tr := NewSimilarityTransform(2, math.Pi/2, 10, -5)
+
+	x, y := tr.Apply(1, 0)
+
+	if math.Abs(x-10) > 1e-9 || math.Abs(y-(-3)) > 1e-9 {
+		t.Errorf("Apply(1, 0) = (%f, %f), expected (10, -3)", x, y)
+	}
+
+	if math.Abs(tr.Scale()-2) > 1e-9 {
+		t.Errorf("Scale() = %f, expected 2", tr.Scale())
+	}
+
+	if math.Abs(tr.Rotation()-math.Pi/2) > 1e-9 {
+		t.Errorf("Rotation() = %f, expected %f", tr.Rotation(), math.Pi/2)
+	}
+
+	inv, err := tr.Inverse()
+
+	if err != nil {
+		t.Fatalf("Inverse() error: %v", err)
+	}
+
+	x, y = tr.Then(inv).Apply(3, 4)
+
+	if math.Abs(x-3) > 1e-9 || math.Abs(y-4) > 1e-9 {
+		t.Errorf("Then(Inverse()).Apply(3, 4) = (%f, %f), expected (3, 4)", x, y)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFitTransformRecoversModels(t *testing.T) {
+	homography := Transform{
+		Model:  Homography,
+		Matrix: [3][3]float64{{1.02, 0.05, 4}, {-0.03, 0.98, -7}, {1e-4, -5e-5, 1}},
+	}
+
+	affine := Transform{
+		Model:  Affine,
+		Matrix: [3][3]float64{{1.1, 0.2, -3}, {-0.1, 0.9, 12}, {0, 0, 1}},
+	}
+
+	for _, expected := range []Transform{
+		NewTranslationTransform(5.5, -2.25),
+		NewSimilarityTransform(1.05, 0.3, 20, -15),
+		affine,
+		homography,
+	} {
+		src := getTestPoints()
+
+		dst := make([]Point, len(src))
+
+		for i, p := range src {
+			dst[i] = expected.ApplyPoint(p)
+		}
+
+		fitted, err := FitTransform(expected.Model, src, dst)
+
+		if err != nil {
+			t.Fatalf("FitTransform(%s) error: %v", expected.Model, err)
+		}
+
+		for i := 0; i < 3; i++ {
+			for j := 0; j < 3; j++ {
+				if math.Abs(fitted.Matrix[i][j]-expected.Matrix[i][j]) > 1e-6 {
+					t.Errorf("FitTransform(%s) Matrix[%d][%d] = %g, expected %g", expected.Model, i, j, fitted.Matrix[i][j], expected.Matrix[i][j])
+				}
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestFitTransformTooFewPoints(t *testing.T) {
+	_, err := FitTransform(Affine, getTestPoints()[:2], getTestPoints()[:2])
+
+	if err == nil {
+		t.Errorf("expected an error fitting an affine transform to two points")
+	}
+}
+
+/*****************************************************************************************************************/
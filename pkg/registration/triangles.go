@@ -0,0 +1,129 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/registration
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package registration
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"sort"
+)
+
+/*****************************************************************************************************************/
+
+// Triangle is an asterism of three stars, described by invariants of its shape which are unchanged under any
+// translation, rotation or uniform scaling (and approximately unchanged by small shears).
+type Triangle struct {
+	Vertices [3]int     // The indices of the stars, ordered by the length of the opposite side (shortest first)
+	Ratios   [2]float64 // The ratios of the shortest and middle sides to the longest side, i.e., the invariants
+}
+
+/*****************************************************************************************************************/
+
+// Creates the triangle of the three points with the given indices, ordering the vertices canonically by the
+// length of their opposite side, such that matching triangles have corresponding vertices.
+func NewTriangle(points []Point, i int, j int, k int) Triangle {
+	// The length of the side opposite each vertex:
+	sides := [3]struct {
+		vertex int
+		length float64
+	}{
+		{i, math.Hypot(points[j].X-points[k].X, points[j].Y-points[k].Y)},
+		{j, math.Hypot(points[i].X-points[k].X, points[i].Y-points[k].Y)},
+		{k, math.Hypot(points[i].X-points[j].X, points[i].Y-points[j].Y)},
+	}
+
+	sort.Slice(sides[:], func(a, b int) bool {
+		return sides[a].length < sides[b].length
+	})
+
+	t := Triangle{
+		Vertices: [3]int{sides[0].vertex, sides[1].vertex, sides[2].vertex},
+	}
+
+	if sides[2].length > 0 {
+		t.Ratios = [2]float64{sides[0].length / sides[2].length, sides[1].length / sides[2].length}
+	}
+
+	return t
+}
+
+/*****************************************************************************************************************/
+
+// Creates all of the triangles of the points, rejecting those smaller than the minimum side length (in pixels)
+// and the most elongated, whose invariants are poorly determined by the positional uncertainty of the stars.
+func NewTriangles(points []Point, minimumSide float64) []Triangle {
+	n := len(points)
+
+	triangles := make([]Triangle, 0, n*(n-1)*(n-2)/6)
+
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			for k := j + 1; k < n; k++ {
+				t := NewTriangle(points, i, j, k)
+
+				// The shortest side is opposite the first vertex:
+				a, b := points[t.Vertices[1]], points[t.Vertices[2]]
+
+				shortest := math.Hypot(a.X-b.X, a.Y-b.Y)
+
+				if shortest < minimumSide || t.Ratios[0] < 0.1 {
+					continue
+				}
+
+				triangles = append(triangles, t)
+			}
+		}
+	}
+
+	return triangles
+}
+
+/*****************************************************************************************************************/
+
+// Votes for the correspondence of each pair of reference and target stars which are vertices of matching
+// triangles, i.e., triangles whose invariants agree within the tolerance. Returns votes[reference][target].
+func voteTriangleCorrespondences(reference []Triangle, target []Triangle, nr int, nt int, tolerance float64) [][]int {
+	votes := make([][]int, nr)
+
+	for i := range votes {
+		votes[i] = make([]int, nt)
+	}
+
+	// Sort the target triangles by their first invariant, to search only those within the tolerance:
+	sorted := make([]Triangle, len(target))
+
+	copy(sorted, target)
+
+	sort.Slice(sorted, func(a, b int) bool {
+		return sorted[a].Ratios[0] < sorted[b].Ratios[0]
+	})
+
+	for _, r := range reference {
+		start := sort.Search(len(sorted), func(i int) bool {
+			return sorted[i].Ratios[0] >= r.Ratios[0]-tolerance
+		})
+
+		for i := start; i < len(sorted) && sorted[i].Ratios[0] <= r.Ratios[0]+tolerance; i++ {
+			t := sorted[i]
+
+			if math.Abs(t.Ratios[1]-r.Ratios[1]) > tolerance {
+				continue
+			}
+
+			for v := 0; v < 3; v++ {
+				votes[r.Vertices[v]][t.Vertices[v]]++
+			}
+		}
+	}
+
+	return votes
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,74 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/registration
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package registration
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+func TestNewTriangleIsInvariant(t *testing.T) {
+	points := []Point{{0, 0}, {30, 0}, {0, 40}}
+
+	tr := NewTriangle(points, 0, 1, 2)
+
+	// The sides are 30 (opposite vertex 2), 40 (opposite vertex 1) and 50 (opposite vertex 0):
+	if tr.Vertices != [3]int{2, 1, 0} {
+		t.Errorf("Vertices = %v, expected [2 1 0]", tr.Vertices)
+	}
+
+	if math.Abs(tr.Ratios[0]-0.6) > 1e-12 || math.Abs(tr.Ratios[1]-0.8) > 1e-12 {
+		t.Errorf("Ratios = %v, expected [0.6 0.8]", tr.Ratios)
+	}
+
+	s := NewSimilarityTransform(1.7, 1.1, 100, -40)
+
+	transformed := make([]Point, len(points))
+
+	for i, p := range points {
+		transformed[i] = s.ApplyPoint(p)
+	}
+
+	u := NewTriangle(transformed, 1, 2, 0)
+
+	if u.Vertices != tr.Vertices {
+		t.Errorf("transformed Vertices = %v, expected %v", u.Vertices, tr.Vertices)
+	}
+
+	if math.Abs(u.Ratios[0]-tr.Ratios[0]) > 1e-9 || math.Abs(u.Ratios[1]-tr.Ratios[1]) > 1e-9 {
+		t.Errorf("transformed Ratios = %v, expected %v", u.Ratios, tr.Ratios)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewTrianglesRejectsSmallTriangles(t *testing.T) {
+	points := []Point{{0, 0}, {30, 0}, {0, 40}, {2, 2}}
+
+	triangles := NewTriangles(points, 10)
+
+	// The triangles with both of the two nearby points 0 and 3 as vertices are rejected:
+	if len(triangles) != 2 {
+		t.Fatalf("len(triangles) = %d, expected 2", len(triangles))
+	}
+
+	for _, tr := range triangles {
+		has := map[int]bool{tr.Vertices[0]: true, tr.Vertices[1]: true, tr.Vertices[2]: true}
+
+		if has[0] && has[3] {
+			t.Errorf("triangle %v has a side shorter than the minimum", tr.Vertices)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,130 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/resample
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package resample
+
+/*****************************************************************************************************************/
+
+import "math"
+
+/*****************************************************************************************************************/
+
+// Kernel is the interpolation kernel used to resample the image data between pixel centres.
+type Kernel int
+
+/*****************************************************************************************************************/
+
+const (
+	// The value of the nearest pixel, i.e., no interpolation, which preserves the noise distribution:
+	Nearest Kernel = iota
+	// The linear interpolation of the 2x2 nearest pixels:
+	Bilinear
+	// The Keys cubic convolution (a = -0.5) of the 4x4 nearest pixels:
+	Bicubic
+	// The Lanczos windowed sinc of the 6x6 nearest pixels:
+	Lanczos3
+	// The Lanczos windowed sinc of the 8x8 nearest pixels:
+	Lanczos4
+)
+
+/*****************************************************************************************************************/
+
+// Returns the name of the kernel, e.g., "lanczos3".
+func (k Kernel) String() string {
+	switch k {
+	case Nearest:
+		return "nearest"
+	case Bilinear:
+		return "bilinear"
+	case Bicubic:
+		return "bicubic"
+	case Lanczos3:
+		return "lanczos3"
+	case Lanczos4:
+		return "lanczos4"
+	default:
+		return "unknown"
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Returns the radius of the kernel, in pixels, i.e., half the width of its support.
+func (k Kernel) Radius() int {
+	switch k {
+	case Bilinear:
+		return 1
+	case Bicubic:
+		return 2
+	case Lanczos3:
+		return 3
+	case Lanczos4:
+		return 4
+	default:
+		return 0
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Returns the weight of the kernel at the given distance (in pixels) from the interpolated position.
+func (k Kernel) Weight(d float64) float64 {
+	d = math.Abs(d)
+
+	switch k {
+	case Nearest:
+		if d < 0.5 {
+			return 1
+		}
+
+		return 0
+	case Bilinear:
+		if d < 1 {
+			return 1 - d
+		}
+
+		return 0
+	case Bicubic:
+		// @see Keys, R. (1981). Cubic Convolution Interpolation for Digital Image Processing. IEEE TASSP, 29(6)
+		const a = -0.5
+
+		switch {
+		case d < 1:
+			return (a+2)*d*d*d - (a+3)*d*d + 1
+		case d < 2:
+			return a*d*d*d - 5*a*d*d + 8*a*d - 4*a
+		default:
+			return 0
+		}
+	case Lanczos3, Lanczos4:
+		r := float64(k.Radius())
+
+		if d >= r {
+			return 0
+		}
+
+		return sinc(d) * sinc(d/r)
+	default:
+		return 0
+	}
+}
+
+/*****************************************************************************************************************/
+
+// The normalised sinc function, i.e., sin(πx) / πx:
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+
+	x *= math.Pi
+
+	return math.Sin(x) / x
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,50 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/resample
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package resample
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+func TestKernelWeightIsInterpolating(t *testing.T) {
+	for _, k := range []Kernel{Nearest, Bilinear, Bicubic, Lanczos3, Lanczos4} {
+		if k.Weight(0) != 1 {
+			t.Errorf("%s Weight(0) = %f, expected 1", k, k.Weight(0))
+		}
+
+		for d := 1; d <= 4; d++ {
+			if w := k.Weight(float64(d)); math.Abs(w) > 1e-12 {
+				t.Errorf("%s Weight(%d) = %f, expected 0", k, d, w)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestKernelWeightPartitionOfUnity(t *testing.T) {
+	for _, k := range []Kernel{Bilinear, Bicubic} {
+		sum := 0.0
+
+		for i := -4; i <= 4; i++ {
+			sum += k.Weight(0.3 + float64(i))
+		}
+
+		if math.Abs(sum-1) > 1e-12 {
+			t.Errorf("%s weights sum to %f, expected 1", k, sum)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,310 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/resample
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package resample
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+// Params describes how the image data is resampled through a geometric transform.
+type Params struct {
+	Kernel  Kernel  // The interpolation kernel
+	Clamp   bool    // Whether to clamp the interpolated values to the range of the 2x2 nearest pixels, to limit ringing
+	Width   int     // The width of the resampled image, in pixels (zero is the width of the input image)
+	Height  int     // The height of the resampled image, in pixels (zero is the height of the input image)
+	Fill    float32 // The value of resampled pixels which fall outside of the input image, e.g., NaN
+	Workers int     // The number of row bands resampled in parallel (zero is the number of CPUs)
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default resampling parameters, i.e., a clamped Lanczos-3 kernel with NaN for pixels outside of the
+// input image, resampled in parallel over all of the CPUs.
+func DefaultParams() Params {
+	return Params{
+		Kernel:  Lanczos3,
+		Clamp:   true,
+		Fill:    float32(math.NaN()),
+		Workers: 0,
+	}
+}
+
+/*****************************************************************************************************************/
+
+/*
+Warp()
+
+Warps the image through the given affine or projective transform, i.e., the 3x3 matrix in
+homogeneous coordinates mapping input pixel coordinates (x, y, 1) to output pixel coordinates,
+e.g., the transform of a registration.Result mapping a target frame onto the reference frame.
+
+Pixel centres are at integer coordinates. Each output pixel is interpolated from the input
+pixels around its position in the input image under the inverse transform, with the kernel
+given by the params.
+
+Returns a copy of the image with the resampled data and dimensions.
+*/
+func Warp(f *fits.FITSImage, matrix [3][3]float64, params Params) (*fits.FITSImage, error) {
+	xs, ys, _ := f.GetDimensions()
+
+	width, height := params.Width, params.Height
+
+	if width <= 0 {
+		width = xs
+	}
+
+	if height <= 0 {
+		height = ys
+	}
+
+	params.Width, params.Height = width, height
+
+	data, err := WarpData(f.Data, xs, ys, matrix, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	w := f.Copy()
+
+	w.Data = data
+
+	w.Naxisn = []int32{int32(width), int32(height)}
+
+	w.Pixels = int32(width * height)
+
+	w.Header.Naxis1 = int32(width)
+
+	w.Header.Naxis2 = int32(height)
+
+	w.Header.History = append(
+		w.Header.History,
+		fmt.Sprintf("Resampled with the %s kernel through the transform %v", params.Kernel, matrix),
+	)
+
+	return w, nil
+}
+
+/*****************************************************************************************************************/
+
+/*
+WarpData()
+
+Warps the xs by ys image data through the given affine or projective transform of input pixel
+coordinates to output pixel coordinates, into an image of params.Width by params.Height pixels
+(by default, the dimensions of the input image).
+
+Output pixels whose position in the input image lies outside of it are set to params.Fill. A
+NaN input pixel propagates to the output pixels nearest to it, and is otherwise excluded from
+the interpolation, such that NaN regions are neither grown nor shrunk by resampling.
+*/
+func WarpData(data []float32, xs int, ys int, matrix [3][3]float64, params Params) ([]float32, error) {
+	if xs <= 0 || ys <= 0 || len(data) != xs*ys {
+		return nil, fmt.Errorf("the image data of %d pixels does not match the dimensions %dx%d", len(data), xs, ys)
+	}
+
+	inverse, err := invert(matrix)
+
+	if err != nil {
+		return nil, err
+	}
+
+	width, height := params.Width, params.Height
+
+	if width <= 0 {
+		width = xs
+	}
+
+	if height <= 0 {
+		height = ys
+	}
+
+	workers := params.Workers
+
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+
+	workers = min(workers, height)
+
+	out := make([]float32, width*height)
+
+	band := (height + workers - 1) / workers
+
+	var wg sync.WaitGroup
+
+	// Resample each band of rows in parallel, each writing only to its own rows of the output:
+	for y0 := 0; y0 < height; y0 += band {
+		wg.Add(1)
+
+		go func(y0 int, y1 int) {
+			defer wg.Done()
+
+			for y := y0; y < y1; y++ {
+				for x := 0; x < width; x++ {
+					fx, fy := float64(x), float64(y)
+
+					w := inverse[2][0]*fx + inverse[2][1]*fy + inverse[2][2]
+
+					// The output pixel maps to the line at infinity of the input image:
+					if w == 0 {
+						out[y*width+x] = params.Fill
+						continue
+					}
+
+					u := (inverse[0][0]*fx + inverse[0][1]*fy + inverse[0][2]) / w
+
+					v := (inverse[1][0]*fx + inverse[1][1]*fy + inverse[1][2]) / w
+
+					if !isWithin(u, v, xs, ys) {
+						out[y*width+x] = params.Fill
+						continue
+					}
+
+					out[y*width+x] = Interpolate(data, xs, ys, u, v, params.Kernel, params.Clamp)
+				}
+			}
+		}(y0, min(y0+band, height))
+	}
+
+	wg.Wait()
+
+	return out, nil
+}
+
+/*****************************************************************************************************************/
+
+/*
+Interpolate()
+
+Interpolates the xs by ys image data at the position (x, y) with the given kernel, where pixel
+centres are at integer coordinates.
+
+Kernel taps which fall outside of the image, or on NaN pixels, are excluded and the remaining
+weights renormalised. Returns NaN if the position lies outside of the image, or if the pixel
+nearest to the position is NaN.
+*/
+func Interpolate(data []float32, xs int, ys int, x float64, y float64, kernel Kernel, clamp bool) float32 {
+	if !isWithin(x, y, xs, ys) {
+		return float32(math.NaN())
+	}
+
+	nx := min(max(int(math.Round(x)), 0), xs-1)
+
+	ny := min(max(int(math.Round(y)), 0), ys-1)
+
+	nearest := data[ny*xs+nx]
+
+	r := kernel.Radius()
+
+	if r == 0 || math.IsNaN(float64(nearest)) {
+		return nearest
+	}
+
+	fx, fy := int(math.Floor(x)), int(math.Floor(y))
+
+	var wx, wy [8]float64
+
+	for i := 0; i < 2*r; i++ {
+		wx[i] = kernel.Weight(x - float64(fx-r+1+i))
+
+		wy[i] = kernel.Weight(y - float64(fy-r+1+i))
+	}
+
+	sum, weights := 0.0, 0.0
+
+	lo, hi := math.Inf(1), math.Inf(-1)
+
+	for j := 0; j < 2*r; j++ {
+		py := fy - r + 1 + j
+
+		if py < 0 || py >= ys || wy[j] == 0 {
+			continue
+		}
+
+		for i := 0; i < 2*r; i++ {
+			px := fx - r + 1 + i
+
+			if px < 0 || px >= xs {
+				continue
+			}
+
+			v := float64(data[py*xs+px])
+
+			if math.IsNaN(v) {
+				continue
+			}
+
+			// The range of the 2x2 nearest pixels, used for clamping:
+			if (px == fx || px == fx+1) && (py == fy || py == fy+1) {
+				lo, hi = math.Min(lo, v), math.Max(hi, v)
+			}
+
+			w := wx[i] * wy[j]
+
+			sum += w * v
+
+			weights += w
+		}
+	}
+
+	if weights == 0 {
+		return nearest
+	}
+
+	value := sum / weights
+
+	if clamp && lo <= hi {
+		value = math.Min(math.Max(value, lo), hi)
+	}
+
+	return float32(value)
+}
+
+/*****************************************************************************************************************/
+
+// Determines whether the position lies within the image, i.e., within half a pixel of the outermost pixel centres.
+func isWithin(x float64, y float64, xs int, ys int) bool {
+	return x >= -0.5 && x <= float64(xs)-0.5 && y >= -0.5 && y <= float64(ys)-0.5
+}
+
+/*****************************************************************************************************************/
+
+// Inverts the 3x3 transform matrix by its adjugate, returning an error if the transform is singular.
+func invert(m [3][3]float64) ([3][3]float64, error) {
+	c00 := m[1][1]*m[2][2] - m[1][2]*m[2][1]
+
+	c01 := m[1][2]*m[2][0] - m[1][0]*m[2][2]
+
+	c02 := m[1][0]*m[2][1] - m[1][1]*m[2][0]
+
+	det := m[0][0]*c00 + m[0][1]*c01 + m[0][2]*c02
+
+	if math.Abs(det) < 1e-12 || math.IsNaN(det) {
+		return [3][3]float64{}, errors.New("the transform is singular, and cannot be inverted")
+	}
+
+	return [3][3]float64{
+		{c00 / det, (m[0][2]*m[2][1] - m[0][1]*m[2][2]) / det, (m[0][1]*m[1][2] - m[0][2]*m[1][1]) / det},
+		{c01 / det, (m[0][0]*m[2][2] - m[0][2]*m[2][0]) / det, (m[0][2]*m[1][0] - m[0][0]*m[1][2]) / det},
+		{c02 / det, (m[0][1]*m[2][0] - m[0][0]*m[2][1]) / det, (m[0][0]*m[1][1] - m[0][1]*m[1][0]) / det},
+	}, nil
+}
+
+/*****************************************************************************************************************/
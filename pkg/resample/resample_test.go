@@ -0,0 +1,199 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/resample
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package resample
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+var identity = [3][3]float64{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+
+/*****************************************************************************************************************/
+
+func getTestData(xs int, ys int) []float32 {
+	data := make([]float32, xs*ys)
+
+	for y := 0; y < ys; y++ {
+		for x := 0; x < xs; x++ {
+			data[y*xs+x] = float32(10*x + y)
+		}
+	}
+
+	return data
+}
+
+/*****************************************************************************************************************/
+
+func TestWarpDataIdentity(t *testing.T) {
+	data := getTestData(8, 6)
+
+	for _, k := range []Kernel{Nearest, Bilinear, Bicubic, Lanczos3, Lanczos4} {
+		params := DefaultParams()
+
+		params.Kernel = k
+
+		out, err := WarpData(data, 8, 6, identity, params)
+
+		if err != nil {
+			t.Fatalf("%s WarpData() error: %v", k, err)
+		}
+
+		for i := range data {
+			if math.Abs(float64(out[i]-data[i])) > 1e-4 {
+				t.Errorf("%s pixel %d = %f, expected %f", k, i, out[i], data[i])
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestWarpDataTranslationFillsOutOfBounds(t *testing.T) {
+	data := getTestData(8, 6)
+
+	params := DefaultParams()
+
+	params.Kernel = Bilinear
+
+	params.Workers = 3
+
+	// Shift the image by 2 pixels in x and 1 pixel in y:
+	out, err := WarpData(data, 8, 6, [3][3]float64{{1, 0, 2}, {0, 1, 1}, {0, 0, 1}}, params)
+
+	if err != nil {
+		t.Fatalf("WarpData() error: %v", err)
+	}
+
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 8; x++ {
+			v := out[y*8+x]
+
+			if x < 2 || y < 1 {
+				if !math.IsNaN(float64(v)) {
+					t.Errorf("pixel (%d, %d) = %f, expected NaN", x, y, v)
+				}
+
+				continue
+			}
+
+			if expected := data[(y-1)*8+x-2]; math.Abs(float64(v-expected)) > 1e-4 {
+				t.Errorf("pixel (%d, %d) = %f, expected %f", x, y, v, expected)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestInterpolateBilinearHalfPixel(t *testing.T) {
+	data := getTestData(4, 4)
+
+	v := Interpolate(data, 4, 4, 1.5, 2.5, Bilinear, false)
+
+	if v != 17.5 {
+		t.Errorf("Interpolate(1.5, 2.5) = %f, expected 17.5", v)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestInterpolatePropagatesNaN(t *testing.T) {
+	data := getTestData(6, 6)
+
+	data[2*6+2] = float32(math.NaN())
+
+	if v := Interpolate(data, 6, 6, 2.2, 1.9, Lanczos3, true); !math.IsNaN(float64(v)) {
+		t.Errorf("Interpolate() nearest to a NaN pixel = %f, expected NaN", v)
+	}
+
+	v := Interpolate(data, 6, 6, 2.7, 2.7, Bicubic, true)
+
+	if math.IsNaN(float64(v)) {
+		t.Errorf("Interpolate() next to a NaN pixel = NaN, expected a value")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestInterpolateClampLimitsRinging(t *testing.T) {
+	// A sharp step edge, which rings under the Lanczos kernel:
+	data := make([]float32, 8)
+
+	for i := 4; i < 8; i++ {
+		data[i] = 100
+	}
+
+	unclamped := Interpolate(data, 8, 1, 2.3, 0, Lanczos3, false)
+
+	if unclamped >= 0 {
+		t.Errorf("unclamped Interpolate() = %f, expected undershoot below 0", unclamped)
+	}
+
+	if clamped := Interpolate(data, 8, 1, 2.3, 0, Lanczos3, true); clamped != 0 {
+		t.Errorf("clamped Interpolate() = %f, expected 0", clamped)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestWarpSingularTransform(t *testing.T) {
+	_, err := WarpData(getTestData(4, 4), 4, 4, [3][3]float64{{1, 2, 0}, {2, 4, 0}, {0, 0, 1}}, DefaultParams())
+
+	if err == nil {
+		t.Errorf("expected an error for a singular transform")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestWarpFITSImage(t *testing.T) {
+	f := fits.NewFITSImage(2, 8, 6, 65535)
+
+	f.Data = getTestData(8, 6)
+
+	params := DefaultParams()
+
+	params.Width = 16
+
+	params.Height = 12
+
+	params.Kernel = Bilinear
+
+	// Scale the image by a factor of 2:
+	w, err := Warp(f, [3][3]float64{{2, 0, 0}, {0, 2, 0}, {0, 0, 1}}, params)
+
+	if err != nil {
+		t.Fatalf("Warp() error: %v", err)
+	}
+
+	if w.Header.Naxis1 != 16 || w.Header.Naxis2 != 12 || len(w.Data) != 16*12 {
+		t.Errorf("dimensions = %dx%d (%d pixels), expected 16x12", w.Header.Naxis1, w.Header.Naxis2, len(w.Data))
+	}
+
+	if v := w.Data[4*16+6]; v != 32 {
+		t.Errorf("pixel (6, 4) = %f, expected 32", v)
+	}
+
+	if len(w.Header.History) != 1 {
+		t.Errorf("len(History) = %d, expected 1", len(w.Header.History))
+	}
+
+	if len(f.Data) != 8*6 {
+		t.Errorf("the input image was modified")
+	}
+}
+
+/*****************************************************************************************************************/
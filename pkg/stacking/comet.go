@@ -0,0 +1,211 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/stacking
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package stacking
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/frames"
+	"github.com/observerly/iris/pkg/qsort"
+	"github.com/observerly/iris/pkg/registration"
+)
+
+/*****************************************************************************************************************/
+
+// CometParams describes the apparent motion of a comet (or any other moving object) across the light frames,
+// either by its position in the first and last light frames, or by its ephemeris rate.
+type CometParams struct {
+	First registration.Point  // The position of the nucleus in the first light frame (by DATE-OBS), in its pixel coordinates
+	Last  *registration.Point // The position of the nucleus in the last light frame (by DATE-OBS), in its pixel coordinates
+	Rate  registration.Point  // The ephemeris rate of the nucleus on the reference frame, in pixels per hour, if Last is nil
+}
+
+/*****************************************************************************************************************/
+
+// CometResult is the star-aligned and comet-aligned stacks of the light frames, with their combination.
+type CometResult struct {
+	Stars          *Result              // The star-aligned stack, with the report of each light frame
+	Comet          *fits.FITSImage      // The comet-aligned stack, on which the nucleus is fixed and the stars are rejected
+	CometRejection *fits.FITSImage      // The number of values rejected at each pixel of the comet-aligned stack
+	Combined       *fits.FITSImage      // The combination of the star-aligned and comet-aligned stacks
+	Offsets        []registration.Point // The offset of the nucleus on the reference frame from its position at the reference time, for each light frame
+	Rate           registration.Point   // The rate of the nucleus on the reference frame, in pixels per hour
+}
+
+/*****************************************************************************************************************/
+
+/*
+StackComet()
+
+Stacks the light frames twice: once aligned on the stars (as Stack()), and once aligned on the
+nucleus of a comet (or any other moving object) moving linearly across the frames.
+
+The time of each light frame is its mid-exposure time, from DATE-OBS and the exposure time. The
+offset of the nucleus on the reference frame is interpolated for each light frame from its time,
+either between its positions in the first and last light frames, or from its ephemeris rate.
+Each light frame is then registered onto the reference frame by its star-aligned transform
+followed by the shift which cancels the motion of the nucleus, such that the comet is sharp and
+the stars trail, and are largely rejected.
+
+The combination takes, at each pixel, the brighter of the star-aligned and the (background
+matched) comet-aligned stacks, such that both the stars and the nucleus are sharp.
+*/
+func StackComet(lights []fits.FITSImage, library *frames.CalibrationLibrary, params Params, comet CometParams) (*CometResult, error) {
+	a, err := align(lights, library, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Obtain the mid-exposure time of each light frame:
+	times := make([]time.Time, len(lights))
+
+	first, last := -1, -1
+
+	for i := range lights {
+		if !a.registered[i] {
+			continue
+		}
+
+		t, err := lights[i].GetMidExposureTime()
+
+		if err != nil {
+			return nil, fmt.Errorf("light frame %d: %w", i, err)
+		}
+
+		times[i] = t
+
+		if first < 0 || t.Before(times[first]) {
+			first = i
+		}
+
+		if last < 0 || t.After(times[last]) {
+			last = i
+		}
+	}
+
+	// The position of the nucleus in the first light frame, on the reference frame:
+	origin := a.reports[first].Transform.ApplyPoint(comet.First)
+
+	rate := comet.Rate
+
+	if comet.Last != nil {
+		hours := times[last].Sub(times[first]).Hours()
+
+		if hours <= 0 {
+			return nil, errors.New("the first and last light frames must have different observation times to interpolate the comet motion")
+		}
+
+		end := a.reports[last].Transform.ApplyPoint(*comet.Last)
+
+		rate = registration.Point{X: (end.X - origin.X) / hours, Y: (end.Y - origin.Y) / hours}
+	}
+
+	// The offset of the nucleus of each light frame from its position at the time of the reference frame:
+	offsets := make([]registration.Point, len(lights))
+
+	for i := range lights {
+		if !a.registered[i] {
+			continue
+		}
+
+		hours := times[i].Sub(times[a.reference]).Hours()
+
+		offsets[i] = registration.Point{X: rate.X * hours, Y: rate.Y * hours}
+	}
+
+	stars, err := a.stack(params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// The comet-aligned stack, shifting each frame such that the nucleus is at its position at the reference time:
+	cometStack, cometRejection, included, _, err := a.integrate(params, func(i int) registration.Transform {
+		return registration.NewTranslationTransform(-offsets[i].X, -offsets[i].Y)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	nucleus := registration.Point{
+		X: origin.X + rate.X*times[a.reference].Sub(times[first]).Hours(),
+		Y: origin.Y + rate.Y*times[a.reference].Sub(times[first]).Hours(),
+	}
+
+	cometStack.Header.Set("COMETX", float32(nucleus.X), "The x position of the comet nucleus (pixels)")
+
+	cometStack.Header.Set("COMETY", float32(nucleus.Y), "The y position of the comet nucleus (pixels)")
+
+	cometStack.Header.Set("COMETRX", float32(rate.X), "The x rate of the comet nucleus (pixels/hour)")
+
+	cometStack.Header.Set("COMETRY", float32(rate.Y), "The y rate of the comet nucleus (pixels/hour)")
+
+	cometStack.Header.History = append(
+		cometStack.Header.History,
+		fmt.Sprintf("Stacked %d of %d light frames, aligned on the comet nucleus of light frame %d", len(included), len(lights), a.reference),
+	)
+
+	combined, err := combineStarsAndComet(stars.Stacked, cometStack)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &CometResult{
+		Stars:          stars,
+		Comet:          cometStack,
+		CometRejection: cometRejection,
+		Combined:       combined,
+		Offsets:        offsets,
+		Rate:           rate,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+// Combines the star-aligned and comet-aligned stacks by taking the brighter of the two at each pixel, once the
+// comet-aligned stack is matched to the median background level of the star-aligned stack.
+func combineStarsAndComet(stars *fits.FITSImage, comet *fits.FITSImage) (*fits.FITSImage, error) {
+	if len(stars.Data) != len(comet.Data) {
+		return nil, fmt.Errorf("the comet-aligned stack of %d pixels does not match the star-aligned stack of %d pixels", len(comet.Data), len(stars.Data))
+	}
+
+	offset := qsort.MedianFloat32(stars.Data) - qsort.MedianFloat32(comet.Data)
+
+	combined := comet.Copy()
+
+	combined.Data = make([]float32, len(stars.Data))
+
+	for i := range stars.Data {
+		s, c := stars.Data[i], comet.Data[i]+offset
+
+		switch {
+		case math.IsNaN(float64(s)):
+			combined.Data[i] = c
+		case math.IsNaN(float64(c)):
+			combined.Data[i] = s
+		default:
+			combined.Data[i] = max(s, c)
+		}
+	}
+
+	combined.Header.History = append(combined.Header.History, "Combined with the star-aligned stack, by the brighter of the two")
+
+	return combined, nil
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,160 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/stacking
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package stacking
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/registration"
+)
+
+/*****************************************************************************************************************/
+
+// Creates the light frames of a synthetic star field with a comet moving at the given rate (in reference frame
+// pixels per hour) from the given position, each frame taken 10 minutes apart:
+func getTestCometFrames(t *testing.T, start registration.Point, rate registration.Point) ([]fits.FITSImage, []registration.Transform) {
+	transforms := []registration.Transform{
+		registration.NewIdentityTransform(),
+		registration.NewTranslationTransform(3, -2),
+		registration.NewTranslationTransform(-4, 5),
+		registration.NewTranslationTransform(1, 1),
+		registration.NewTranslationTransform(-2, -3),
+		registration.NewTranslationTransform(5, 2),
+	}
+
+	epoch := time.Date(2026, 10, 19, 20, 0, 0, 0, time.UTC)
+
+	lights := make([]fits.FITSImage, len(transforms))
+
+	for i, tr := range transforms {
+		f := getTestLightFrame(160, 160, 24, tr, int64(i+1))
+
+		date := epoch.Add(time.Duration(i) * 10 * time.Minute)
+
+		f.Header.Set("DATE-OBS", date.Format("2006-01-02T15:04:05"), "The date of the observation")
+
+		f.Header.Set("EXPOSURE", float32(60), "The exposure time (s)")
+
+		// The position of the nucleus at mid-exposure, on the light frame:
+		hours := date.Add(30 * time.Second).Sub(epoch).Hours()
+
+		inverse, err := tr.Inverse()
+
+		if err != nil {
+			t.Fatalf("Inverse() error: %v", err)
+		}
+
+		x, y := inverse.Apply(start.X+rate.X*hours, start.Y+rate.Y*hours)
+
+		for py := 0; py < 160; py++ {
+			for px := 0; px < 160; px++ {
+				d2 := (float64(px)-x)*(float64(px)-x) + (float64(py)-y)*(float64(py)-y)
+
+				f.Data[py*160+px] += float32(20000 * math.Exp(-d2/(2*2.5*2.5)))
+			}
+		}
+
+		lights[i] = *f
+	}
+
+	return lights, transforms
+}
+
+/*****************************************************************************************************************/
+
+func TestStackCometFromPositions(t *testing.T) {
+	start, rate := registration.Point{X: 50, Y: 100}, registration.Point{X: 30, Y: -12}
+
+	lights, transforms := getTestCometFrames(t, start, rate)
+
+	// The positions of the nucleus on the first and last light frames, at mid-exposure:
+	first := registration.Point{X: start.X + rate.X*30/3600, Y: start.Y + rate.Y*30/3600}
+
+	hours := (50*60 + 30) / 3600.0
+
+	inverse, _ := transforms[5].Inverse()
+
+	last := inverse.ApplyPoint(registration.Point{X: start.X + rate.X*hours, Y: start.Y + rate.Y*hours})
+
+	params := DefaultParams()
+
+	params.Integration.HighSigma = 2
+
+	result, err := StackComet(lights, nil, params, CometParams{First: first, Last: &last})
+
+	if err != nil {
+		t.Fatalf("StackComet() error: %v", err)
+	}
+
+	if math.Abs(result.Rate.X-rate.X) > 0.5 || math.Abs(result.Rate.Y-rate.Y) > 0.5 {
+		t.Errorf("Rate = %+v, expected %+v", result.Rate, rate)
+	}
+
+	x := result.Comet.Header.Floats["COMETX"].Value
+
+	y := result.Comet.Header.Floats["COMETY"].Value
+
+	i := int(math.Round(float64(y)))*160 + int(math.Round(float64(x)))
+
+	// The nucleus is sharp on the comet-aligned stack, but trailed on the star-aligned stack:
+	if c, s := result.Comet.Data[i], result.Stars.Stacked.Data[i]; c < 15000 || s > c/2 {
+		t.Errorf("nucleus = %f on the comet-aligned stack and %f on the star-aligned stack", c, s)
+	}
+
+	if result.Combined.Data[i] < result.Comet.Data[i]-100 {
+		t.Errorf("combined nucleus = %f, expected at least %f", result.Combined.Data[i], result.Comet.Data[i]-100)
+	}
+
+	for _, r := range result.Stars.Frames {
+		if !r.Included {
+			t.Errorf("light frame %d was excluded: %v", r.Index, r.Err)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestStackCometFromRate(t *testing.T) {
+	rate := registration.Point{X: 30, Y: -12}
+
+	lights, _ := getTestCometFrames(t, registration.Point{X: 50, Y: 100}, rate)
+
+	result, err := StackComet(lights, nil, DefaultParams(), CometParams{First: registration.Point{X: 50, Y: 100}, Rate: rate})
+
+	if err != nil {
+		t.Fatalf("StackComet() error: %v", err)
+	}
+
+	reference := result.Stars.Reference
+
+	for i, offset := range result.Offsets {
+		hours := float64(i-reference) / 6
+
+		if math.Abs(offset.X-rate.X*hours) > 1e-9 || math.Abs(offset.Y-rate.Y*hours) > 1e-9 {
+			t.Errorf("Offsets[%d] = %+v, expected {%f %f}", i, offset, rate.X*hours, rate.Y*hours)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestStackCometRequiresDateObs(t *testing.T) {
+	lights := []fits.FITSImage{*getTestLightFrame(64, 64, 8, registration.NewIdentityTransform(), 1)}
+
+	if _, err := StackComet(lights, nil, DefaultParams(), CometParams{}); err == nil {
+		t.Errorf("expected an error stacking light frames without DATE-OBS")
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,317 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/stacking
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package stacking
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// Combination is how the (unrejected) values of each pixel across the registered frames are combined.
+type Combination int
+
+/*****************************************************************************************************************/
+
+const (
+	// The mean of the values, which maximises the signal-to-noise ratio:
+	CombineMean Combination = iota
+	// The median of the values, which is robust to outliers without rejection:
+	CombineMedian
+)
+
+/*****************************************************************************************************************/
+
+// Rejection is how outlying values of each pixel across the registered frames (e.g., cosmic rays, satellite
+// trails or hot pixels) are rejected before combining.
+type Rejection int
+
+/*****************************************************************************************************************/
+
+const (
+	// No values are rejected:
+	NoRejection Rejection = iota
+	// Values more than the low or high number of standard deviations from the median are iteratively rejected:
+	SigmaClipping
+)
+
+/*****************************************************************************************************************/
+
+// Returns the name of the rejection method, e.g., "sigma".
+func (r Rejection) String() string {
+	switch r {
+	case NoRejection:
+		return "none"
+	case SigmaClipping:
+		return "sigma"
+	default:
+		return "unknown"
+	}
+}
+
+/*****************************************************************************************************************/
+
+// IntegrationParams describes how the registered frames are integrated into the stacked frame.
+type IntegrationParams struct {
+	Combination Combination // How the unrejected values of each pixel are combined
+	Rejection   Rejection   // How the outlying values of each pixel are rejected
+	LowSigma    float32     // The number of standard deviations below the median at which values are rejected
+	HighSigma   float32     // The number of standard deviations above the median at which values are rejected
+	Iterations  int         // The maximum number of rejection iterations
+	Normalise   bool        // Whether to match the median background level of each frame to the first frame
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default integration parameters, i.e., the mean of the background-normalised frames after 3σ
+// (low) and 3σ (high) iterative sigma clipping.
+func DefaultIntegrationParams() IntegrationParams {
+	return IntegrationParams{
+		Combination: CombineMean,
+		Rejection:   SigmaClipping,
+		LowSigma:    3,
+		HighSigma:   3,
+		Iterations:  5,
+		Normalise:   true,
+	}
+}
+
+/*****************************************************************************************************************/
+
+/*
+Integrate()
+
+Integrates the registered frames (of equal length) pixel-by-pixel, rejecting the outlying
+values of each pixel before combining those which remain. NaN values, e.g., pixels of a
+registered frame which fall outside of the original frame, are excluded, and pixels with no
+values in any frame are NaN in the integrated data.
+
+Returns the integrated data, the rejection map (the number of values rejected at each pixel),
+and the number of values rejected from each frame.
+*/
+func Integrate(data [][]float32, params IntegrationParams) ([]float32, []float32, []int, error) {
+	return IntegrateWithMasks(data, nil, params)
+}
+
+/*****************************************************************************************************************/
+
+/*
+IntegrateWithMasks()
+
+Integrates the registered frames as Integrate(), excluding the masked pixels of each frame,
+e.g., the pixels affected by a satellite trail, such that only those pixels (rather than the
+whole frame) are rejected. The masks may be nil, as may the mask of any frame.
+
+Masked pixels are excluded before rejection, so are not counted in the rejection map.
+*/
+func IntegrateWithMasks(data [][]float32, masks [][]bool, params IntegrationParams) ([]float32, []float32, []int, error) {
+	if len(data) == 0 {
+		return nil, nil, nil, errors.New("at least one frame is required to integrate")
+	}
+
+	pixels := len(data[0])
+
+	for i := range data {
+		if len(data[i]) != pixels {
+			return nil, nil, nil, fmt.Errorf("frame %d has %d pixels, expected %d", i, len(data[i]), pixels)
+		}
+	}
+
+	if masks != nil && len(masks) != len(data) {
+		return nil, nil, nil, fmt.Errorf("there are %d masks for %d frames", len(masks), len(data))
+	}
+
+	for i := range masks {
+		if masks[i] != nil && len(masks[i]) != pixels {
+			return nil, nil, nil, fmt.Errorf("the mask of frame %d has %d pixels, expected %d", i, len(masks[i]), pixels)
+		}
+	}
+
+	// Whether the pixel of the frame is masked:
+	isMasked := func(i int, p int) bool {
+		return masks != nil && masks[i] != nil && masks[i][p]
+	}
+
+	// Obtain the additive offset which matches the median background level of each frame to the first frame:
+	offsets := make([]float32, len(data))
+
+	if params.Normalise {
+		reference := qsort.MedianFloat32(data[0])
+
+		for i := 1; i < len(data); i++ {
+			offsets[i] = reference - qsort.MedianFloat32(data[i])
+		}
+	}
+
+	integrated := make([]float32, pixels)
+
+	rejections := make([]float32, pixels)
+
+	workers := min(runtime.NumCPU(), max(pixels, 1))
+
+	band := (pixels + workers - 1) / workers
+
+	// The number of values rejected from each frame, by each band:
+	rejected := make([][]int, 0, workers)
+
+	var wg sync.WaitGroup
+
+	for p0 := 0; p0 < pixels; p0 += band {
+		counts := make([]int, len(data))
+
+		rejected = append(rejected, counts)
+
+		wg.Add(1)
+
+		go func(p0 int, p1 int, counts []int) {
+			defer wg.Done()
+
+			values := make([]float32, 0, len(data))
+
+			frames := make([]int, 0, len(data))
+
+			buffer := make([]float32, len(data))
+
+			for p := p0; p < p1; p++ {
+				values, frames = values[:0], frames[:0]
+
+				for i := range data {
+					if v := data[i][p]; !math.IsNaN(float64(v)) && !isMasked(i, p) {
+						values = append(values, v+offsets[i])
+
+						frames = append(frames, i)
+					}
+				}
+
+				if len(values) == 0 {
+					integrated[p] = float32(math.NaN())
+					continue
+				}
+
+				kept := len(values)
+
+				if params.Rejection == SigmaClipping {
+					kept = sigmaClip(values, frames, buffer, params)
+
+					for _, i := range frames[kept:] {
+						counts[i]++
+					}
+				}
+
+				rejections[p] = float32(len(values) - kept)
+
+				integrated[p] = combine(values[:kept], buffer, params.Combination)
+			}
+		}(p0, min(p0+band, pixels), counts)
+	}
+
+	wg.Wait()
+
+	counts := make([]int, len(data))
+
+	for _, c := range rejected {
+		for i := range c {
+			counts[i] += c[i]
+		}
+	}
+
+	return integrated, rejections, counts, nil
+}
+
+/*****************************************************************************************************************/
+
+// Iteratively rejects the values more than the low or high number of standard deviations from their median,
+// reordering the values (and their frames) such that the kept values come first. Returns the number kept.
+func sigmaClip(values []float32, frames []int, buffer []float32, params IntegrationParams) int {
+	kept := len(values)
+
+	for iteration := 0; iteration < params.Iterations && kept > 2; iteration++ {
+		median := getMedianWithBuffer(values[:kept], buffer)
+
+		mean := float64(0)
+
+		for _, v := range values[:kept] {
+			mean += float64(v)
+		}
+
+		mean /= float64(kept)
+
+		variance := float64(0)
+
+		for _, v := range values[:kept] {
+			variance += (float64(v) - mean) * (float64(v) - mean)
+		}
+
+		sigma := float32(math.Sqrt(variance / float64(kept-1)))
+
+		if sigma == 0 {
+			break
+		}
+
+		lower, upper := median-params.LowSigma*sigma, median+params.HighSigma*sigma
+
+		n := 0
+
+		for i := 0; i < kept; i++ {
+			if values[i] >= lower && values[i] <= upper {
+				values[n], values[i] = values[i], values[n]
+
+				frames[n], frames[i] = frames[i], frames[n]
+
+				n++
+			}
+		}
+
+		if n == kept {
+			break
+		}
+
+		kept = n
+	}
+
+	return kept
+}
+
+/*****************************************************************************************************************/
+
+// Combines the values by their mean or median.
+func combine(values []float32, buffer []float32, combination Combination) float32 {
+	if combination == CombineMedian {
+		return getMedianWithBuffer(values, buffer)
+	}
+
+	sum := float64(0)
+
+	for _, v := range values {
+		sum += float64(v)
+	}
+
+	return float32(sum / float64(len(values)))
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the median of the values using the buffer, without reordering them.
+func getMedianWithBuffer(values []float32, buffer []float32) float32 {
+	b := buffer[:len(values)]
+
+	copy(b, values)
+
+	return qsort.QSelectMedianFloat32(b)
+}
+
+/*****************************************************************************************************************/
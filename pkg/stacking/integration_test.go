@@ -0,0 +1,150 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/stacking
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package stacking
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+func TestIntegrateSigmaClippingRejectsOutliers(t *testing.T) {
+	data := [][]float32{
+		{10, 20}, {11, 21}, {9, 19}, {10, 20}, {10, 20}, {11, 21}, {9, 19}, {100, 20},
+	}
+
+	params := DefaultIntegrationParams()
+
+	params.Normalise = false
+
+	params.LowSigma, params.HighSigma = 2, 2
+
+	integrated, rejections, rejected, err := Integrate(data, params)
+
+	if err != nil {
+		t.Fatalf("Integrate() error: %v", err)
+	}
+
+	if integrated[0] != 10 {
+		t.Errorf("integrated[0] = %f, expected 10", integrated[0])
+	}
+
+	if integrated[1] != 20 {
+		t.Errorf("integrated[1] = %f, expected 20", integrated[1])
+	}
+
+	if rejections[0] != 1 || rejections[1] != 0 {
+		t.Errorf("rejections = %v, expected [1 0]", rejections)
+	}
+
+	if rejected[7] != 1 {
+		t.Errorf("rejected[7] = %d, expected 1", rejected[7])
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestIntegrateExcludesNaN(t *testing.T) {
+	nan := float32(math.NaN())
+
+	data := [][]float32{{1, nan}, {3, nan}, {nan, nan}}
+
+	params := DefaultIntegrationParams()
+
+	params.Normalise = false
+
+	params.Rejection = NoRejection
+
+	integrated, _, _, err := Integrate(data, params)
+
+	if err != nil {
+		t.Fatalf("Integrate() error: %v", err)
+	}
+
+	if integrated[0] != 2 {
+		t.Errorf("integrated[0] = %f, expected 2", integrated[0])
+	}
+
+	if !math.IsNaN(float64(integrated[1])) {
+		t.Errorf("integrated[1] = %f, expected NaN", integrated[1])
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestIntegrateNormalisesAndCombinesByMedian(t *testing.T) {
+	data := [][]float32{{10, 10, 10}, {15, 15, 20}, {5, 5, 8}}
+
+	params := DefaultIntegrationParams()
+
+	params.Combination = CombineMedian
+
+	params.Rejection = NoRejection
+
+	integrated, _, _, err := Integrate(data, params)
+
+	if err != nil {
+		t.Fatalf("Integrate() error: %v", err)
+	}
+
+	// The frames are offset by -5 and +5 to the median background of the first frame:
+	expected := []float32{10, 10, 13}
+
+	for i := range expected {
+		if integrated[i] != expected[i] {
+			t.Errorf("integrated[%d] = %f, expected %f", i, integrated[i], expected[i])
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestIntegrateMismatchedFrames(t *testing.T) {
+	if _, _, _, err := Integrate([][]float32{{1, 2}, {1}}, DefaultIntegrationParams()); err == nil {
+		t.Errorf("expected an error integrating frames of different lengths")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestIntegrateWithMasksExcludesMaskedPixels(t *testing.T) {
+	data := [][]float32{{10, 10, 10}, {10, 500, 10}, {10, 10, 10}}
+
+	// The trail through the second pixel of the second frame is masked:
+	masks := [][]bool{nil, {false, true, false}, nil}
+
+	params := DefaultIntegrationParams()
+
+	params.Rejection = NoRejection
+
+	integrated, rejections, _, err := IntegrateWithMasks(data, masks, params)
+
+	if err != nil {
+		t.Fatalf("IntegrateWithMasks() error: %v", err)
+	}
+
+	for i, v := range integrated {
+		if v != 10 {
+			t.Errorf("integrated[%d] = %f, expected 10", i, v)
+		}
+
+		if rejections[i] != 0 {
+			t.Errorf("rejections[%d] = %f, expected 0", i, rejections[i])
+		}
+	}
+
+	if _, _, _, err := IntegrateWithMasks(data, [][]bool{nil, {true}, nil}, params); err == nil {
+		t.Errorf("expected an error integrating with a mask of a different length")
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,473 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/stacking
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package stacking
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/frames"
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/iris/pkg/registration"
+	"github.com/observerly/iris/pkg/resample"
+	stats "github.com/observerly/iris/pkg/statistics"
+	"github.com/observerly/iris/pkg/trails"
+)
+
+/*****************************************************************************************************************/
+
+// ReferenceSelection is how the reference frame, onto which every other frame is registered, is selected.
+type ReferenceSelection int
+
+/*****************************************************************************************************************/
+
+const (
+	// The frame with the most detected stars, e.g., the frame least affected by cloud or haze:
+	ReferenceMostStars ReferenceSelection = iota
+	// The frame with the lowest (average) Half-Flux Radius, i.e., the frame with the best seeing and focus:
+	ReferenceLowestHFR
+)
+
+/*****************************************************************************************************************/
+
+// Params describes each step of the stacking pipeline.
+type Params struct {
+	Calibration  frames.CalibrationOptions // The options used to calibrate each light frame
+	Reference    ReferenceSelection        // How the reference frame is selected
+	StarRadius   float32                   // The radius (in pixels) used to detect stars
+	StarSigma    float32                   // The detection threshold of stars, in standard deviations above the background
+	StarInOut    float32                   // The ratio of the inner to outer flux used to reject implausible stars
+	Registration registration.Params       // How each frame is registered onto the reference frame
+	Resample     resample.Params           // How each frame is resampled onto the reference frame
+	Integration  IntegrationParams         // How the registered frames are integrated
+	RejectTrails bool                      // Whether to detect satellite and aircraft trails, and exclude their pixels from integration
+	Trails       trails.Params             // How the trails of each light frame are detected and masked
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default stacking parameters, i.e., the frame with the most stars as the reference frame, onto which
+// every other frame is registered by a similarity transform, resampled by a clamped Lanczos-3 kernel, and then
+// integrated by the mean after sigma clipping.
+func DefaultParams() Params {
+	return Params{
+		Calibration:  frames.CalibrationOptions{},
+		Reference:    ReferenceMostStars,
+		StarRadius:   16,
+		StarSigma:    5,
+		StarInOut:    2,
+		Registration: registration.DefaultParams(),
+		Resample:     resample.DefaultParams(),
+		Integration:  DefaultIntegrationParams(),
+		RejectTrails: false,
+		Trails:       trails.DefaultParams(),
+	}
+}
+
+/*****************************************************************************************************************/
+
+// FrameReport describes how each light frame was stacked, or why it was excluded from the stack.
+type FrameReport struct {
+	Index     int                          // The index of the light frame
+	Filename  string                       // The file name of the light frame, if any
+	Stars     int                          // The number of stars detected in the (calibrated) light frame
+	HFR       float32                      // The average Half-Flux Radius of the detected stars, in pixels
+	Reference bool                         // Whether the light frame is the reference frame
+	Included  bool                         // Whether the light frame was integrated into the stack
+	Transform registration.Transform       // The transform of the light frame onto the reference frame
+	Matches   int                          // The number of stars matched with the reference frame
+	RMS       float64                      // The root-mean-square residual of the matched stars, in pixels
+	Rejected  int                          // The number of pixels of the light frame rejected during integration
+	Trails    []trails.Trail               // The satellite and aircraft trails detected in the light frame, if rejected
+	Masked    int                          // The number of pixels of the light frame masked by its trails
+	Warnings  []*frames.CompatibilityError // The calibration compatibility warnings, if any
+	Err       error                        // The reason the light frame was excluded from the stack, if any
+}
+
+/*****************************************************************************************************************/
+
+// Result is the stacked frame, with its rejection map and the report of each light frame.
+type Result struct {
+	Stacked   *fits.FITSImage // The stacked frame, with the dimensions of the reference frame
+	Rejection *fits.FITSImage // The number of values rejected at each pixel of the stacked frame
+	Reference int             // The index of the reference light frame
+	Frames    []FrameReport   // The report of each light frame, in the order given
+}
+
+/*****************************************************************************************************************/
+
+// A light frame prepared for registration, i.e., calibrated and with its stars detected:
+type preparedFrame struct {
+	frame *fits.FITSImage
+	xs    int
+	ys    int
+	stars []photometry.Star
+	mask  []bool
+}
+
+/*****************************************************************************************************************/
+
+/*
+Stack()
+
+Stacks the light frames into a single frame:
+
+ 1. Each light frame is calibrated with the best matching master frames of the calibration
+    library (or used as given, if the library is nil).
+ 2. Stars are detected in each calibrated light frame, and (optionally) its satellite and
+    aircraft trails are masked, such that only their pixels are excluded from integration.
+ 3. The reference frame is selected, i.e., the frame with the most stars or the lowest HFR.
+ 4. Each other frame is registered onto the reference frame by matching their stars.
+ 5. Each other frame is resampled onto the pixel grid of the reference frame.
+ 6. The registered frames are integrated with pixel rejection.
+
+Light frames which cannot be calibrated or registered are excluded from the stack, with the
+reason recorded in their report, rather than failing the whole stack.
+
+Returns an error if no light frame could be calibrated and have its stars detected.
+*/
+func Stack(lights []fits.FITSImage, library *frames.CalibrationLibrary, params Params) (*Result, error) {
+	a, err := align(lights, library, params)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return a.stack(params)
+}
+
+/*****************************************************************************************************************/
+
+// Integrates the star-aligned frames, recording the number of pixels rejected from each in its report.
+func (a *alignment) stack(params Params) (*Result, error) {
+	stacked, rejection, included, rejected, err := a.integrate(params, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for j, i := range included {
+		a.reports[i].Included = true
+
+		a.reports[i].Rejected = rejected[j]
+	}
+
+	stacked.Header.History = append(
+		stacked.Header.History,
+		fmt.Sprintf("Stacked %d of %d light frames, registered onto light frame %d", len(included), len(a.lights), a.reference),
+	)
+
+	return &Result{
+		Stacked:   stacked,
+		Rejection: rejection,
+		Reference: a.reference,
+		Frames:    a.reports,
+	}, nil
+}
+
+/*****************************************************************************************************************/
+
+// The light frames, prepared and registered onto the reference frame:
+type alignment struct {
+	lights     []fits.FITSImage
+	prepared   []*preparedFrame
+	reports    []FrameReport
+	reference  int
+	registered []bool
+}
+
+/*****************************************************************************************************************/
+
+// Calibrates each light frame, detects its stars, selects the reference frame and registers each other frame onto
+// it, recording the transform (or the reason for its exclusion) in the report of each frame.
+func align(lights []fits.FITSImage, library *frames.CalibrationLibrary, params Params) (*alignment, error) {
+	if len(lights) == 0 {
+		return nil, errors.New("at least one light frame is required to stack")
+	}
+
+	a := &alignment{
+		lights:     lights,
+		prepared:   make([]*preparedFrame, len(lights)),
+		reports:    make([]FrameReport, len(lights)),
+		registered: make([]bool, len(lights)),
+	}
+
+	// Calibrate each light frame, and detect its stars:
+	for i := range lights {
+		a.reports[i] = FrameReport{
+			Index:     i,
+			Filename:  lights[i].Filename,
+			Transform: registration.NewIdentityTransform(),
+		}
+
+		p, err := prepare(&lights[i], library, params, &a.reports[i])
+
+		if err != nil {
+			a.reports[i].Err = err
+			continue
+		}
+
+		a.prepared[i] = p
+	}
+
+	a.reference = selectReference(a.prepared, a.reports, params.Reference)
+
+	if a.reference < 0 {
+		return nil, errors.Join(errors.New("no light frame could be prepared for stacking"), a.reports[0].Err)
+	}
+
+	ref := a.prepared[a.reference]
+
+	a.reports[a.reference].Reference = true
+
+	a.registered[a.reference] = true
+
+	// Register each other frame onto the reference frame:
+	for i, p := range a.prepared {
+		if p == nil || i == a.reference {
+			continue
+		}
+
+		result, err := registration.Register(ref.stars, p.stars, params.Registration)
+
+		if err != nil {
+			a.reports[i].Err = fmt.Errorf("failed to register onto the reference frame: %w", err)
+			continue
+		}
+
+		a.reports[i].Transform = result.Transform
+
+		a.reports[i].Matches = len(result.Matches)
+
+		a.reports[i].RMS = result.RMS
+
+		a.registered[i] = true
+	}
+
+	return a, nil
+}
+
+/*****************************************************************************************************************/
+
+// Resamples each registered frame onto the pixel grid of the reference frame, through its transform followed by
+// the (optional) shift of each frame, and integrates them. Returns the stacked frame, the rejection map, and the
+// indices of the integrated frames with the number of pixels rejected from each.
+func (a *alignment) integrate(params Params, shift func(i int) registration.Transform) (*fits.FITSImage, *fits.FITSImage, []int, []int, error) {
+	ref := a.prepared[a.reference]
+
+	resampling := params.Resample
+
+	resampling.Width, resampling.Height = ref.xs, ref.ys
+
+	// The reference frame is integrated first, such that the other frames are normalised to it:
+	order := []int{a.reference}
+
+	for i := range a.prepared {
+		if a.registered[i] && i != a.reference {
+			order = append(order, i)
+		}
+	}
+
+	included := make([]int, 0, len(order))
+
+	registered := make([][]float32, 0, len(order))
+
+	for _, i := range order {
+		p, transform := a.prepared[i], a.reports[i].Transform
+
+		if shift != nil {
+			transform = transform.Then(shift(i))
+		}
+
+		data := p.frame.Data
+
+		// The pixels masked by trails are NaN, which resampling neither grows nor shrinks, and integration excludes:
+		if p.mask != nil {
+			data = make([]float32, len(p.frame.Data))
+
+			for j, v := range p.frame.Data {
+				if p.mask[j] {
+					v = float32(math.NaN())
+				}
+
+				data[j] = v
+			}
+		}
+
+		// The reference frame is only resampled if it is shifted:
+		if transform.Matrix == registration.NewIdentityTransform().Matrix {
+			included = append(included, i)
+
+			registered = append(registered, data)
+
+			continue
+		}
+
+		data, err := resample.WarpData(data, p.xs, p.ys, transform.Matrix, resampling)
+
+		if err != nil {
+			a.reports[i].Err = fmt.Errorf("failed to resample onto the reference frame: %w", err)
+			continue
+		}
+
+		included = append(included, i)
+
+		registered = append(registered, data)
+	}
+
+	integrated, rejections, rejected, err := Integrate(registered, params.Integration)
+
+	if err != nil {
+		return nil, nil, nil, nil, err
+	}
+
+	inputs := make([]fits.FITSImage, len(included))
+
+	for j, i := range included {
+		inputs[j] = a.lights[i]
+	}
+
+	stacked := ref.frame.Copy()
+
+	stacked.Data = integrated
+
+	stacked.Naxisn = []int32{int32(ref.xs), int32(ref.ys)}
+
+	stacked.Pixels = int32(ref.xs * ref.ys)
+
+	// Record how the stacked frame was created, once its data is final:
+	provenance := frames.NewProvenance("light", inputs)
+
+	provenance.Rejection = params.Integration.Rejection.String()
+
+	if params.Integration.Rejection == SigmaClipping {
+		provenance.RejectionParameters = fmt.Sprintf(
+			"low=%g high=%g iterations=%d",
+			params.Integration.LowSigma,
+			params.Integration.HighSigma,
+			params.Integration.Iterations,
+		)
+	}
+
+	provenance.WriteToFITSImage(stacked)
+
+	rejection := fits.NewFITSImage(2, int32(ref.xs), int32(ref.ys), int32(len(included)))
+
+	rejection.Data = rejections
+
+	rejection.Naxisn = []int32{int32(ref.xs), int32(ref.ys)}
+
+	rejection.Pixels = int32(ref.xs * ref.ys)
+
+	rejection.Header.Set("IMAGETYP", "Rejection Map", "The number of values rejected at each pixel")
+
+	rejection.Header.Set("NCOMBINE", len(included), "The number of frames combined")
+
+	return stacked, rejection, included, rejected, nil
+}
+
+/*****************************************************************************************************************/
+
+// Calibrates the light frame (if a calibration library is given), and detects its stars.
+func prepare(light *fits.FITSImage, library *frames.CalibrationLibrary, params Params, report *FrameReport) (*preparedFrame, error) {
+	frame := light
+
+	if library != nil {
+		calibrated, _, err := library.Calibrate(light, params.Calibration)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to calibrate: %w", err)
+		}
+
+		frame = calibrated.Combined
+
+		report.Warnings = calibrated.Warnings
+	}
+
+	xs, ys, ok := frame.GetDimensions()
+
+	if !ok || len(frame.Data) != xs*ys {
+		return nil, fmt.Errorf("the frame data of %d pixels does not match the dimensions %dx%d", len(frame.Data), xs, ys)
+	}
+
+	adu := frame.ADU
+
+	if adu <= 0 {
+		adu = 65535
+	}
+
+	extractor := photometry.NewStarsExtractor(frame.Data, xs, ys, params.StarRadius, adu)
+
+	stars := extractor.FindStars(stats.NewStats(frame.Data, adu, xs), params.StarSigma, params.StarInOut)
+
+	report.Stars = len(stars)
+
+	report.HFR = extractor.HFR
+
+	if len(stars) == 0 {
+		return nil, errors.New("no stars were detected")
+	}
+
+	var mask []bool
+
+	if params.RejectTrails {
+		result, err := trails.Detect(frame, params.Trails)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to detect trails: %w", err)
+		}
+
+		report.Trails, report.Masked = result.Trails, result.Masked
+
+		if result.Masked > 0 {
+			mask = result.Mask
+		}
+	}
+
+	return &preparedFrame{frame: frame, xs: xs, ys: ys, stars: stars, mask: mask}, nil
+}
+
+/*****************************************************************************************************************/
+
+// Selects the index of the reference frame amongst the prepared frames, or -1 if there are none.
+func selectReference(prepared []*preparedFrame, reports []FrameReport, selection ReferenceSelection) int {
+	reference := -1
+
+	for i, p := range prepared {
+		if p == nil {
+			continue
+		}
+
+		if reference < 0 {
+			reference = i
+			continue
+		}
+
+		r, c := reports[reference], reports[i]
+
+		switch selection {
+		case ReferenceLowestHFR:
+			if c.HFR > 0 && (c.HFR < r.HFR || r.HFR <= 0 || (c.HFR == r.HFR && c.Stars > r.Stars)) {
+				reference = i
+			}
+		default:
+			if c.Stars > r.Stars || (c.Stars == r.Stars && c.HFR > 0 && c.HFR < r.HFR) {
+				reference = i
+			}
+		}
+	}
+
+	return reference
+}
+
+/*****************************************************************************************************************/
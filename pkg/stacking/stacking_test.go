@@ -0,0 +1,241 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/stacking
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package stacking
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/registration"
+)
+
+/*****************************************************************************************************************/
+
+// Creates a light frame of a synthetic star field, as seen through the given transform of the reference frame:
+func getTestLightFrame(xs int, ys int, stars int, transform registration.Transform, seed int64) *fits.FITSImage {
+	field := rand.New(rand.NewSource(42))
+
+	type star struct {
+		x, y, amplitude float64
+	}
+
+	s := make([]star, stars)
+
+	for i := range s {
+		s[i] = star{15 + field.Float64()*float64(xs-30), 15 + field.Float64()*float64(ys-30), 3000 + field.Float64()*20000}
+	}
+
+	inverse, _ := transform.Inverse()
+
+	noise := rand.New(rand.NewSource(seed))
+
+	f := fits.NewFITSImage(2, int32(xs), int32(ys), 65535)
+
+	f.Data = make([]float32, xs*ys)
+
+	for y := 0; y < ys; y++ {
+		for x := 0; x < xs; x++ {
+			v := 1000 + noise.NormFloat64()*10
+
+			for _, st := range s {
+				sx, sy := inverse.Apply(st.x, st.y)
+
+				d2 := (float64(x)-sx)*(float64(x)-sx) + (float64(y)-sy)*(float64(y)-sy)
+
+				v += st.amplitude * math.Exp(-d2/(2*1.8*1.8))
+			}
+
+			f.Data[y*xs+x] = float32(v)
+		}
+	}
+
+	return f
+}
+
+/*****************************************************************************************************************/
+
+func TestStackRegistersAndRejects(t *testing.T) {
+	transforms := []registration.Transform{
+		registration.NewIdentityTransform(),
+		registration.NewTranslationTransform(3, -2),
+		registration.NewTranslationTransform(-4, 5),
+		registration.NewSimilarityTransform(1, 0.01, 2, 1),
+		registration.NewTranslationTransform(1, 1),
+		registration.NewTranslationTransform(-2, -3),
+		registration.NewTranslationTransform(5, 2),
+		registration.NewTranslationTransform(-1, 4),
+	}
+
+	lights := make([]fits.FITSImage, len(transforms))
+
+	for i, tr := range transforms {
+		lights[i] = *getTestLightFrame(160, 160, 24, tr, int64(i+1))
+	}
+
+	// A cosmic ray strike on the background of the fifth light frame:
+	lights[4].Data[80*160+40] = 60000
+
+	params := DefaultParams()
+
+	params.Integration.HighSigma = 2
+
+	result, err := Stack(lights, nil, params)
+
+	if err != nil {
+		t.Fatalf("Stack() error: %v", err)
+	}
+
+	if len(result.Frames) != len(lights) {
+		t.Fatalf("len(Frames) = %d, expected %d", len(result.Frames), len(lights))
+	}
+
+	for _, r := range result.Frames {
+		if !r.Included {
+			t.Errorf("light frame %d was excluded: %v", r.Index, r.Err)
+		}
+
+		if r.Reference != (r.Index == result.Reference) {
+			t.Errorf("light frame %d Reference = %t, expected %t", r.Index, r.Reference, r.Index == result.Reference)
+		}
+	}
+
+	if result.Stacked.Header.Naxis1 != 160 || result.Stacked.Header.Naxis2 != 160 {
+		t.Errorf("stacked dimensions = %dx%d, expected 160x160", result.Stacked.Header.Naxis1, result.Stacked.Header.Naxis2)
+	}
+
+	if n := result.Stacked.Header.Ints["NCOMBINE"].Value; n != 8 {
+		t.Errorf("NCOMBINE = %d, expected 8", n)
+	}
+
+	// The position of the cosmic ray strike on the reference frame:
+	x, y := result.Frames[4].Transform.Apply(40, 80)
+
+	i := int(math.Round(y))*160 + int(math.Round(x))
+
+	if v := result.Stacked.Data[i]; v > 1100 {
+		t.Errorf("stacked pixel at the cosmic ray = %f, expected the background level", v)
+	}
+
+	if result.Rejection.Data[i] < 1 {
+		t.Errorf("rejection map at the cosmic ray = %f, expected at least 1", result.Rejection.Data[i])
+	}
+
+	if result.Frames[4].Rejected < 1 {
+		t.Errorf("light frame 4 Rejected = %d, expected at least 1", result.Frames[4].Rejected)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestStackRejectsTrails(t *testing.T) {
+	lights := make([]fits.FITSImage, 4)
+
+	for i := range lights {
+		lights[i] = *getTestLightFrame(160, 160, 24, registration.NewTranslationTransform(float64(i), -float64(i)), int64(i+1))
+	}
+
+	// A satellite trail across the third light frame:
+	for y := 0; y < 160; y++ {
+		for x := 0; x < 160; x++ {
+			d := (float64(y) - 20 - 0.8*float64(x)) / math.Hypot(1, 0.8)
+
+			lights[2].Data[y*160+x] += float32(400 * math.Exp(-d*d/2))
+		}
+	}
+
+	params := DefaultParams()
+
+	params.Integration.Rejection = NoRejection
+
+	params.RejectTrails = true
+
+	params.Trails.Background.BoxSize = 32
+
+	result, err := Stack(lights, nil, params)
+
+	if err != nil {
+		t.Fatalf("Stack() error: %v", err)
+	}
+
+	for _, r := range result.Frames {
+		if !r.Included {
+			t.Errorf("light frame %d was excluded: %v", r.Index, r.Err)
+		}
+
+		expected := 0
+
+		if r.Index == 2 {
+			expected = 1
+		}
+
+		if len(r.Trails) != expected {
+			t.Errorf("light frame %d has %d trails, expected %d: %+v", r.Index, len(r.Trails), expected, r.Trails)
+		}
+	}
+
+	if result.Frames[2].Masked == 0 {
+		t.Errorf("light frame 2 Masked = 0, expected the pixels of its trail")
+	}
+
+	// Without rejection, the trail is only excluded from the stack by its mask:
+	for x := 20; x < 140; x += 10 {
+		tx, ty := result.Frames[2].Transform.Apply(float64(x), 20+0.8*float64(x))
+
+		i := int(math.Round(ty))*160 + int(math.Round(tx))
+
+		if v := result.Stacked.Data[i]; v > 1100 {
+			t.Errorf("stacked pixel on the trail at (%.0f, %.0f) = %f, expected the background level", tx, ty, v)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestStackExcludesFramesWithoutStars(t *testing.T) {
+	blank := fits.NewFITSImage(2, 64, 64, 65535)
+
+	blank.Data = make([]float32, 64*64)
+
+	for i := range blank.Data {
+		blank.Data[i] = 1000
+	}
+
+	lights := []fits.FITSImage{
+		*blank,
+		*getTestLightFrame(64, 64, 8, registration.NewIdentityTransform(), 1),
+	}
+
+	result, err := Stack(lights, nil, DefaultParams())
+
+	if err != nil {
+		t.Fatalf("Stack() error: %v", err)
+	}
+
+	if result.Reference != 1 {
+		t.Errorf("Reference = %d, expected 1", result.Reference)
+	}
+
+	if result.Frames[0].Included || result.Frames[0].Err == nil {
+		t.Errorf("expected light frame 0 to be excluded with an error")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestStackNoLightFrames(t *testing.T) {
+	if _, err := Stack(nil, nil, DefaultParams()); err == nil {
+		t.Errorf("expected an error stacking no light frames")
+	}
+}
+
+/*****************************************************************************************************************/
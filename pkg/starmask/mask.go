@@ -0,0 +1,242 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/starmask
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package starmask
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/photometry"
+)
+
+/*****************************************************************************************************************/
+
+// Size is the measure of the size of each star by which its mask is scaled.
+type Size int
+
+/*****************************************************************************************************************/
+
+const (
+	// The Half-Flux Radius of each star, as found by StarsExtractor.FindStars():
+	SizeHFR Size = iota
+	// The FWHM of the PSF fitted to each star, or twice its HFR (i.e., for a Gaussian) if the fit fails:
+	SizeFWHM
+)
+
+/*****************************************************************************************************************/
+
+// Returns the name of the size, e.g., "HFR".
+func (s Size) String() string {
+	switch s {
+	case SizeHFR:
+		return "HFR"
+	case SizeFWHM:
+		return "FWHM"
+	default:
+		return "unknown"
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Params describes how the stars are found, and how the mask of each star is sized.
+type Params struct {
+	Size       Size    // The measure of the size of each star
+	Scale      float64 // The radius of the mask of each star, as a multiple of its size
+	Growth     float64 // The number of pixels by which the radius of the mask of each star is grown
+	MinRadius  float64 // The minimum radius of the mask of each star, in pixels
+	MaxRadius  float64 // The maximum radius of the mask of each star, in pixels (zero is unlimited)
+	Feather    float64 // The width of the linear falloff of the mask beyond the radius, in pixels (zero is a hard edge)
+	StarRadius float32 // The radius of the star extraction
+	StarSigma  float32 // The detection threshold of the star extraction
+	StarInOut  float32 // The HFR in-out ratio of the star extraction
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default star mask parameters, i.e., three times the HFR of each star, grown by 2 pixels and feathered
+// over 2 pixels.
+func DefaultParams() Params {
+	return Params{
+		Size:       SizeHFR,
+		Scale:      3,
+		Growth:     2,
+		MinRadius:  3,
+		MaxRadius:  0,
+		Feather:    2,
+		StarRadius: 16,
+		StarSigma:  5,
+		StarInOut:  2,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Mask is the star mask of an image, from 0 (unmasked) to 1 (masked), e.g., for the deringing of deconvolution.
+type Mask struct {
+	Width  int               // The width of the image, in pixels
+	Height int               // The height of the image, in pixels
+	Params Params            // The parameters of the mask
+	Stars  []photometry.Star // The stars masked
+	Radii  []float64         // The radius of the mask of each star, excluding the feather, in pixels
+	Data   []float32         // The mask, of the dimensions of the image
+}
+
+/*****************************************************************************************************************/
+
+/*
+NewMask()
+
+Builds the star mask of the image from its stars, as found by StarsExtractor.FindStars().
+*/
+func NewMask(f *fits.FITSImage, params Params) (*Mask, error) {
+	xs, ys, ok := f.GetDimensions()
+
+	if !ok || len(f.Data) != xs*ys {
+		return nil, fmt.Errorf("the image data of %d pixels does not match the dimensions %dx%d", len(f.Data), xs, ys)
+	}
+
+	stars, _ := photometry.FindStars(f.Data, xs, ys, f.ADU, params.StarRadius, params.StarSigma, params.StarInOut)
+
+	return NewMaskFromStars(f.Data, xs, ys, stars, params)
+}
+
+/*****************************************************************************************************************/
+
+/*
+NewMaskFromStars()
+
+Builds the star mask of the image from the given stars, e.g., of StarsExtractor.FindStars().
+
+The radius of the mask of each star is its size (its HFR, or the FWHM of its fitted PSF) times
+the scale, plus the growth, limited to the minimum and maximum radii. The mask is 1 within the
+radius, and falls linearly to 0 over the feather beyond it. Overlapping stars are combined by
+their maximum.
+*/
+func NewMaskFromStars(data []float32, xs int, ys int, stars []photometry.Star, params Params) (*Mask, error) {
+	if xs <= 0 || ys <= 0 || len(data) != xs*ys {
+		return nil, fmt.Errorf("the image data of %d pixels does not match the dimensions %dx%d", len(data), xs, ys)
+	}
+
+	if params.Scale < 0 || params.Growth < 0 || params.Feather < 0 {
+		return nil, errors.New("the scale, growth and feather of the mask must not be negative")
+	}
+
+	if params.MaxRadius > 0 && params.MaxRadius < params.MinRadius {
+		return nil, errors.New("the maximum radius of the mask must not be less than the minimum radius")
+	}
+
+	m := &Mask{
+		Width:  xs,
+		Height: ys,
+		Params: params,
+		Stars:  stars,
+		Radii:  make([]float64, len(stars)),
+		Data:   make([]float32, xs*ys),
+	}
+
+	for k, s := range stars {
+		size := float64(s.HFR)
+
+		if params.Size == SizeFWHM {
+			// For a Gaussian, the FWHM is twice the HFR:
+			size = 2 * float64(s.HFR)
+
+			psf, err := photometry.FitPSF(data, xs, ys, s, photometry.PSFParams{Model: photometry.GaussianPSF})
+
+			if err == nil && psf.FWHMMajor > 0 {
+				size = psf.FWHMMajor
+			}
+		}
+
+		radius := math.Max(params.Scale*size+params.Growth, params.MinRadius)
+
+		if params.MaxRadius > 0 {
+			radius = math.Min(radius, params.MaxRadius)
+		}
+
+		m.Radii[k] = radius
+
+		m.draw(float64(s.X), float64(s.Y), radius)
+	}
+
+	return m, nil
+}
+
+/*****************************************************************************************************************/
+
+// Draws the mask of radius (and the feather beyond it) about { x, y }, combined by the maximum with the mask.
+func (m *Mask) draw(x float64, y float64, radius float64) {
+	extent := radius + m.Params.Feather
+
+	for j := max(int(math.Floor(y-extent)), 0); j <= min(int(math.Ceil(y+extent)), m.Height-1); j++ {
+		for i := max(int(math.Floor(x-extent)), 0); i <= min(int(math.Ceil(x+extent)), m.Width-1); i++ {
+			d := math.Hypot(float64(i)-x, float64(j)-y)
+
+			value := 0.0
+
+			switch {
+			case d <= radius:
+				value = 1
+			case d < extent:
+				value = 1 - (d-radius)/m.Params.Feather
+			}
+
+			if float32(value) > m.Data[j*m.Width+i] {
+				m.Data[j*m.Width+i] = float32(value)
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the fraction of the image which is masked, i.e., the mean of the mask.
+func (m *Mask) GetMaskedFraction() float64 {
+	if len(m.Data) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+
+	for _, v := range m.Data {
+		sum += float64(v)
+	}
+
+	return sum / float64(len(m.Data))
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the mask as an image, from 0 (unmasked) to 1 (masked), with the header of the given image.
+func (m *Mask) GetFITSImage(f *fits.FITSImage) *fits.FITSImage {
+	mask := f.Copy()
+
+	mask.Data = make([]float32, len(m.Data))
+
+	copy(mask.Data, m.Data)
+
+	mask.Header.Set("IMAGETYP", "Star Mask", "The type of the image")
+
+	mask.Header.Set("MASKSTAR", int32(len(m.Stars)), "The number of stars masked")
+
+	mask.Header.Set("MASKSIZE", m.Params.Size.String(), "The measure of the size of each star")
+
+	mask.Header.Set("MASKSCAL", float32(m.Params.Scale), "The radius of each star mask as a multiple of its size")
+
+	mask.Header.Set("MASKGROW", float32(m.Params.Growth), "The growth of each star mask (pixels)")
+
+	return mask
+}
+
+/*****************************************************************************************************************/
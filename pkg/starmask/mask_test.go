@@ -0,0 +1,162 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/starmask
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package starmask
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/internal/starfield"
+	"github.com/observerly/iris/pkg/photometry"
+)
+
+/*****************************************************************************************************************/
+
+// Renders Gaussian stars (σ of 1.5 pixels) on a grid of positions 32 pixels apart, on the given sky (without stars)
+// with Gaussian noise, returning the image, the sky and the positions of the stars.
+func getTestImage(xs int, ys int, sky func(x int, y int) float64) (*fits.FITSImage, []float32, [][2]float64) {
+	random := rand.New(rand.NewSource(42))
+
+	data, background := make([]float32, xs*ys), make([]float32, xs*ys)
+
+	for y := 0; y < ys; y++ {
+		for x := 0; x < xs; x++ {
+			background[y*xs+x] = float32(sky(x, y))
+
+			data[y*xs+x] = background[y*xs+x] + float32(random.NormFloat64()*5)
+		}
+	}
+
+	positions := [][2]float64{}
+
+	for cy := 16; cy < ys-8; cy += 32 {
+		for cx := 16; cx < xs-8; cx += 32 {
+			x, y := float64(cx)+0.4*random.Float64(), float64(cy)+0.4*random.Float64()
+
+			starfield.AddStar(data, xs, ys, x, y, 40000)
+
+			positions = append(positions, [2]float64{x, y})
+		}
+	}
+
+	f := fits.NewFITSImage(2, int32(xs), int32(ys), 65535)
+
+	f.Data = data
+
+	return f, background, positions
+}
+
+/*****************************************************************************************************************/
+
+func TestNewMaskFromStars(t *testing.T) {
+	xs, ys := 64, 64
+
+	data := make([]float32, xs*ys)
+
+	stars := []photometry.Star{{X: 32, Y: 30, HFR: 2}}
+
+	m, err := NewMaskFromStars(data, xs, ys, stars, DefaultParams())
+
+	if err != nil {
+		t.Fatalf("NewMaskFromStars() error: %v", err)
+	}
+
+	// The radius is 3 × 2 + 2 = 8 pixels, feathered over 2 pixels:
+	if m.Radii[0] != 8 {
+		t.Errorf("radius = %f, expected 8", m.Radii[0])
+	}
+
+	for _, c := range []struct {
+		x, y     int
+		expected float32
+	}{{32, 30, 1}, {40, 30, 1}, {32, 39, 0.5}, {32, 41, 0}, {0, 0, 0}} {
+		if math.Abs(float64(m.Data[c.y*xs+c.x]-c.expected)) > 1e-6 {
+			t.Errorf("mask at { %d, %d } = %f, expected %f", c.x, c.y, m.Data[c.y*xs+c.x], c.expected)
+		}
+	}
+
+	if f := m.GetMaskedFraction(); f <= 0 || f >= 0.1 {
+		t.Errorf("masked fraction = %f, expected within (0, 0.1)", f)
+	}
+
+	params := DefaultParams()
+
+	params.MaxRadius = 5
+
+	params.Feather = 0
+
+	m, _ = NewMaskFromStars(data, xs, ys, stars, params)
+
+	if m.Radii[0] != 5 || m.Data[30*xs+38] != 0 || m.Data[30*xs+37] != 1 {
+		t.Errorf("expected the hard-edged mask to be limited to a radius of 5, but got %f", m.Radii[0])
+	}
+
+	params.Scale = -1
+
+	if _, err := NewMaskFromStars(data, xs, ys, stars, params); err == nil {
+		t.Errorf("expected an error for a negative scale")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewMaskFromStarsFWHM(t *testing.T) {
+	f, _, positions := getTestImage(64, 64, func(x int, y int) float64 { return 1000 })
+
+	stars := []photometry.Star{{X: float32(positions[0][0]), Y: float32(positions[0][1]), HFR: 1.5}}
+
+	params := DefaultParams()
+
+	params.Size, params.Scale, params.Growth, params.MinRadius = SizeFWHM, 1, 0, 0
+
+	m, err := NewMaskFromStars(f.Data, 64, 64, stars, params)
+
+	if err != nil {
+		t.Fatalf("NewMaskFromStars() error: %v", err)
+	}
+
+	// The FWHM of the fitted PSF, i.e., 2√(2ln2) × 1.5:
+	if math.Abs(m.Radii[0]-3.532) > 0.1 {
+		t.Errorf("radius = %f, expected the FWHM of 3.532", m.Radii[0])
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewMask(t *testing.T) {
+	f, _, positions := getTestImage(128, 128, func(x int, y int) float64 { return 1000 + 0.5*float64(x) })
+
+	m, err := NewMask(f, DefaultParams())
+
+	if err != nil {
+		t.Fatalf("NewMask() error: %v", err)
+	}
+
+	if len(m.Stars) != len(positions) {
+		t.Errorf("expected %d stars to be masked, but %d were", len(positions), len(m.Stars))
+	}
+
+	for _, p := range positions {
+		if v := m.Data[int(p[1])*128+int(p[0])]; v != 1 {
+			t.Errorf("mask at the star { %f, %f } = %f, expected 1", p[0], p[1], v)
+		}
+	}
+
+	image := m.GetFITSImage(f)
+
+	if image.Header.Strings["IMAGETYP"].Value != "Star Mask" || image.Header.Ints["MASKSTAR"].Value != int32(len(m.Stars)) {
+		t.Errorf("expected the mask to be described in the header")
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,149 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/starmask
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package starmask
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+// ReductionParams describes the morphological reduction of the stars of an image.
+type ReductionParams struct {
+	Radius     int     // The radius of the (circular) structuring element of the erosion, in pixels
+	Iterations int     // The number of erosions
+	Amount     float64 // The strength of the reduction, from 0 (unchanged) to 1 (fully eroded)
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default star reduction parameters, i.e., two full erosions of radius 1.
+func DefaultReductionParams() ReductionParams {
+	return ReductionParams{
+		Radius:     1,
+		Iterations: 2,
+		Amount:     1,
+	}
+}
+
+/*****************************************************************************************************************/
+
+/*
+Reduce()
+
+Reduces the stars of a copy of the image by morphological erosion within the star mask, i.e., the
+image is repeatedly eroded (each pixel replaced by the minimum of its circular neighbourhood),
+and the eroded image is blended with the original by the mask times the amount, such that the
+stars shrink while the unmasked (e.g., nebulous) areas are unchanged.
+
+NaN pixels are excluded from the erosion of their neighbours, and are unchanged.
+*/
+func Reduce(f *fits.FITSImage, mask *Mask, params ReductionParams) (*fits.FITSImage, error) {
+	if len(f.Data) != len(mask.Data) {
+		return nil, fmt.Errorf("the image data of %d pixels does not match the mask of %d pixels", len(f.Data), len(mask.Data))
+	}
+
+	if params.Radius < 1 || params.Iterations < 1 {
+		return nil, errors.New("the radius and number of iterations of the erosion must be positive")
+	}
+
+	if params.Amount < 0 || params.Amount > 1 {
+		return nil, errors.New("the amount of the reduction must be between 0 and 1")
+	}
+
+	eroded := f.Data
+
+	for i := 0; i < params.Iterations; i++ {
+		eroded = erode(eroded, mask.Width, mask.Height, params.Radius)
+	}
+
+	reduced := f.Copy()
+
+	reduced.Data = make([]float32, len(f.Data))
+
+	for i, v := range f.Data {
+		w := float32(float64(mask.Data[i]) * params.Amount)
+
+		reduced.Data[i] = v + w*(eroded[i]-v)
+	}
+
+	reduced.Header.History = append(
+		reduced.Header.History,
+		fmt.Sprintf("Stars reduced by %d erosions of radius %d within the mask of %d stars (amount %.2f)", params.Iterations, params.Radius, len(mask.Stars), params.Amount),
+	)
+
+	return reduced, nil
+}
+
+/*****************************************************************************************************************/
+
+// Erodes the image by the circular structuring element of the given radius, truncated at the edges of the image,
+// i.e., each pixel is the minimum of the (non-NaN) pixels of its neighbourhood.
+func erode(data []float32, xs int, ys int, radius int) []float32 {
+	// The horizontal half-width of the structuring element on each row:
+	widths := make([]int, 2*radius+1)
+
+	for j := -radius; j <= radius; j++ {
+		widths[j+radius] = int(math.Floor(math.Sqrt(float64(radius*radius - j*j))))
+	}
+
+	out := make([]float32, len(data))
+
+	workers := min(runtime.NumCPU(), max(ys, 1))
+
+	band := (ys + workers - 1) / workers
+
+	var wg sync.WaitGroup
+
+	// Erode each band of rows in parallel, each writing only to its own rows of the output:
+	for y0 := 0; y0 < ys; y0 += band {
+		wg.Add(1)
+
+		go func(y0 int, y1 int) {
+			defer wg.Done()
+
+			for y := y0; y < y1; y++ {
+				for x := 0; x < xs; x++ {
+					v := data[y*xs+x]
+
+					if math.IsNaN(float64(v)) {
+						out[y*xs+x] = v
+						continue
+					}
+
+					for j := max(y-radius, 0); j <= min(y+radius, ys-1); j++ {
+						w := widths[j-y+radius]
+
+						for i := max(x-w, 0); i <= min(x+w, xs-1); i++ {
+							if n := data[j*xs+i]; n < v {
+								v = n
+							}
+						}
+					}
+
+					out[y*xs+x] = v
+				}
+			}
+		}(y0, min(y0+band, ys))
+	}
+
+	wg.Wait()
+
+	return out
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,117 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/starmask
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package starmask
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+)
+
+/*****************************************************************************************************************/
+
+func TestReduce(t *testing.T) {
+	xs, ys := 96, 96
+
+	f, _, positions := getTestImage(xs, ys, func(x int, y int) float64 { return 1000 })
+
+	m, err := NewMask(f, DefaultParams())
+
+	if err != nil {
+		t.Fatalf("NewMask() error: %v", err)
+	}
+
+	f.Data[80*xs+90] = float32(math.NaN())
+
+	reduced, err := Reduce(f, m, DefaultReductionParams())
+
+	if err != nil {
+		t.Fatalf("Reduce() error: %v", err)
+	}
+
+	// The flux of each star, above the sky, within 4 pixels:
+	getFlux := func(data []float32, x float64, y float64) float64 {
+		sum := 0.0
+
+		for j := int(y) - 4; j <= int(y)+4; j++ {
+			for i := int(x) - 4; i <= int(x)+4; i++ {
+				sum += float64(data[j*xs+i]) - 1000
+			}
+		}
+
+		return sum
+	}
+
+	for _, p := range positions {
+		if before, after := getFlux(f.Data, p[0], p[1]), getFlux(reduced.Data, p[0], p[1]); after > 0.5*before {
+			t.Errorf("flux of the star at { %f, %f } = %f, expected less than half of %f", p[0], p[1], after, before)
+		}
+	}
+
+	for i, v := range m.Data {
+		if v == 0 && !math.IsNaN(float64(f.Data[i])) && reduced.Data[i] != f.Data[i] {
+			t.Fatalf("expected the unmasked pixel { %d, %d } to be unchanged", i%xs, i/xs)
+		}
+	}
+
+	if !math.IsNaN(float64(reduced.Data[80*xs+90])) {
+		t.Errorf("expected the NaN pixel to be unchanged")
+	}
+
+	if len(reduced.Header.History) != len(f.Header.History)+1 {
+		t.Errorf("expected the reduction to be recorded in the header")
+	}
+
+	// Without any reduction, the image is unchanged:
+	params := DefaultReductionParams()
+
+	params.Amount = 0
+
+	unchanged, _ := Reduce(f, m, params)
+
+	if unchanged.Data[int(positions[0][1])*xs+int(positions[0][0])] != f.Data[int(positions[0][1])*xs+int(positions[0][0])] {
+		t.Errorf("expected no reduction for an amount of zero")
+	}
+
+	params.Amount = 2
+
+	if _, err := Reduce(f, m, params); err == nil {
+		t.Errorf("expected an error for an amount above 1")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestErode(t *testing.T) {
+	data := []float32{
+		5, 5, 5, 5, 5,
+		5, 5, 5, 5, 5,
+		5, 5, 1, 5, 5,
+		5, 5, 5, 5, 5,
+		5, 5, 5, 5, 5,
+	}
+
+	eroded := erode(data, 5, 5, 1)
+
+	// The structuring element of radius 1 is a cross:
+	for i, expected := range []float32{
+		5, 5, 5, 5, 5,
+		5, 5, 1, 5, 5,
+		5, 1, 1, 1, 5,
+		5, 5, 1, 5, 5,
+		5, 5, 5, 5, 5,
+	} {
+		if eroded[i] != expected {
+			t.Errorf("eroded[%d] = %f, expected %f", i, eroded[i], expected)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,142 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/starmask
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package starmask
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/observerly/iris/pkg/background"
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// StarlessParams describes how the masked areas of an image are inpainted.
+type StarlessParams struct {
+	Annulus float64 // The width of the annulus beyond the mask of each star, from which its local offset is measured, in pixels (zero disables the offset)
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default starless parameters, i.e., the local offset of each star measured within 4 pixels of its mask.
+func DefaultStarlessParams() StarlessParams {
+	return StarlessParams{
+		Annulus: 4,
+	}
+}
+
+/*****************************************************************************************************************/
+
+/*
+Starless()
+
+Approximates the starless image of a copy of the image by inpainting its masked areas from the
+background model (e.g., of background.Estimate()), blended with the original by the mask.
+
+The background model is smooth, so it does not follow local (e.g., nebulous) structure. Each star
+is therefore filled by the model plus its local offset, i.e., the median difference of the image
+and the model within the annulus beyond its mask (excluding the masks of other stars). Where the
+masks of stars overlap, the offset of the star nearest (relative to its radius) is used. Noise
+is not synthesised, so the inpainted areas are smoother than their surroundings.
+*/
+func Starless(f *fits.FITSImage, mask *Mask, b *background.Background, params StarlessParams) (*fits.FITSImage, error) {
+	if len(f.Data) != len(mask.Data) {
+		return nil, fmt.Errorf("the image data of %d pixels does not match the mask of %d pixels", len(f.Data), len(mask.Data))
+	}
+
+	if len(b.Data) != len(mask.Data) {
+		return nil, fmt.Errorf("the background model of %d pixels does not match the mask of %d pixels", len(b.Data), len(mask.Data))
+	}
+
+	if params.Annulus < 0 {
+		return nil, errors.New("the width of the annulus must not be negative")
+	}
+
+	xs, ys := mask.Width, mask.Height
+
+	// The local offset of each masked pixel, and its distance (relative to the radius) from its star:
+	offsets, nearest := make([]float64, len(mask.Data)), make([]float64, len(mask.Data))
+
+	for i := range nearest {
+		nearest[i] = math.Inf(1)
+	}
+
+	residuals := []float64{}
+
+	for k, s := range mask.Stars {
+		x, y, radius := float64(s.X), float64(s.Y), mask.Radii[k]
+
+		extent := radius + mask.Params.Feather
+
+		offset := 0.0
+
+		if params.Annulus > 0 {
+			residuals = residuals[:0]
+
+			outer := extent + params.Annulus
+
+			for j := max(int(math.Floor(y-outer)), 0); j <= min(int(math.Ceil(y+outer)), ys-1); j++ {
+				for i := max(int(math.Floor(x-outer)), 0); i <= min(int(math.Ceil(x+outer)), xs-1); i++ {
+					v := f.Data[j*xs+i]
+
+					if mask.Data[j*xs+i] > 0 || math.IsNaN(float64(v)) || math.Hypot(float64(i)-x, float64(j)-y) > outer {
+						continue
+					}
+
+					residuals = append(residuals, float64(v-b.Data[j*xs+i]))
+				}
+			}
+
+			offset = qsort.MedianFloat64(residuals)
+		}
+
+		for j := max(int(math.Floor(y-extent)), 0); j <= min(int(math.Ceil(y+extent)), ys-1); j++ {
+			for i := max(int(math.Floor(x-extent)), 0); i <= min(int(math.Ceil(x+extent)), xs-1); i++ {
+				d := math.Hypot(float64(i)-x, float64(j)-y) / math.Max(extent, 1)
+
+				if d <= 1 && d < nearest[j*xs+i] {
+					nearest[j*xs+i], offsets[j*xs+i] = d, offset
+				}
+			}
+		}
+	}
+
+	starless := f.Copy()
+
+	starless.Data = make([]float32, len(f.Data))
+
+	for i, v := range f.Data {
+		m := mask.Data[i]
+
+		if m <= 0 || math.IsNaN(float64(v)) {
+			starless.Data[i] = v
+			continue
+		}
+
+		fill := float32(float64(b.Data[i]) + offsets[i])
+
+		starless.Data[i] = v + m*(fill-v)
+	}
+
+	starless.Header.Set("STARLESS", true, "The stars have been removed from the image")
+
+	starless.Header.History = append(
+		starless.Header.History,
+		fmt.Sprintf("Stars removed by inpainting the mask of %d stars from the %s background model", len(mask.Stars), b.Params.Model),
+	)
+
+	return starless, nil
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,86 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/starmask
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package starmask
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"testing"
+
+	"github.com/observerly/iris/pkg/background"
+)
+
+/*****************************************************************************************************************/
+
+func TestStarless(t *testing.T) {
+	xs, ys := 256, 256
+
+	// A gradient, and a broad nebula which the background model does not follow:
+	f, sky, positions := getTestImage(xs, ys, func(x int, y int) float64 {
+		r2 := float64((x-128)*(x-128) + (y-128)*(y-128))
+
+		return 1000 + 0.5*float64(x) + 200*math.Exp(-r2/(2*30*30))
+	})
+
+	// The HFR is overestimated on the nebula, so the masks are limited:
+	mask := DefaultParams()
+
+	mask.MaxRadius = 10
+
+	m, err := NewMask(f, mask)
+
+	if err != nil {
+		t.Fatalf("NewMask() error: %v", err)
+	}
+
+	params := background.DefaultParams()
+
+	params.BoxSize = 32
+
+	b, err := background.Estimate(f, params)
+
+	if err != nil {
+		t.Fatalf("Estimate() error: %v", err)
+	}
+
+	starless, err := Starless(f, m, b, DefaultStarlessParams())
+
+	if err != nil {
+		t.Fatalf("Starless() error: %v", err)
+	}
+
+	// The stars are replaced by the sky, including the nebula:
+	for _, p := range positions {
+		i := int(math.Round(p[1]))*xs + int(math.Round(p[0]))
+
+		if math.Abs(float64(starless.Data[i]-sky[i])) > 20 {
+			t.Errorf("starless pixel at { %f, %f } = %f, expected the sky of %f", p[0], p[1], starless.Data[i], sky[i])
+		}
+	}
+
+	for i, v := range m.Data {
+		if v == 0 && starless.Data[i] != f.Data[i] {
+			t.Fatalf("expected the unmasked pixel { %d, %d } to be unchanged", i%xs, i/xs)
+		}
+	}
+
+	if !starless.Header.Bools["STARLESS"].Value {
+		t.Errorf("expected the starless image to be recorded in the header")
+	}
+
+	// The background model must be of the dimensions of the image:
+	b.Data = b.Data[1:]
+
+	if _, err := Starless(f, m, b, DefaultStarlessParams()); err == nil {
+		t.Errorf("expected an error for a background model of the wrong size")
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,238 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/subframe
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package subframe
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"path/filepath"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/photometry"
+	"github.com/observerly/iris/pkg/qsort"
+	stats "github.com/observerly/iris/pkg/statistics"
+)
+
+/*****************************************************************************************************************/
+
+// Params describes how stars are detected in each sub-frame.
+type Params struct {
+	Radius    float32 // The radius (in pixels) used to detect stars
+	Sigma     float32 // The detection threshold of stars, in standard deviations above the background
+	StarInOut float32 // The ratio of the inner to outer flux used to reject implausible stars
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default sub-frame measurement parameters.
+func DefaultParams() Params {
+	return Params{
+		Radius:    16,
+		Sigma:     5,
+		StarInOut: 2,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Metrics are the quality measurements of a sub-frame, with whether it was approved by the selection policy.
+type Metrics struct {
+	Index        int      `json:"index"`        // The index of the sub-frame
+	Filename     string   `json:"filename"`     // The (base) file name of the sub-frame, if any
+	Stars        int      `json:"stars"`        // The number of detected stars
+	HFR          float64  `json:"hfr"`          // The median Half-Flux Radius of the stars, in pixels
+	FWHM         float64  `json:"fwhm"`         // The median Full Width at Half Maximum of the stars, in pixels
+	Eccentricity float64  `json:"eccentricity"` // The median eccentricity of the stars, from 0 (round) to 1 (elongated)
+	Background   float64  `json:"background"`   // The median background level, in ADU
+	Noise        float64  `json:"noise"`        // The Gaussian noise, in ADU
+	SNR          float64  `json:"snr"`          // The median signal-to-noise ratio of the peaks of the stars
+	Approved     bool     `json:"approved"`     // Whether the sub-frame was approved by the selection policy
+	Reasons      []string `json:"reasons"`      // The reasons the sub-frame was rejected, if any
+}
+
+/*****************************************************************************************************************/
+
+/*
+Measure()
+
+Measures the quality of the sub-frame, i.e., the number of stars, their median HFR, FWHM and
+eccentricity, the background level, the noise and the signal-to-noise ratio.
+
+A sub-frame without any (measurable) stars cannot be graded by their shapes, so an error is
+returned, with the background level and noise still measured.
+
+The FWHM and eccentricity of each star are computed from the second moments of its (background
+subtracted) light about its centroid, i.e., for a Gaussian star with principal axes σ1 ≥ σ2,
+the FWHM is 2√(2ln2) times their root-mean-square and the eccentricity is √(1 - σ2²/σ1²).
+*/
+func Measure(f *fits.FITSImage, params Params) (Metrics, error) {
+	xs, ys, ok := f.GetDimensions()
+
+	if !ok || len(f.Data) != xs*ys {
+		return Metrics{}, fmt.Errorf("the frame data of %d pixels does not match the dimensions %dx%d", len(f.Data), xs, ys)
+	}
+
+	m := Metrics{}
+
+	if f.Filename != "" {
+		m.Filename = filepath.Base(f.Filename)
+	}
+
+	adu := f.ADU
+
+	if adu <= 0 {
+		adu = 65535
+	}
+
+	m.Background = float64(qsort.MedianFloat32(f.Data))
+
+	m.Noise = photometry.NewNoiseExtractor(f.Data, xs, ys).GetGaussianNoise()
+
+	extractor := photometry.NewStarsExtractor(f.Data, xs, ys, params.Radius, adu)
+
+	stars := extractor.FindStars(stats.NewStats(f.Data, adu, xs), params.Sigma, params.StarInOut)
+
+	m.Stars = len(stars)
+
+	// Without stars, the sub-frame cannot be graded by the shapes of its stars (e.g., it is clouded out):
+	if len(stars) == 0 {
+		return m, errors.New("no stars were detected in the sub-frame")
+	}
+
+	hfr := make([]float64, 0, len(stars))
+
+	fwhm := make([]float64, 0, len(stars))
+
+	eccentricity := make([]float64, 0, len(stars))
+
+	snr := make([]float64, 0, len(stars))
+
+	for _, s := range stars {
+		hfr = append(hfr, float64(s.HFR))
+
+		w, e, peak, ok := getStarShape(f.Data, xs, ys, s, m.Background)
+
+		if !ok {
+			continue
+		}
+
+		fwhm = append(fwhm, w)
+
+		eccentricity = append(eccentricity, e)
+
+		if m.Noise > 0 {
+			snr = append(snr, peak/m.Noise)
+		}
+	}
+
+	if len(fwhm) == 0 {
+		return m, fmt.Errorf("the shapes of none of the %d stars of the sub-frame could be measured", len(stars))
+	}
+
+	m.HFR = qsort.MedianFloat64(hfr)
+
+	m.FWHM = qsort.MedianFloat64(fwhm)
+
+	m.Eccentricity = qsort.MedianFloat64(eccentricity)
+
+	m.SNR = qsort.MedianFloat64(snr)
+
+	return m, nil
+}
+
+/*****************************************************************************************************************/
+
+// Computes the FWHM, eccentricity and (background subtracted) peak of the star from the second moments of the
+// (background subtracted) pixels within four half-flux radii of its position, i.e., within 4.7σ of a Gaussian star.
+// The pixels are not thresholded by the noise, as the truncated wings of the star would bias the moments low.
+func getStarShape(data []float32, xs int, ys int, s photometry.Star, background float64) (float64, float64, float64, bool) {
+	radius := int(math.Ceil(4 * math.Max(float64(s.HFR), 1)))
+
+	cx, cy := int(math.Round(float64(s.X))), int(math.Round(float64(s.Y)))
+
+	mass, mx, my, peak := 0.0, 0.0, 0.0, 0.0
+
+	for y := max(cy-radius, 0); y <= min(cy+radius, ys-1); y++ {
+		for x := max(cx-radius, 0); x <= min(cx+radius, xs-1); x++ {
+			if (x-cx)*(x-cx)+(y-cy)*(y-cy) > radius*radius {
+				continue
+			}
+
+			v := float64(data[y*xs+x]) - background
+
+			if math.IsNaN(v) {
+				continue
+			}
+
+			peak = math.Max(peak, v)
+
+			mass += v
+
+			mx += v * float64(x)
+
+			my += v * float64(y)
+		}
+	}
+
+	if mass <= 0 {
+		return 0, 0, 0, false
+	}
+
+	mx /= mass
+
+	my /= mass
+
+	mxx, myy, mxy := 0.0, 0.0, 0.0
+
+	for y := max(cy-radius, 0); y <= min(cy+radius, ys-1); y++ {
+		for x := max(cx-radius, 0); x <= min(cx+radius, xs-1); x++ {
+			if (x-cx)*(x-cx)+(y-cy)*(y-cy) > radius*radius {
+				continue
+			}
+
+			v := float64(data[y*xs+x]) - background
+
+			if math.IsNaN(v) {
+				continue
+			}
+
+			dx, dy := float64(x)-mx, float64(y)-my
+
+			mxx += v * dx * dx
+
+			myy += v * dy * dy
+
+			mxy += v * dx * dy
+		}
+	}
+
+	mxx /= mass
+
+	myy /= mass
+
+	mxy /= mass
+
+	// The eigenvalues of the second moment matrix, i.e., the variances along the principal axes:
+	root := math.Sqrt((mxx-myy)*(mxx-myy)/4 + mxy*mxy)
+
+	l1, l2 := (mxx+myy)/2+root, (mxx+myy)/2-root
+
+	if l1 <= 0 || l2 < 0 {
+		return 0, 0, 0, false
+	}
+
+	fwhm := 2 * math.Sqrt(2*math.Ln2) * math.Sqrt((l1+l2)/2)
+
+	return fwhm, math.Sqrt(1 - l2/l1), peak, true
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,298 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/subframe
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package subframe
+
+/*****************************************************************************************************************/
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+)
+
+/*****************************************************************************************************************/
+
+// Policy approves or rejects a sub-frame from its metrics, relative to the median metrics of all of the sub-frames.
+type Policy interface {
+	// Returns whether the sub-frame is approved, with the reasons it is rejected (if any):
+	Evaluate(m Metrics, medians Metrics) (bool, []string, error)
+}
+
+/*****************************************************************************************************************/
+
+// ThresholdPolicy rejects sub-frames whose metrics fall outside of the absolute thresholds, or which are worse
+// than the median sub-frame by more than the relative thresholds. A zero threshold is not checked.
+type ThresholdPolicy struct {
+	MinStars         int     // The minimum number of detected stars
+	MaxHFR           float64 // The maximum median HFR, in pixels
+	MaxFWHM          float64 // The maximum median FWHM, in pixels
+	MaxEccentricity  float64 // The maximum median eccentricity
+	MaxBackground    float64 // The maximum background level, in ADU
+	MaxNoise         float64 // The maximum noise, in ADU
+	MinSNR           float64 // The minimum signal-to-noise ratio
+	MaxRelativeFWHM  float64 // The maximum ratio of the FWHM to the median FWHM of all sub-frames, e.g., 1.3
+	MinRelativeStars float64 // The minimum ratio of the star count to the median star count of all sub-frames, e.g., 0.5
+}
+
+/*****************************************************************************************************************/
+
+// Returns whether the sub-frame is within each of the thresholds, with the reasons it is rejected (if any).
+func (p ThresholdPolicy) Evaluate(m Metrics, medians Metrics) (bool, []string, error) {
+	reasons := make([]string, 0)
+
+	if p.MinStars > 0 && m.Stars < p.MinStars {
+		reasons = append(reasons, fmt.Sprintf("stars %d < %d", m.Stars, p.MinStars))
+	}
+
+	relativeFWHM := 0.0
+
+	if medians.FWHM > 0 {
+		relativeFWHM = m.FWHM / medians.FWHM
+	}
+
+	for _, check := range []struct {
+		name      string
+		value     float64
+		threshold float64
+	}{
+		{"HFR", m.HFR, p.MaxHFR},
+		{"FWHM", m.FWHM, p.MaxFWHM},
+		{"eccentricity", m.Eccentricity, p.MaxEccentricity},
+		{"background", m.Background, p.MaxBackground},
+		{"noise", m.Noise, p.MaxNoise},
+		{"relative FWHM", relativeFWHM, p.MaxRelativeFWHM},
+	} {
+		if check.threshold > 0 && check.value > check.threshold {
+			reasons = append(reasons, fmt.Sprintf("%s %.3g > %.3g", check.name, check.value, check.threshold))
+		}
+	}
+
+	if p.MinSNR > 0 && m.SNR < p.MinSNR {
+		reasons = append(reasons, fmt.Sprintf("SNR %.3g < %.3g", m.SNR, p.MinSNR))
+	}
+
+	if p.MinRelativeStars > 0 && medians.Stars > 0 && float64(m.Stars)/float64(medians.Stars) < p.MinRelativeStars {
+		reasons = append(reasons, fmt.Sprintf("relative stars %.3g < %.3g", float64(m.Stars)/float64(medians.Stars), p.MinRelativeStars))
+	}
+
+	return len(reasons) == 0, reasons, nil
+}
+
+/*****************************************************************************************************************/
+
+// ExpressionPolicy approves sub-frames for which a boolean expression of their metrics is true, e.g.,
+// "FWHM < 1.2 * MedianFWHM && Stars > 50 && Eccentricity < 0.6".
+//
+// The expression may use the variables Stars, HFR, FWHM, Eccentricity, Background, Noise and SNR, and the median
+// of each over all of the sub-frames (e.g., MedianHFR), with numeric literals, the arithmetic operators + - * /,
+// the comparison operators < <= > >= == !=, the logical operators && || !, and parentheses.
+type ExpressionPolicy struct {
+	Expression string
+	expr       ast.Expr
+}
+
+/*****************************************************************************************************************/
+
+// Creates an expression policy, returning an error if the expression is malformed or uses unknown variables.
+func NewExpressionPolicy(expression string) (*ExpressionPolicy, error) {
+	expr, err := parser.ParseExpr(expression)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expression, err)
+	}
+
+	p := &ExpressionPolicy{Expression: expression, expr: expr}
+
+	// Evaluate the expression once against zero metrics, to reject unknown variables and type errors up front:
+	v, err := p.evaluate(p.expr, getVariables(Metrics{}, Metrics{}))
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid expression %q: %w", expression, err)
+	}
+
+	if _, ok := v.(bool); !ok {
+		return nil, fmt.Errorf("invalid expression %q: the expression is not a boolean", expression)
+	}
+
+	return p, nil
+}
+
+/*****************************************************************************************************************/
+
+// Returns whether the expression is true for the sub-frame.
+func (p *ExpressionPolicy) Evaluate(m Metrics, medians Metrics) (bool, []string, error) {
+	v, err := p.evaluate(p.expr, getVariables(m, medians))
+
+	if err != nil {
+		return false, nil, err
+	}
+
+	approved, ok := v.(bool)
+
+	if !ok {
+		return false, nil, fmt.Errorf("the expression %q is not a boolean", p.Expression)
+	}
+
+	if !approved {
+		return false, []string{fmt.Sprintf("expression %q is false", p.Expression)}, nil
+	}
+
+	return true, []string{}, nil
+}
+
+/*****************************************************************************************************************/
+
+// Evaluates the expression to either a float64 or a bool value.
+func (p *ExpressionPolicy) evaluate(e ast.Expr, variables map[string]float64) (interface{}, error) {
+	switch e := e.(type) {
+	case *ast.ParenExpr:
+		return p.evaluate(e.X, variables)
+	case *ast.BasicLit:
+		if e.Kind != token.INT && e.Kind != token.FLOAT {
+			return nil, fmt.Errorf("unsupported literal %s", e.Value)
+		}
+
+		return strconv.ParseFloat(e.Value, 64)
+	case *ast.Ident:
+		switch e.Name {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+
+		v, ok := variables[e.Name]
+
+		if !ok {
+			return nil, fmt.Errorf("unknown variable %s", e.Name)
+		}
+
+		return v, nil
+	case *ast.UnaryExpr:
+		x, err := p.evaluate(e.X, variables)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch x := x.(type) {
+		case float64:
+			switch e.Op {
+			case token.SUB:
+				return -x, nil
+			case token.ADD:
+				return x, nil
+			}
+		case bool:
+			if e.Op == token.NOT {
+				return !x, nil
+			}
+		}
+
+		return nil, fmt.Errorf("unsupported unary operator %s", e.Op)
+	case *ast.BinaryExpr:
+		x, err := p.evaluate(e.X, variables)
+
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := p.evaluate(e.Y, variables)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if a, ok := x.(bool); ok {
+			b, ok := y.(bool)
+
+			if !ok {
+				return nil, fmt.Errorf("mismatched operands of %s", e.Op)
+			}
+
+			switch e.Op {
+			case token.LAND:
+				return a && b, nil
+			case token.LOR:
+				return a || b, nil
+			case token.EQL:
+				return a == b, nil
+			case token.NEQ:
+				return a != b, nil
+			}
+
+			return nil, fmt.Errorf("unsupported boolean operator %s", e.Op)
+		}
+
+		a, ok := x.(float64)
+
+		if !ok {
+			return nil, fmt.Errorf("mismatched operands of %s", e.Op)
+		}
+
+		b, ok := y.(float64)
+
+		if !ok {
+			return nil, fmt.Errorf("mismatched operands of %s", e.Op)
+		}
+
+		switch e.Op {
+		case token.ADD:
+			return a + b, nil
+		case token.SUB:
+			return a - b, nil
+		case token.MUL:
+			return a * b, nil
+		case token.QUO:
+			return a / b, nil
+		case token.LSS:
+			return a < b, nil
+		case token.LEQ:
+			return a <= b, nil
+		case token.GTR:
+			return a > b, nil
+		case token.GEQ:
+			return a >= b, nil
+		case token.EQL:
+			return a == b, nil
+		case token.NEQ:
+			return a != b, nil
+		}
+
+		return nil, fmt.Errorf("unsupported numeric operator %s", e.Op)
+	default:
+		return nil, fmt.Errorf("unsupported expression %T", e)
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the variables of the expression from the metrics of the sub-frame and the medians of all sub-frames.
+func getVariables(m Metrics, medians Metrics) map[string]float64 {
+	return map[string]float64{
+		"Stars":              float64(m.Stars),
+		"HFR":                m.HFR,
+		"FWHM":               m.FWHM,
+		"Eccentricity":       m.Eccentricity,
+		"Background":         m.Background,
+		"Noise":              m.Noise,
+		"SNR":                m.SNR,
+		"MedianStars":        float64(medians.Stars),
+		"MedianHFR":          medians.HFR,
+		"MedianFWHM":         medians.FWHM,
+		"MedianEccentricity": medians.Eccentricity,
+		"MedianBackground":   medians.Background,
+		"MedianNoise":        medians.Noise,
+		"MedianSNR":          medians.SNR,
+	}
+}
+
+/*****************************************************************************************************************/
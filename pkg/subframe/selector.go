@@ -0,0 +1,200 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/subframe
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package subframe
+
+/*****************************************************************************************************************/
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// Report is the metrics of each sub-frame, with the medians over all of them, and the selection of each.
+type Report struct {
+	Frames   []Metrics `json:"frames"`   // The metrics of each sub-frame, in the order given
+	Medians  Metrics   `json:"medians"`  // The median of each metric over all of the sub-frames
+	Approved int       `json:"approved"` // The number of approved sub-frames
+	Rejected int       `json:"rejected"` // The number of rejected sub-frames
+}
+
+/*****************************************************************************************************************/
+
+/*
+Select()
+
+Measures each sub-frame, and approves or rejects it by the policy, relative to the medians of
+the metrics over all of the sub-frames. Sub-frames which cannot be measured are rejected. A nil
+policy approves every sub-frame which can be measured.
+
+Returns the report of the selection.
+*/
+func Select(frames []fits.FITSImage, params Params, policy Policy) (*Report, error) {
+	if len(frames) == 0 {
+		return nil, errors.New("at least one sub-frame is required to select")
+	}
+
+	report := &Report{Frames: make([]Metrics, len(frames))}
+
+	measured := make([]bool, len(frames))
+
+	for i := range frames {
+		m, err := Measure(&frames[i], params)
+
+		m.Index = i
+
+		if err != nil {
+			m.Reasons = []string{err.Error()}
+		} else {
+			measured[i] = true
+		}
+
+		report.Frames[i] = m
+	}
+
+	report.Medians = getMedianMetrics(report.Frames, measured)
+
+	for i := range report.Frames {
+		m := &report.Frames[i]
+
+		if !measured[i] {
+			report.Rejected++
+			continue
+		}
+
+		m.Approved, m.Reasons = true, []string{}
+
+		if policy != nil {
+			approved, reasons, err := policy.Evaluate(*m, report.Medians)
+
+			if err != nil {
+				return nil, fmt.Errorf("sub-frame %d: %w", i, err)
+			}
+
+			m.Approved, m.Reasons = approved, reasons
+		}
+
+		if m.Approved {
+			report.Approved++
+		} else {
+			report.Rejected++
+		}
+	}
+
+	return report, nil
+}
+
+/*****************************************************************************************************************/
+
+// Returns the approved sub-frames, e.g., to be stacked.
+func (r *Report) GetApprovedFrames(frames []fits.FITSImage) []fits.FITSImage {
+	approved := make([]fits.FITSImage, 0, r.Approved)
+
+	for _, m := range r.Frames {
+		if m.Approved && m.Index < len(frames) {
+			approved = append(approved, frames[m.Index])
+		}
+	}
+
+	return approved
+}
+
+/*****************************************************************************************************************/
+
+// Writes the report as CSV, with a header row and one row for each sub-frame.
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+
+	err := writer.Write([]string{
+		"index", "filename", "stars", "hfr", "fwhm", "eccentricity", "background", "noise", "snr", "approved", "reasons",
+	})
+
+	if err != nil {
+		return err
+	}
+
+	format := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 4, 64)
+	}
+
+	for _, m := range r.Frames {
+		err := writer.Write([]string{
+			strconv.Itoa(m.Index),
+			m.Filename,
+			strconv.Itoa(m.Stars),
+			format(m.HFR),
+			format(m.FWHM),
+			format(m.Eccentricity),
+			format(m.Background),
+			format(m.Noise),
+			format(m.SNR),
+			strconv.FormatBool(m.Approved),
+			strings.Join(m.Reasons, "; "),
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+
+	return writer.Error()
+}
+
+/*****************************************************************************************************************/
+
+// Writes the report as indented JSON.
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(r)
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the median of each metric over the measured sub-frames.
+func getMedianMetrics(metrics []Metrics, measured []bool) Metrics {
+	values := make([][]float64, 7)
+
+	for i, m := range metrics {
+		if !measured[i] {
+			continue
+		}
+
+		for j, v := range []float64{float64(m.Stars), m.HFR, m.FWHM, m.Eccentricity, m.Background, m.Noise, m.SNR} {
+			values[j] = append(values[j], v)
+		}
+	}
+
+	return Metrics{
+		Index:        -1,
+		Stars:        int(qsort.MedianFloat64(values[0])),
+		HFR:          qsort.MedianFloat64(values[1]),
+		FWHM:         qsort.MedianFloat64(values[2]),
+		Eccentricity: qsort.MedianFloat64(values[3]),
+		Background:   qsort.MedianFloat64(values[4]),
+		Noise:        qsort.MedianFloat64(values[5]),
+		SNR:          qsort.MedianFloat64(values[6]),
+		Reasons:      []string{},
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,296 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/subframe
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package subframe
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/photometry"
+)
+
+/*****************************************************************************************************************/
+
+// Creates a sub-frame of a synthetic star field, with Gaussian stars of the given widths along x and y:
+func getTestSubFrame(sigmaX float64, sigmaY float64, background float64, seed int64) *fits.FITSImage {
+	xs, ys := 160, 160
+
+	field := rand.New(rand.NewSource(42))
+
+	noise := rand.New(rand.NewSource(seed))
+
+	f := fits.NewFITSImage(2, int32(xs), int32(ys), 65535)
+
+	f.Data = make([]float32, xs*ys)
+
+	for i := range f.Data {
+		f.Data[i] = float32(background + noise.NormFloat64()*10)
+	}
+
+	for s := 0; s < 20; s++ {
+		cx, cy := 15+field.Float64()*130, 15+field.Float64()*130
+
+		amplitude := 5000 + field.Float64()*20000
+
+		for y := 0; y < ys; y++ {
+			for x := 0; x < xs; x++ {
+				dx, dy := float64(x)-cx, float64(y)-cy
+
+				f.Data[y*xs+x] += float32(amplitude * math.Exp(-dx*dx/(2*sigmaX*sigmaX)-dy*dy/(2*sigmaY*sigmaY)))
+			}
+		}
+	}
+
+	return f
+}
+
+/*****************************************************************************************************************/
+
+func TestMeasureRoundStars(t *testing.T) {
+	m, err := Measure(getTestSubFrame(1.5, 1.5, 1000, 1), DefaultParams())
+
+	if err != nil {
+		t.Fatalf("Measure() error: %v", err)
+	}
+
+	if m.Stars == 0 {
+		t.Fatalf("Stars = 0, expected stars to be detected")
+	}
+
+	// The FWHM of a Gaussian of σ = 1.5 pixels is 3.53 pixels:
+	if math.Abs(m.FWHM-3.53) > 0.4 {
+		t.Errorf("FWHM = %f, expected 3.53", m.FWHM)
+	}
+
+	if m.Eccentricity > 0.35 {
+		t.Errorf("Eccentricity = %f, expected a round star", m.Eccentricity)
+	}
+
+	if math.Abs(m.Background-1000) > 5 {
+		t.Errorf("Background = %f, expected 1000", m.Background)
+	}
+
+	// The noise of the background is 10 ADU, but the noise estimate also responds to the structure of the stars:
+	if m.Noise < 8 || m.Noise > 20 {
+		t.Errorf("Noise = %f, expected between 8 and 20", m.Noise)
+	}
+
+	if m.SNR < 100 {
+		t.Errorf("SNR = %f, expected at least 100", m.SNR)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestStarShapeFWHM(t *testing.T) {
+	xs, ys := 64, 64
+
+	data := make([]float32, xs*ys)
+
+	for y := 0; y < ys; y++ {
+		for x := 0; x < xs; x++ {
+			dx, dy := float64(x)-32, float64(y)-32
+
+			data[y*xs+x] = float32(1000 + 1000*math.Exp(-(dx*dx+dy*dy)/(2*2*2)))
+		}
+	}
+
+	// The half-flux radius of a Gaussian of σ = 2 pixels is 2.35 pixels:
+	star := photometry.Star{X: 32, Y: 32, HFR: 2.35}
+
+	fwhm, eccentricity, peak, ok := getStarShape(data, xs, ys, star, 1000)
+
+	if !ok {
+		t.Fatalf("getStarShape() failed to measure the star")
+	}
+
+	// The FWHM of a Gaussian of σ = 2 pixels is 4.71 pixels, which the wings of the star are needed to recover:
+	if math.Abs(fwhm-4.71) > 0.05 {
+		t.Errorf("FWHM = %f, expected 4.71", fwhm)
+	}
+
+	if eccentricity > 0.05 {
+		t.Errorf("Eccentricity = %f, expected a round star", eccentricity)
+	}
+
+	if math.Abs(peak-1000) > 1 {
+		t.Errorf("Peak = %f, expected 1000", peak)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestMeasureElongatedStars(t *testing.T) {
+	m, err := Measure(getTestSubFrame(3, 1.5, 1000, 1), DefaultParams())
+
+	if err != nil {
+		t.Fatalf("Measure() error: %v", err)
+	}
+
+	// The eccentricity of a Gaussian of σx = 2σy is √(1 - 1/4) = 0.87:
+	if m.Stars == 0 {
+		t.Fatalf("Stars = 0, expected stars to be detected")
+	}
+
+	if math.Abs(m.Eccentricity-0.87) > 0.1 {
+		t.Errorf("Eccentricity = %f, expected 0.87", m.Eccentricity)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestSelectThresholdPolicy(t *testing.T) {
+	frames := []fits.FITSImage{
+		*getTestSubFrame(1.5, 1.5, 1000, 1),
+		*getTestSubFrame(1.5, 1.5, 1000, 2),
+		*getTestSubFrame(1.5, 1.5, 5000, 3),
+		*getTestSubFrame(1.5, 1.5, 1000, 4),
+	}
+
+	report, err := Select(frames, DefaultParams(), ThresholdPolicy{MaxBackground: 2000, MaxRelativeFWHM: 1.5})
+
+	if err != nil {
+		t.Fatalf("Select() error: %v", err)
+	}
+
+	if report.Approved != 3 || report.Rejected != 1 {
+		t.Fatalf("Approved = %d and Rejected = %d, expected 3 and 1", report.Approved, report.Rejected)
+	}
+
+	if report.Frames[2].Approved || len(report.Frames[2].Reasons) != 1 || !strings.HasPrefix(report.Frames[2].Reasons[0], "background") {
+		t.Errorf("sub-frame 2 Approved = %t with reasons %v, expected rejection by background", report.Frames[2].Approved, report.Frames[2].Reasons)
+	}
+
+	if approved := report.GetApprovedFrames(frames); len(approved) != 3 {
+		t.Errorf("len(GetApprovedFrames()) = %d, expected 3", len(approved))
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestSelectBlankFrame(t *testing.T) {
+	blank := fits.NewFITSImage(2, 160, 160, 65535)
+
+	noise := rand.New(rand.NewSource(5))
+
+	blank.Data = make([]float32, 160*160)
+
+	for i := range blank.Data {
+		blank.Data[i] = float32(1000 + noise.NormFloat64()*10)
+	}
+
+	frames := []fits.FITSImage{
+		*getTestSubFrame(1.5, 1.5, 1000, 1),
+		*blank,
+		*getTestSubFrame(1.5, 1.5, 1000, 2),
+		*getTestSubFrame(1.5, 1.5, 1000, 3),
+	}
+
+	if _, err := Measure(blank, DefaultParams()); err == nil {
+		t.Errorf("expected an error measuring a sub-frame without stars")
+	}
+
+	report, err := Select(frames, DefaultParams(), ThresholdPolicy{MaxFWHM: 5, MaxEccentricity: 0.5})
+
+	if err != nil {
+		t.Fatalf("Select() error: %v", err)
+	}
+
+	// The blank sub-frame is rejected as unmeasured, rather than passing the shape thresholds:
+	if report.Frames[1].Approved || report.Approved != 3 || report.Rejected != 1 {
+		t.Fatalf("Approved = %d and Rejected = %d, expected only the blank sub-frame to be rejected", report.Approved, report.Rejected)
+	}
+
+	// The blank sub-frame does not pull down the medians of the star shapes:
+	if math.Abs(report.Medians.FWHM-report.Frames[0].FWHM) > 0.2 {
+		t.Errorf("median FWHM = %f, expected approximately %f", report.Medians.FWHM, report.Frames[0].FWHM)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestExpressionPolicy(t *testing.T) {
+	p, err := NewExpressionPolicy("FWHM < 1.2 * MedianFWHM && (Stars >= 10 || !(SNR > 5))")
+
+	if err != nil {
+		t.Fatalf("NewExpressionPolicy() error: %v", err)
+	}
+
+	medians := Metrics{FWHM: 3}
+
+	if approved, _, _ := p.Evaluate(Metrics{FWHM: 3.2, Stars: 12, SNR: 50}, medians); !approved {
+		t.Errorf("expected a sub-frame with FWHM 3.2 to be approved")
+	}
+
+	approved, reasons, _ := p.Evaluate(Metrics{FWHM: 4, Stars: 12, SNR: 50}, medians)
+
+	if approved || len(reasons) != 1 {
+		t.Errorf("expected a sub-frame with FWHM 4 to be rejected, got %t with reasons %v", approved, reasons)
+	}
+
+	for _, expression := range []string{"FWHM <", "Seeing < 2", "FWHM + 1", "FWHM && Stars"} {
+		if _, err := NewExpressionPolicy(expression); err == nil {
+			t.Errorf("expected an error for the expression %q", expression)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestReportWriteCSVAndJSON(t *testing.T) {
+	report := &Report{
+		Frames: []Metrics{
+			{Index: 0, Filename: "a.fits", Stars: 10, FWHM: 3.5, Approved: true, Reasons: []string{}},
+			{Index: 1, Filename: "b.fits", Stars: 2, FWHM: 6, Reasons: []string{"stars 2 < 5", "FWHM 6 > 5"}},
+		},
+		Approved: 1,
+		Rejected: 1,
+	}
+
+	var b bytes.Buffer
+
+	if err := report.WriteCSV(&b); err != nil {
+		t.Fatalf("WriteCSV() error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+
+	if len(lines) != 3 {
+		t.Fatalf("CSV has %d lines, expected 3", len(lines))
+	}
+
+	if lines[2] != "1,b.fits,2,0.0000,6.0000,0.0000,0.0000,0.0000,0.0000,false,stars 2 < 5; FWHM 6 > 5" {
+		t.Errorf("CSV row = %q", lines[2])
+	}
+
+	b.Reset()
+
+	if err := report.WriteJSON(&b); err != nil {
+		t.Fatalf("WriteJSON() error: %v", err)
+	}
+
+	var decoded Report
+
+	if err := json.Unmarshal(b.Bytes(), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal() error: %v", err)
+	}
+
+	if len(decoded.Frames) != 2 || decoded.Frames[1].Filename != "b.fits" || decoded.Approved != 1 {
+		t.Errorf("decoded report = %+v", decoded)
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,142 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/trails
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package trails
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"runtime"
+	"sync"
+)
+
+/*****************************************************************************************************************/
+
+// A pixel of the image above the detection threshold:
+type point struct {
+	x float64
+	y float64
+}
+
+/*****************************************************************************************************************/
+
+/*
+The Hough transform of the detected pixels, i.e., the number of pixels on each line
+x·cos(θ) + y·sin(θ) = ρ, for θ in [0, π) and ρ in [-diagonal, diagonal], in bins of one pixel.
+*/
+type hough struct {
+	thetas     int       // The number of angles, i.e., π / the angular resolution
+	rhos       int       // The number of distances, i.e., 2·diagonal + 1
+	diagonal   int       // The length of the diagonal of the image, in pixels
+	cos        []float64 // The cosine of each angle
+	sin        []float64 // The sine of each angle
+	votes      []int32   // The votes of each { θ, ρ } bin, in angle-major order
+	suppressed []bool    // Whether each bin has been examined and rejected
+}
+
+/*****************************************************************************************************************/
+
+// Creates the (empty) Hough transform of an image of the dimensions, at the angular resolution (in degrees).
+func newHough(xs int, ys int, step float64) *hough {
+	thetas := max(int(math.Round(180/step)), 1)
+
+	diagonal := int(math.Ceil(math.Hypot(float64(xs), float64(ys))))
+
+	h := &hough{
+		thetas:     thetas,
+		rhos:       2*diagonal + 1,
+		diagonal:   diagonal,
+		cos:        make([]float64, thetas),
+		sin:        make([]float64, thetas),
+		votes:      make([]int32, thetas*(2*diagonal+1)),
+		suppressed: make([]bool, thetas*(2*diagonal+1)),
+	}
+
+	for k := 0; k < thetas; k++ {
+		theta := float64(k) * math.Pi / float64(thetas)
+
+		h.cos[k], h.sin[k] = math.Cos(theta), math.Sin(theta)
+	}
+
+	return h
+}
+
+/*****************************************************************************************************************/
+
+// Adds (or, for a negative weight, removes) the votes of the points, in parallel over bands of angles.
+func (h *hough) vote(points []point, weight int32) {
+	workers := min(runtime.NumCPU(), h.thetas)
+
+	band := (h.thetas + workers - 1) / workers
+
+	var wg sync.WaitGroup
+
+	for k0 := 0; k0 < h.thetas; k0 += band {
+		wg.Add(1)
+
+		go func(k0 int, k1 int) {
+			defer wg.Done()
+
+			for k := k0; k < k1; k++ {
+				row := h.votes[k*h.rhos : (k+1)*h.rhos]
+
+				for _, p := range points {
+					row[int(math.Round(p.x*h.cos[k]+p.y*h.sin[k]))+h.diagonal] += weight
+				}
+			}
+		}(k0, min(k0+band, h.thetas))
+	}
+
+	wg.Wait()
+}
+
+/*****************************************************************************************************************/
+
+// Finds the unsuppressed bin with the most votes, returning its angle (in radians), distance and votes.
+func (h *hough) peak() (float64, float64, int, int) {
+	best := -1
+
+	for i, v := range h.votes {
+		if !h.suppressed[i] && (best < 0 || v > h.votes[best]) {
+			best = i
+		}
+	}
+
+	if best < 0 {
+		return 0, 0, 0, -1
+	}
+
+	k, r := best/h.rhos, best%h.rhos
+
+	return float64(k) * math.Pi / float64(h.thetas), float64(r - h.diagonal), int(h.votes[best]), best
+}
+
+/*****************************************************************************************************************/
+
+// Suppresses the bins about the bin, such that the neighbouring bins of a rejected line are not examined again.
+func (h *hough) suppress(bin int, dk int, dr int) {
+	k, r := bin/h.rhos, bin%h.rhos
+
+	for j := k - dk; j <= k+dk; j++ {
+		// The angles wrap around at π, where the sign of the distance is reversed:
+		kk, rr := j, r
+
+		if kk < 0 {
+			kk, rr = kk+h.thetas, h.rhos-1-r
+		} else if kk >= h.thetas {
+			kk, rr = kk-h.thetas, h.rhos-1-r
+		}
+
+		for i := max(rr-dr, 0); i <= min(rr+dr, h.rhos-1); i++ {
+			h.suppressed[kk*h.rhos+i] = true
+		}
+	}
+}
+
+/*****************************************************************************************************************/
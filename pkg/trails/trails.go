@@ -0,0 +1,606 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/trails
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package trails
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/observerly/iris/pkg/background"
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/qsort"
+)
+
+/*****************************************************************************************************************/
+
+// The minimum ratio of the faintest to the brightest quarter of a trail, such that the compact (and so very
+// non-uniform) profiles of bright stars and galaxies are not mistaken for trails:
+const minUniformity = 0.25
+
+/*****************************************************************************************************************/
+
+// Params describes how satellite and aircraft trails are detected and masked.
+type Params struct {
+	Background background.Params // How the background is estimated and subtracted before detection
+	Sigma      float64           // The detection threshold of the (3x3 smoothed) background-subtracted image, in standard deviations
+	ThetaStep  float64           // The angular resolution of the Hough transform, in degrees
+	Tolerance  float64           // The maximum distance of a detected pixel from a trail for it to belong to the trail, in pixels
+	MinLength  float64           // The minimum length of a trail, in pixels
+	MaxGap     float64           // The maximum gap between the detected pixels along a trail, in pixels
+	MinFill    float64           // The minimum fraction of the length of a trail covered by its detected pixels
+	MaxWidth   float64           // The maximum width (FWHM) of a trail, in pixels
+	MaxTrails  int               // The maximum number of trails detected
+	MaskScale  float64           // The half-width of the mask of each trail, as a multiple of its width
+}
+
+/*****************************************************************************************************************/
+
+// Returns the default trail parameters, i.e., trails of at least 64 pixels detected at 3σ, masked to 1.5 times
+// their width either side.
+func DefaultParams() Params {
+	return Params{
+		Background: background.DefaultParams(),
+		Sigma:      3,
+		ThetaStep:  0.25,
+		Tolerance:  2,
+		MinLength:  64,
+		MaxGap:     16,
+		MinFill:    0.5,
+		MaxWidth:   20,
+		MaxTrails:  10,
+		MaskScale:  1.5,
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Trail is a linear feature of the image, e.g., the trail of a satellite, aircraft or meteor.
+type Trail struct {
+	X1         float64 // The x position of the first endpoint of the trail, in pixels
+	Y1         float64 // The y position of the first endpoint of the trail, in pixels
+	X2         float64 // The x position of the second endpoint of the trail, in pixels
+	Y2         float64 // The y position of the second endpoint of the trail, in pixels
+	Length     float64 // The length of the trail, in pixels
+	Width      float64 // The width (FWHM) of the trail, in pixels
+	Angle      float64 // The angle of the trail from the x axis, in degrees in [0, 180)
+	Pixels     int     // The number of detected pixels of the trail
+	Brightness float64 // The median brightness of the trail above the background, in ADU
+}
+
+/*****************************************************************************************************************/
+
+// Result is the trails detected in an image, with the mask of the pixels affected by them.
+type Result struct {
+	Width  int     // The width of the image, in pixels
+	Height int     // The height of the image, in pixels
+	Trails []Trail // The detected trails, in the order of detection, i.e., the strongest first
+	Mask   []bool  // The mask of the pixels affected by the trails, of the dimensions of the image
+	Masked int     // The number of masked pixels
+	Noise  float64 // The standard deviation of the background, in ADU
+}
+
+/*****************************************************************************************************************/
+
+/*
+Detect()
+
+Detects the satellite and aircraft trails of the image.
+
+The background is estimated and subtracted, and the residual image (smoothed by a 3x3 box
+filter, to favour faint but extended features) is thresholded at a number of standard
+deviations of the background. The Hough transform of the thresholded pixels is searched for
+its strongest lines, each of which is verified as a trail by:
+
+ 1. the longest run of pixels along the line, without gaps beyond the maximum gap,
+ 2. a refinement of the line to the principal axis of the pixels of the run,
+ 3. its length, and the fraction of its length covered by detected pixels,
+ 4. its width, i.e., the FWHM of its perpendicular profile, and
+ 5. its uniformity, such that the compact profiles of bright stars are rejected.
+
+The pixels of each trail are removed from the Hough transform before the next is sought.
+
+The mask of the result covers the pixels within a multiple of the width of each trail, such
+that integration may reject only the pixels affected by a trail rather than the whole frame.
+*/
+func Detect(f *fits.FITSImage, params Params) (*Result, error) {
+	xs, ys, ok := f.GetDimensions()
+
+	if !ok || len(f.Data) != xs*ys {
+		return nil, fmt.Errorf("the image data of %d pixels does not match the dimensions %dx%d", len(f.Data), xs, ys)
+	}
+
+	if params.Sigma <= 0 || params.ThetaStep <= 0 || params.Tolerance <= 0 || params.MinLength <= 0 {
+		return nil, errors.New("the detection threshold, angular resolution, tolerance and minimum length must be positive")
+	}
+
+	b, err := background.Estimate(f, params.Background)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate the background: %w", err)
+	}
+
+	residual := make([]float32, len(f.Data))
+
+	for i, v := range f.Data {
+		residual[i] = v - b.Data[i]
+	}
+
+	noise := getNoise(b)
+
+	if noise <= 0 {
+		return nil, errors.New("the noise of the background could not be estimated")
+	}
+
+	d := &detector{
+		xs:       xs,
+		ys:       ys,
+		residual: residual,
+		params:   params,
+	}
+
+	// The noise of the mean of 3x3 pixels is a third of that of a single pixel:
+	threshold := float32(params.Sigma * noise / 3)
+
+	smoothed := getBoxFiltered(residual, xs, ys)
+
+	for j := 0; j < ys; j++ {
+		for i := 0; i < xs; i++ {
+			if v := smoothed[j*xs+i]; v > threshold {
+				d.points = append(d.points, point{x: float64(i), y: float64(j)})
+			}
+		}
+	}
+
+	d.active = make([]bool, len(d.points))
+
+	for i := range d.active {
+		d.active[i] = true
+	}
+
+	h := newHough(xs, ys, params.ThetaStep)
+
+	h.vote(d.points, 1)
+
+	result := &Result{
+		Width:  xs,
+		Height: ys,
+		Trails: []Trail{},
+		Mask:   make([]bool, xs*ys),
+		Noise:  noise,
+	}
+
+	// Each rejected line suppresses its neighbourhood of the transform, so the number of lines examined is limited:
+	for examined := 0; len(result.Trails) < params.MaxTrails && examined < 10*max(params.MaxTrails, 1); examined++ {
+		theta, rho, votes, bin := h.peak()
+
+		if bin < 0 || float64(votes) < params.MinFill*params.MinLength {
+			break
+		}
+
+		trail, ok := d.fit(theta, rho)
+
+		if !ok {
+			h.suppress(bin, 2, int(math.Ceil(params.Tolerance)))
+			continue
+		}
+
+		halfWidth := math.Max(params.MaskScale*trail.Width, params.Tolerance)
+
+		d.mask(result.Mask, trail, halfWidth)
+
+		h.vote(d.remove(trail, halfWidth), -1)
+
+		result.Trails = append(result.Trails, trail)
+	}
+
+	for _, m := range result.Mask {
+		if m {
+			result.Masked++
+		}
+	}
+
+	return result, nil
+}
+
+/*****************************************************************************************************************/
+
+// The state of the detection of the trails of an image:
+type detector struct {
+	xs       int
+	ys       int
+	residual []float32
+	points   []point
+	active   []bool
+	params   Params
+}
+
+/*****************************************************************************************************************/
+
+// Fits a trail to the detected pixels about the line x·cos(θ) + y·sin(θ) = ρ, returning whether it is a trail.
+func (d *detector) fit(theta float64, rho float64) (Trail, bool) {
+	cos, sin := math.Cos(theta), math.Sin(theta)
+
+	// The line through the point nearest the origin, along the direction perpendicular to its normal:
+	cx, cy, dx, dy := rho*cos, rho*sin, -sin, cos
+
+	run, ts := d.getLongestRun(cx, cy, dx, dy)
+
+	if len(run) < 2 {
+		return Trail{}, false
+	}
+
+	// Refine the line to the principal axis of the pixels of the run, which converges onto the trail as its pixels
+	// are recollected about the refined line:
+	for iteration := 0; iteration < 3; iteration++ {
+		cx, cy, dx, dy = getPrincipalAxis(d.points, run)
+
+		run, ts = d.getLongestRun(cx, cy, dx, dy)
+
+		if len(run) < 2 {
+			return Trail{}, false
+		}
+	}
+
+	t0, t1 := ts[0], ts[len(ts)-1]
+
+	length := t1 - t0
+
+	if length < d.params.MinLength {
+		return Trail{}, false
+	}
+
+	// The fraction of the length of the trail covered by its detected pixels:
+	covered := map[int]bool{}
+
+	for _, t := range ts {
+		covered[int(math.Floor(t-t0))] = true
+	}
+
+	if float64(len(covered)) < d.params.MinFill*(length+1) {
+		return Trail{}, false
+	}
+
+	width, offset, brightness, ok := d.getProfile(cx, cy, dx, dy, t0, t1)
+
+	if !ok {
+		return Trail{}, false
+	}
+
+	// Centre the line on the peak of the perpendicular profile:
+	cx, cy = cx-offset*dy, cy+offset*dx
+
+	angle := math.Mod(math.Atan2(dy, dx)*180/math.Pi+360, 180)
+
+	return Trail{
+		X1:         cx + t0*dx,
+		Y1:         cy + t0*dy,
+		X2:         cx + t1*dx,
+		Y2:         cy + t1*dy,
+		Length:     length,
+		Width:      width,
+		Angle:      angle,
+		Pixels:     len(run),
+		Brightness: brightness,
+	}, true
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the (active) detected pixels within the tolerance of the line through { cx, cy } along { dx, dy }, in the
+// longest run without gaps beyond the maximum gap, with their positions along the line in ascending order.
+func (d *detector) getLongestRun(cx float64, cy float64, dx float64, dy float64) ([]int, []float64) {
+	type member struct {
+		index int
+		t     float64
+	}
+
+	members := []member{}
+
+	for i, p := range d.points {
+		if !d.active[i] {
+			continue
+		}
+
+		x, y := p.x-cx, p.y-cy
+
+		// The perpendicular distance from the line, and the position along the line:
+		if math.Abs(x*dy-y*dx) <= d.params.Tolerance {
+			members = append(members, member{index: i, t: x*dx + y*dy})
+		}
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].t < members[j].t
+	})
+
+	start, best, bestLength := 0, [2]int{0, 0}, -1.0
+
+	for i := range members {
+		if i > 0 && members[i].t-members[i-1].t > d.params.MaxGap {
+			start = i
+		}
+
+		if l := members[i].t - members[start].t; l > bestLength {
+			best, bestLength = [2]int{start, i + 1}, l
+		}
+	}
+
+	run, ts := make([]int, 0, best[1]-best[0]), make([]float64, 0, best[1]-best[0])
+
+	for _, m := range members[best[0]:best[1]] {
+		run = append(run, m.index)
+
+		ts = append(ts, m.t)
+	}
+
+	return run, ts
+}
+
+/*****************************************************************************************************************/
+
+/*
+Obtains the width (FWHM), perpendicular offset of the centre and median brightness of the trail from
+its perpendicular profile in the background-subtracted image, between the positions t0 and t1 along
+the line through { cx, cy } along { dx, dy }, returning whether the profile is that of a trail, i.e.,
+no wider than the maximum width and uniform along its length.
+*/
+func (d *detector) getProfile(cx float64, cy float64, dx float64, dy float64, t0 float64, t1 float64) (float64, float64, float64, bool) {
+	r := max(int(math.Ceil(d.params.MaxWidth)), 1)
+
+	// The residual at the offset o perpendicular to the line, at the position t along the line:
+	at := func(t float64, o float64) (float64, bool) {
+		x, y := int(math.Round(cx+t*dx-o*dy)), int(math.Round(cy+t*dy+o*dx))
+
+		if x < 0 || y < 0 || x >= d.xs || y >= d.ys {
+			return 0, false
+		}
+
+		v := float64(d.residual[y*d.xs+x])
+
+		return v, !math.IsNaN(v)
+	}
+
+	sums, counts := make([]float64, 2*r+1), make([]int, 2*r+1)
+
+	for t := t0; t <= t1; t++ {
+		for o := -r; o <= r; o++ {
+			if v, ok := at(t, float64(o)); ok {
+				sums[o+r] += v
+
+				counts[o+r]++
+			}
+		}
+	}
+
+	profile := make([]float64, 2*r+1)
+
+	for i := range profile {
+		if counts[i] > 0 {
+			profile[i] = sums[i] / float64(counts[i])
+		}
+	}
+
+	// The peak of the profile, within the tolerance of the line:
+	peak := r
+
+	for i := max(r-int(math.Ceil(d.params.Tolerance)), 0); i <= min(r+int(math.Ceil(d.params.Tolerance)), 2*r); i++ {
+		if profile[i] > profile[peak] {
+			peak = i
+		}
+	}
+
+	if profile[peak] <= 0 {
+		return 0, 0, 0, false
+	}
+
+	half := profile[peak] / 2
+
+	// The offsets at which the profile falls to half of its peak, interpolated between pixels:
+	left, right := -1.0, -1.0
+
+	for i := peak; i > 0; i-- {
+		if profile[i-1] < half {
+			left = float64(i) - (profile[i]-half)/(profile[i]-profile[i-1])
+			break
+		}
+	}
+
+	for i := peak; i < 2*r; i++ {
+		if profile[i+1] < half {
+			right = float64(i) + (profile[i]-half)/(profile[i]-profile[i+1])
+			break
+		}
+	}
+
+	if left < 0 || right < 0 {
+		return 0, 0, 0, false
+	}
+
+	width, centre := math.Max(right-left, 1), (left+right)/2-float64(r)
+
+	if width > d.params.MaxWidth {
+		return 0, 0, 0, false
+	}
+
+	// The brightness along the ridge of the trail, i.e., the mean within half of its width of its peak:
+	ridge := []float64{}
+
+	offset := float64(peak - r)
+
+	for t := t0; t <= t1; t++ {
+		sum, n := 0.0, 0
+
+		for o := -math.Floor(width / 2); o <= math.Floor(width/2); o++ {
+			if v, ok := at(t, offset+o); ok {
+				sum += v
+
+				n++
+			}
+		}
+
+		if n > 0 {
+			ridge = append(ridge, sum/float64(n))
+		}
+	}
+
+	if len(ridge) < 4 {
+		return 0, 0, 0, false
+	}
+
+	// The median brightness of each quarter of the trail must be comparable:
+	faintest, brightest := math.Inf(1), math.Inf(-1)
+
+	for q := 0; q < 4; q++ {
+		m := qsort.MedianFloat64(ridge[q*len(ridge)/4 : (q+1)*len(ridge)/4])
+
+		faintest, brightest = math.Min(faintest, m), math.Max(brightest, m)
+	}
+
+	if brightest <= 0 || faintest < minUniformity*brightest {
+		return 0, 0, 0, false
+	}
+
+	return width, centre, qsort.MedianFloat64(ridge), true
+}
+
+/*****************************************************************************************************************/
+
+// Masks the pixels within the half-width of the segment of the trail.
+func (d *detector) mask(mask []bool, trail Trail, halfWidth float64) {
+	x0, x1 := int(math.Floor(math.Min(trail.X1, trail.X2)-halfWidth)), int(math.Ceil(math.Max(trail.X1, trail.X2)+halfWidth))
+
+	y0, y1 := int(math.Floor(math.Min(trail.Y1, trail.Y2)-halfWidth)), int(math.Ceil(math.Max(trail.Y1, trail.Y2)+halfWidth))
+
+	for j := max(y0, 0); j <= min(y1, d.ys-1); j++ {
+		for i := max(x0, 0); i <= min(x1, d.xs-1); i++ {
+			if getDistanceToSegment(float64(i), float64(j), trail) <= halfWidth {
+				mask[j*d.xs+i] = true
+			}
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Deactivates the detected pixels within the half-width of the segment of the trail, returning them.
+func (d *detector) remove(trail Trail, halfWidth float64) []point {
+	removed := []point{}
+
+	for i, p := range d.points {
+		if d.active[i] && getDistanceToSegment(p.x, p.y, trail) <= halfWidth {
+			d.active[i] = false
+
+			removed = append(removed, p)
+		}
+	}
+
+	return removed
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the centroid and the (unit) direction of the principal axis of the points.
+func getPrincipalAxis(points []point, indices []int) (float64, float64, float64, float64) {
+	cx, cy := 0.0, 0.0
+
+	for _, i := range indices {
+		cx += points[i].x
+
+		cy += points[i].y
+	}
+
+	n := float64(len(indices))
+
+	cx, cy = cx/n, cy/n
+
+	sxx, syy, sxy := 0.0, 0.0, 0.0
+
+	for _, i := range indices {
+		x, y := points[i].x-cx, points[i].y-cy
+
+		sxx += x * x
+
+		syy += y * y
+
+		sxy += x * y
+	}
+
+	// The angle of the eigenvector of the largest eigenvalue of the covariance matrix:
+	phi := 0.5 * math.Atan2(2*sxy, sxx-syy)
+
+	return cx, cy, math.Cos(phi), math.Sin(phi)
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the distance of the position { x, y } from the segment of the trail.
+func getDistanceToSegment(x float64, y float64, trail Trail) float64 {
+	dx, dy := trail.X2-trail.X1, trail.Y2-trail.Y1
+
+	l2 := dx*dx + dy*dy
+
+	if l2 == 0 {
+		return math.Hypot(x-trail.X1, y-trail.Y1)
+	}
+
+	t := math.Max(0, math.Min(1, ((x-trail.X1)*dx+(y-trail.Y1)*dy)/l2))
+
+	return math.Hypot(x-trail.X1-t*dx, y-trail.Y1-t*dy)
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the mean of each pixel and its (in-bounds, non-NaN) neighbours in a 3x3 box.
+func getBoxFiltered(data []float32, xs int, ys int) []float32 {
+	out := make([]float32, len(data))
+
+	for j := 0; j < ys; j++ {
+		for i := 0; i < xs; i++ {
+			sum, n := float32(0), 0
+
+			for y := max(j-1, 0); y <= min(j+1, ys-1); y++ {
+				for x := max(i-1, 0); x <= min(i+1, xs-1); x++ {
+					if v := data[y*xs+x]; !math.IsNaN(float64(v)) {
+						sum += v
+
+						n++
+					}
+				}
+			}
+
+			if n > 0 {
+				out[j*xs+i] = sum / float32(n)
+			}
+		}
+	}
+
+	return out
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the standard deviation of the background, i.e., the median of the robust standard deviations of its
+// accepted samples.
+func getNoise(b *background.Background) float64 {
+	sigmas := []float64{}
+
+	for _, s := range b.Samples {
+		if !s.Rejected && s.Sigma > 0 {
+			sigmas = append(sigmas, s.Sigma)
+		}
+	}
+
+	return qsort.MedianFloat64(sigmas)
+}
+
+/*****************************************************************************************************************/
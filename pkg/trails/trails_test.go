@@ -0,0 +1,186 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/trails
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package trails
+
+/*****************************************************************************************************************/
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+	"github.com/observerly/iris/pkg/internal/starfield"
+)
+
+/*****************************************************************************************************************/
+
+// Creates a test image of a field of stars on a flat background, with Gaussian noise and the given trails (of a
+// Gaussian profile with a σ of 1 pixel, i.e., a FWHM of 2.35 pixels):
+func getTestImage(xs int, ys int, trails []Trail) *fits.FITSImage {
+	random := rand.New(rand.NewSource(42))
+
+	data := make([]float32, xs*ys)
+
+	for i := range data {
+		data[i] = float32(1000 + random.NormFloat64()*10)
+	}
+
+	for n := 0; n < 30; n++ {
+		x, y, flux := 10+random.Float64()*float64(xs-20), 10+random.Float64()*float64(ys-20), 5000+random.Float64()*30000
+
+		starfield.AddStar(data, xs, ys, x, y, flux)
+	}
+
+	for _, t := range trails {
+		for j := 0; j < ys; j++ {
+			for i := 0; i < xs; i++ {
+				d := getDistanceToSegment(float64(i), float64(j), t)
+
+				data[j*xs+i] += float32(t.Brightness * math.Exp(-d*d/2))
+			}
+		}
+	}
+
+	f := fits.NewFITSImage(2, int32(xs), int32(ys), 65535)
+
+	f.Data = data
+
+	f.Naxisn = []int32{int32(xs), int32(ys)}
+
+	return f
+}
+
+/*****************************************************************************************************************/
+
+// Obtains whether the detected trail matches the expected trail, with endpoints in either order:
+func isMatchingTrail(detected Trail, expected Trail, tolerance float64) bool {
+	forward := math.Hypot(detected.X1-expected.X1, detected.Y1-expected.Y1) <= tolerance &&
+		math.Hypot(detected.X2-expected.X2, detected.Y2-expected.Y2) <= tolerance
+
+	backward := math.Hypot(detected.X1-expected.X2, detected.Y1-expected.Y2) <= tolerance &&
+		math.Hypot(detected.X2-expected.X1, detected.Y2-expected.Y1) <= tolerance
+
+	return forward || backward
+}
+
+/*****************************************************************************************************************/
+
+func TestDetectTrails(t *testing.T) {
+	expected := []Trail{
+		{X1: 20, Y1: 30, X2: 230, Y2: 210, Brightness: 80},
+		{X1: 40, Y1: 150, X2: 200, Y2: 150, Brightness: 30},
+	}
+
+	result, err := Detect(getTestImage(256, 256, expected), DefaultParams())
+
+	if err != nil {
+		t.Fatalf("Detect() failed: %v", err)
+	}
+
+	if len(result.Trails) != len(expected) {
+		t.Fatalf("expected %d trails, got %d: %+v", len(expected), len(result.Trails), result.Trails)
+	}
+
+	for _, e := range expected {
+		found := false
+
+		for _, d := range result.Trails {
+			if !isMatchingTrail(d, e, 6) {
+				continue
+			}
+
+			found = true
+
+			if math.Abs(d.Width-2.35) > 1 {
+				t.Errorf("expected a trail width of about 2.35 pixels, got %v", d.Width)
+			}
+
+			if math.Abs(d.Brightness-e.Brightness) > 0.4*e.Brightness {
+				t.Errorf("expected a trail brightness of about %v, got %v", e.Brightness, d.Brightness)
+			}
+		}
+
+		if !found {
+			t.Errorf("expected the trail %+v to be detected, got %+v", e, result.Trails)
+		}
+	}
+
+	// The mask must cover the trails, but not the rest of the image:
+	for _, e := range expected {
+		for s := 0.0; s <= 1; s += 0.05 {
+			x, y := int(math.Round(e.X1+s*(e.X2-e.X1))), int(math.Round(e.Y1+s*(e.Y2-e.Y1)))
+
+			if !result.Mask[y*256+x] {
+				t.Errorf("expected the trail pixel (%d, %d) to be masked", x, y)
+			}
+		}
+	}
+
+	if result.Masked > 256*256/10 {
+		t.Errorf("expected at most a tenth of the image to be masked, got %d pixels", result.Masked)
+	}
+
+	if result.Mask[5*256+250] {
+		t.Errorf("expected pixels away from the trails not to be masked")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestDetectNoTrails(t *testing.T) {
+	result, err := Detect(getTestImage(256, 256, nil), DefaultParams())
+
+	if err != nil {
+		t.Fatalf("Detect() failed: %v", err)
+	}
+
+	if len(result.Trails) != 0 {
+		t.Errorf("expected no trails in an image of stars, got %+v", result.Trails)
+	}
+
+	if result.Masked != 0 {
+		t.Errorf("expected no masked pixels, got %d", result.Masked)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestDetectInvalidParams(t *testing.T) {
+	params := DefaultParams()
+
+	params.MinLength = 0
+
+	if _, err := Detect(getTestImage(64, 64, nil), params); err == nil {
+		t.Errorf("expected an error for a non-positive minimum length")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestGetDistanceToSegment(t *testing.T) {
+	trail := Trail{X1: 0, Y1: 0, X2: 10, Y2: 0}
+
+	tests := []struct {
+		x, y, distance float64
+	}{
+		{5, 3, 3},
+		{-3, 4, 5},
+		{13, -4, 5},
+		{0, 0, 0},
+	}
+
+	for _, test := range tests {
+		if d := getDistanceToSegment(test.x, test.y, trail); math.Abs(d-test.distance) > 1e-9 {
+			t.Errorf("expected the distance of (%v, %v) to be %v, got %v", test.x, test.y, test.distance, d)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
@@ -0,0 +1,374 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/wcs
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+// WCS is the World Coordinate System of a frame, i.e., the gnomonic (TAN) projection of the equatorial coordinates
+// of the sky onto the pixels of the frame, with optional Simple Imaging Polynomial (SIP) distortion.
+type WCS struct {
+	CRPIX1   float64 // The x reference pixel (1-based, as per the FITS standard)
+	CRPIX2   float64 // The y reference pixel (1-based, as per the FITS standard)
+	CRVAL1   float64 // The Right Ascension of the reference pixel, in degrees
+	CRVAL2   float64 // The Declination of the reference pixel, in degrees
+	CD1_1    float64 // The linear transform of pixel offsets to intermediate world coordinates, in degrees per pixel
+	CD1_2    float64
+	CD2_1    float64
+	CD2_2    float64
+	SIPOrder int         // The order of the SIP distortion polynomials, or zero if there is no distortion
+	A        [][]float64 // The SIP coefficients A_p_q of the x distortion, indexed [p][q] for 2 <= p + q <= SIPOrder
+	B        [][]float64 // The SIP coefficients B_p_q of the y distortion, indexed [p][q] for 2 <= p + q <= SIPOrder
+}
+
+/*****************************************************************************************************************/
+
+// The maximum order of the SIP distortion polynomials:
+const maxSIPOrder = 9
+
+/*****************************************************************************************************************/
+
+/*
+NewWCSFromHeader()
+
+Creates the World Coordinate System of a frame from its FITS header, i.e., from the CTYPEn,
+CRPIXn and CRVALn keywords with either the CDi_j matrix, the PCi_j matrix with CDELTn, or
+CDELTn with the CROTA2 rotation (in that order of precedence).
+
+For the TAN-SIP projection, the A_ORDER, B_ORDER, A_p_q and B_p_q keywords describe the SIP
+distortion of the pixel offsets from the reference pixel.
+
+Returns an error if the header does not describe a gnomonic (TAN) projection of equatorial
+coordinates.
+
+@see Calabretta, M. R. & Greisen, E. W. (2002). Representations of celestial coordinates in FITS. A&A, 395, 1077
+@see Shupe, D. L. et al. (2005). The SIP Convention for Representing Distortion in FITS Image Headers. ASP Conf. Ser., 347, 491
+*/
+func NewWCSFromHeader(h fits.FITSHeader) (*WCS, error) {
+	sip := false
+
+	for _, key := range []string{"CTYPE1", "CTYPE2"} {
+		ctype, ok := h.Strings[key]
+
+		if !ok {
+			continue
+		}
+
+		switch value := strings.TrimSpace(ctype.Value); {
+		case strings.HasSuffix(value, "-TAN-SIP"):
+			sip = true
+		case !strings.HasSuffix(value, "-TAN"):
+			return nil, fmt.Errorf("unsupported projection %s = %q, only the gnomonic (TAN) projection is supported", key, ctype.Value)
+		}
+	}
+
+	w := &WCS{}
+
+	var ok bool
+
+	for key, value := range map[string]*float64{"CRPIX1": &w.CRPIX1, "CRPIX2": &w.CRPIX2, "CRVAL1": &w.CRVAL1, "CRVAL2": &w.CRVAL2} {
+		if *value, ok = h.GetFloat(key); !ok {
+			return nil, fmt.Errorf("the %s keyword is required for the world coordinate system", key)
+		}
+	}
+
+	cd11, ok11 := h.GetFloat("CD1_1")
+	cd12, ok12 := h.GetFloat("CD1_2")
+	cd21, ok21 := h.GetFloat("CD2_1")
+	cd22, ok22 := h.GetFloat("CD2_2")
+
+	cdelt1, okDelt1 := h.GetFloat("CDELT1")
+	cdelt2, okDelt2 := h.GetFloat("CDELT2")
+
+	pc11, okPC11 := h.GetFloat("PC1_1")
+	pc22, okPC22 := h.GetFloat("PC2_2")
+
+	switch {
+	case ok11 || ok12 || ok21 || ok22:
+		w.CD1_1, w.CD1_2, w.CD2_1, w.CD2_2 = cd11, cd12, cd21, cd22
+	case okDelt1 && okDelt2 && (okPC11 || okPC22):
+		// Missing diagonal elements of the PC matrix default to unity, missing off-diagonal elements to zero:
+		if !okPC11 {
+			pc11 = 1
+		}
+
+		if !okPC22 {
+			pc22 = 1
+		}
+
+		pc12, _ := h.GetFloat("PC1_2")
+		pc21, _ := h.GetFloat("PC2_1")
+
+		w.CD1_1, w.CD1_2, w.CD2_1, w.CD2_2 = cdelt1*pc11, cdelt1*pc12, cdelt2*pc21, cdelt2*pc22
+	case okDelt1 && okDelt2:
+		crota, _ := h.GetFloat("CROTA2")
+
+		cos, sin := math.Cos(crota*math.Pi/180), math.Sin(crota*math.Pi/180)
+
+		w.CD1_1, w.CD1_2, w.CD2_1, w.CD2_2 = cdelt1*cos, -cdelt2*sin, cdelt1*sin, cdelt2*cos
+	default:
+		return nil, errors.New("either the CDi_j or CDELTn keywords are required for the world coordinate system")
+	}
+
+	if w.CD1_1*w.CD2_2-w.CD1_2*w.CD2_1 == 0 {
+		return nil, errors.New("the linear transform of the world coordinate system is singular")
+	}
+
+	if sip {
+		aOrder, _ := h.GetFloat("A_ORDER")
+
+		bOrder, _ := h.GetFloat("B_ORDER")
+
+		order := int(math.Max(aOrder, bOrder))
+
+		if order < 0 || order > maxSIPOrder {
+			return nil, fmt.Errorf("unsupported SIP order %d, the order must be at most %d", order, maxSIPOrder)
+		}
+
+		w.SetSIPOrder(order)
+
+		for p := 0; p <= order; p++ {
+			for q := 0; p+q <= order; q++ {
+				if p+q < 2 {
+					continue
+				}
+
+				w.A[p][q], _ = h.GetFloat(fmt.Sprintf("A_%d_%d", p, q))
+
+				w.B[p][q], _ = h.GetFloat(fmt.Sprintf("B_%d_%d", p, q))
+			}
+		}
+	}
+
+	return w, nil
+}
+
+/*****************************************************************************************************************/
+
+// Sets the order of the SIP distortion polynomials, with all of their coefficients zero (zero removes the distortion).
+func (w *WCS) SetSIPOrder(order int) {
+	w.SIPOrder = order
+
+	if order <= 0 {
+		w.SIPOrder, w.A, w.B = 0, nil, nil
+		return
+	}
+
+	w.A, w.B = make([][]float64, order+1), make([][]float64, order+1)
+
+	for p := range w.A {
+		w.A[p], w.B[p] = make([]float64, order+1), make([]float64, order+1)
+	}
+}
+
+/*****************************************************************************************************************/
+
+/*
+WriteToHeader()
+
+Writes the World Coordinate System to the FITS header, i.e., the CTYPEn, CUNITn, CRPIXn, CRVALn
+and CDi_j keywords of the TAN projection, or of the TAN-SIP projection with the A_ORDER, B_ORDER,
+A_p_q and B_p_q keywords if there is distortion.
+
+Any other description of the linear transform (i.e., CDELTn, CROTA2 or PCi_j), or of a previous
+distortion, is removed from the header, such that it cannot take precedence.
+
+N.B. The header stores (and writes) floating point values in single precision, i.e., to about seven
+significant digits, so the written solution is only accurate to better than 0.1" (e.g., a CRVAL1 of
+the order of 300° is rounded to within about 2e-05°), which is well within the seeing of most frames.
+*/
+func (w *WCS) WriteToHeader(h *fits.FITSHeader) {
+	for _, key := range []string{"CDELT1", "CDELT2", "CROTA1", "CROTA2", "PC1_1", "PC1_2", "PC2_1", "PC2_2", "A_ORDER", "B_ORDER"} {
+		delete(h.Floats, key)
+
+		delete(h.Ints, key)
+	}
+
+	for p := 0; p <= maxSIPOrder; p++ {
+		for q := 0; p+q <= maxSIPOrder; q++ {
+			for _, key := range []string{fmt.Sprintf("A_%d_%d", p, q), fmt.Sprintf("B_%d_%d", p, q)} {
+				delete(h.Floats, key)
+
+				delete(h.Ints, key)
+			}
+		}
+	}
+
+	suffix := ""
+
+	if w.SIPOrder > 0 {
+		suffix = "-SIP"
+	}
+
+	h.Set("CTYPE1", "RA---TAN"+suffix, "The gnomonic projection of the Right Ascension")
+	h.Set("CTYPE2", "DEC--TAN"+suffix, "The gnomonic projection of the Declination")
+	h.Set("CUNIT1", "deg", "The unit of the first axis")
+	h.Set("CUNIT2", "deg", "The unit of the second axis")
+	h.Set("CRPIX1", w.CRPIX1, "The x reference pixel")
+	h.Set("CRPIX2", w.CRPIX2, "The y reference pixel")
+	h.Set("CRVAL1", w.CRVAL1, "The Right Ascension of the reference pixel")
+	h.Set("CRVAL2", w.CRVAL2, "The Declination of the reference pixel")
+	h.Set("CD1_1", w.CD1_1, "The linear transform of the pixel offsets")
+	h.Set("CD1_2", w.CD1_2, "The linear transform of the pixel offsets")
+	h.Set("CD2_1", w.CD2_1, "The linear transform of the pixel offsets")
+	h.Set("CD2_2", w.CD2_2, "The linear transform of the pixel offsets")
+
+	if w.SIPOrder <= 0 {
+		return
+	}
+
+	h.Set("A_ORDER", w.SIPOrder, "The order of the SIP x distortion polynomial")
+	h.Set("B_ORDER", w.SIPOrder, "The order of the SIP y distortion polynomial")
+
+	for p := 0; p <= w.SIPOrder; p++ {
+		for q := 0; p+q <= w.SIPOrder; q++ {
+			if p+q < 2 {
+				continue
+			}
+
+			h.Set(fmt.Sprintf("A_%d_%d", p, q), w.A[p][q], "The SIP x distortion coefficient")
+			h.Set(fmt.Sprintf("B_%d_%d", p, q), w.B[p][q], "The SIP y distortion coefficient")
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the pixel coordinates (0-based, i.e., the index of the pixel in the data array) of the equatorial
+// coordinates { ra, dec } (in degrees), returning an error if they are not in the projected hemisphere.
+func (w *WCS) EquatorialToPixel(ra float64, dec float64) (float64, float64, error) {
+	alpha, delta := ra*math.Pi/180, dec*math.Pi/180
+
+	alpha0, delta0 := w.CRVAL1*math.Pi/180, w.CRVAL2*math.Pi/180
+
+	// The cosine of the angular distance from the reference point:
+	cosc := math.Sin(delta0)*math.Sin(delta) + math.Cos(delta0)*math.Cos(delta)*math.Cos(alpha-alpha0)
+
+	if cosc <= 0 {
+		return math.NaN(), math.NaN(), fmt.Errorf("the coordinates { ra: %f, dec: %f } are not in the projected hemisphere", ra, dec)
+	}
+
+	// The intermediate world coordinates, in degrees:
+	xi := math.Cos(delta) * math.Sin(alpha-alpha0) / cosc * 180 / math.Pi
+
+	eta := (math.Cos(delta0)*math.Sin(delta) - math.Sin(delta0)*math.Cos(delta)*math.Cos(alpha-alpha0)) / cosc * 180 / math.Pi
+
+	det := w.CD1_1*w.CD2_2 - w.CD1_2*w.CD2_1
+
+	p := (w.CD2_2*xi - w.CD1_2*eta) / det
+
+	q := (-w.CD2_1*xi + w.CD1_1*eta) / det
+
+	u, v := w.undistort(p, q)
+
+	return u + w.CRPIX1 - 1, v + w.CRPIX2 - 1, nil
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the equatorial coordinates { ra, dec } (in degrees, with ra in [0, 360)) of the pixel coordinates (0-based,
+// i.e., the index of the pixel in the data array).
+func (w *WCS) PixelToEquatorial(x float64, y float64) (float64, float64) {
+	p, q := w.distort(x+1-w.CRPIX1, y+1-w.CRPIX2)
+
+	// The intermediate world coordinates, in radians:
+	xi := (w.CD1_1*p + w.CD1_2*q) * math.Pi / 180
+
+	eta := (w.CD2_1*p + w.CD2_2*q) * math.Pi / 180
+
+	alpha0, delta0 := w.CRVAL1*math.Pi/180, w.CRVAL2*math.Pi/180
+
+	d := math.Cos(delta0) - eta*math.Sin(delta0)
+
+	alpha := alpha0 + math.Atan2(xi, d)
+
+	delta := math.Atan2(math.Sin(delta0)+eta*math.Cos(delta0), math.Hypot(xi, d))
+
+	ra := math.Mod(alpha*180/math.Pi, 360)
+
+	if ra < 0 {
+		ra += 360
+	}
+
+	return ra, delta * 180 / math.Pi
+}
+
+/*****************************************************************************************************************/
+
+// Obtains the pixel scale of the frame, i.e., the geometric mean of the scale along each axis, in arcseconds per pixel.
+func (w *WCS) GetPixelScale() float64 {
+	return math.Sqrt(math.Abs(w.CD1_1*w.CD2_2-w.CD1_2*w.CD2_1)) * 3600
+}
+
+/*****************************************************************************************************************/
+
+// Applies the SIP distortion to the pixel offsets { u, v } from the reference pixel.
+func (w *WCS) distort(u float64, v float64) (float64, float64) {
+	if w.SIPOrder <= 0 {
+		return u, v
+	}
+
+	f, g := 0.0, 0.0
+
+	up := 1.0
+
+	for p := 0; p <= w.SIPOrder; p++ {
+		vq := 1.0
+
+		for q := 0; p+q <= w.SIPOrder; q++ {
+			if p+q >= 2 {
+				f += w.A[p][q] * up * vq
+
+				g += w.B[p][q] * up * vq
+			}
+
+			vq *= v
+		}
+
+		up *= u
+	}
+
+	return u + f, v + g
+}
+
+/*****************************************************************************************************************/
+
+// Inverts the SIP distortion of the distorted pixel offsets { p, q } from the reference pixel, by fixed-point
+// iteration, which converges for the small distortions of astronomical optics.
+func (w *WCS) undistort(p float64, q float64) (float64, float64) {
+	if w.SIPOrder <= 0 {
+		return p, q
+	}
+
+	u, v := p, q
+
+	for iteration := 0; iteration < 50; iteration++ {
+		du, dv := w.distort(u, v)
+
+		u, v = u+p-du, v+q-dv
+
+		if math.Abs(p-du) < 1e-8 && math.Abs(q-dv) < 1e-8 {
+			break
+		}
+	}
+
+	return u, v
+}
+
+/*****************************************************************************************************************/
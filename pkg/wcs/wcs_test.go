@@ -0,0 +1,319 @@
+/*****************************************************************************************************************/
+
+//	@author		Michael Roberts <michael@observerly.com>
+//	@package	@observerly/iris/wcs
+//	@license	Copyright © 2021-2025 observerly
+
+/*****************************************************************************************************************/
+
+package wcs
+
+/*****************************************************************************************************************/
+
+import (
+	"bytes"
+	"math"
+	"testing"
+
+	"github.com/observerly/iris/pkg/fits"
+)
+
+/*****************************************************************************************************************/
+
+// Creates the header of a 800x600 frame centred on M42, with a pixel scale of 1.8"/px rotated by 30°:
+func getTestHeader() fits.FITSHeader {
+	h := fits.NewFITSHeader(2, 800, 600)
+
+	scale, rotation := 0.0005, 30*math.Pi/180
+
+	h.Set("CTYPE1", "RA---TAN", "The projection of the first axis")
+	h.Set("CTYPE2", "DEC--TAN", "The projection of the second axis")
+	h.Set("CRPIX1", 400.5, "The x reference pixel")
+	h.Set("CRPIX2", 300.5, "The y reference pixel")
+	h.Set("CRVAL1", 83.82, "The Right Ascension of the reference pixel")
+	h.Set("CRVAL2", -5.39, "The Declination of the reference pixel")
+	h.Set("CD1_1", -scale*math.Cos(rotation), "")
+	h.Set("CD1_2", -scale*math.Sin(rotation), "")
+	h.Set("CD2_1", -scale*math.Sin(rotation), "")
+	h.Set("CD2_2", scale*math.Cos(rotation), "")
+
+	return h
+}
+
+/*****************************************************************************************************************/
+
+func TestNewWCSFromHeader(t *testing.T) {
+	w, err := NewWCSFromHeader(getTestHeader())
+
+	if err != nil {
+		t.Fatalf("NewWCSFromHeader() failed: %v", err)
+	}
+
+	// The reference pixel (1-based) is at the centre of the 0-based pixel { 399.5, 299.5 }:
+	ra, dec := w.PixelToEquatorial(399.5, 299.5)
+
+	if math.Abs(ra-83.82) > 1e-4 || math.Abs(dec+5.39) > 1e-4 {
+		t.Errorf("expected the reference pixel at { 83.82, -5.39 }, but got { %f, %f }", ra, dec)
+	}
+
+	if math.Abs(w.GetPixelScale()-1.8) > 1e-3 {
+		t.Errorf("expected a pixel scale of 1.8\"/px, but got %f", w.GetPixelScale())
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestWCSRoundTrip(t *testing.T) {
+	w, err := NewWCSFromHeader(getTestHeader())
+
+	if err != nil {
+		t.Fatalf("NewWCSFromHeader() failed: %v", err)
+	}
+
+	for _, p := range [][2]float64{{0, 0}, {799, 0}, {0, 599}, {799, 599}, {123.4, 456.7}} {
+		ra, dec := w.PixelToEquatorial(p[0], p[1])
+
+		x, y, err := w.EquatorialToPixel(ra, dec)
+
+		if err != nil {
+			t.Fatalf("EquatorialToPixel() failed: %v", err)
+		}
+
+		if math.Abs(x-p[0]) > 1e-6 || math.Abs(y-p[1]) > 1e-6 {
+			t.Errorf("expected the pixel { %f, %f }, but got { %f, %f }", p[0], p[1], x, y)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestWCSAcrossZeroRightAscension(t *testing.T) {
+	w := &WCS{CRPIX1: 1, CRPIX2: 1, CRVAL1: 0.01, CRVAL2: 45, CD1_1: -0.001, CD2_2: 0.001}
+
+	// East is left, i.e., increasing x decreases the Right Ascension through zero:
+	ra, dec := w.PixelToEquatorial(100, 0)
+
+	if ra < 359 || ra >= 360 {
+		t.Errorf("expected a Right Ascension just below 360°, but got %f", ra)
+	}
+
+	x, _, err := w.EquatorialToPixel(ra, dec)
+
+	if err != nil || math.Abs(x-100) > 1e-6 {
+		t.Errorf("expected the pixel x = 100, but got %f (%v)", x, err)
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewWCSFromHeaderCDELT(t *testing.T) {
+	h := getTestHeader()
+
+	for _, key := range []string{"CD1_1", "CD1_2", "CD2_1", "CD2_2"} {
+		delete(h.Floats, key)
+	}
+
+	h.Set("CDELT1", -0.0005, "")
+	h.Set("CDELT2", 0.0005, "")
+	h.Set("CROTA2", 30.0, "")
+
+	w, err := NewWCSFromHeader(h)
+
+	if err != nil {
+		t.Fatalf("NewWCSFromHeader() failed: %v", err)
+	}
+
+	expected, _ := NewWCSFromHeader(getTestHeader())
+
+	for i, v := range []float64{w.CD1_1, w.CD1_2, w.CD2_1, w.CD2_2} {
+		e := []float64{expected.CD1_1, expected.CD1_2, expected.CD2_1, expected.CD2_2}[i]
+
+		if math.Abs(v-e) > 1e-8 {
+			t.Errorf("expected CD element %d to be %e, but got %e", i, e, v)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestNewWCSFromHeaderErrors(t *testing.T) {
+	h := getTestHeader()
+
+	h.Set("CTYPE1", "RA---SIN", "")
+
+	if _, err := NewWCSFromHeader(h); err == nil {
+		t.Errorf("expected an error for an unsupported projection")
+	}
+
+	h = getTestHeader()
+
+	delete(h.Floats, "CRVAL1")
+
+	if _, err := NewWCSFromHeader(h); err == nil {
+		t.Errorf("expected an error for a missing CRVAL1 keyword")
+	}
+
+	w, _ := NewWCSFromHeader(getTestHeader())
+
+	// The antipode of the reference point is not in the projected hemisphere:
+	if _, _, err := w.EquatorialToPixel(83.82+180, 5.39); err == nil {
+		t.Errorf("expected an error for coordinates in the opposite hemisphere")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestWCSRoundTripSIP(t *testing.T) {
+	w, err := NewWCSFromHeader(getTestHeader())
+
+	if err != nil {
+		t.Fatalf("NewWCSFromHeader() failed: %v", err)
+	}
+
+	w.SetSIPOrder(3)
+
+	w.A[2][0], w.A[1][1], w.A[0][2], w.A[3][0] = 1e-5, -5e-6, 7.5e-6, 5e-9
+
+	w.B[2][0], w.B[1][1], w.B[0][2], w.B[0][3] = -5e-6, 1e-5, 5e-6, -5e-9
+
+	// The distortion displaces the corners of the frame by a few pixels:
+	ra, dec := w.PixelToEquatorial(0, 0)
+
+	x, y, _ := (&WCS{CRPIX1: w.CRPIX1, CRPIX2: w.CRPIX2, CRVAL1: w.CRVAL1, CRVAL2: w.CRVAL2, CD1_1: w.CD1_1, CD1_2: w.CD1_2, CD2_1: w.CD2_1, CD2_2: w.CD2_2}).EquatorialToPixel(ra, dec)
+
+	if math.Hypot(x, y) < 0.5 {
+		t.Errorf("expected the distortion to displace the corner, but got { %f, %f }", x, y)
+	}
+
+	for _, p := range [][2]float64{{0, 0}, {799, 0}, {0, 599}, {799, 599}, {123.4, 456.7}} {
+		ra, dec := w.PixelToEquatorial(p[0], p[1])
+
+		x, y, err := w.EquatorialToPixel(ra, dec)
+
+		if err != nil {
+			t.Fatalf("EquatorialToPixel() failed: %v", err)
+		}
+
+		if math.Abs(x-p[0]) > 1e-6 || math.Abs(y-p[1]) > 1e-6 {
+			t.Errorf("expected the pixel { %f, %f }, but got { %f, %f }", p[0], p[1], x, y)
+		}
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestWCSWriteToHeader(t *testing.T) {
+	w, err := NewWCSFromHeader(getTestHeader())
+
+	if err != nil {
+		t.Fatalf("NewWCSFromHeader() failed: %v", err)
+	}
+
+	w.SetSIPOrder(2)
+
+	w.A[2][0], w.B[1][1] = 2e-6, -1.5e-6
+
+	h := fits.NewFITSHeader(2, 800, 600)
+
+	// Any previous description of the linear transform is removed:
+	h.Set("CDELT1", 0.1, "")
+	h.Set("CDELT2", 0.1, "")
+	h.Set("CROTA2", 45.0, "")
+
+	w.WriteToHeader(&h)
+
+	if h.Strings["CTYPE1"].Value != "RA---TAN-SIP" || h.Strings["CTYPE2"].Value != "DEC--TAN-SIP" {
+		t.Errorf("expected the TAN-SIP projection, but got %q and %q", h.Strings["CTYPE1"].Value, h.Strings["CTYPE2"].Value)
+	}
+
+	if _, ok := h.Floats["CROTA2"]; ok {
+		t.Errorf("expected CROTA2 to be removed")
+	}
+
+	read, err := NewWCSFromHeader(h)
+
+	if err != nil {
+		t.Fatalf("NewWCSFromHeader() failed: %v", err)
+	}
+
+	if read.SIPOrder != 2 {
+		t.Fatalf("expected a SIP order of 2, but got %d", read.SIPOrder)
+	}
+
+	if math.Abs(read.A[2][0]-2e-6) > 1e-12 || math.Abs(read.B[1][1]+1.5e-6) > 1e-12 {
+		t.Errorf("expected A_2_0 = 2e-6 and B_1_1 = -1.5e-6, but got %g and %g", read.A[2][0], read.B[1][1])
+	}
+
+	for _, p := range [][2]float64{{0, 0}, {799, 599}, {400, 300}} {
+		ra1, dec1 := w.PixelToEquatorial(p[0], p[1])
+
+		ra2, dec2 := read.PixelToEquatorial(p[0], p[1])
+
+		// To the (single) precision of the header, i.e., about 0.05":
+		if math.Abs(ra1-ra2)*3600 > 0.05 || math.Abs(dec1-dec2)*3600 > 0.05 {
+			t.Errorf("expected { %f, %f }, but got { %f, %f }", ra1, dec1, ra2, dec2)
+		}
+	}
+
+	// Without distortion, the SIP keywords are removed:
+	w.SetSIPOrder(0)
+
+	w.WriteToHeader(&h)
+
+	if _, ok := h.Floats["A_2_0"]; ok || h.Strings["CTYPE1"].Value != "RA---TAN" {
+		t.Errorf("expected the SIP keywords to be removed")
+	}
+}
+
+/*****************************************************************************************************************/
+
+func TestWCSWriteToHeaderPrecision(t *testing.T) {
+	scale, rotation := 0.000312345678, 123.456789*math.Pi/180
+
+	// A reference at a large Right Ascension, i.e., with the coarsest single precision rounding of CRVAL1:
+	w := &WCS{
+		CRPIX1: 2048.123456,
+		CRPIX2: 1536.654321,
+		CRVAL1: 299.868123456789,
+		CRVAL2: 40.733987654321,
+		CD1_1:  -scale * math.Cos(rotation),
+		CD1_2:  scale * math.Sin(rotation),
+		CD2_1:  scale * math.Sin(rotation),
+		CD2_2:  scale * math.Cos(rotation),
+	}
+
+	h := fits.NewFITSHeader(2, 4096, 3072)
+
+	w.WriteToHeader(&h)
+
+	buf := new(bytes.Buffer)
+
+	h.WriteToBuffer(buf)
+
+	written := fits.NewFITSHeader(2, 4096, 3072)
+
+	if err := written.Read(buf); err != nil {
+		t.Fatalf("Header.Read() failed: %v", err)
+	}
+
+	read, err := NewWCSFromHeader(written)
+
+	if err != nil {
+		t.Fatalf("NewWCSFromHeader() failed: %v", err)
+	}
+
+	for _, p := range [][2]float64{{0, 0}, {4095, 0}, {0, 3071}, {4095, 3071}, {2047, 1535}} {
+		ra1, dec1 := w.PixelToEquatorial(p[0], p[1])
+
+		ra2, dec2 := read.PixelToEquatorial(p[0], p[1])
+
+		// The angular separation (in arcseconds) of the written solution from the original:
+		separation := math.Hypot((ra1-ra2)*math.Cos(dec1*math.Pi/180), dec1-dec2) * 3600
+
+		if separation > 0.1 {
+			t.Errorf("expected the written solution to be within 0.1\" at { %g, %g }, but it is %.4f\" away", p[0], p[1], separation)
+		}
+	}
+}
+
+/*****************************************************************************************************************/